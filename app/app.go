@@ -2,6 +2,7 @@ package app
 
 import (
 	"io"
+	"log/slog"
 
 	"github.com/lyuangg/gadmin/config"
 	"github.com/lyuangg/gadmin/database"
@@ -23,21 +24,46 @@ type App struct {
 	Responder response.IResponder
 
 	CaptchaProvider services.CaptchaProvider
-	TokenGenerator services.TokenGenerator
+	TokenGenerator  services.TokenGenerator
+	Authenticators  []services.Authenticator
+	// 多实例部署时在 NewApp 之后注入，用于同步权限缓存失效；为 nil 时只清空本实例缓存
+	PermissionCacheBroadcaster services.PermissionCacheBroadcaster
 
 	AuthService         services.IAuthService
+	SessionService      services.ISessionService
+	TwoFactorService    services.ITwoFactorService
+	LoginLockService    services.ILoginLockService
+	PasswordService     services.IPasswordService
+	OIDCService         services.IOIDCService
+	APIKeyService       services.IAPIKeyService
 	UserService         services.IUserService
 	RoleService         services.IRoleService
 	PermissionService   services.IPermissionService
 	OperationLogService services.IOperationLogService
 	DictionaryService   services.IDictionaryService
+	IPRuleService       services.IIPRuleService
+	DataScopeService    services.IDataScopeService
+	DepartmentService   services.IDepartmentService
+	PositionService     services.IPositionService
+	MenuService         services.IMenuService
+
+	SecurityEventService services.ISecurityEventService
 }
 
 // NewApp 若初始化失败会 panic
 func NewApp(cfg *config.Config) *App {
+	return newApp(cfg, database.InitDB)
+}
+
+// NewAppWithoutMigrate 只连接数据库，不迁移表结构、不写入默认数据，供 -scan-dry-run 等只读命令使用；若初始化失败会 panic
+func NewAppWithoutMigrate(cfg *config.Config) *App {
+	return newApp(cfg, database.OpenDB)
+}
+
+func newApp(cfg *config.Config, openDB func(*config.Config, *slog.Logger) (*gorm.DB, error)) *App {
 	slogLogger, logHandler := logger.NewLogger(cfg)
 
-	db, err := database.InitDB(cfg, slogLogger)
+	db, err := openDB(cfg, slogLogger)
 	if err != nil {
 		panic("数据库初始化失败: " + err.Error())
 	}
@@ -50,15 +76,28 @@ func NewApp(cfg *config.Config) *App {
 		Responder: response.NewResponder(slogLogger),
 	}
 
-	app.CaptchaProvider = services.NewRealCaptchaProvider()
+	app.CaptchaProvider = services.NewCaptchaProvider(cfg, db)
 	app.TokenGenerator = services.NewRealTokenGenerator()
+	app.Authenticators = services.NewAuthenticators(app)
 
 	app.AuthService = services.NewAuthService(app)
+	app.SessionService = services.NewSessionService(app)
+	app.TwoFactorService = services.NewTwoFactorService(app)
+	app.LoginLockService = services.NewLoginLockService(app)
+	app.PasswordService = services.NewPasswordService(app)
+	app.OIDCService = services.NewOIDCService(app)
+	app.APIKeyService = services.NewAPIKeyService(app)
 	app.UserService = services.NewUserService(app)
 	app.RoleService = services.NewRoleService(app)
 	app.PermissionService = services.NewPermissionService(app)
 	app.OperationLogService = services.NewOperationLogService(app)
 	app.DictionaryService = services.NewDictionaryService(app)
+	app.IPRuleService = services.NewIPRuleService(app)
+	app.DataScopeService = services.NewDataScopeService(app)
+	app.DepartmentService = services.NewDepartmentService(app)
+	app.PositionService = services.NewPositionService(app)
+	app.MenuService = services.NewMenuService(app)
+	app.SecurityEventService = services.NewSecurityEventService(app)
 
 	return app
 }
@@ -83,10 +122,44 @@ func (a *App) GetTokenGenerator() services.TokenGenerator {
 	return a.TokenGenerator
 }
 
+// GetAuthenticators 为空时 AuthService 只使用本地密码认证
+func (a *App) GetAuthenticators() []services.Authenticator {
+	return a.Authenticators
+}
+
+// GetPermissionCacheBroadcaster 为 nil 时权限变更只使本实例缓存失效，其他实例按 permission_cache_seconds 过期
+func (a *App) GetPermissionCacheBroadcaster() services.PermissionCacheBroadcaster {
+	return a.PermissionCacheBroadcaster
+}
+
 func (a *App) GetAuthService() services.IAuthService {
 	return a.AuthService
 }
 
+func (a *App) GetSessionService() services.ISessionService {
+	return a.SessionService
+}
+
+func (a *App) GetTwoFactorService() services.ITwoFactorService {
+	return a.TwoFactorService
+}
+
+func (a *App) GetLoginLockService() services.ILoginLockService {
+	return a.LoginLockService
+}
+
+func (a *App) GetPasswordService() services.IPasswordService {
+	return a.PasswordService
+}
+
+func (a *App) GetOIDCService() services.IOIDCService {
+	return a.OIDCService
+}
+
+func (a *App) GetAPIKeyService() services.IAPIKeyService {
+	return a.APIKeyService
+}
+
 func (a *App) GetUserService() services.IUserService {
 	return a.UserService
 }
@@ -107,6 +180,30 @@ func (a *App) GetDictionaryService() services.IDictionaryService {
 	return a.DictionaryService
 }
 
+func (a *App) GetIPRuleService() services.IIPRuleService {
+	return a.IPRuleService
+}
+
+func (a *App) GetDataScopeService() services.IDataScopeService {
+	return a.DataScopeService
+}
+
+func (a *App) GetDepartmentService() services.IDepartmentService {
+	return a.DepartmentService
+}
+
+func (a *App) GetPositionService() services.IPositionService {
+	return a.PositionService
+}
+
+func (a *App) GetMenuService() services.IMenuService {
+	return a.MenuService
+}
+
+func (a *App) GetSecurityEventService() services.ISecurityEventService {
+	return a.SecurityEventService
+}
+
 // RegisterCloser 注册退出时需关闭的对象
 func (a *App) RegisterCloser(c io.Closer) {
 	if c != nil {
@@ -14,11 +14,24 @@ import (
 // ServiceMocks 单测用：可注入的 service 接口 mock，仅需提供被测 controller 用到的 service
 type ServiceMocks struct {
 	AuthService         services.IAuthService
+	SessionService      services.ISessionService
+	TwoFactorService    services.ITwoFactorService
+	LoginLockService    services.ILoginLockService
+	PasswordService     services.IPasswordService
+	OIDCService         services.IOIDCService
+	APIKeyService       services.IAPIKeyService
 	UserService         services.IUserService
 	RoleService         services.IRoleService
 	PermissionService   services.IPermissionService
 	OperationLogService services.IOperationLogService
 	DictionaryService   services.IDictionaryService
+	IPRuleService       services.IIPRuleService
+	DataScopeService    services.IDataScopeService
+	DepartmentService   services.IDepartmentService
+	PositionService     services.IPositionService
+	MenuService         services.IMenuService
+
+	SecurityEventService services.ISecurityEventService
 }
 
 // NewTestAppWithServiceMocks 供 controller 单测用：不设置 db，仅注入 mock service；未提供的 service 为 nil，调用会 panic。
@@ -41,14 +54,30 @@ func newTestAppWithLoggerAndResponder(l logger.ILogger, resp response.IResponder
 		Responder:       resp,
 		CaptchaProvider: nil,
 		TokenGenerator:  nil,
+		// 安全事件是旁路记录，未注入时用不落库的 Fake，避免每个中间件单测都要提供
+		SecurityEventService: &services.FakeSecurityEventService{},
 	}
 	if mocks != nil {
 		a.AuthService = mocks.AuthService
+		a.SessionService = mocks.SessionService
+		a.TwoFactorService = mocks.TwoFactorService
+		a.LoginLockService = mocks.LoginLockService
+		a.PasswordService = mocks.PasswordService
+		a.OIDCService = mocks.OIDCService
+		a.APIKeyService = mocks.APIKeyService
 		a.UserService = mocks.UserService
 		a.RoleService = mocks.RoleService
 		a.PermissionService = mocks.PermissionService
 		a.OperationLogService = mocks.OperationLogService
 		a.DictionaryService = mocks.DictionaryService
+		a.IPRuleService = mocks.IPRuleService
+		a.DataScopeService = mocks.DataScopeService
+		a.DepartmentService = mocks.DepartmentService
+		a.PositionService = mocks.PositionService
+		a.MenuService = mocks.MenuService
+		if mocks.SecurityEventService != nil {
+			a.SecurityEventService = mocks.SecurityEventService
+		}
 	}
 	return a
 }
@@ -78,10 +107,22 @@ func NewTestAppWithMocks(db *gorm.DB, captcha services.CaptchaProvider, tokenGen
 		TokenGenerator:  tokenGen,
 	}
 	a.AuthService = services.NewAuthService(a)
+	a.SessionService = services.NewSessionService(a)
+	a.TwoFactorService = services.NewTwoFactorService(a)
+	a.LoginLockService = services.NewLoginLockService(a)
+	a.PasswordService = services.NewPasswordService(a)
+	a.OIDCService = services.NewOIDCService(a)
+	a.APIKeyService = services.NewAPIKeyService(a)
 	a.UserService = services.NewUserService(a)
 	a.RoleService = services.NewRoleService(a)
 	a.PermissionService = services.NewPermissionService(a)
 	a.OperationLogService = services.NewOperationLogService(a)
 	a.DictionaryService = services.NewDictionaryService(a)
+	a.IPRuleService = services.NewIPRuleService(a)
+	a.DataScopeService = services.NewDataScopeService(a)
+	a.DepartmentService = services.NewDepartmentService(a)
+	a.PositionService = services.NewPositionService(a)
+	a.MenuService = services.NewMenuService(a)
+	a.SecurityEventService = services.NewSecurityEventService(a)
 	return a
 }
@@ -8,6 +8,9 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// DefaultJWTSecret 未配置 jwt_secret 时的占位密钥，release 模式下拒绝使用
+const DefaultJWTSecret = "your-secret-key-change-in-production"
+
 type Config struct {
 	DBHost                  string `yaml:"db_host"`
 	DBPort                  string `yaml:"db_port"`
@@ -25,7 +28,80 @@ type Config struct {
 	DBSlowThresholdMs       int    `yaml:"db_slow_threshold_ms"`       // SQL 慢查询阈值（毫秒）
 	DBLogColorful           bool   `yaml:"db_log_colorful"`            // SQL 日志是否带颜色（仅终端友好，文件建议关闭）
 	DBTablePrefix           string `yaml:"db_table_prefix"`            // 数据库表前缀
-	OperationLogRetainCount int    `yaml:"operation_log_retain_count"` // 操作日志保留条数，每日凌晨清理时保留最近 N 条，默认 10000
+	OperationLogRetainCount int    `yaml:"operation_log_retain_count"` // 操作日志与安全事件各自的保留条数，每日凌晨清理时保留最近 N 条，默认 10000
+	AccessTokenTTLMinutes   int    `yaml:"access_token_ttl_minutes"`   // 访问 Token 有效期（分钟），默认 15
+	RefreshTokenTTLHours    int    `yaml:"refresh_token_ttl_hours"`    // 刷新 Token 有效期（小时），默认 168（7 天）
+	TOTPIssuer              string `yaml:"totp_issuer"`                // 两步验证在验证器 App 中显示的发行方名称，默认 gadmin
+	ImpersonationTTLMinutes int    `yaml:"impersonation_ttl_minutes"`  // 超级管理员模拟登录 Token 的有效期（分钟），到期不自动续期，默认 30
+	PermissionCacheSeconds  int    `yaml:"permission_cache_seconds"`   // 角色权限匹配器在内存中的缓存时间（秒），本实例修改权限时立即失效，默认 60
+	PermissionOrphanPolicy  string `yaml:"permission_orphan_policy"`   // 路由删除后其自动导入权限的处理: mark（标记失效，保留角色分配）或 delete（删除并解除角色分配），默认 mark
+
+	// 登录防暴力破解
+	LoginMaxFailures          int `yaml:"login_max_failures"`           // 同一用户名连续登录失败多少次后锁定，默认 5
+	LoginMaxFailuresPerIP     int `yaml:"login_max_failures_per_ip"`    // 同一 IP 连续登录失败多少次后锁定，默认 20
+	LoginFailureWindowMinutes int `yaml:"login_failure_window_minutes"` // 失败计数窗口（分钟），超过该时间未再失败则重新计数，默认 15
+	LoginLockoutMinutes       int `yaml:"login_lockout_minutes"`        // 首次锁定时长（分钟），之后每次锁定翻倍，默认 5
+	LoginLockoutMaxMinutes    int `yaml:"login_lockout_max_minutes"`    // 锁定时长上限（分钟），默认 1440（1 天）
+
+	// 密码策略：创建用户、修改密码、管理员改密与重置密码统一校验
+	PasswordMinLength  int      `yaml:"password_min_length"`   // 密码最小长度，默认 8
+	PasswordMinClasses *int     `yaml:"password_min_classes"`  // 至少包含几类字符（大写字母、小写字母、数字、符号），默认 3，0 表示不要求
+	PasswordDenyList   []string `yaml:"password_deny_list"`    // 额外禁止使用的密码（在内置常见弱密码之外），不区分大小写
+	PasswordHistory    *int     `yaml:"password_history"`      // 不能与最近 N 次使用过的密码相同，默认 5，0 表示不限制
+	PasswordMaxAgeDays int      `yaml:"password_max_age_days"` // 密码有效期（天），过期后下次登录须修改密码，0 表示不限制
+
+	// 图形验证码
+	CaptchaStore         string `yaml:"captcha_store"`          // 验证码存储: memory（仅单实例）或 db（多实例部署共享），默认 memory
+	CaptchaType          string `yaml:"captcha_type"`           // 验证码类型: digit（数字）、math（算术）、alphanumeric（字母数字），默认 digit
+	CaptchaWidth         int    `yaml:"captcha_width"`          // 图片宽度（像素），默认 240
+	CaptchaHeight        int    `yaml:"captcha_height"`         // 图片高度（像素），默认 80
+	CaptchaLength        int    `yaml:"captcha_length"`         // 字符个数（math 类型不使用），默认 4
+	CaptchaExpireSeconds int    `yaml:"captcha_expire_seconds"` // 验证码有效期（秒），默认 300
+	CaptchaRateLimit     int    `yaml:"captcha_rate_limit"`     // 每个 IP 每分钟最多获取的验证码数，默认 20
+
+	// 登录认证方式与 LDAP / Active Directory
+	AuthProviders    []string          `yaml:"auth_providers"`     // 依次尝试的认证方式: local（本地密码）、ldap，默认仅 local
+	LDAPURL          string            `yaml:"ldap_url"`           // LDAP 地址，如 ldap://ldap.example.com:389 或 ldaps://ad.example.com:636
+	LDAPStartTLS     bool              `yaml:"ldap_start_tls"`     // ldap:// 连接后是否升级为 TLS
+	LDAPSkipVerify   bool              `yaml:"ldap_skip_verify"`   // 跳过 TLS 证书校验（仅测试环境）
+	LDAPBindDN       string            `yaml:"ldap_bind_dn"`       // 查询用户用的服务账号 DN，为空时匿名查询
+	LDAPBindPassword string            `yaml:"ldap_bind_password"` // 服务账号密码
+	LDAPBaseDN       string            `yaml:"ldap_base_dn"`       // 用户查询的起始 DN
+	LDAPUserFilter   string            `yaml:"ldap_user_filter"`   // 用户查询条件，%s 替换为用户名，默认 (uid=%s)；AD 可用 (sAMAccountName=%s)
+	LDAPNicknameAttr string            `yaml:"ldap_nickname_attr"` // 作为昵称的属性，默认 cn；AD 可用 displayName
+	LDAPGroupAttr    string            `yaml:"ldap_group_attr"`    // 用户所属组的属性，默认 memberOf
+	LDAPGroupRoles   map[string]string `yaml:"ldap_group_roles"`   // 组（完整 DN 或 CN）到角色名的映射；配置后每次登录按组同步角色
+
+	// OpenID Connect 单点登录（授权码 + PKCE），配置 oidc_issuer 后登录页显示单点登录入口
+	OIDCIssuer        string   `yaml:"oidc_issuer"`         // IdP 的 issuer，用于获取 /.well-known/openid-configuration
+	OIDCClientID      string   `yaml:"oidc_client_id"`      // 在 IdP 注册的客户端 ID
+	OIDCClientSecret  string   `yaml:"oidc_client_secret"`  // 客户端密钥，公共客户端可为空（仅 PKCE）
+	OIDCRedirectURL   string   `yaml:"oidc_redirect_url"`   // 回调地址，须与 IdP 中登记的一致，如 https://admin.example.com/api/oidc/callback
+	OIDCScopes        []string `yaml:"oidc_scopes"`         // 申请的 scope，默认 openid profile email
+	OIDCUsernameClaim string   `yaml:"oidc_username_claim"` // 作为用户名的 claim，默认 preferred_username；首次登录按此关联同名账号
+	OIDCLinkLocal     bool     `yaml:"oidc_link_local"`     // 首次登录时是否关联同名的本地账号（超级管理员除外），默认只关联单点登录创建的账号
+
+	// JWT 非对称签名：配置 jwt_keys 后改用 RS256 / EdDSA 签名，公钥通过 /.well-known/jwks.json 公开
+	JWTKeys         []JWTKey `yaml:"jwt_keys"`        // 签名与验签密钥，按 kid 区分；轮换时新增密钥并切换 jwt_signing_kid，旧密钥保留到其签发的 Token 全部过期
+	JWTSigningKeyID string   `yaml:"jwt_signing_kid"` // 当前用于签名的密钥 kid，默认 jwt_keys 中第一个配置了私钥的
+
+	// Cookie 属性：登录 Token、刷新 Token 与 CSRF Token 的 cookie 共用
+	CookieSecure   bool   `yaml:"cookie_secure"`    // 仅通过 HTTPS 发送，生产环境使用 HTTPS 时应开启
+	CookieSameSite string `yaml:"cookie_same_site"` // lax（默认）、strict 或 none；none 须同时开启 cookie_secure，否则按 lax 处理
+	CookieDomain   string `yaml:"cookie_domain"`    // cookie 的 Domain，为空时只对当前域名有效
+
+	// 查询参数 Token：只有显式允许的路由（如文件下载）接受，且须是为该路径单独签发的短期 Token
+	QueryTokenTTLSeconds int `yaml:"query_token_ttl_seconds"` // 有效期（秒），默认 60
+
+	// 反向代理：只有来自这些地址的请求头 X-Forwarded-For / X-Real-IP 才被采信为客户端 IP（IP 规则、日志与登录锁定都依赖它）
+	TrustedProxies []string `yaml:"trusted_proxies"` // 代理的 IP 或网段，如 ["10.0.0.0/8"]；为空时不信任任何代理，客户端 IP 即连接的对端地址
+}
+
+// JWTKey 一把 JWT 密钥，算法由密钥类型决定：RSA 为 RS256，Ed25519 为 EdDSA
+type JWTKey struct {
+	KID            string `yaml:"kid"`              // 密钥标识，写入 Token 头部的 kid
+	PrivateKeyFile string `yaml:"private_key_file"` // PEM 私钥文件（PKCS#8，RSA 也可为 PKCS#1），用于签名
+	PublicKeyFile  string `yaml:"public_key_file"`  // PEM 公钥文件（PKIX），只验签的旧密钥可只配置公钥
 }
 
 func Load(configPath string) (*Config, error) {
@@ -73,7 +149,7 @@ func loadFromEnv(cfg *Config) {
 		cfg.DBName = getEnv("DB_NAME", "gadmin")
 	}
 	if cfg.JWTSecret == "" {
-		cfg.JWTSecret = getEnv("JWT_SECRET", "your-secret-key-change-in-production")
+		cfg.JWTSecret = getEnv("JWT_SECRET", DefaultJWTSecret)
 	}
 	if cfg.Port == "" {
 		cfg.Port = getEnv("PORT", "8080")
@@ -110,6 +186,165 @@ func loadFromEnv(cfg *Config) {
 	if cfg.OperationLogRetainCount <= 0 {
 		cfg.OperationLogRetainCount = getEnvInt("OPERATION_LOG_RETAIN_COUNT", 10000)
 	}
+	if cfg.AccessTokenTTLMinutes <= 0 {
+		cfg.AccessTokenTTLMinutes = getEnvInt("ACCESS_TOKEN_TTL_MINUTES", 15)
+	}
+	if cfg.RefreshTokenTTLHours <= 0 {
+		cfg.RefreshTokenTTLHours = getEnvInt("REFRESH_TOKEN_TTL_HOURS", 168)
+	}
+	if cfg.PermissionCacheSeconds <= 0 {
+		cfg.PermissionCacheSeconds = getEnvInt("PERMISSION_CACHE_SECONDS", 60)
+	}
+	if cfg.PermissionOrphanPolicy == "" {
+		cfg.PermissionOrphanPolicy = getEnv("PERMISSION_ORPHAN_POLICY", "mark")
+	}
+	if cfg.ImpersonationTTLMinutes <= 0 {
+		cfg.ImpersonationTTLMinutes = getEnvInt("IMPERSONATION_TTL_MINUTES", 30)
+	}
+	if cfg.TOTPIssuer == "" {
+		cfg.TOTPIssuer = getEnv("TOTP_ISSUER", "gadmin")
+	}
+	if cfg.LoginMaxFailures <= 0 {
+		cfg.LoginMaxFailures = getEnvInt("LOGIN_MAX_FAILURES", 5)
+	}
+	if cfg.LoginMaxFailuresPerIP <= 0 {
+		cfg.LoginMaxFailuresPerIP = getEnvInt("LOGIN_MAX_FAILURES_PER_IP", 20)
+	}
+	if cfg.LoginFailureWindowMinutes <= 0 {
+		cfg.LoginFailureWindowMinutes = getEnvInt("LOGIN_FAILURE_WINDOW_MINUTES", 15)
+	}
+	if cfg.LoginLockoutMinutes <= 0 {
+		cfg.LoginLockoutMinutes = getEnvInt("LOGIN_LOCKOUT_MINUTES", 5)
+	}
+	if cfg.LoginLockoutMaxMinutes <= 0 {
+		cfg.LoginLockoutMaxMinutes = getEnvInt("LOGIN_LOCKOUT_MAX_MINUTES", 1440)
+	}
+	if cfg.PasswordMinLength <= 0 {
+		cfg.PasswordMinLength = getEnvInt("PASSWORD_MIN_LENGTH", 8)
+	}
+	// 0 是有效值，只在未配置时取默认值
+	if cfg.PasswordMinClasses == nil {
+		cfg.PasswordMinClasses = getEnvIntPtr("PASSWORD_MIN_CLASSES", 3)
+	}
+	if len(cfg.PasswordDenyList) == 0 {
+		if v := os.Getenv("PASSWORD_DENY_LIST"); v != "" {
+			cfg.PasswordDenyList = strings.Split(v, ",")
+		}
+	}
+	if cfg.PasswordHistory == nil {
+		cfg.PasswordHistory = getEnvIntPtr("PASSWORD_HISTORY", 5)
+	}
+	if cfg.PasswordMaxAgeDays <= 0 {
+		cfg.PasswordMaxAgeDays = getEnvInt("PASSWORD_MAX_AGE_DAYS", 0)
+	}
+	if cfg.CaptchaStore == "" {
+		cfg.CaptchaStore = getEnv("CAPTCHA_STORE", "memory")
+	}
+	if cfg.CaptchaType == "" {
+		cfg.CaptchaType = getEnv("CAPTCHA_TYPE", "digit")
+	}
+	if cfg.CaptchaWidth <= 0 {
+		cfg.CaptchaWidth = getEnvInt("CAPTCHA_WIDTH", 240)
+	}
+	if cfg.CaptchaHeight <= 0 {
+		cfg.CaptchaHeight = getEnvInt("CAPTCHA_HEIGHT", 80)
+	}
+	if cfg.CaptchaLength <= 0 {
+		cfg.CaptchaLength = getEnvInt("CAPTCHA_LENGTH", 4)
+	}
+	if cfg.CaptchaExpireSeconds <= 0 {
+		cfg.CaptchaExpireSeconds = getEnvInt("CAPTCHA_EXPIRE_SECONDS", 300)
+	}
+	if cfg.CaptchaRateLimit <= 0 {
+		cfg.CaptchaRateLimit = getEnvInt("CAPTCHA_RATE_LIMIT", 20)
+	}
+	if len(cfg.AuthProviders) == 0 {
+		cfg.AuthProviders = strings.Split(getEnv("AUTH_PROVIDERS", "local"), ",")
+	}
+	if cfg.LDAPURL == "" {
+		cfg.LDAPURL = getEnv("LDAP_URL", "")
+	}
+	if !cfg.LDAPStartTLS {
+		if v := os.Getenv("LDAP_START_TLS"); v == "1" || strings.ToLower(v) == "true" {
+			cfg.LDAPStartTLS = true
+		}
+	}
+	if !cfg.LDAPSkipVerify {
+		if v := os.Getenv("LDAP_SKIP_VERIFY"); v == "1" || strings.ToLower(v) == "true" {
+			cfg.LDAPSkipVerify = true
+		}
+	}
+	if cfg.LDAPBindDN == "" {
+		cfg.LDAPBindDN = getEnv("LDAP_BIND_DN", "")
+	}
+	if cfg.LDAPBindPassword == "" {
+		cfg.LDAPBindPassword = getEnv("LDAP_BIND_PASSWORD", "")
+	}
+	if cfg.LDAPBaseDN == "" {
+		cfg.LDAPBaseDN = getEnv("LDAP_BASE_DN", "")
+	}
+	if cfg.LDAPUserFilter == "" {
+		cfg.LDAPUserFilter = getEnv("LDAP_USER_FILTER", "(uid=%s)")
+	}
+	if cfg.LDAPNicknameAttr == "" {
+		cfg.LDAPNicknameAttr = getEnv("LDAP_NICKNAME_ATTR", "cn")
+	}
+	if cfg.LDAPGroupAttr == "" {
+		cfg.LDAPGroupAttr = getEnv("LDAP_GROUP_ATTR", "memberOf")
+	}
+	if cfg.OIDCIssuer == "" {
+		cfg.OIDCIssuer = getEnv("OIDC_ISSUER", "")
+	}
+	if cfg.OIDCClientID == "" {
+		cfg.OIDCClientID = getEnv("OIDC_CLIENT_ID", "")
+	}
+	if cfg.OIDCClientSecret == "" {
+		cfg.OIDCClientSecret = getEnv("OIDC_CLIENT_SECRET", "")
+	}
+	if cfg.OIDCRedirectURL == "" {
+		cfg.OIDCRedirectURL = getEnv("OIDC_REDIRECT_URL", "")
+	}
+	if len(cfg.OIDCScopes) == 0 {
+		cfg.OIDCScopes = strings.Split(getEnv("OIDC_SCOPES", "openid,profile,email"), ",")
+	}
+	if cfg.OIDCUsernameClaim == "" {
+		cfg.OIDCUsernameClaim = getEnv("OIDC_USERNAME_CLAIM", "preferred_username")
+	}
+	if !cfg.OIDCLinkLocal {
+		if v := os.Getenv("OIDC_LINK_LOCAL"); v == "1" || strings.ToLower(v) == "true" {
+			cfg.OIDCLinkLocal = true
+		}
+	}
+	if len(cfg.JWTKeys) == 0 {
+		// JWT_KEY_FILES 格式: kid=私钥文件路径，多个用逗号分隔
+		for _, item := range strings.Split(os.Getenv("JWT_KEY_FILES"), ",") {
+			if kid, file, ok := strings.Cut(strings.TrimSpace(item), "="); ok {
+				cfg.JWTKeys = append(cfg.JWTKeys, JWTKey{KID: kid, PrivateKeyFile: file})
+			}
+		}
+	}
+	if cfg.JWTSigningKeyID == "" {
+		cfg.JWTSigningKeyID = getEnv("JWT_SIGNING_KID", "")
+	}
+	if !cfg.CookieSecure {
+		if v := os.Getenv("COOKIE_SECURE"); v == "1" || strings.ToLower(v) == "true" {
+			cfg.CookieSecure = true
+		}
+	}
+	if cfg.CookieSameSite == "" {
+		cfg.CookieSameSite = getEnv("COOKIE_SAME_SITE", "lax")
+	}
+	if cfg.CookieDomain == "" {
+		cfg.CookieDomain = getEnv("COOKIE_DOMAIN", "")
+	}
+	if cfg.QueryTokenTTLSeconds <= 0 {
+		cfg.QueryTokenTTLSeconds = getEnvInt("QUERY_TOKEN_TTL_SECONDS", 60)
+	}
+	if len(cfg.TrustedProxies) == 0 {
+		if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+			cfg.TrustedProxies = strings.Split(v, ",")
+		}
+	}
 }
 
 func getEnvInt(key string, defaultValue int) int {
@@ -122,6 +357,18 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvIntPtr 与 getEnvInt 相同，但 0 也是有效值，用于 0 表示关闭的配置项
+func getEnvIntPtr(key string, defaultValue int) *int {
+	n := defaultValue
+	if value := os.Getenv(key); value != "" {
+		var v int
+		if _, err := fmt.Sscanf(value, "%d", &v); err == nil && v >= 0 {
+			n = v
+		}
+	}
+	return &n
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
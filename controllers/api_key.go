@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController struct {
+	app *app.App
+}
+
+func NewAPIKeyController(a *app.App) *APIKeyController {
+	return &APIKeyController{app: a}
+}
+
+// GetMyKeys 当前用户的 API Key 列表
+func (ctrl *APIKeyController) GetMyKeys(c *gin.Context) {
+	claims, ok := utils.ClaimsFromContext(c)
+	if !ok {
+		ctrl.app.Responder.RespondError(c, errors.UnauthorizedMsg("未认证"))
+		return
+	}
+
+	keys, err := ctrl.app.GetAPIKeyService().GetUserKeys(c, claims.UserID)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.Success(c, keys)
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string     `json:"name" binding:"required"`
+	ExpiresAt     *time.Time `json:"expires_at"` // 为空表示永不过期
+	PermissionIDs []uint     `json:"permission_ids" binding:"required"`
+}
+
+// CreateMyKey 当前用户创建 API Key，原文只在本次响应中返回
+func (ctrl *APIKeyController) CreateMyKey(c *gin.Context) {
+	claims, ok := utils.ClaimsFromContext(c)
+	if !ok {
+		ctrl.app.Responder.RespondError(c, errors.UnauthorizedMsg("未认证"))
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	key, raw, err := ctrl.app.GetAPIKeyService().CreateKey(c, claims.UserID, req.Name, req.ExpiresAt, req.PermissionIDs)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.SuccessWithMsg(c, "API Key已创建，请立即保存，关闭后无法再次查看", gin.H{
+		"key":     raw,
+		"api_key": key,
+	})
+}
+
+// RevokeMyKey 当前用户删除自己的 API Key
+func (ctrl *APIKeyController) RevokeMyKey(c *gin.Context) {
+	claims, ok := utils.ClaimsFromContext(c)
+	if !ok {
+		ctrl.app.Responder.RespondError(c, errors.UnauthorizedMsg("未认证"))
+		return
+	}
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的API Key ID"))
+		return
+	}
+
+	if err := ctrl.app.GetAPIKeyService().RevokeKey(c, uint(keyID), claims.UserID); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.SuccessWithMsg(c, "API Key已删除", nil)
+}
+
+type getAPIKeysQuery struct {
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+	UserID   string `form:"user_id"`
+	Username string `form:"username"`
+}
+
+// GetKeys 管理端查询所有用户的 API Key
+func (ctrl *APIKeyController) GetKeys(c *gin.Context) {
+	var req getAPIKeysQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+	page, pageSize := req.Page, req.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	filters := map[string]string{
+		"user_id":  req.UserID,
+		"username": req.Username,
+	}
+
+	keys, total, err := ctrl.app.GetAPIKeyService().GetKeys(c, page, pageSize, filters)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.Success(c, gin.H{
+		"data": keys,
+		"pagination": gin.H{
+			"page":       page,
+			"page_size":  pageSize,
+			"total":      total,
+			"total_page": (int(total) + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// RevokeKey 管理端删除任意 API Key
+func (ctrl *APIKeyController) RevokeKey(c *gin.Context) {
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的API Key ID"))
+		return
+	}
+
+	if err := ctrl.app.GetAPIKeyService().RevokeKey(c, uint(keyID), 0); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.SuccessWithMsg(c, "API Key已删除", nil)
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/services"
+	"github.com/lyuangg/gadmin/utils"
+)
+
+func TestAPIKeyController_CreateMyKey(t *testing.T) {
+	keyMock := &services.FakeAPIKeyService{
+		CreateResult: &models.APIKey{ID: 1, Name: "deploy", Prefix: "gak_abcdefgh"},
+		CreateRaw:    "gak_abcdefgh-secret",
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{APIKeyService: keyMock})
+	ctrl := NewAPIKeyController(a)
+
+	body := []byte(`{"name":"deploy","expires_at":"2030-01-01T00:00:00Z","permission_ids":[1]}`)
+	c, w := newGinContextWithClaims(http.MethodPost, "/admin/api/profile/api-keys", body, &utils.Claims{UserID: 1})
+	ctrl.CreateMyKey(c)
+
+	var resp struct {
+		Code int `json:"code"`
+		Data struct {
+			Key    string         `json:"key"`
+			APIKey map[string]any `json:"api_key"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Code != 0 || resp.Data.Key != "gak_abcdefgh-secret" || resp.Data.APIKey["prefix"] != "gak_abcdefgh" {
+		t.Errorf("resp = %s", w.Body.String())
+	}
+	if _, leaked := resp.Data.APIKey["key_hash"]; leaked {
+		t.Error("key hash must not be returned")
+	}
+}
+
+func TestAPIKeyController_RevokeMyKey_BadID(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{APIKeyService: &services.FakeAPIKeyService{}})
+	ctrl := NewAPIKeyController(a)
+
+	c, w := newGinContextWithClaims(http.MethodDelete, "/admin/api/profile/api-keys/x", nil, &utils.Claims{UserID: 1})
+	ctrl.RevokeMyKey(c)
+
+	var resp struct {
+		Code int `json:"code"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Code == 0 {
+		t.Errorf("expected error for invalid id, got %s", w.Body.String())
+	}
+}
@@ -1,9 +1,15 @@
 package controllers
 
 import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
 	"github.com/lyuangg/gadmin/app"
 	"github.com/lyuangg/gadmin/errors"
 	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/services"
 	"github.com/lyuangg/gadmin/utils"
 
 	"github.com/gin-gonic/gin"
@@ -29,6 +35,9 @@ type LoginResponse struct {
 	User  interface{} `json:"user"`
 }
 
+// refreshTokenCookiePath 刷新 Token cookie 只随刷新接口发送，减少暴露面
+const refreshTokenCookiePath = "/api/token"
+
 func (ctrl *AuthController) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -36,12 +45,76 @@ func (ctrl *AuthController) Login(c *gin.Context) {
 		return
 	}
 
-	user, token, err := ctrl.app.GetAuthService().Login(c, req.Username, req.Password, req.CaptchaID, req.CaptchaVal)
+	result, err := ctrl.app.GetAuthService().Login(c, req.Username, req.Password, req.CaptchaID, req.CaptchaVal, clientInfo(c))
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.respondLoginResult(c, result)
+}
+
+// respondLoginResult 需要两步验证时返回登录挑战，否则返回登录成功数据
+func (ctrl *AuthController) respondLoginResult(c *gin.Context, result *services.LoginResult) {
+	if result.ChallengeToken != "" {
+		ctrl.app.Responder.Success(c, gin.H{
+			"two_factor_required":  true,
+			"setup_required":       result.SetupRequired,
+			"challenge_token":      result.ChallengeToken,
+			"challenge_expires_in": result.ChallengeExpiresIn,
+		})
+		return
+	}
+
+	ctrl.respondLoginSuccess(c, result)
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// LoginTwoFactor 登录第二步：提交验证码或备用码，通过后签发 Token
+func (ctrl *AuthController) LoginTwoFactor(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	result, err := ctrl.app.GetAuthService().CompleteTwoFactorLogin(c, req.ChallengeToken, req.Code, clientInfo(c))
 	if err != nil {
 		ctrl.app.Responder.RespondError(c, err)
 		return
 	}
 
+	ctrl.respondLoginSuccess(c, result)
+}
+
+type TwoFactorSetupRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+// LoginTwoFactorSetup 登录第二步中，被角色强制要求但尚未绑定的用户获取绑定密钥
+func (ctrl *AuthController) LoginTwoFactorSetup(c *gin.Context) {
+	var req TwoFactorSetupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	setup, err := ctrl.app.GetAuthService().BeginTwoFactorSetup(c, req.ChallengeToken)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.Success(c, setup)
+}
+
+// respondLoginSuccess 写入 Token cookie 并返回登录成功数据；刷新 Token 只写入 HttpOnly cookie，不放入响应体
+func (ctrl *AuthController) respondLoginSuccess(c *gin.Context, result *services.LoginResult) {
+	user, tokens := result.User, result.Tokens
 	userResponse := gin.H{
 		"id":       user.ID,
 		"username": user.Username,
@@ -50,14 +123,137 @@ func (ctrl *AuthController) Login(c *gin.Context) {
 		"roles":    user.Roles,
 	}
 
-	c.SetCookie("token", token, 24*3600, "/", "", false, true)
+	ctrl.setTokenCookies(c, tokens, true)
 
-	ctrl.app.Responder.Success(c, gin.H{
-		"token": token,
-		"user":  userResponse,
+	data := gin.H{
+		"token":              tokens.AccessToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_expires_in": tokens.RefreshIn,
+		"user":               userResponse,
+	}
+	if len(result.BackupCodes) > 0 {
+		data["backup_codes"] = result.BackupCodes
+	}
+	if result.MustChangePassword {
+		data["must_change_password"] = true
+		data["password_expired"] = result.PasswordExpired
+	}
+	ctrl.app.Responder.Success(c, data)
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshToken 用刷新 Token 换取新 Token；请求体未携带时从 refresh_token cookie 读取
+func (ctrl *AuthController) RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+			return
+		}
+	}
+	if req.RefreshToken == "" {
+		if cookie, err := c.Cookie("refresh_token"); err == nil {
+			req.RefreshToken = cookie
+		}
+	}
+
+	tokens, err := ctrl.app.GetAuthService().RefreshToken(c, req.RefreshToken, clientInfo(c))
+	if err != nil {
+		ctrl.clearTokenCookies(c)
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.setTokenCookies(c, tokens, false)
+	ctrl.app.Responder.Success(c, tokens)
+}
+
+// clientInfo 提取请求方 IP 与 User-Agent，用于会话记录
+func clientInfo(c *gin.Context) services.ClientInfo {
+	return services.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// currentJTI 当前请求所属会话的 jti（未经认证中间件时为空）
+func currentJTI(c *gin.Context) string {
+	if claims, ok := utils.ClaimsFromContext(c); ok {
+		return claims.ID
+	}
+	return ""
+}
+
+// setTokenCookies 写入 Token cookie 与 CSRF Token cookie；登录时（rotateCSRF）重新生成 CSRF Token，刷新时沿用已有的
+func (ctrl *AuthController) setTokenCookies(c *gin.Context, tokens *services.TokenPair, rotateCSRF bool) {
+	cfg := ctrl.app.Config
+	utils.SetCookie(c, cfg, "token", tokens.AccessToken, int(tokens.ExpiresIn), "/", true)
+	utils.SetCookie(c, cfg, "refresh_token", tokens.RefreshToken, int(tokens.RefreshIn), refreshTokenCookiePath, true)
+
+	csrfToken, _ := c.Cookie(utils.CSRFCookieName)
+	if rotateCSRF || csrfToken == "" {
+		var err error
+		if csrfToken, err = utils.RandomToken(32); err != nil {
+			ctrl.app.Logger().ErrorContext(c, "生成 CSRF Token 失败", "error", err)
+			return
+		}
+	}
+	// 前端须读取后放入请求头，不能设置 HttpOnly
+	utils.SetCookie(c, cfg, utils.CSRFCookieName, csrfToken, int(tokens.RefreshIn), "/", false)
+}
+
+func (ctrl *AuthController) clearTokenCookies(c *gin.Context) {
+	cfg := ctrl.app.Config
+	utils.SetCookie(c, cfg, "token", "", -1, "/", true)
+	utils.SetCookie(c, cfg, "refresh_token", "", -1, refreshTokenCookiePath, true)
+	utils.SetCookie(c, cfg, utils.CSRFCookieName, "", -1, "/", false)
+}
+
+// Impersonate 超级管理员模拟登录指定用户：token cookie 换成被模拟用户的短期 Token，刷新 Token 仍是发起人的，
+// 退出模拟时前端调用 /api/token/refresh 即可换回自己的身份
+func (ctrl *AuthController) Impersonate(c *gin.Context) {
+	claims, ok := utils.ClaimsFromContext(c)
+	if !ok {
+		ctrl.app.Responder.RespondError(c, errors.UnauthorizedMsg("未认证"))
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的用户ID"))
+		return
+	}
+
+	result, err := ctrl.app.GetAuthService().Impersonate(c, claims, uint(userID), clientInfo(c))
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	utils.SetCookie(c, ctrl.app.Config, "token", result.Token, int(result.ExpiresIn), "/", true)
+	user := result.User
+	ctrl.app.Responder.SuccessWithMsg(c, "已开始模拟登录", gin.H{
+		"token":      result.Token,
+		"expires_in": result.ExpiresIn,
+		"user": gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+			"nickname": user.Nickname,
+			"avatar":   user.Avatar,
+			"roles":    user.Roles,
+		},
+		"impersonator": gin.H{
+			"id":       claims.UserID,
+			"username": claims.Username,
+		},
 	})
 }
 
+// JWKS 公开签名公钥（RFC 7517 格式，不包装统一响应体），供其他服务验证本系统签发的 Token
+func (ctrl *AuthController) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.JWKS())
+}
+
 func (ctrl *AuthController) GetCaptcha(c *gin.Context) {
 	id, b64s, err := ctrl.app.GetAuthService().GenerateCaptcha(c)
 	if err != nil {
@@ -79,14 +275,27 @@ func (ctrl *AuthController) Logout(c *gin.Context) {
 	}
 	userModel := user.(models.User)
 
-	if err := ctrl.app.GetAuthService().Logout(c, userModel.ID); err != nil {
+	// 模拟登录期间的会话与刷新 Token 属于发起人，退出即结束发起人的会话
+	userID, username := userModel.ID, userModel.Username
+	if claims, ok := utils.ClaimsFromContext(c); ok && claims.ImpersonatorID != 0 {
+		userID, username = claims.ImpersonatorID, claims.ImpersonatorName
+	}
+
+	if err := ctrl.app.GetAuthService().Logout(c, userID, currentJTI(c)); err != nil {
 		ctrl.app.Responder.RespondError(c, err)
 		return
 	}
 
-	ctrl.app.Logger().InfoContext(c, "用户退出登录", "user_id", userModel.ID, "username", userModel.Username)
+	ctrl.app.Logger().InfoContext(c, "用户退出登录", "user_id", userID, "username", username)
+	ctrl.app.GetSecurityEventService().Record(c, &models.SecurityEvent{
+		EventType: models.SecurityEventLogout,
+		UserID:    userID,
+		Username:  username,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
 
-	c.SetCookie("token", "", -1, "/", "", false, true)
+	ctrl.clearTokenCookies(c)
 	ctrl.app.Responder.SuccessWithMsg(c, "退出登录成功", nil)
 }
 
@@ -109,7 +318,7 @@ func (ctrl *AuthController) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	if err := ctrl.app.GetAuthService().ChangePassword(c, userModel.ID, req.OldPassword, req.NewPassword); err != nil {
+	if err := ctrl.app.GetAuthService().ChangePassword(c, userModel.ID, currentJTI(c), req.OldPassword, req.NewPassword); err != nil {
 		ctrl.app.Responder.RespondError(c, err)
 		return
 	}
@@ -143,6 +352,41 @@ func (ctrl *AuthController) UpdateAvatar(c *gin.Context) {
 	ctrl.app.Responder.SuccessWithMsg(c, "头像更新成功", nil)
 }
 
+type CreateQueryTokenRequest struct {
+	Path string `json:"path" binding:"required"`
+}
+
+// CreateQueryToken 为 path 签发短期 Token，前端拼成 path?token=... 供文件下载等链接使用；
+// 只有显式接受查询参数 Token 的路由会认它，权限仍按当前会话校验
+func (ctrl *AuthController) CreateQueryToken(c *gin.Context) {
+	claims, ok := utils.ClaimsFromContext(c)
+	if !ok {
+		ctrl.app.Responder.RespondError(c, errors.UnauthorizedMsg("未认证"))
+		return
+	}
+
+	var req CreateQueryTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+	if !strings.HasPrefix(req.Path, "/admin/") || path.Clean(req.Path) != req.Path || strings.ContainsAny(req.Path, "?#") {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("path 须为 /admin/ 下的请求路径，不含查询参数"))
+		return
+	}
+
+	token, err := utils.GenerateQueryToken(claims, req.Path)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.Success(c, gin.H{
+		"token":      token,
+		"expires_in": int64(utils.QueryTokenTTL().Seconds()),
+	})
+}
+
 func (ctrl *AuthController) GetUserPermissions(c *gin.Context) {
 	claims, ok := utils.ClaimsFromContext(c)
 	if !ok {
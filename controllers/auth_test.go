@@ -1,13 +1,22 @@
 package controllers
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/config"
+	"github.com/lyuangg/gadmin/errors"
 	"github.com/lyuangg/gadmin/models"
 	"github.com/lyuangg/gadmin/services"
+	"github.com/lyuangg/gadmin/utils"
 )
 
 func TestAuthController_Login_BadRequest(t *testing.T) {
@@ -34,9 +43,11 @@ func TestAuthController_Login_BadRequest(t *testing.T) {
 
 func TestAuthController_Login_Success(t *testing.T) {
 	authMock := &services.FakeAuthService{
-		LoginUser:  &models.User{ID: 1, Username: "ctrluser", Nickname: "测试", Avatar: "", Type: 0, Status: 1, Roles: nil},
-		LoginToken: "test-token",
-		LoginErr:   nil,
+		LoginResult: &services.LoginResult{
+			User:   &models.User{ID: 1, Username: "ctrluser", Nickname: "测试", Avatar: "", Type: 0, Status: 1, Roles: nil},
+			Tokens: &services.TokenPair{AccessToken: "test-token", RefreshToken: "test-refresh", ExpiresIn: 900, RefreshIn: 3600},
+		},
+		LoginErr: nil,
 	}
 	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{AuthService: authMock})
 	ctrl := NewAuthController(a)
@@ -67,6 +78,130 @@ func TestAuthController_Login_Success(t *testing.T) {
 	if data["token"] != "test-token" {
 		t.Errorf("expected token test-token, got %v", data["token"])
 	}
+	if _, ok := data["refresh_token"]; ok {
+		t.Error("refresh token should only be sent in the HttpOnly cookie")
+	}
+	var refreshCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "refresh_token" {
+			refreshCookie = cookie
+		}
+	}
+	if refreshCookie == nil || refreshCookie.Value != "test-refresh" || !refreshCookie.HttpOnly {
+		t.Errorf("refresh_token cookie = %+v", refreshCookie)
+	}
+}
+
+func TestAuthController_Login_MustChangePassword(t *testing.T) {
+	authMock := &services.FakeAuthService{
+		LoginResult: &services.LoginResult{
+			User:               &models.User{ID: 1, Username: "ctrluser"},
+			Tokens:             &services.TokenPair{AccessToken: "test-token", RefreshToken: "test-refresh"},
+			MustChangePassword: true,
+		},
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{AuthService: authMock})
+	ctrl := NewAuthController(a)
+
+	body, _ := json.Marshal(map[string]string{
+		"username":    "ctrluser",
+		"password":    "pass123",
+		"captcha_id":  "any",
+		"captcha_val": "any",
+	})
+	c, w := newGinContext(http.MethodPost, "/api/login", body)
+	ctrl.Login(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	data, _ := resp["data"].(map[string]interface{})
+	if data == nil || data["must_change_password"] != true || data["password_expired"] != false || data["token"] != "test-token" {
+		t.Errorf("unexpected data: %s", w.Body.Bytes())
+	}
+}
+
+func TestAuthController_Login_TwoFactorChallenge(t *testing.T) {
+	authMock := &services.FakeAuthService{
+		LoginResult: &services.LoginResult{
+			User:               &models.User{ID: 1, Username: "ctrluser"},
+			ChallengeToken:     "challenge",
+			ChallengeExpiresIn: 300,
+		},
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{AuthService: authMock})
+	ctrl := NewAuthController(a)
+
+	body, _ := json.Marshal(map[string]string{
+		"username":    "ctrluser",
+		"password":    "pass123",
+		"captcha_id":  "any",
+		"captcha_val": "any",
+	})
+	c, w := newGinContext(http.MethodPost, "/api/login", body)
+	ctrl.Login(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	data, _ := resp["data"].(map[string]interface{})
+	if data == nil || data["two_factor_required"] != true || data["challenge_token"] != "challenge" {
+		t.Fatalf("unexpected data: %s", w.Body.Bytes())
+	}
+	if _, ok := data["token"]; ok {
+		t.Error("token must not be issued before 2FA")
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("no cookies should be set before 2FA")
+	}
+}
+
+func TestAuthController_LoginTwoFactor(t *testing.T) {
+	authMock := &services.FakeAuthService{
+		TwoFactorLoginResult: &services.LoginResult{
+			User:        &models.User{ID: 1, Username: "ctrluser"},
+			Tokens:      &services.TokenPair{AccessToken: "2fa-token", RefreshToken: "2fa-refresh", ExpiresIn: 900, RefreshIn: 3600},
+			BackupCodes: []string{"abcd-efgh"},
+		},
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{AuthService: authMock})
+	ctrl := NewAuthController(a)
+
+	body, _ := json.Marshal(map[string]string{"challenge_token": "challenge", "code": "123456"})
+	c, w := newGinContext(http.MethodPost, "/api/login/2fa", body)
+	ctrl.LoginTwoFactor(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	data, _ := resp["data"].(map[string]interface{})
+	if data == nil || data["token"] != "2fa-token" {
+		t.Fatalf("unexpected data: %s", w.Body.Bytes())
+	}
+	if codes, _ := data["backup_codes"].([]interface{}); len(codes) != 1 {
+		t.Errorf("backup_codes = %v", data["backup_codes"])
+	}
+}
+
+func TestAuthController_LoginTwoFactor_Error(t *testing.T) {
+	authMock := &services.FakeAuthService{TwoFactorLoginErr: errors.UnauthorizedMsg("验证码错误")}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{AuthService: authMock})
+	ctrl := NewAuthController(a)
+
+	body, _ := json.Marshal(map[string]string{"challenge_token": "challenge", "code": "000000"})
+	c, w := newGinContext(http.MethodPost, "/api/login/2fa", body)
+	ctrl.LoginTwoFactor(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if code, _ := resp["code"].(float64); code == 0 {
+		t.Errorf("expected error response, body=%s", w.Body.Bytes())
+	}
 }
 
 func TestAuthController_GetCaptcha(t *testing.T) {
@@ -134,3 +269,178 @@ func TestAuthController_Logout_Success(t *testing.T) {
 		t.Errorf("expected code 0, got %v", resp["code"])
 	}
 }
+
+// 模拟登录期间退出：吊销的是发起人的会话，安全事件记在发起人名下
+func TestAuthController_Logout_Impersonating(t *testing.T) {
+	authMock := &services.FakeAuthService{}
+	events := &services.FakeSecurityEventService{}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{AuthService: authMock, SecurityEventService: events})
+	ctrl := NewAuthController(a)
+
+	u := models.User{ID: 5, Username: "ops", Status: 1}
+	c, w := newGinContextWithUser(http.MethodPost, "/api/logout", nil, u)
+	claims := &utils.Claims{UserID: 5, Username: "ops", ImpersonatorID: 1, ImpersonatorName: "root"}
+	claims.ID = "sess-root"
+	c.Set("claims", claims)
+	ctrl.Logout(c)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); code != 0 {
+		t.Fatalf("expected code 0, got %s", w.Body.Bytes())
+	}
+	if authMock.LogoutUserID != 1 {
+		t.Errorf("Logout user = %d, want impersonator 1", authMock.LogoutUserID)
+	}
+	if len(events.Recorded) != 1 || events.Recorded[0].UserID != 1 || events.Recorded[0].Username != "root" {
+		t.Errorf("recorded events = %+v", events.Recorded)
+	}
+}
+
+func TestAuthController_RefreshToken(t *testing.T) {
+	authMock := &services.FakeAuthService{
+		RefreshTokens: &services.TokenPair{AccessToken: "new-token", RefreshToken: "new-refresh", ExpiresIn: 900, RefreshIn: 3600},
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{AuthService: authMock})
+	ctrl := NewAuthController(a)
+
+	c, w := newGinContext(http.MethodPost, "/api/token/refresh", []byte(`{"refresh_token":"old-refresh"}`))
+	ctrl.RefreshToken(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if code, _ := resp["code"].(float64); code != 0 {
+		t.Fatalf("expected code 0, got %v", resp["code"])
+	}
+	data, _ := resp["data"].(map[string]interface{})
+	if data["token"] != "new-token" {
+		t.Errorf("tokens mismatch: %v", data)
+	}
+	if _, ok := data["refresh_token"]; ok {
+		t.Error("refresh token should only be sent in the HttpOnly cookie")
+	}
+	names := map[string]bool{}
+	for _, cookie := range w.Result().Cookies() {
+		names[cookie.Name] = true
+	}
+	if !names["token"] || !names["refresh_token"] || !names[utils.CSRFCookieName] {
+		t.Errorf("expected token, refresh_token and csrf_token cookies, got %v", names)
+	}
+}
+
+func TestAuthController_RefreshToken_Error(t *testing.T) {
+	authMock := &services.FakeAuthService{RefreshErr: errors.UnauthorizedMsg("刷新Token已失效")}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{AuthService: authMock})
+	ctrl := NewAuthController(a)
+
+	c, w := newGinContext(http.MethodPost, "/api/token/refresh", nil)
+	ctrl.RefreshToken(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if code, _ := resp["code"].(float64); code != float64(errors.CodeUnauthorized) {
+		t.Errorf("expected code %d, got %v", errors.CodeUnauthorized, resp["code"])
+	}
+}
+
+func TestAuthController_JWKS(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(priv)
+	keyFile := filepath.Join(t.TempDir(), "jwt.pem")
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	if err := utils.InitJWT(&config.Config{JWTKeys: []config.JWTKey{{KID: "k1", PrivateKeyFile: keyFile}}}); err != nil {
+		t.Fatalf("InitJWT: %v", err)
+	}
+	t.Cleanup(func() { utils.InitJWT(&config.Config{JWTSecret: "test-secret"}) })
+
+	ctrl := NewAuthController(app.NewTestAppWithServiceMocks(nil))
+	c, w := newGinContextGET("/.well-known/jwks.json")
+	ctrl.JWKS(c)
+
+	var body struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(body.Keys) != 1 || body.Keys[0]["kid"] != "k1" || body.Keys[0]["alg"] != "EdDSA" || body.Keys[0]["d"] != "" {
+		t.Errorf("jwks = %s", w.Body.String())
+	}
+}
+
+func TestAuthController_CreateQueryToken(t *testing.T) {
+	if err := utils.InitJWT(&config.Config{JWTSecret: "test-secret"}); err != nil {
+		t.Fatalf("InitJWT: %v", err)
+	}
+	ctrl := NewAuthController(app.NewTestAppWithServiceMocks(&app.ServiceMocks{}))
+	claims := &utils.Claims{UserID: 1, Username: "admin"}
+	claims.ID = "sess-1"
+
+	c, w := newGinContextWithClaims(http.MethodPost, "/admin/api/profile/query-token", []byte(`{"path":"/admin/download/1"}`), claims)
+	ctrl.CreateQueryToken(c)
+	var resp struct {
+		Code int `json:"code"`
+		Data struct {
+			Token     string `json:"token"`
+			ExpiresIn int64  `json:"expires_in"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Code != 0 || resp.Data.ExpiresIn != 60 {
+		t.Fatalf("resp = %+v", resp)
+	}
+	if _, err := utils.ParseQueryToken(resp.Data.Token, "/admin/download/1"); err != nil {
+		t.Errorf("ParseQueryToken: %v", err)
+	}
+
+	for _, path := range []string{"/api/token/refresh", "/admin/../api/login", "/admin/download/1?x=1"} {
+		c, w := newGinContextWithClaims(http.MethodPost, "/admin/api/profile/query-token", []byte(`{"path":"`+path+`"}`), claims)
+		ctrl.CreateQueryToken(c)
+		var body map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		if code, _ := body["code"].(float64); code != float64(errors.CodeBadRequest) {
+			t.Errorf("path %q: expected code %d, got %v", path, errors.CodeBadRequest, body["code"])
+		}
+	}
+}
+
+func TestAuthController_Impersonate(t *testing.T) {
+	authMock := &services.FakeAuthService{ImpersonateResult: &services.ImpersonationResult{
+		User:      &models.User{ID: 5, Username: "ops"},
+		Token:     "imp-token",
+		ExpiresIn: 1800,
+	}}
+	ctrl := NewAuthController(app.NewTestAppWithServiceMocks(&app.ServiceMocks{AuthService: authMock}))
+	claims := &utils.Claims{UserID: 1, Username: "root", IsSuperAdmin: true}
+
+	c, w := newGinContextWithParam(http.MethodPost, "/admin/api/users/5/impersonate", nil, "id", "5")
+	c.Set("claims", claims)
+	ctrl.Impersonate(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	data, _ := resp["data"].(map[string]interface{})
+	if code, _ := resp["code"].(float64); code != 0 || data["token"] != "imp-token" {
+		t.Fatalf("resp = %v", resp)
+	}
+	if impersonator, _ := data["impersonator"].(map[string]interface{}); impersonator["username"] != "root" {
+		t.Errorf("impersonator = %v", data["impersonator"])
+	}
+	var tokenCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "token" {
+			tokenCookie = cookie
+		}
+	}
+	if tokenCookie == nil || tokenCookie.Value != "imp-token" || tokenCookie.MaxAge != 1800 || !tokenCookie.HttpOnly {
+		t.Errorf("token cookie = %+v", tokenCookie)
+	}
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DepartmentController struct {
+	app *app.App
+}
+
+func NewDepartmentController(a *app.App) *DepartmentController {
+	return &DepartmentController{app: a}
+}
+
+type getDepartmentTreeQuery struct {
+	Name   string `form:"name"`
+	Status string `form:"status"`
+}
+
+// GetTree 获取部门树
+func (ctrl *DepartmentController) GetTree(c *gin.Context) {
+	var req getDepartmentTreeQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+	filters := map[string]string{
+		"name":   req.Name,
+		"status": req.Status,
+	}
+	tree, err := ctrl.app.GetDepartmentService().GetDepartmentTree(c.Request.Context(), filters)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.Success(c, gin.H{"data": tree})
+}
+
+type CreateDepartmentRequest struct {
+	ParentID uint   `json:"parent_id"`
+	Name     string `json:"name" binding:"required"`
+	LeaderID uint   `json:"leader_id"`
+	Sort     int    `json:"sort"`
+	Status   *int   `json:"status"` // 不传时为启用
+}
+
+func (ctrl *DepartmentController) CreateDepartment(c *gin.Context) {
+	var req CreateDepartmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+	status := 1
+	if req.Status != nil && *req.Status == 0 {
+		status = 0
+	}
+
+	dept, err := ctrl.app.GetDepartmentService().CreateDepartment(c.Request.Context(), req.ParentID, req.Name, req.LeaderID, req.Sort, status)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.SuccessWithMsg(c, "创建成功", dept)
+}
+
+// UpdateDepartmentRequest 未传的字段保持不变；parent_id 传 0 移到顶级，leader_id 传 0 清除负责人
+type UpdateDepartmentRequest struct {
+	ParentID *uint  `json:"parent_id"`
+	Name     string `json:"name"`
+	LeaderID *uint  `json:"leader_id"`
+	Sort     *int   `json:"sort"`
+	Status   *int   `json:"status"`
+}
+
+func (ctrl *DepartmentController) UpdateDepartment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的部门ID"))
+		return
+	}
+
+	var req UpdateDepartmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	dept, err := ctrl.app.GetDepartmentService().UpdateDepartment(c.Request.Context(), uint(id), req.ParentID, req.Name, req.LeaderID, req.Sort, req.Status)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.SuccessWithMsg(c, "更新成功", dept)
+}
+
+func (ctrl *DepartmentController) DeleteDepartment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的部门ID"))
+		return
+	}
+
+	if err := ctrl.app.GetDepartmentService().DeleteDepartment(c.Request.Context(), uint(id)); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.SuccessWithMsg(c, "删除成功", nil)
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/services"
+)
+
+func TestDepartmentController_GetTree(t *testing.T) {
+	deptMock := &services.FakeDepartmentService{
+		GetDepartmentTreeList: []*models.Department{
+			{ID: 1, Name: "总部", Children: []*models.Department{{ID: 2, ParentID: 1, Name: "研发"}}},
+		},
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{DepartmentService: deptMock})
+	ctrl := NewDepartmentController(a)
+
+	c, w := newGinContextGET("/api/departments/tree")
+	ctrl.GetTree(c)
+
+	var resp struct {
+		Code int `json:"code"`
+		Data struct {
+			Data []models.Department `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Code != 0 || len(resp.Data.Data) != 1 || len(resp.Data.Data[0].Children) != 1 {
+		t.Errorf("unexpected response: %s", w.Body.Bytes())
+	}
+}
+
+func TestDepartmentController_CreateDepartment(t *testing.T) {
+	deptMock := &services.FakeDepartmentService{
+		CreateDepartmentResult: &models.Department{ID: 1, Name: "研发", Status: 1},
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{DepartmentService: deptMock})
+	ctrl := NewDepartmentController(a)
+
+	body, _ := json.Marshal(map[string]interface{}{"name": "研发", "parent_id": 0})
+	c, w := newGinContext(http.MethodPost, "/api/departments", body)
+	ctrl.CreateDepartment(c)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); code != 0 {
+		t.Errorf("expected code 0, got %v body=%s", resp["code"], w.Body.Bytes())
+	}
+
+	// 缺少名称
+	c, w = newGinContext(http.MethodPost, "/api/departments", []byte(`{"parent_id":1}`))
+	ctrl.CreateDepartment(c)
+	resp = nil
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); code == 0 {
+		t.Error("expected error when name is missing")
+	}
+}
+
+func TestDepartmentController_DeleteDepartment(t *testing.T) {
+	deptMock := &services.FakeDepartmentService{DeleteDepartmentErr: errors.BadRequestMsg("请先删除下级部门")}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{DepartmentService: deptMock})
+	ctrl := NewDepartmentController(a)
+
+	c, w := newGinContextWithParam(http.MethodDelete, "/api/departments/1", nil, "id", "1")
+	ctrl.DeleteDepartment(c)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["msg"] != "请先删除下级部门" {
+		t.Errorf("expected service error, got %s", w.Body.Bytes())
+	}
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IPRuleController struct {
+	app *app.App
+}
+
+func NewIPRuleController(a *app.App) *IPRuleController {
+	return &IPRuleController{app: a}
+}
+
+type getIPRulesQuery struct {
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+	Scope    string `form:"scope"`
+	UserID   string `form:"user_id"`
+	Action   string `form:"action"`
+	CIDR     string `form:"cidr"`
+}
+
+// GetRules 查询全局与用户 IP 规则
+func (ctrl *IPRuleController) GetRules(c *gin.Context) {
+	var req getIPRulesQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+	page, pageSize := req.Page, req.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	filters := map[string]string{
+		"scope":   req.Scope,
+		"user_id": req.UserID,
+		"action":  req.Action,
+		"cidr":    req.CIDR,
+	}
+
+	rules, total, err := ctrl.app.GetIPRuleService().GetRules(c, page, pageSize, filters)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.Success(c, gin.H{
+		"data": rules,
+		"pagination": gin.H{
+			"page":       page,
+			"page_size":  pageSize,
+			"total":      total,
+			"total_page": (int(total) + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+type CreateIPRuleRequest struct {
+	UserID uint   `json:"user_id"` // 为 0 时为全局规则
+	Action string `json:"action" binding:"required,oneof=allow deny"`
+	CIDR   string `json:"cidr" binding:"required"` // 网段或单个 IP
+	Remark string `json:"remark"`
+}
+
+// CreateRule 创建 IP 规则，保存后当前 IP 将无法访问时拒绝
+func (ctrl *IPRuleController) CreateRule(c *gin.Context) {
+	var req CreateIPRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	rule, err := ctrl.app.GetIPRuleService().CreateRule(c, req.UserID, req.Action, req.CIDR, req.Remark, operatorID(c), c.ClientIP())
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.SuccessWithMsg(c, "创建成功", rule)
+}
+
+type UpdateIPRuleRequest struct {
+	Action string `json:"action" binding:"required,oneof=allow deny"`
+	CIDR   string `json:"cidr" binding:"required"`
+	Remark string `json:"remark"`
+}
+
+// UpdateRule 修改 IP 规则
+func (ctrl *IPRuleController) UpdateRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的规则ID"))
+		return
+	}
+
+	var req UpdateIPRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	rule, err := ctrl.app.GetIPRuleService().UpdateRule(c, uint(id), req.Action, req.CIDR, req.Remark, operatorID(c), c.ClientIP())
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.SuccessWithMsg(c, "更新成功", rule)
+}
+
+// DeleteRule 删除 IP 规则
+func (ctrl *IPRuleController) DeleteRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的规则ID"))
+		return
+	}
+
+	if err := ctrl.app.GetIPRuleService().DeleteRule(c, uint(id), operatorID(c), c.ClientIP()); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.SuccessWithMsg(c, "删除成功", nil)
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/services"
+)
+
+func TestIPRuleController_CreateRule(t *testing.T) {
+	ruleMock := &services.FakeIPRuleService{CreateRuleResult: &models.IPRule{ID: 1, Action: models.IPRuleAllow, CIDR: "10.0.0.0/8"}}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{IPRuleService: ruleMock})
+	ctrl := NewIPRuleController(a)
+
+	c, w := newGinContext(http.MethodPost, "/admin/api/ip-rules", []byte(`{"action":"allow","cidr":"10.0.0.0/8"}`))
+	ctrl.CreateRule(c)
+
+	var resp struct {
+		Code int           `json:"code"`
+		Data models.IPRule `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Code != 0 || resp.Data.CIDR != "10.0.0.0/8" {
+		t.Errorf("body=%s", w.Body.Bytes())
+	}
+}
+
+func TestIPRuleController_CreateRule_InvalidAction(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{IPRuleService: &services.FakeIPRuleService{}})
+	ctrl := NewIPRuleController(a)
+
+	c, w := newGinContext(http.MethodPost, "/admin/api/ip-rules", []byte(`{"action":"block","cidr":"10.0.0.0/8"}`))
+	ctrl.CreateRule(c)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); code != float64(errors.CodeBadRequest) {
+		t.Errorf("expected code %d, got %v", errors.CodeBadRequest, resp["code"])
+	}
+}
+
+func TestIPRuleController_DeleteRule_BadID(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{IPRuleService: &services.FakeIPRuleService{}})
+	ctrl := NewIPRuleController(a)
+
+	c, w := newGinContextWithParam(http.MethodDelete, "/admin/api/ip-rules/abc", nil, "id", "abc")
+	ctrl.DeleteRule(c)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); code != float64(errors.CodeBadRequest) {
+		t.Errorf("expected code %d, got %v", errors.CodeBadRequest, resp["code"])
+	}
+}
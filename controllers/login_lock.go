@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoginLockController struct {
+	app *app.App
+}
+
+func NewLoginLockController(a *app.App) *LoginLockController {
+	return &LoginLockController{app: a}
+}
+
+type getLoginLocksQuery struct {
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+	Scope    string `form:"scope"`
+	Target   string `form:"target"`
+	Action   string `form:"action"`
+}
+
+// GetLocks 查询当前被锁定的用户名与 IP
+func (ctrl *LoginLockController) GetLocks(c *gin.Context) {
+	var req getLoginLocksQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+	page, pageSize := req.Page, req.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	filters := map[string]string{
+		"scope":  req.Scope,
+		"target": req.Target,
+	}
+
+	locks, total, err := ctrl.app.GetLoginLockService().GetLocks(c, page, pageSize, filters)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.Success(c, gin.H{
+		"data": locks,
+		"pagination": gin.H{
+			"page":       page,
+			"page_size":  pageSize,
+			"total":      total,
+			"total_page": (int(total) + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// GetEvents 查询锁定与解锁的审计记录
+func (ctrl *LoginLockController) GetEvents(c *gin.Context) {
+	var req getLoginLocksQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+	page, pageSize := req.Page, req.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	filters := map[string]string{
+		"scope":  req.Scope,
+		"target": req.Target,
+		"action": req.Action,
+	}
+
+	events, total, err := ctrl.app.GetLoginLockService().GetEvents(c, page, pageSize, filters)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.Success(c, gin.H{
+		"data": events,
+		"pagination": gin.H{
+			"page":       page,
+			"page_size":  pageSize,
+			"total":      total,
+			"total_page": (int(total) + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// Unlock 按锁定记录 ID 解除锁定
+func (ctrl *LoginLockController) Unlock(c *gin.Context) {
+	lockID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的锁定记录ID"))
+		return
+	}
+
+	if err := ctrl.app.GetLoginLockService().Unlock(c, uint(lockID), operatorID(c)); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.SuccessWithMsg(c, "已解除锁定", nil)
+}
+
+// UnlockUser 解锁指定用户的账户
+func (ctrl *LoginLockController) UnlockUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的用户ID"))
+		return
+	}
+
+	if err := ctrl.app.GetLoginLockService().UnlockUser(c, uint(userID), operatorID(c)); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.SuccessWithMsg(c, "用户已解锁", nil)
+}
+
+// operatorID 当前操作的管理员 ID（未经认证中间件时为 0）
+func operatorID(c *gin.Context) uint {
+	if claims, ok := utils.ClaimsFromContext(c); ok {
+		return claims.UserID
+	}
+	return 0
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/services"
+)
+
+func TestLoginLockController_GetLocks(t *testing.T) {
+	until := time.Now().Add(time.Minute)
+	lockMock := &services.FakeLoginLockService{
+		GetLocksList:  []models.LoginLock{{ID: 1, Scope: models.LoginLockScopeUser, Target: "alice", LockedUntil: &until}},
+		GetLocksTotal: 1,
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{LoginLockService: lockMock})
+	ctrl := NewLoginLockController(a)
+
+	c, w := newGinContextGET("/admin/api/login-locks?scope=user")
+	ctrl.GetLocks(c)
+
+	var resp struct {
+		Code int `json:"code"`
+		Data struct {
+			Data       []models.LoginLock     `json:"data"`
+			Pagination map[string]interface{} `json:"pagination"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Code != 0 || len(resp.Data.Data) != 1 || resp.Data.Data[0].Target != "alice" {
+		t.Errorf("body=%s", w.Body.Bytes())
+	}
+}
+
+func TestLoginLockController_UnlockUser_BadID(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{LoginLockService: &services.FakeLoginLockService{}})
+	ctrl := NewLoginLockController(a)
+
+	c, w := newGinContextWithParam(http.MethodPost, "/admin/api/users/abc/unlock", nil, "id", "abc")
+	ctrl.UnlockUser(c)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); code != float64(errors.CodeBadRequest) {
+		t.Errorf("expected code %d, got %v", errors.CodeBadRequest, resp["code"])
+	}
+}
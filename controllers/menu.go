@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/services"
+	"github.com/lyuangg/gadmin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MenuController struct {
+	app *app.App
+}
+
+func NewMenuController(a *app.App) *MenuController {
+	return &MenuController{app: a}
+}
+
+// GetTree 获取完整的菜单树（菜单管理用）
+func (ctrl *MenuController) GetTree(c *gin.Context) {
+	tree, err := ctrl.app.GetMenuService().GetMenuTree(c.Request.Context())
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.Success(c, gin.H{"data": tree})
+}
+
+// GetUserMenus 当前用户可见的侧栏菜单与各页面可用的按钮
+func (ctrl *MenuController) GetUserMenus(c *gin.Context) {
+	claims, ok := utils.ClaimsFromContext(c)
+	if !ok {
+		ctrl.app.Responder.RespondError(c, errors.UnauthorizedMsg("未认证"))
+		return
+	}
+
+	menus, err := ctrl.app.GetMenuService().GetUserMenus(c.Request.Context(), claims.IsSuperAdmin, claims.RoleIDs)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.Success(c, menus)
+}
+
+type MenuRequest struct {
+	ParentID      uint   `json:"parent_id"`
+	Type          string `json:"type" binding:"required"`
+	Name          string `json:"name" binding:"required"`
+	Code          string `json:"code"`
+	Path          string `json:"path"`
+	Icon          string `json:"icon"`
+	Sort          int    `json:"sort"`
+	Visible       *bool  `json:"visible"` // 不传时为显示
+	PermissionIDs []uint `json:"permission_ids"`
+}
+
+func (r *MenuRequest) input() services.MenuInput {
+	return services.MenuInput{
+		ParentID:      r.ParentID,
+		Type:          r.Type,
+		Name:          r.Name,
+		Code:          r.Code,
+		Path:          r.Path,
+		Icon:          r.Icon,
+		Sort:          r.Sort,
+		Visible:       r.Visible == nil || *r.Visible,
+		PermissionIDs: r.PermissionIDs,
+	}
+}
+
+func (ctrl *MenuController) CreateMenu(c *gin.Context) {
+	var req MenuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	menu, err := ctrl.app.GetMenuService().CreateMenu(c.Request.Context(), req.input())
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.SuccessWithMsg(c, "创建成功", menu)
+}
+
+// UpdateMenu 更新菜单，请求体为菜单的完整字段
+func (ctrl *MenuController) UpdateMenu(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的菜单ID"))
+		return
+	}
+
+	var req MenuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	menu, err := ctrl.app.GetMenuService().UpdateMenu(c.Request.Context(), uint(id), req.input())
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.SuccessWithMsg(c, "更新成功", menu)
+}
+
+func (ctrl *MenuController) DeleteMenu(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的菜单ID"))
+		return
+	}
+
+	if err := ctrl.app.GetMenuService().DeleteMenu(c.Request.Context(), uint(id)); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.SuccessWithMsg(c, "删除成功", nil)
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/services"
+	"github.com/lyuangg/gadmin/utils"
+)
+
+func TestMenuController_GetUserMenus(t *testing.T) {
+	menuMock := &services.FakeMenuService{
+		UserMenus: &services.UserMenus{
+			Menus: []*models.Menu{
+				{ID: 1, Type: models.MenuTypeDirectory, Name: "系统管理", Children: []*models.Menu{
+					{ID: 2, ParentID: 1, Type: models.MenuTypePage, Name: "用户管理", Path: "/admin/users"},
+				}},
+			},
+			Buttons: map[string][]string{"/admin/users": {"add"}},
+		},
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{MenuService: menuMock})
+	ctrl := NewMenuController(a)
+
+	c, w := newGinContextWithClaims(http.MethodGet, "/admin/api/user/menus", nil, &utils.Claims{UserID: 1, RoleIDs: []uint{2}})
+	ctrl.GetUserMenus(c)
+
+	var resp struct {
+		Code int                `json:"code"`
+		Data services.UserMenus `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Code != 0 || len(resp.Data.Menus) != 1 || len(resp.Data.Menus[0].Children) != 1 ||
+		len(resp.Data.Buttons["/admin/users"]) != 1 {
+		t.Errorf("unexpected response: %s", w.Body.Bytes())
+	}
+
+	// 未认证
+	c, w = newGinContext(http.MethodGet, "/admin/api/user/menus", nil)
+	ctrl.GetUserMenus(c)
+	var errResp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &errResp)
+	if code, _ := errResp["code"].(float64); int(code) != errors.CodeUnauthorized {
+		t.Errorf("expected code %d, got %v", errors.CodeUnauthorized, errResp["code"])
+	}
+}
+
+func TestMenuController_CreateMenu(t *testing.T) {
+	menuMock := &services.FakeMenuService{
+		CreateMenuResult: &models.Menu{ID: 1, Type: models.MenuTypePage, Name: "用户管理", Path: "/admin/users", Visible: true},
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{MenuService: menuMock})
+	ctrl := NewMenuController(a)
+
+	body, _ := json.Marshal(map[string]interface{}{"type": "page", "name": "用户管理", "path": "/admin/users", "permission_ids": []uint{1}})
+	c, w := newGinContext(http.MethodPost, "/admin/api/menus", body)
+	ctrl.CreateMenu(c)
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); code != 0 {
+		t.Errorf("expected code 0, got %v body=%s", resp["code"], w.Body.Bytes())
+	}
+
+	// 缺少类型
+	c, w = newGinContext(http.MethodPost, "/admin/api/menus", []byte(`{"name":"x"}`))
+	ctrl.CreateMenu(c)
+	resp = nil
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); int(code) != errors.CodeBadRequest {
+		t.Errorf("expected code %d, got %v", errors.CodeBadRequest, resp["code"])
+	}
+}
+
+func TestMenuController_UpdateAndDeleteMenu(t *testing.T) {
+	menuMock := &services.FakeMenuService{
+		UpdateMenuErr: errors.BadRequestMsg("上级菜单不能是自己或自己的下级菜单"),
+		DeleteMenuErr: errors.NotFoundMsg("菜单不存在"),
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{MenuService: menuMock})
+	ctrl := NewMenuController(a)
+
+	c, w := newGinContextWithParam(http.MethodPut, "/admin/api/menus/abc", []byte(`{"type":"page","name":"x"}`), "id", "abc")
+	ctrl.UpdateMenu(c)
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["msg"] != "无效的菜单ID" {
+		t.Errorf("invalid id: %s", w.Body.Bytes())
+	}
+
+	c, w = newGinContextWithParam(http.MethodPut, "/admin/api/menus/1", []byte(`{"type":"directory","name":"x","parent_id":2}`), "id", "1")
+	ctrl.UpdateMenu(c)
+	resp = nil
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); int(code) != errors.CodeBadRequest {
+		t.Errorf("expected service error, got %s", w.Body.Bytes())
+	}
+
+	c, w = newGinContextWithParam(http.MethodDelete, "/admin/api/menus/9", nil, "id", "9")
+	ctrl.DeleteMenu(c)
+	resp = nil
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); int(code) != errors.CodeNotFound {
+		t.Errorf("expected code %d, got %s", errors.CodeNotFound, w.Body.Bytes())
+	}
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	stderrors "errors"
+	"net/http"
+	"net/url"
+
+	"github.com/lyuangg/gadmin/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie 保存发起单点登录时的 state，回调时比对，防止把他人的授权结果登录到当前浏览器
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/oidc"
+)
+
+// OIDCLogin 发起单点登录：保存 state 后跳转到 IdP 授权页
+func (ctrl *AuthController) OIDCLogin(c *gin.Context) {
+	authURL, state, err := ctrl.app.GetOIDCService().AuthCodeURL(c)
+	if err != nil {
+		ctrl.redirectLoginWithError(c, err)
+		return
+	}
+	ctrl.setOIDCStateCookie(c, state, 600)
+	c.Redirect(302, authURL)
+}
+
+// OIDCCallback IdP 回调：校验通过后带一次性票据跳回登录页，由登录页换取 Token，Token 不出现在地址栏中
+func (ctrl *AuthController) OIDCCallback(c *gin.Context) {
+	if idpErr := c.Query("error"); idpErr != "" {
+		ctrl.app.Logger().WarnContext(c, "IdP 拒绝了单点登录", "error", idpErr, "description", c.Query("error_description"))
+		ctrl.redirectLoginWithError(c, errors.UnauthorizedMsg("单点登录已取消或被拒绝"))
+		return
+	}
+
+	state := c.Query("state")
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	ctrl.setOIDCStateCookie(c, "", -1)
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		ctrl.redirectLoginWithError(c, errors.UnauthorizedMsg("单点登录已失效，请重试"))
+		return
+	}
+
+	ticket, err := ctrl.app.GetOIDCService().HandleCallback(c, state, c.Query("code"))
+	if err != nil {
+		ctrl.redirectLoginWithError(c, err)
+		return
+	}
+	c.Redirect(302, "/login?sso_ticket="+url.QueryEscape(ticket))
+}
+
+// setOIDCStateCookie 回调是从 IdP 跳转回来的跨站导航，SameSite 固定为 Lax，否则浏览器不会带上 state cookie
+func (ctrl *AuthController) setOIDCStateCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, value, maxAge, oidcStateCookiePath, "", ctrl.app.Config.CookieSecure, true)
+}
+
+type OIDCExchangeRequest struct {
+	Ticket string `json:"ticket" binding:"required"`
+}
+
+// OIDCExchange 登录页用单点登录票据换取 Token，响应与密码登录相同
+func (ctrl *AuthController) OIDCExchange(c *gin.Context) {
+	var req OIDCExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	result, err := ctrl.app.GetAuthService().LoginWithOIDC(c, req.Ticket, clientInfo(c))
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.respondLoginResult(c, result)
+}
+
+// redirectLoginWithError 浏览器跳转类接口出错时回到登录页展示错误；非业务错误不透出细节
+func (ctrl *AuthController) redirectLoginWithError(c *gin.Context, err error) {
+	msg := "单点登录失败，请稍后重试"
+	var bizErr *errors.BizError
+	if stderrors.As(err, &bizErr) {
+		msg = bizErr.Msg
+	} else {
+		ctrl.app.Logger().ErrorContext(c, "单点登录出错", "error", err)
+	}
+	c.Redirect(302, "/login?sso_error="+url.QueryEscape(msg))
+}
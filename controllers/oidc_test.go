@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/services"
+)
+
+func TestAuthController_OIDCLogin_RedirectsToIdP(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{
+		OIDCService: &services.FakeOIDCService{AuthURL: "https://idp.test/authorize?state=s1", State: "s1"},
+	})
+	ctrl := NewAuthController(a)
+
+	c, w := newGinContextGET("/api/oidc/login")
+	ctrl.OIDCLogin(c)
+
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "https://idp.test/authorize?state=s1" {
+		t.Fatalf("code=%d location=%q", w.Code, w.Header().Get("Location"))
+	}
+	if !strings.Contains(w.Header().Get("Set-Cookie"), "oidc_state=s1") {
+		t.Errorf("state cookie not set: %q", w.Header().Get("Set-Cookie"))
+	}
+}
+
+func TestAuthController_OIDCCallback(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{
+		OIDCService: &services.FakeOIDCService{Ticket: "t1"},
+	})
+	ctrl := NewAuthController(a)
+
+	// state 与发起登录时的 cookie 不一致
+	c, w := newGinContextGET("/api/oidc/callback?state=s1&code=c1")
+	c.Request.AddCookie(&http.Cookie{Name: "oidc_state", Value: "other"})
+	ctrl.OIDCCallback(c)
+	if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/login?sso_error=") {
+		t.Errorf("state mismatch location = %q", loc)
+	}
+
+	c, w = newGinContextGET("/api/oidc/callback?state=s1&code=c1")
+	c.Request.AddCookie(&http.Cookie{Name: "oidc_state", Value: "s1"})
+	ctrl.OIDCCallback(c)
+	if loc := w.Header().Get("Location"); loc != "/login?sso_ticket=t1" {
+		t.Errorf("location = %q", loc)
+	}
+}
+
+func TestAuthController_OIDCExchange(t *testing.T) {
+	authMock := &services.FakeAuthService{
+		OIDCLoginResult: &services.LoginResult{
+			User:   &models.User{ID: 1, Username: "alice"},
+			Tokens: &services.TokenPair{AccessToken: "sso-token", RefreshToken: "sso-refresh", ExpiresIn: 900, RefreshIn: 3600},
+		},
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{AuthService: authMock})
+	ctrl := NewAuthController(a)
+
+	c, w := newGinContext(http.MethodPost, "/api/oidc/exchange", []byte(`{"ticket":"t1"}`))
+	ctrl.OIDCExchange(c)
+
+	var resp struct {
+		Code int `json:"code"`
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Code != 0 || resp.Data.Token != "sso-token" {
+		t.Errorf("resp = %s", w.Body.String())
+	}
+}
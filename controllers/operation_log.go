@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"encoding/csv"
 	stderrors "errors"
+	"strconv"
 	"time"
 
 	"github.com/lyuangg/gadmin/app"
@@ -30,35 +32,19 @@ type getOperationLogsQuery struct {
 	OrderBy    string `form:"order_by"`
 }
 
-func (ctrl *OperationLogController) GetOperationLogs(c *gin.Context) {
-	var req getOperationLogsQuery
-	if err := c.ShouldBindQuery(&req); err != nil {
-		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
-		return
-	}
-
-	page, pageSize := req.Page, req.PageSize
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 {
-		pageSize = 10
-	}
-
+// filters 校验时间格式并转为服务层的筛选条件
+func (req *getOperationLogsQuery) filters() (map[string]string, error) {
 	if req.StartTime != "" {
 		if _, err := time.Parse(time.RFC3339, req.StartTime); err != nil {
-			ctrl.app.Responder.RespondError(c, errors.BadRequestErr(stderrors.New("start_time 格式错误，需使用 RFC3339，例如 2025-01-01T00:00:00Z")))
-			return
+			return nil, errors.BadRequestErr(stderrors.New("start_time 格式错误，需使用 RFC3339，例如 2025-01-01T00:00:00Z"))
 		}
 	}
 	if req.EndTime != "" {
 		if _, err := time.Parse(time.RFC3339, req.EndTime); err != nil {
-			ctrl.app.Responder.RespondError(c, errors.BadRequestErr(stderrors.New("end_time 格式错误，需使用 RFC3339，例如 2025-01-01T23:59:59Z")))
-			return
+			return nil, errors.BadRequestErr(stderrors.New("end_time 格式错误，需使用 RFC3339，例如 2025-01-01T23:59:59Z"))
 		}
 	}
-
-	filters := map[string]string{
+	return map[string]string{
 		"start_time":  req.StartTime,
 		"end_time":    req.EndTime,
 		"username":    req.Username,
@@ -66,6 +52,28 @@ func (ctrl *OperationLogController) GetOperationLogs(c *gin.Context) {
 		"path":        req.Path,
 		"status_code": req.StatusCode,
 		"order_by":    req.OrderBy,
+	}, nil
+}
+
+func (ctrl *OperationLogController) GetOperationLogs(c *gin.Context) {
+	var req getOperationLogsQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	page, pageSize := req.Page, req.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters, err := req.filters()
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
 	}
 
 	logs, total, err := ctrl.app.GetOperationLogService().GetOperationLogs(c, page, pageSize, filters)
@@ -84,3 +92,47 @@ func (ctrl *OperationLogController) GetOperationLogs(c *gin.Context) {
 		},
 	})
 }
+
+// ExportOperationLogs 按列表的筛选条件导出 CSV（不含请求与响应体）；通过下载链接访问，接受查询参数 Token
+func (ctrl *OperationLogController) ExportOperationLogs(c *gin.Context) {
+	var req getOperationLogsQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+	filters, err := req.filters()
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	logs, err := ctrl.app.GetOperationLogService().ExportOperationLogs(c, filters)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	filename := "operation_logs_" + time.Now().Format("20060102150405") + ".csv"
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	// UTF-8 BOM，Excel 打开时中文不乱码
+	c.Writer.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"ID", "时间", "用户名", "昵称", "模拟发起人", "方法", "路径", "操作", "状态码", "IP", "耗时(ms)"})
+	for _, l := range logs {
+		w.Write([]string{
+			strconv.FormatUint(uint64(l.ID), 10),
+			l.CreatedAt.Format("2006-01-02 15:04:05"),
+			l.Username,
+			l.Nickname,
+			l.ImpersonatorName,
+			l.Method,
+			l.Path,
+			l.RouteName,
+			strconv.Itoa(l.StatusCode),
+			l.IP,
+			strconv.FormatInt(l.Duration, 10),
+		})
+	}
+	w.Flush()
+}
@@ -1,11 +1,14 @@
 package controllers
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/models"
 	"github.com/lyuangg/gadmin/services"
 )
 
@@ -59,3 +62,51 @@ func TestOperationLogController_GetOperationLogs_InvalidStartTime(t *testing.T)
 		t.Error("expected error for invalid start_time")
 	}
 }
+
+func TestOperationLogController_ExportOperationLogs(t *testing.T) {
+	logMock := &services.FakeOperationLogService{
+		ExportList: []models.OperationLog{
+			{ID: 7, Username: "alice", Nickname: "爱丽丝", Method: "POST", Path: "/admin/api/users", RouteName: "创建用户", StatusCode: 200, IP: "10.0.0.1", Duration: 12},
+		},
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{OperationLogService: logMock})
+	ctrl := NewOperationLogController(a)
+
+	c, w := newGinContextGET("/admin/download/operation-logs?method=POST")
+	ctrl.ExportOperationLogs(c)
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("Content-Type = %q, want text/csv, body=%s", ct, w.Body.String())
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	body := strings.TrimPrefix(w.Body.String(), "\xEF\xBB\xBF")
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want header + 1 row", len(records))
+	}
+	row := records[1]
+	if row[0] != "7" || row[2] != "alice" || row[6] != "/admin/api/users" || row[7] != "创建用户" || row[10] != "12" {
+		t.Errorf("row = %v", row)
+	}
+}
+
+func TestOperationLogController_ExportOperationLogs_InvalidEndTime(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{OperationLogService: &services.FakeOperationLogService{}})
+	ctrl := NewOperationLogController(a)
+
+	c, w := newGinContextGET("/admin/download/operation-logs?end_time=invalid")
+	ctrl.ExportOperationLogs(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("expected JSON error, got %q", w.Body.String())
+	}
+	if code, _ := resp["code"].(float64); code == 0 {
+		t.Error("expected error for invalid end_time")
+	}
+}
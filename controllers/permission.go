@@ -71,6 +71,7 @@ type CreatePermissionRequest struct {
 	Name        string `json:"name"`
 	Group       string `json:"group"`
 	Description string `json:"description"`
+	Effect      string `json:"effect"` // allow（默认）或 deny
 }
 
 func (ctrl *PermissionController) CreatePermission(c *gin.Context) {
@@ -80,7 +81,7 @@ func (ctrl *PermissionController) CreatePermission(c *gin.Context) {
 		return
 	}
 
-	permission, err := ctrl.app.GetPermissionService().CreatePermission(c, req.Path, req.Method, req.Name, req.Group, req.Description)
+	permission, err := ctrl.app.GetPermissionService().CreatePermission(c, req.Path, req.Method, req.Name, req.Group, req.Description, req.Effect)
 	if err != nil {
 		ctrl.app.Responder.RespondError(c, err)
 		return
@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionSyncController 路由权限同步预览，路由列表由 routes 包的扫描器提供
+type PermissionSyncController struct {
+	app    *app.App
+	routes func() []services.RoutePermission
+}
+
+func NewPermissionSyncController(a *app.App, routes func() []services.RoutePermission) *PermissionSyncController {
+	return &PermissionSyncController{app: a, routes: routes}
+}
+
+// Preview 对比当前路由与权限表，返回新增、变更与孤儿权限，不修改数据库；变更在下次启动时按配置执行
+func (ctrl *PermissionSyncController) Preview(c *gin.Context) {
+	report, err := ctrl.app.GetPermissionService().SyncRoutePermissions(c.Request.Context(), ctrl.routes(), true)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.Success(c, report)
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/services"
+)
+
+func TestPermissionSyncController_Preview(t *testing.T) {
+	permMock := &services.FakePermissionService{
+		SyncReport: &services.PermissionSyncReport{
+			DryRun:   true,
+			Policy:   services.PermissionOrphanMark,
+			Added:    []services.PermissionSyncItem{{Method: "GET", Path: "/admin/api/new", Name: "新接口"}},
+			Changed:  []services.PermissionSyncItem{},
+			Orphaned: []services.PermissionSyncItem{{ID: 3, Method: "POST", Path: "/admin/api/legacy", RoleCount: 2}},
+		},
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{PermissionService: permMock})
+	routesCalled := false
+	ctrl := NewPermissionSyncController(a, func() []services.RoutePermission {
+		routesCalled = true
+		return nil
+	})
+
+	c, w := newGinContext(http.MethodGet, "/admin/api/permissions/sync-preview", nil)
+	ctrl.Preview(c)
+
+	var resp struct {
+		Code int                           `json:"code"`
+		Data services.PermissionSyncReport `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !routesCalled {
+		t.Error("route source was not called")
+	}
+	if resp.Code != 0 || !resp.Data.DryRun || len(resp.Data.Added) != 1 || len(resp.Data.Orphaned) != 1 || resp.Data.Orphaned[0].RoleCount != 2 {
+		t.Errorf("unexpected response: %s", w.Body.Bytes())
+	}
+
+	permMock.SyncReport, permMock.SyncErr = nil, errors.BadRequestMsg("失败")
+	c, w = newGinContext(http.MethodGet, "/admin/api/permissions/sync-preview", nil)
+	ctrl.Preview(c)
+	var errResp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &errResp)
+	if code, _ := errResp["code"].(float64); int(code) != errors.CodeBadRequest {
+		t.Errorf("expected code %d, got %v", errors.CodeBadRequest, errResp["code"])
+	}
+}
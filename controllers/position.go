@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PositionController struct {
+	app *app.App
+}
+
+func NewPositionController(a *app.App) *PositionController {
+	return &PositionController{app: a}
+}
+
+type getPositionsQuery struct {
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+	OrderBy  string `form:"order_by"`
+	Code     string `form:"code"`
+	Name     string `form:"name"`
+	Status   string `form:"status"`
+}
+
+func (ctrl *PositionController) GetPositions(c *gin.Context) {
+	var req getPositionsQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+	filters := map[string]string{
+		"order_by": req.OrderBy,
+		"code":     req.Code,
+		"name":     req.Name,
+		"status":   req.Status,
+	}
+	list, total, err := ctrl.app.GetPositionService().GetPositions(c.Request.Context(), req.Page, req.PageSize, filters)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	totalPage := 0
+	if req.PageSize > 0 {
+		totalPage = (int(total) + req.PageSize - 1) / req.PageSize
+	}
+	ctrl.app.Responder.Success(c, gin.H{
+		"data": list,
+		"pagination": gin.H{
+			"page":       req.Page,
+			"page_size":  req.PageSize,
+			"total":      total,
+			"total_page": totalPage,
+		},
+	})
+}
+
+type CreatePositionRequest struct {
+	Code   string `json:"code" binding:"required"`
+	Name   string `json:"name" binding:"required"`
+	Sort   int    `json:"sort"`
+	Status *int   `json:"status"` // 不传时为启用
+	Remark string `json:"remark"`
+}
+
+func (ctrl *PositionController) CreatePosition(c *gin.Context) {
+	var req CreatePositionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+	status := 1
+	if req.Status != nil && *req.Status == 0 {
+		status = 0
+	}
+
+	position, err := ctrl.app.GetPositionService().CreatePosition(c.Request.Context(), req.Code, req.Name, req.Sort, status, req.Remark)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.SuccessWithMsg(c, "创建成功", position)
+}
+
+type UpdatePositionRequest struct {
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Sort   *int   `json:"sort"`
+	Status *int   `json:"status"`
+	Remark string `json:"remark"`
+}
+
+func (ctrl *PositionController) UpdatePosition(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的岗位ID"))
+		return
+	}
+
+	var req UpdatePositionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	position, err := ctrl.app.GetPositionService().UpdatePosition(c.Request.Context(), uint(id), req.Code, req.Name, req.Sort, req.Status, req.Remark)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.SuccessWithMsg(c, "更新成功", position)
+}
+
+func (ctrl *PositionController) DeletePosition(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的岗位ID"))
+		return
+	}
+
+	if err := ctrl.app.GetPositionService().DeletePosition(c.Request.Context(), uint(id)); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+	ctrl.app.Responder.SuccessWithMsg(c, "删除成功", nil)
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/services"
+)
+
+func TestPositionController_GetPositions(t *testing.T) {
+	posMock := &services.FakePositionService{
+		GetPositionsList:  []models.Position{{ID: 1, Code: "dev", Name: "开发"}},
+		GetPositionsTotal: 1,
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{PositionService: posMock})
+	ctrl := NewPositionController(a)
+
+	c, w := newGinContextGET("/api/positions?page=1&page_size=10")
+	ctrl.GetPositions(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	data, _ := resp["data"].(map[string]interface{})
+	if data == nil || data["pagination"] == nil {
+		t.Fatalf("expected paginated data, got %s", w.Body.Bytes())
+	}
+	if list, _ := data["data"].([]interface{}); len(list) != 1 {
+		t.Errorf("expected 1 position, got %v", data["data"])
+	}
+}
+
+func TestPositionController_CreatePosition_BadRequest(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{PositionService: &services.FakePositionService{}})
+	ctrl := NewPositionController(a)
+
+	c, w := newGinContext(http.MethodPost, "/api/positions", []byte(`{"name":"开发"}`)) // 缺少 code
+	ctrl.CreatePosition(c)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); code == 0 {
+		t.Error("expected error when code is missing")
+	}
+}
@@ -61,6 +61,7 @@ func (ctrl *RoleController) GetRoles(c *gin.Context) {
 type CreateRoleRequest struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
+	ParentID    uint   `json:"parent_id"`
 }
 
 func (ctrl *RoleController) CreateRole(c *gin.Context) {
@@ -70,7 +71,7 @@ func (ctrl *RoleController) CreateRole(c *gin.Context) {
 		return
 	}
 
-	role, err := ctrl.app.GetRoleService().CreateRole(c, req.Name, req.Description)
+	role, err := ctrl.app.GetRoleService().CreateRole(c, req.Name, req.Description, req.ParentID)
 	if err != nil {
 		ctrl.app.Responder.RespondError(c, err)
 		return
@@ -82,6 +83,7 @@ func (ctrl *RoleController) CreateRole(c *gin.Context) {
 type UpdateRoleRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	ParentID    *uint  `json:"parent_id"` // 不传时不修改，0 表示取消上级
 }
 
 func (ctrl *RoleController) UpdateRole(c *gin.Context) {
@@ -98,7 +100,7 @@ func (ctrl *RoleController) UpdateRole(c *gin.Context) {
 		return
 	}
 
-	role, err := ctrl.app.GetRoleService().UpdateRole(c, uint(roleID), req.Name, req.Description)
+	role, err := ctrl.app.GetRoleService().UpdateRole(c, uint(roleID), req.Name, req.Description, req.ParentID)
 	if err != nil {
 		ctrl.app.Responder.RespondError(c, err)
 		return
@@ -148,3 +150,56 @@ func (ctrl *RoleController) AssignPermissions(c *gin.Context) {
 
 	ctrl.app.Responder.SuccessWithMsg(c, "分配权限成功", nil)
 }
+
+type SetRequire2FARequest struct {
+	Require2FA *bool `json:"require_2fa" binding:"required"`
+}
+
+// SetRequire2FA 设置角色是否强制两步验证
+func (ctrl *RoleController) SetRequire2FA(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的角色ID"))
+		return
+	}
+
+	var req SetRequire2FARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	if err := ctrl.app.GetRoleService().SetRequire2FA(c, uint(roleID), *req.Require2FA); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.SuccessWithMsg(c, "设置成功", nil)
+}
+
+type SetDataScopeRequest struct {
+	DataScope     string `json:"data_scope" binding:"required"`
+	DepartmentIDs []uint `json:"department_ids"` // data_scope 为 custom 时必填
+}
+
+// SetDataScope 设置角色数据范围
+func (ctrl *RoleController) SetDataScope(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的角色ID"))
+		return
+	}
+
+	var req SetDataScopeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	if err := ctrl.app.GetRoleService().SetDataScope(c, uint(roleID), req.DataScope, req.DepartmentIDs); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.SuccessWithMsg(c, "设置成功", nil)
+}
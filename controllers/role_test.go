@@ -182,3 +182,28 @@ func TestRoleController_AssignPermissions_InvalidRoleID(t *testing.T) {
 		t.Error("expected error for invalid role id")
 	}
 }
+
+func TestRoleController_SetDataScope(t *testing.T) {
+	roleMock := &services.FakeRoleService{}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{RoleService: roleMock})
+	ctrl := NewRoleController(a)
+
+	body, _ := json.Marshal(map[string]interface{}{"data_scope": "custom", "department_ids": []uint{1, 2}})
+	c, w := newGinContextWithParam(http.MethodPut, "/api/roles/1/data-scope", body, "id", "1")
+	ctrl.SetDataScope(c)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); code != 0 {
+		t.Errorf("expected code 0, got %v body=%s", resp["code"], w.Body.Bytes())
+	}
+
+	// 缺少 data_scope
+	c, w = newGinContextWithParam(http.MethodPut, "/api/roles/1/data-scope", []byte(`{}`), "id", "1")
+	ctrl.SetDataScope(c)
+	resp = nil
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); code == 0 {
+		t.Error("expected error when data_scope is missing")
+	}
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	stderrors "errors"
+	"time"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SecurityEventController struct {
+	app *app.App
+}
+
+func NewSecurityEventController(a *app.App) *SecurityEventController {
+	return &SecurityEventController{app: a}
+}
+
+type getSecurityEventsQuery struct {
+	Page      int    `form:"page"`
+	PageSize  int    `form:"page_size"`
+	StartTime string `form:"start_time"`
+	EndTime   string `form:"end_time"`
+	EventType string `form:"event_type"`
+	UserID    string `form:"user_id"`
+	Username  string `form:"username"`
+	IP        string `form:"ip"`
+	TraceID   string `form:"trace_id"`
+}
+
+// GetEvents 查询安全事件（登录、退出、Token 拒绝、权限拒绝等）
+func (ctrl *SecurityEventController) GetEvents(c *gin.Context) {
+	var req getSecurityEventsQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	page, pageSize := req.Page, req.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	if req.StartTime != "" {
+		if _, err := time.Parse(time.RFC3339, req.StartTime); err != nil {
+			ctrl.app.Responder.RespondError(c, errors.BadRequestErr(stderrors.New("start_time 格式错误，需使用 RFC3339，例如 2025-01-01T00:00:00Z")))
+			return
+		}
+	}
+	if req.EndTime != "" {
+		if _, err := time.Parse(time.RFC3339, req.EndTime); err != nil {
+			ctrl.app.Responder.RespondError(c, errors.BadRequestErr(stderrors.New("end_time 格式错误，需使用 RFC3339，例如 2025-01-01T23:59:59Z")))
+			return
+		}
+	}
+
+	filters := map[string]string{
+		"start_time": req.StartTime,
+		"end_time":   req.EndTime,
+		"event_type": req.EventType,
+		"user_id":    req.UserID,
+		"username":   req.Username,
+		"ip":         req.IP,
+		"trace_id":   req.TraceID,
+	}
+
+	events, total, err := ctrl.app.GetSecurityEventService().GetEvents(c, page, pageSize, filters)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.Success(c, gin.H{
+		"data": events,
+		"pagination": gin.H{
+			"page":       page,
+			"page_size":  pageSize,
+			"total":      total,
+			"total_page": (int(total) + pageSize - 1) / pageSize,
+		},
+	})
+}
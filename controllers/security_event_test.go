@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/services"
+)
+
+func TestSecurityEventController_GetEvents(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{
+		SecurityEventService: &services.FakeSecurityEventService{
+			GetEventsList:  []models.SecurityEvent{{ID: 1, EventType: models.SecurityEventLoginFailed, Username: "alice"}},
+			GetEventsTotal: 1,
+		},
+	})
+	ctrl := NewSecurityEventController(a)
+
+	c, w := newGinContextGET("/admin/api/security-events?event_type=login_failed")
+	ctrl.GetEvents(c)
+
+	var resp struct {
+		Code int `json:"code"`
+		Data struct {
+			Data       []models.SecurityEvent `json:"data"`
+			Pagination struct {
+				Total int `json:"total"`
+			} `json:"pagination"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Code != 0 || len(resp.Data.Data) != 1 || resp.Data.Pagination.Total != 1 {
+		t.Errorf("resp = %s", w.Body.String())
+	}
+
+	c, w = newGinContextGET("/admin/api/security-events?start_time=yesterday")
+	ctrl.GetEvents(c)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Code == 0 {
+		t.Error("invalid start_time should be rejected")
+	}
+}
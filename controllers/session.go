@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionController struct {
+	app *app.App
+}
+
+func NewSessionController(a *app.App) *SessionController {
+	return &SessionController{app: a}
+}
+
+// sessionItem 会话列表项；current 标记发起请求的当前会话
+func sessionItem(s models.UserSession, currentJTI string) gin.H {
+	item := gin.H{
+		"id":           s.ID,
+		"user_id":      s.UserID,
+		"ip":           s.IP,
+		"user_agent":   s.UserAgent,
+		"created_at":   s.CreatedAt,
+		"last_seen_at": s.LastSeenAt,
+		"expires_at":   s.ExpiresAt,
+		"current":      currentJTI != "" && s.JTI == currentJTI,
+	}
+	if s.User != nil {
+		item["username"] = s.User.Username
+		item["nickname"] = s.User.Nickname
+	}
+	return item
+}
+
+// GetMySessions 当前用户的登录会话列表
+func (ctrl *SessionController) GetMySessions(c *gin.Context) {
+	claims, ok := utils.ClaimsFromContext(c)
+	if !ok {
+		ctrl.app.Responder.RespondError(c, errors.UnauthorizedMsg("未认证"))
+		return
+	}
+
+	sessions, err := ctrl.app.GetSessionService().GetUserSessions(c, claims.UserID)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	list := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, sessionItem(s, claims.ID))
+	}
+	ctrl.app.Responder.Success(c, list)
+}
+
+// RevokeMySession 当前用户下线自己的某个会话
+func (ctrl *SessionController) RevokeMySession(c *gin.Context) {
+	claims, ok := utils.ClaimsFromContext(c)
+	if !ok {
+		ctrl.app.Responder.RespondError(c, errors.UnauthorizedMsg("未认证"))
+		return
+	}
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的会话ID"))
+		return
+	}
+
+	if err := ctrl.app.GetSessionService().RevokeSession(c, uint(sessionID), claims.UserID); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.SuccessWithMsg(c, "会话已下线", nil)
+}
+
+type getSessionsQuery struct {
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+	UserID   string `form:"user_id"`
+	Username string `form:"username"`
+}
+
+// GetSessions 管理端查询所有用户的有效会话
+func (ctrl *SessionController) GetSessions(c *gin.Context) {
+	var req getSessionsQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+	page, pageSize := req.Page, req.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	filters := map[string]string{
+		"user_id":  req.UserID,
+		"username": req.Username,
+	}
+
+	sessions, total, err := ctrl.app.GetSessionService().GetSessions(c, page, pageSize, filters)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	jti := currentJTI(c)
+	list := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, sessionItem(s, jti))
+	}
+	ctrl.app.Responder.Success(c, gin.H{
+		"data": list,
+		"pagination": gin.H{
+			"page":       page,
+			"page_size":  pageSize,
+			"total":      total,
+			"total_page": (int(total) + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// RevokeSession 管理端强制下线任意会话
+func (ctrl *SessionController) RevokeSession(c *gin.Context) {
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的会话ID"))
+		return
+	}
+
+	if err := ctrl.app.GetSessionService().RevokeSession(c, uint(sessionID), 0); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.SuccessWithMsg(c, "会话已下线", nil)
+}
+
+// RevokeUserSessions 管理端强制下线某用户的全部会话
+func (ctrl *SessionController) RevokeUserSessions(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的用户ID"))
+		return
+	}
+
+	if err := ctrl.app.GetSessionService().RevokeUserSessions(c, uint(userID), ""); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.SuccessWithMsg(c, "用户已全部下线", nil)
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/services"
+	"github.com/lyuangg/gadmin/utils"
+)
+
+func TestSessionController_GetMySessions_MarksCurrent(t *testing.T) {
+	sessionMock := &services.FakeSessionService{
+		GetUserSessionsList: []models.UserSession{
+			{ID: 1, UserID: 1, JTI: "cur", IP: "1.1.1.1"},
+			{ID: 2, UserID: 1, JTI: "other", IP: "2.2.2.2"},
+		},
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{SessionService: sessionMock})
+	ctrl := NewSessionController(a)
+
+	claims := &utils.Claims{UserID: 1}
+	claims.ID = "cur"
+	c, w := newGinContextWithClaims(http.MethodGet, "/admin/api/profile/sessions", nil, claims)
+	ctrl.GetMySessions(c)
+
+	var resp struct {
+		Code int                      `json:"code"`
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Code != 0 || len(resp.Data) != 2 {
+		t.Fatalf("code=%d len=%d body=%s", resp.Code, len(resp.Data), w.Body.Bytes())
+	}
+	if resp.Data[0]["current"] != true || resp.Data[1]["current"] != false {
+		t.Errorf("current flags = %v, %v", resp.Data[0]["current"], resp.Data[1]["current"])
+	}
+	if _, ok := resp.Data[0]["jti"]; ok {
+		t.Error("jti should not be exposed")
+	}
+}
+
+func TestSessionController_RevokeMySession_BadID(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{SessionService: &services.FakeSessionService{}})
+	ctrl := NewSessionController(a)
+
+	c, w := newGinContextWithClaims(http.MethodDelete, "/admin/api/profile/sessions/abc", nil, &utils.Claims{UserID: 1})
+	ctrl.RevokeMySession(c)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); code != float64(errors.CodeBadRequest) {
+		t.Errorf("expected code %d, got %v", errors.CodeBadRequest, resp["code"])
+	}
+}
+
+func TestSessionController_RevokeSession_NotFound(t *testing.T) {
+	sessionMock := &services.FakeSessionService{RevokeSessionErr: errors.NotFoundMsg("会话不存在")}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{SessionService: sessionMock})
+	ctrl := NewSessionController(a)
+
+	c, w := newGinContextWithParam(http.MethodDelete, "/admin/api/sessions/9", nil, "id", "9")
+	ctrl.RevokeSession(c)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); code != float64(errors.CodeNotFound) {
+		t.Errorf("expected code %d, got %v", errors.CodeNotFound, resp["code"])
+	}
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorController struct {
+	app *app.App
+}
+
+func NewTwoFactorController(a *app.App) *TwoFactorController {
+	return &TwoFactorController{app: a}
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// GetStatus 当前用户的两步验证状态
+func (ctrl *TwoFactorController) GetStatus(c *gin.Context) {
+	claims, ok := utils.ClaimsFromContext(c)
+	if !ok {
+		ctrl.app.Responder.RespondError(c, errors.UnauthorizedMsg("未认证"))
+		return
+	}
+
+	status, err := ctrl.app.GetTwoFactorService().GetStatus(c, claims.UserID)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.Success(c, status)
+}
+
+// BeginSetup 生成绑定密钥与 otpauth 链接
+func (ctrl *TwoFactorController) BeginSetup(c *gin.Context) {
+	claims, ok := utils.ClaimsFromContext(c)
+	if !ok {
+		ctrl.app.Responder.RespondError(c, errors.UnauthorizedMsg("未认证"))
+		return
+	}
+
+	setup, err := ctrl.app.GetTwoFactorService().BeginSetup(c, claims.UserID)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.Success(c, setup)
+}
+
+// Enable 提交验证码确认绑定，返回备用码
+func (ctrl *TwoFactorController) Enable(c *gin.Context) {
+	claims, ok := utils.ClaimsFromContext(c)
+	if !ok {
+		ctrl.app.Responder.RespondError(c, errors.UnauthorizedMsg("未认证"))
+		return
+	}
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	codes, err := ctrl.app.GetTwoFactorService().Enable(c, claims.UserID, req.Code)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.SuccessWithMsg(c, "两步验证已开启", gin.H{"backup_codes": codes})
+}
+
+// Disable 提交验证码关闭两步验证
+func (ctrl *TwoFactorController) Disable(c *gin.Context) {
+	claims, ok := utils.ClaimsFromContext(c)
+	if !ok {
+		ctrl.app.Responder.RespondError(c, errors.UnauthorizedMsg("未认证"))
+		return
+	}
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	if err := ctrl.app.GetTwoFactorService().Disable(c, claims.UserID, req.Code); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.SuccessWithMsg(c, "两步验证已关闭", nil)
+}
+
+// RegenerateBackupCodes 提交验证码重新生成备用码
+func (ctrl *TwoFactorController) RegenerateBackupCodes(c *gin.Context) {
+	claims, ok := utils.ClaimsFromContext(c)
+	if !ok {
+		ctrl.app.Responder.RespondError(c, errors.UnauthorizedMsg("未认证"))
+		return
+	}
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	codes, err := ctrl.app.GetTwoFactorService().RegenerateBackupCodes(c, claims.UserID, req.Code)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.SuccessWithMsg(c, "备用码已重新生成", gin.H{"backup_codes": codes})
+}
+
+// ResetUser 管理端重置指定用户的两步验证
+func (ctrl *TwoFactorController) ResetUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的用户ID"))
+		return
+	}
+
+	if err := ctrl.app.GetTwoFactorService().Reset(c, uint(userID)); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Logger().InfoContext(c, "管理员重置用户两步验证", "user_id", userID)
+	ctrl.app.Responder.SuccessWithMsg(c, "两步验证已重置", nil)
+}
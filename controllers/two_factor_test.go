@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/services"
+	"github.com/lyuangg/gadmin/utils"
+)
+
+func TestTwoFactorController_Enable_ReturnsBackupCodes(t *testing.T) {
+	tfaMock := &services.FakeTwoFactorService{EnableCodes: []string{"aaaa-bbbb", "cccc-dddd"}}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{TwoFactorService: tfaMock})
+	ctrl := NewTwoFactorController(a)
+
+	body, _ := json.Marshal(map[string]string{"code": "123456"})
+	c, w := newGinContextWithClaims(http.MethodPost, "/admin/api/profile/2fa/enable", body, &utils.Claims{UserID: 1})
+	ctrl.Enable(c)
+
+	var resp struct {
+		Code int `json:"code"`
+		Data struct {
+			BackupCodes []string `json:"backup_codes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Code != 0 || len(resp.Data.BackupCodes) != 2 {
+		t.Errorf("body=%s", w.Body.Bytes())
+	}
+}
+
+func TestTwoFactorController_Enable_MissingCode(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{TwoFactorService: &services.FakeTwoFactorService{}})
+	ctrl := NewTwoFactorController(a)
+
+	c, w := newGinContextWithClaims(http.MethodPost, "/admin/api/profile/2fa/enable", []byte(`{}`), &utils.Claims{UserID: 1})
+	ctrl.Enable(c)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); code != float64(errors.CodeBadRequest) {
+		t.Errorf("expected code %d, got %v", errors.CodeBadRequest, resp["code"])
+	}
+}
+
+func TestTwoFactorController_ResetUser(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{TwoFactorService: &services.FakeTwoFactorService{}})
+	ctrl := NewTwoFactorController(a)
+
+	c, w := newGinContextWithParam(http.MethodDelete, "/admin/api/users/3/2fa", nil, "id", "3")
+	ctrl.ResetUser(c)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if code, _ := resp["code"].(float64); code != 0 {
+		t.Errorf("expected success, body=%s", w.Body.Bytes())
+	}
+}
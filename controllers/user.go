@@ -25,6 +25,7 @@ type getUsersQuery struct {
 	Type     string `form:"type"`
 	Status   string `form:"status"`
 	RoleID   string `form:"role_id"`
+	DeptID   string `form:"department_id"` // 包含下级部门
 	OrderBy  string `form:"order_by"`
 }
 
@@ -42,12 +43,13 @@ func (ctrl *UserController) GetUsers(c *gin.Context) {
 		pageSize = 10
 	}
 	filters := map[string]string{
-		"username": req.Username,
-		"nickname": req.Nickname,
-		"type":     req.Type,
-		"status":   req.Status,
-		"role_id":  req.RoleID,
-		"order_by": req.OrderBy,
+		"username":      req.Username,
+		"nickname":      req.Nickname,
+		"type":          req.Type,
+		"status":        req.Status,
+		"role_id":       req.RoleID,
+		"department_id": req.DeptID,
+		"order_by":      req.OrderBy,
 	}
 
 	users, total, err := ctrl.app.GetUserService().GetUsers(c, page, pageSize, filters)
@@ -59,15 +61,18 @@ func (ctrl *UserController) GetUsers(c *gin.Context) {
 	var userList []gin.H
 	for _, user := range users {
 		userList = append(userList, gin.H{
-			"id":         user.ID,
-			"username":   user.Username,
-			"nickname":   user.Nickname,
-			"avatar":     user.Avatar,
-			"type":       user.Type,
-			"status":     user.Status,
-			"remark":     user.Remark,
-			"roles":      user.Roles,
-			"created_at": user.CreatedAt,
+			"id":            user.ID,
+			"username":      user.Username,
+			"nickname":      user.Nickname,
+			"avatar":        user.Avatar,
+			"type":          user.Type,
+			"status":        user.Status,
+			"remark":        user.Remark,
+			"roles":         user.Roles,
+			"department_id": user.DepartmentID,
+			"department":    user.Department,
+			"positions":     user.Positions,
+			"created_at":    user.CreatedAt,
 		})
 	}
 
@@ -189,3 +194,30 @@ func (ctrl *UserController) ToggleStatus(c *gin.Context) {
 
 	ctrl.app.Responder.SuccessWithMsg(c, "状态更新成功", nil)
 }
+
+type AssignOrganizationRequest struct {
+	DepartmentID uint   `json:"department_id"` // 0 表示不属于任何部门
+	PositionIDs  []uint `json:"position_ids"`
+}
+
+// AssignOrganization 设置用户所属部门与岗位
+func (ctrl *UserController) AssignOrganization(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestMsg("无效的用户ID"))
+		return
+	}
+
+	var req AssignOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.app.Responder.RespondError(c, errors.BadRequestErr(err))
+		return
+	}
+
+	if err := ctrl.app.GetUserService().AssignOrganization(c, uint(userID), req.DepartmentID, req.PositionIDs); err != nil {
+		ctrl.app.Responder.RespondError(c, err)
+		return
+	}
+
+	ctrl.app.Responder.SuccessWithMsg(c, "设置成功", nil)
+}
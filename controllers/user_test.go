@@ -212,3 +212,21 @@ func TestUserController_InvalidID(t *testing.T) {
 		t.Error("expected error for invalid user id")
 	}
 }
+
+func TestUserController_AssignOrganization(t *testing.T) {
+	userMock := &services.FakeUserService{}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock})
+	ctrl := NewUserController(a)
+
+	body, _ := json.Marshal(map[string]interface{}{"department_id": 2, "position_ids": []uint{1, 3}})
+	c, w := newGinContextWithParam(http.MethodPut, "/api/users/1/organization", body, "id", "1")
+	ctrl.AssignOrganization(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if code, _ := resp["code"].(float64); code != 0 {
+		t.Errorf("expected code 0, got %v body=%s", resp["code"], w.Body.Bytes())
+	}
+}
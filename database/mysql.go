@@ -49,13 +49,19 @@ func newGormLogger(cfg *config.Config, slogLogger *slog.Logger) gormlogger.Inter
 	)
 }
 
-func InitDB(cfg *config.Config, slogLogger *slog.Logger) (*gorm.DB, error) {
+// OpenDB 只连接数据库，不迁移表结构、不写入默认数据
+func OpenDB(cfg *config.Config, slogLogger *slog.Logger) (*gorm.DB, error) {
 	gormConfig := &gorm.Config{
 		DisableForeignKeyConstraintWhenMigrating: true,
 		NamingStrategy:                           schema.NamingStrategy{TablePrefix: cfg.DBTablePrefix},
 		Logger:                                   newGormLogger(cfg, slogLogger),
 	}
-	db, err := gorm.Open(mysql.Open(cfg.DSN()), gormConfig)
+	return gorm.Open(mysql.Open(cfg.DSN()), gormConfig)
+}
+
+// InitDB 连接数据库，迁移表结构并写入默认数据
+func InitDB(cfg *config.Config, slogLogger *slog.Logger) (*gorm.DB, error) {
+	db, err := OpenDB(cfg, slogLogger)
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +73,22 @@ func InitDB(cfg *config.Config, slogLogger *slog.Logger) (*gorm.DB, error) {
 		&models.OperationLog{},
 		&models.DictType{},
 		&models.DictItem{},
+		&models.RefreshToken{},
+		&models.UserSession{},
+		&models.UserBackupCode{},
+		&models.LoginChallenge{},
+		&models.LoginLock{},
+		&models.LoginLockEvent{},
+		&models.SecurityEvent{},
+		&models.PasswordHistory{},
+		&models.Captcha{},
+		&models.OIDCLogin{},
+		&models.UserIdentity{},
+		&models.APIKey{},
+		&models.IPRule{},
+		&models.Department{},
+		&models.Position{},
+		&models.Menu{},
 	)
 	if err != nil {
 		return nil, err
@@ -81,16 +103,29 @@ func InitDB(cfg *config.Config, slogLogger *slog.Logger) (*gorm.DB, error) {
 
 func initDefaultData(db *gorm.DB, logger *slog.Logger) error {
 	var superAdminRole models.Role
-	result := db.Where("name = ?", "超级管理员").First(&superAdminRole)
+	result := db.Where("code = ?", models.RoleCodeSuperAdmin).First(&superAdminRole)
+	if result.Error == gorm.ErrRecordNotFound {
+		// 旧版本按角色名识别超级管理员：已有同名角色时补上系统编码
+		result = db.Where("name = ?", "超级管理员").First(&superAdminRole)
+		if result.Error == nil {
+			if err := db.Model(&superAdminRole).Update("code", models.RoleCodeSuperAdmin).Error; err != nil {
+				return err
+			}
+			logger.InfoContext(context.Background(), "超级管理员角色已迁移为系统角色", "role_id", superAdminRole.ID)
+		}
+	}
 	if result.Error == gorm.ErrRecordNotFound {
 		superAdminRole = models.Role{
 			Name:        "超级管理员",
+			Code:        models.RoleCodeSuperAdmin,
 			Description: "拥有所有权限的超级管理员",
 		}
 		if err := db.Create(&superAdminRole).Error; err != nil {
 			return err
 		}
 		logger.InfoContext(context.Background(), "创建默认超级管理员角色")
+	} else if result.Error != nil {
+		return result.Error
 	}
 
 	var adminUser models.User
@@ -101,9 +136,11 @@ func initDefaultData(db *gorm.DB, logger *slog.Logger) error {
 			return err
 		}
 
+		// 默认密码公开可知，首次登录后必须修改
 		adminUser = models.User{
-			Username: "admin",
-			Password: string(hashedPassword),
+			Username:           "admin",
+			Password:           string(hashedPassword),
+			MustChangePassword: true,
 		}
 		if err := db.Create(&adminUser).Error; err != nil {
 			return err
@@ -114,6 +151,14 @@ func initDefaultData(db *gorm.DB, logger *slog.Logger) error {
 		}
 		logger.InfoContext(context.Background(), "创建默认管理员账号", "username", "admin", "password", "admin123")
 	}
+	if result.Error == nil && !adminUser.MustChangePassword &&
+		bcrypt.CompareHashAndPassword([]byte(adminUser.Password), []byte("admin123")) == nil {
+		// 已有部署仍在使用默认密码：要求下次登录时修改
+		if err := db.Model(&adminUser).Update("must_change_password", true).Error; err != nil {
+			return err
+		}
+		logger.WarnContext(context.Background(), "默认管理员仍在使用默认密码，下次登录须修改", "username", "admin")
+	}
 
 	return nil
 }
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/lyuangg/gadmin/internal/testutil"
+	"github.com/lyuangg/gadmin/models"
+)
+
+func TestInitDefaultData_CreatesSuperAdminRole(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	if err := initDefaultData(db, slog.Default()); err != nil {
+		t.Fatalf("initDefaultData: %v", err)
+	}
+
+	var role models.Role
+	if err := db.Where("code = ?", models.RoleCodeSuperAdmin).First(&role).Error; err != nil {
+		t.Fatalf("super admin role: %v", err)
+	}
+	var admin models.User
+	if err := db.Preload("Roles").Where("username = ?", "admin").First(&admin).Error; err != nil {
+		t.Fatalf("admin user: %v", err)
+	}
+	if len(admin.Roles) != 1 || admin.Roles[0].ID != role.ID {
+		t.Errorf("admin roles = %+v", admin.Roles)
+	}
+}
+
+// 旧版本按名称识别的超级管理员角色补上系统编码，不重复创建
+func TestInitDefaultData_MigratesRoleByName(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	legacy := models.Role{Name: "超级管理员"}
+	db.Create(&legacy)
+
+	if err := initDefaultData(db, slog.Default()); err != nil {
+		t.Fatalf("initDefaultData: %v", err)
+	}
+
+	var roles []models.Role
+	db.Where("name = ?", "超级管理员").Find(&roles)
+	if len(roles) != 1 || roles[0].ID != legacy.ID || roles[0].Code != models.RoleCodeSuperAdmin {
+		t.Errorf("roles = %+v", roles)
+	}
+}
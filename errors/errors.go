@@ -35,18 +35,24 @@ func NotFoundMsg(msg string) error {
 	return NewBizError(CodeNotFound, msg)
 }
 
+// TooManyRequestsMsg 返回 CodeTooManyRequests 的业务错误（如登录失败次数过多被锁定）
+func TooManyRequestsMsg(msg string) error {
+	return NewBizError(CodeTooManyRequests, msg)
+}
+
 func InternalErrorMsg(msg string) error {
 	return NewBizError(CodeInternalError, msg)
 }
 
 // 错误码定义
 const (
-	CodeSuccess        = 0    // 成功
-	CodeBadRequest     = 400  // 请求参数错误
-	CodeUnauthorized   = 401  // 未认证
-	CodeForbidden      = 403  // 无权限
-	CodeNotFound       = 404  // 资源不存在
-	CodeInternalError  = 500  // 服务器内部错误
+	CodeSuccess         = 0    // 成功
+	CodeBadRequest      = 400  // 请求参数错误
+	CodeUnauthorized    = 401  // 未认证
+	CodeForbidden       = 403  // 无权限
+	CodeNotFound        = 404  // 资源不存在
+	CodeTooManyRequests = 429  // 请求过于频繁
+	CodeInternalError   = 500  // 服务器内部错误
 	CodeValidationError = 1001 // 验证错误
-	CodeBusinessError  = 1002 // 业务逻辑错误
+	CodeBusinessError   = 1002 // 业务逻辑错误
 )
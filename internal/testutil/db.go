@@ -12,7 +12,7 @@ import (
 )
 
 // NewTestDB 创建内存 SQLite DB 并执行迁移，不写入默认数据。供 services、controllers 等单测共用。
-func NewTestDB(t *testing.T) *gorm.DB {
+func NewTestDB(t testing.TB) *gorm.DB {
 	t.Helper()
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
 		DisableForeignKeyConstraintWhenMigrating: true,
@@ -29,6 +29,22 @@ func NewTestDB(t *testing.T) *gorm.DB {
 		&models.OperationLog{},
 		&models.DictType{},
 		&models.DictItem{},
+		&models.RefreshToken{},
+		&models.UserSession{},
+		&models.UserBackupCode{},
+		&models.LoginChallenge{},
+		&models.LoginLock{},
+		&models.LoginLockEvent{},
+		&models.SecurityEvent{},
+		&models.PasswordHistory{},
+		&models.Captcha{},
+		&models.OIDCLogin{},
+		&models.UserIdentity{},
+		&models.APIKey{},
+		&models.IPRule{},
+		&models.Department{},
+		&models.Position{},
+		&models.Menu{},
 	)
 	if err != nil {
 		t.Fatalf("auto migrate: %v", err)
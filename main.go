@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"log/slog"
 	"os"
@@ -32,38 +33,82 @@ func (w *slogGinWriter) Write(p []byte) (n int, err error) {
 }
 
 func main() {
+	os.Exit(run())
+}
+
+// run 启动服务并返回退出码；os.Exit 会跳过 defer，因此退出码交由 main 处理，保证 appInstance.Close 被调用
+func run() int {
 	configPath := flag.String("c", "", "配置文件路径 (例如: -c ./config.yml)")
+	scanDryRun := flag.Bool("scan-dry-run", false, "输出路由与权限表的差异报告（JSON）后退出，不迁移表结构、不写入数据、不启动服务")
 	flag.Parse()
 
 	cfg, err := config.Load(*configPath)
 	if err != nil {
 		slog.Default().ErrorContext(context.Background(), "配置加载失败", "error", err)
-		os.Exit(1)
+		return 1
+	}
+
+	if err := utils.InitJWT(cfg); err != nil {
+		slog.Default().ErrorContext(context.Background(), "JWT 密钥配置错误", "error", err)
+		return 1
 	}
 
-	appInstance := app.NewApp(cfg)
+	var appInstance *app.App
+	if *scanDryRun {
+		// 只读报告：不执行 AutoMigrate 与默认数据初始化
+		appInstance = app.NewAppWithoutMigrate(cfg)
+	} else {
+		appInstance = app.NewApp(cfg)
+	}
 	defer appInstance.Close()
 
-	utils.InitJWT(cfg)
 	gin.SetMode(cfg.GinMode)
 
 	gin.DefaultWriter = &slogGinWriter{logger: appInstance.Logger(), level: slog.LevelInfo}
 	gin.DefaultErrorWriter = &slogGinWriter{logger: appInstance.Logger(), level: slog.LevelError}
 
 	router := gin.Default()
+	// 默认信任所有代理的 X-Forwarded-For，客户端可伪造 IP；只信任配置的代理
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		appInstance.Logger().ErrorContext(context.Background(), "trusted_proxies 配置错误", "error", err)
+		return 1
+	}
 	routes.SetupRoutes(router, appInstance)
 
 	scanner := routes.NewRouteScanner(router, appInstance)
+	if *scanDryRun {
+		report, err := scanner.Sync(true)
+		if err != nil {
+			appInstance.Logger().ErrorContext(context.Background(), "路由扫描失败", "error", err)
+			return 1
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			appInstance.Logger().ErrorContext(context.Background(), "输出报告失败", "error", err)
+			return 1
+		}
+		return 0
+	}
 	if err := scanner.ScanAndImport(); err != nil {
 		appInstance.Logger().ErrorContext(context.Background(), "路由扫描失败", "error", err)
 	}
+	// 首次启动时写入内置菜单，菜单按导入的路由权限绑定，需在路由扫描之后
+	if err := appInstance.GetMenuService().InitDefaultMenus(context.Background()); err != nil {
+		appInstance.Logger().ErrorContext(context.Background(), "初始化菜单失败", "error", err)
+	}
 
 	// 每天凌晨清理操作日志，保留条数见配置 operation_log_retain_count
 	tasks.StartOperationLogCleanScheduler(appInstance)
+	// captcha_store 为 db 时定期清理过期验证码
+	tasks.StartCaptchaCleanScheduler(appInstance)
+	// 每小时清理过期的刷新 Token、会话与登录挑战
+	tasks.StartTokenCleanScheduler(appInstance)
 
 	appInstance.Logger().InfoContext(context.Background(), "服务器启动", "port", cfg.Port)
 	if err := router.Run(":" + cfg.Port); err != nil {
 		appInstance.Logger().ErrorContext(context.Background(), "服务器启动失败", "error", err)
-		os.Exit(1)
+		return 1
 	}
+	return 0
 }
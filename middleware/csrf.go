@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authSourceContextKey AuthMiddleware 记录 Token 的来源（TokenSource），CSRF 校验只针对浏览器会自动附带的 cookie
+const authSourceContextKey = "auth_source"
+
+// CSRFMiddleware 认证来自 token cookie 时，非安全方法须在 X-CSRF-Token 请求头中带回 csrf_token cookie 的值；
+// Bearer 请求不会被跨站页面自动附带凭证，不做校验。须放在 AuthMiddleware 之后
+func CSRFMiddleware(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			c.Next()
+			return
+		}
+		if c.GetString(authSourceContextKey) != string(TokenSourceCookie) || utils.VerifyCSRF(c) {
+			c.Next()
+			return
+		}
+
+		var userID uint
+		var username string
+		if claims, ok := utils.ClaimsFromContext(c); ok {
+			userID, username = claims.UserID, claims.Username
+		}
+		recordSecurityEvent(a, c, models.SecurityEventCSRFRejected, userID, username, "CSRF Token 缺失或不匹配")
+		a.Responder.RespondError(c, errors.ForbiddenMsg("CSRF Token 无效，请刷新页面后重试"))
+		c.Abort()
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/services"
+	"github.com/lyuangg/gadmin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveCSRF 模拟 AuthMiddleware 已按 source 完成认证，再经过 CSRFMiddleware
+func serveCSRF(a *app.App, source TokenSource, req *http.Request) (*httptest.ResponseRecorder, bool) {
+	nextCalled := false
+	e := gin.New()
+	e.Use(func(c *gin.Context) {
+		c.Set("claims", &utils.Claims{UserID: 7, Username: "bob"})
+		c.Set(authSourceContextKey, string(source))
+		c.Next()
+	})
+	e.Use(CSRFMiddleware(a))
+	handler := func(c *gin.Context) { nextCalled = true; c.String(http.StatusOK, "ok") }
+	e.GET("/admin/api/users", handler)
+	e.POST("/admin/api/users", handler)
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w, nextCalled
+}
+
+func TestCSRFMiddleware_cookieAuthWithoutHeader_rejected(t *testing.T) {
+	events := &services.FakeSecurityEventService{}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{SecurityEventService: events})
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/users", nil)
+	req.AddCookie(&http.Cookie{Name: utils.CSRFCookieName, Value: "csrf-value"})
+
+	w, nextCalled := serveCSRF(a, TokenSourceCookie, req)
+	if nextCalled {
+		t.Fatal("expected request without CSRF header to be rejected")
+	}
+	if code, _ := parseResponseBody(t, w); code != errors.CodeForbidden {
+		t.Errorf("expected code %d, got %d", errors.CodeForbidden, code)
+	}
+	if len(events.Recorded) != 1 || events.Recorded[0].EventType != models.SecurityEventCSRFRejected || events.Recorded[0].UserID != 7 {
+		t.Errorf("recorded events = %+v", events.Recorded)
+	}
+}
+
+func TestCSRFMiddleware_cookieAuthMismatch_rejected(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{})
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/users", nil)
+	req.AddCookie(&http.Cookie{Name: utils.CSRFCookieName, Value: "csrf-value"})
+	req.Header.Set(utils.CSRFHeaderName, "other")
+
+	if _, nextCalled := serveCSRF(a, TokenSourceCookie, req); nextCalled {
+		t.Error("expected mismatched CSRF token to be rejected")
+	}
+}
+
+func TestCSRFMiddleware_cookieAuthWithHeader_passes(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{})
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/users", nil)
+	req.AddCookie(&http.Cookie{Name: utils.CSRFCookieName, Value: "csrf-value"})
+	req.Header.Set(utils.CSRFHeaderName, "csrf-value")
+
+	if _, nextCalled := serveCSRF(a, TokenSourceCookie, req); !nextCalled {
+		t.Error("expected matching CSRF token to pass")
+	}
+}
+
+func TestCSRFMiddleware_exemptions(t *testing.T) {
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{})
+
+	if _, nextCalled := serveCSRF(a, TokenSourceHeader, httptest.NewRequest(http.MethodPost, "/admin/api/users", nil)); !nextCalled {
+		t.Error("expected Bearer request to skip CSRF check")
+	}
+	if _, nextCalled := serveCSRF(a, TokenSourceCookie, httptest.NewRequest(http.MethodGet, "/admin/api/users", nil)); !nextCalled {
+		t.Error("expected GET request to skip CSRF check")
+	}
+}
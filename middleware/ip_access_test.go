@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/services"
+	"github.com/lyuangg/gadmin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 全局规则拒绝时返回 403 并记录安全事件，不进入后续处理
+func TestIPAccessMiddleware_Denied(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ruleMock := &services.FakeIPRuleService{CheckAccessErrs: map[uint]error{0: errors.ForbiddenMsg("IP 1.2.3.4 不在允许访问的范围内")}}
+	eventMock := &services.FakeSecurityEventService{}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{IPRuleService: ruleMock, SecurityEventService: eventMock})
+	r := gin.New()
+	r.Use(IPAccessMiddleware(a))
+	called := false
+	r.GET("/admin/api/users", func(c *gin.Context) { called = true })
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/api/users", nil))
+
+	if called {
+		t.Error("handler should not be called")
+	}
+	var body struct {
+		Code int `json:"code"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if body.Code != errors.CodeForbidden {
+		t.Errorf("code = %d, want %d", body.Code, errors.CodeForbidden)
+	}
+	if len(eventMock.Recorded) != 1 || eventMock.Recorded[0].EventType != models.SecurityEventIPDenied {
+		t.Errorf("recorded = %+v", eventMock.Recorded)
+	}
+}
+
+// 用户规则校验当前用户；模拟登录时发起人的规则同样校验
+func TestUserIPAccessMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name    string
+		claims  *utils.Claims
+		errs    map[uint]error
+		allowed bool
+		checked int
+	}{
+		{"无规则", &utils.Claims{UserID: 5}, nil, true, 1},
+		{"用户被拒绝", &utils.Claims{UserID: 5}, map[uint]error{5: errors.ForbiddenMsg("denied")}, false, 1},
+		{"模拟登录校验发起人", &utils.Claims{UserID: 5, ImpersonatorID: 1}, map[uint]error{1: errors.ForbiddenMsg("denied")}, false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ruleMock := &services.FakeIPRuleService{CheckAccessErrs: tt.errs}
+			a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{IPRuleService: ruleMock})
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("claims", tt.claims) })
+			r.Use(UserIPAccessMiddleware(a))
+			called := false
+			r.GET("/admin/api/users", func(c *gin.Context) { called = true })
+
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin/api/users", nil))
+
+			if called != tt.allowed {
+				t.Errorf("called = %v, want %v", called, tt.allowed)
+			}
+			if len(ruleMock.Checked) != tt.checked {
+				t.Errorf("checked = %v, want %d users", ruleMock.Checked, tt.checked)
+			}
+		})
+	}
+}
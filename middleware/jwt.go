@@ -7,37 +7,76 @@ import (
 	"github.com/lyuangg/gadmin/app"
 	"github.com/lyuangg/gadmin/errors"
 	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/routes/routemeta"
+	"github.com/lyuangg/gadmin/services"
 	"github.com/lyuangg/gadmin/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(a *app.App) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		var token string
+// mustChangePasswordRoutes 须修改密码的用户仅能访问的路由：修改密码页面与接口、退出登录
+var mustChangePasswordRoutes = map[string]bool{
+	"/admin/password":             true,
+	"/admin/api/profile/password": true,
+	"/admin/api/logout":           true,
+}
 
-		authHeader := c.GetHeader("Authorization")
-		if authHeader != "" {
-			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) == 2 && parts[0] == "Bearer" {
-				token = parts[1]
-			}
-		}
+// impersonationBlockedRoutes 模拟登录期间不能访问的接口：不能替被模拟用户修改密码、两步验证或创建凭证，也不能再次模拟
+var impersonationBlockedRoutes = map[string]bool{
+	"/admin/api/profile/password":         true,
+	"/admin/api/profile/2fa/setup":        true,
+	"/admin/api/profile/2fa/enable":       true,
+	"/admin/api/profile/2fa/disable":      true,
+	"/admin/api/profile/2fa/backup-codes": true,
+	"/admin/api/profile/api-keys":         true,
+	"/admin/api/profile/api-keys/:id":     true,
+	"/admin/api/profile/query-token":      true,
+	"/admin/api/users/:id/impersonate":    true,
+}
 
-		if token == "" {
-			cookieToken, err := c.Cookie("token")
-			if err == nil && cookieToken != "" {
-				token = cookieToken
-			}
-		}
+// TokenSource 认证 Token 的来源
+type TokenSource string
 
-		if token == "" {
-			token = c.Query("token")
+const (
+	TokenSourceHeader TokenSource = "header" // Authorization: Bearer，含 API Key
+	TokenSourceCookie TokenSource = "cookie" // 登录时写入的 token cookie
+	TokenSourceQuery  TokenSource = "query"  // ?token=，只接受 GenerateQueryToken 为当前路径签发的短期 Token
+)
+
+// AuthConfig 路由组接受的 Token 来源，按顺序读取第一个非空的
+type AuthConfig struct {
+	Sources []TokenSource
+}
+
+// DefaultAuthConfig 后台页面与接口的默认来源：请求头与 cookie，不接受查询参数
+var DefaultAuthConfig = AuthConfig{Sources: []TokenSource{TokenSourceHeader, TokenSourceCookie}}
+
+// AuthMiddleware 按 DefaultAuthConfig 认证
+func AuthMiddleware(a *app.App) gin.HandlerFunc {
+	return AuthMiddlewareWithConfig(a, DefaultAuthConfig)
+}
+
+// AuthMiddlewareWithConfig 按 cfg 中的来源认证；文件下载等只能通过链接访问的路由组可显式加入 TokenSourceQuery，
+// 查询参数会进入访问日志，因此只接受短期、限定路径的 Token，不接受会话 Token
+func AuthMiddlewareWithConfig(a *app.App, cfg AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, source := extractToken(c, cfg.Sources)
+
+		// API Key 只从 Authorization 头读取，不接受 cookie 与查询参数
+		if source == TokenSourceHeader && strings.HasPrefix(token, services.APIKeyPrefix) {
+			authenticateAPIKey(a, c, token)
+			return
 		}
 
+		// 携带了 Token 却被拒绝时记录安全事件；未携带 Token（如未登录访问页面）不记录
+		var rejectedUserID uint
+		var rejectedUsername string
 		redirectToLogin := func(errorMsg string, statusCode int) {
+			if token != "" {
+				recordSecurityEvent(a, c, models.SecurityEventTokenRejected, rejectedUserID, rejectedUsername, errorMsg)
+			}
 			if strings.Contains(c.GetHeader("Accept"), "text/html") {
-				c.SetCookie("token", "", -1, "/", "", false, true)
+				utils.SetCookie(c, a.Config, "token", "", -1, "/", true)
 				c.Redirect(http.StatusFound, "/login")
 				c.Abort()
 			} else {
@@ -55,15 +94,21 @@ func AuthMiddleware(a *app.App) gin.HandlerFunc {
 			return
 		}
 
-		claims, err := utils.ParseToken(token)
+		var claims *utils.Claims
+		var err error
+		if source == TokenSourceQuery {
+			claims, err = utils.ParseQueryToken(token, c.Request.URL.Path)
+		} else {
+			claims, err = utils.ParseToken(token)
+		}
 		if err != nil {
 			redirectToLogin("Token无效或已过期", http.StatusUnauthorized)
 			return
 		}
 
-		// 检查 token 中的 token_version 是否匹配用户的当前 token_version
-		_, tokenVersion, err := utils.GetTokenVersion(claims)
-		if err != nil {
+		rejectedUserID, rejectedUsername = claims.UserID, claims.Username
+
+		if claims.ID == "" {
 			redirectToLogin("Token格式错误", http.StatusUnauthorized)
 			return
 		}
@@ -74,30 +119,145 @@ func AuthMiddleware(a *app.App) gin.HandlerFunc {
 			return
 		}
 
-		if tokenVersion < user.TokenVersion {
+		// 检查 token 中的 token_version 是否匹配用户的当前 token_version
+		if claims.TokenVersion < user.TokenVersion {
 			redirectToLogin("Token已失效", http.StatusUnauthorized)
 			return
 		}
 
+		// 检查 jti 对应的会话未被吊销（单设备退出、管理员强制下线）；模拟登录沿用发起人的会话
+		client := services.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+		if err := a.GetSessionService().ValidateSession(c.Request.Context(), claims.SessionUserID(), claims.ID, client); err != nil {
+			redirectToLogin("登录会话已失效", http.StatusUnauthorized)
+			return
+		}
+
 		// 检查用户状态（0=禁用，1=启用）
 		if user.Status == 0 {
 			redirectToLogin("用户已被禁用", http.StatusForbidden)
 			return
 		}
 
+		// 模拟登录期间发起人被禁用或不再是超级管理员时，模拟登录立即失效
+		if claims.ImpersonatorID != 0 {
+			impersonator, err := a.GetUserService().GetUserForAuth(c.Request.Context(), claims.ImpersonatorID)
+			if err != nil || impersonator == nil || impersonator.Status == 0 || !services.IsSuperAdmin(impersonator) {
+				redirectToLogin("模拟登录已失效", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if claims.ImpersonatorID != 0 && impersonationBlockedRoutes[c.FullPath()] {
+			a.Responder.RespondError(c, errors.ForbiddenMsg("模拟登录期间不能进行此操作"))
+			c.Abort()
+			return
+		}
+
+		// 管理员重置或首次登录的密码须先修改，页面请求跳转到修改密码页；模拟登录只为查看，不受此限制
+		if user.MustChangePassword && claims.ImpersonatorID == 0 && !mustChangePasswordRoutes[c.FullPath()] {
+			if strings.Contains(c.GetHeader("Accept"), "text/html") {
+				c.Redirect(http.StatusFound, "/admin/password?must_change=1")
+			} else {
+				a.Responder.RespondError(c, errors.ForbiddenMsg("请先修改密码"))
+			}
+			c.Abort()
+			return
+		}
+
 		user.ID = claims.UserID
 		user.Username = claims.Username
 		user.Nickname = claims.Nickname
 		user.Type = claims.Type
 
-		user.Roles = make([]models.Role, len(claims.RoleIDs))
-		for i, roleID := range claims.RoleIDs {
-			user.Roles[i] = models.Role{ID: roleID}
+		// 角色与超级管理员身份按用户当前的角色计算，而不是签发时写入 Token 的值，
+		// 调整用户角色、删除角色后无需重新登录即可生效
+		current := *claims
+		current.RoleIDs = make([]uint, len(user.Roles))
+		for i, role := range user.Roles {
+			current.RoleIDs[i] = role.ID
 		}
+		current.IsSuperAdmin = services.IsSuperAdmin(user)
 
 		c.Set("user", *user)
-		c.Set("claims", claims)
+		c.Set("claims", &current)
+		c.Set(authSourceContextKey, string(source))
 
 		c.Next()
 	}
 }
+
+// extractToken 按 sources 的顺序读取 Token，返回第一个非空的及其来源
+func extractToken(c *gin.Context, sources []TokenSource) (string, TokenSource) {
+	for _, source := range sources {
+		var token string
+		switch source {
+		case TokenSourceHeader:
+			parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+			if len(parts) == 2 && parts[0] == "Bearer" {
+				token = parts[1]
+			}
+		case TokenSourceCookie:
+			token, _ = c.Cookie("token")
+		case TokenSourceQuery:
+			token = c.Query("token")
+		}
+		if token != "" {
+			return token, source
+		}
+	}
+	return "", ""
+}
+
+// authenticateAPIKey API Key 认证：只能调用通过 RegisterRouteWithPermission 登记、受 PermissionMiddleware 保护的接口，
+// 页面、个人中心（含 API Key 管理）、退出登录、模拟登录等未登记的接口一律拒绝；
+// 创建者被禁用或须修改密码时拒绝。通过后按创建者当前的角色构造 claims，PermissionMiddleware 再按 Key 的授权范围收窄
+func authenticateAPIKey(a *app.App, c *gin.Context, rawKey string) {
+	if !routemeta.IsRegistered(c.Request.Method, c.FullPath()) {
+		recordSecurityEvent(a, c, models.SecurityEventTokenRejected, 0, "", "API Key 不能访问此接口")
+		a.Responder.RespondError(c, errors.ForbiddenMsg("API Key 不能访问此接口"))
+		c.Abort()
+		return
+	}
+
+	key, err := a.GetAPIKeyService().Authenticate(c.Request.Context(), rawKey, c.ClientIP())
+	if err != nil {
+		recordSecurityEvent(a, c, models.SecurityEventTokenRejected, 0, "", "API Key: "+err.Error())
+		a.Responder.RespondError(c, err)
+		c.Abort()
+		return
+	}
+
+	user := *key.User
+	if user.Status == 0 {
+		recordSecurityEvent(a, c, models.SecurityEventTokenRejected, user.ID, user.Username, "API Key: 用户已被禁用")
+		a.Responder.RespondError(c, errors.ForbiddenMsg("用户已被禁用"))
+		c.Abort()
+		return
+	}
+	if user.MustChangePassword {
+		recordSecurityEvent(a, c, models.SecurityEventTokenRejected, user.ID, user.Username, "API Key: 创建者须先修改密码")
+		a.Responder.RespondError(c, errors.ForbiddenMsg("请先修改密码"))
+		c.Abort()
+		return
+	}
+	roleIDs := make([]uint, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		roleIDs = append(roleIDs, role.ID)
+	}
+	claims := &utils.Claims{
+		UserID:       user.ID,
+		Username:     user.Username,
+		Nickname:     user.Nickname,
+		Type:         user.Type,
+		IsSuperAdmin: services.IsSuperAdmin(&user),
+		RoleIDs:      roleIDs,
+		TokenVersion: user.TokenVersion,
+	}
+
+	c.Set("user", user)
+	c.Set("claims", claims)
+	c.Set("api_key", key)
+	c.Set(authSourceContextKey, string(TokenSourceHeader))
+
+	c.Next()
+}
@@ -5,11 +5,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/lyuangg/gadmin/app"
 	"github.com/lyuangg/gadmin/config"
 	"github.com/lyuangg/gadmin/errors"
 	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/routes/routemeta"
 	"github.com/lyuangg/gadmin/services"
 	"github.com/lyuangg/gadmin/utils"
 
@@ -26,7 +28,8 @@ func initTestJWT(t *testing.T) {
 // 无 token 时 API 请求应返回 401 统一错误
 func TestAuthMiddleware_NoToken(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	a := app.NewTestAppWithServiceMocks(nil)
+	events := &services.FakeSecurityEventService{}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{SecurityEventService: events})
 	r := gin.New()
 	r.Use(AuthMiddleware(a))
 	r.GET("/api/protected", func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) })
@@ -36,6 +39,11 @@ func TestAuthMiddleware_NoToken(t *testing.T) {
 	rec := httptest.NewRecorder()
 	r.ServeHTTP(rec, req)
 
+	// 未携带 Token 不算安全事件
+	if len(events.Recorded) != 0 {
+		t.Errorf("recorded events = %+v, want none", events.Recorded)
+	}
+
 	if rec.Code != http.StatusOK {
 		t.Errorf("status = %d, want 200 (Responder 写 JSON 时用 200)", rec.Code)
 	}
@@ -55,7 +63,8 @@ func TestAuthMiddleware_NoToken(t *testing.T) {
 func TestAuthMiddleware_InvalidToken(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	initTestJWT(t)
-	a := app.NewTestAppWithServiceMocks(nil)
+	events := &services.FakeSecurityEventService{}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{SecurityEventService: events})
 	r := gin.New()
 	r.Use(AuthMiddleware(a))
 	r.GET("/api/protected", func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) })
@@ -66,6 +75,10 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 	rec := httptest.NewRecorder()
 	r.ServeHTTP(rec, req)
 
+	if len(events.Recorded) != 1 || events.Recorded[0].EventType != models.SecurityEventTokenRejected {
+		t.Errorf("recorded events = %+v, want one token_rejected", events.Recorded)
+	}
+
 	if rec.Code != http.StatusOK {
 		t.Errorf("status = %d, want 200", rec.Code)
 	}
@@ -84,7 +97,7 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 func TestAuthMiddleware_UserNotFound(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	initTestJWT(t)
-	token, err := utils.GenerateToken(1, "u", "n", 0, false, []uint{1}, 0)
+	token, err := utils.GenerateToken(1, "u", "n", 0, false, []uint{1}, 0, "sess-1")
 	if err != nil {
 		t.Fatalf("generate token: %v", err)
 	}
@@ -92,7 +105,7 @@ func TestAuthMiddleware_UserNotFound(t *testing.T) {
 		GetUserForAuthUser: nil,
 		GetUserForAuthErr:  errors.UnauthorizedMsg("用户不存在"),
 	}
-	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock})
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock, SessionService: &services.FakeSessionService{}})
 	r := gin.New()
 	r.Use(AuthMiddleware(a))
 	r.GET("/api/protected", func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) })
@@ -122,7 +135,7 @@ func TestAuthMiddleware_TokenVersionMismatch(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	initTestJWT(t)
 	// token 里 token_version=0，用户当前 token_version=1
-	token, err := utils.GenerateToken(1, "u", "n", 0, false, []uint{1}, 0)
+	token, err := utils.GenerateToken(1, "u", "n", 0, false, []uint{1}, 0, "sess-1")
 	if err != nil {
 		t.Fatalf("generate token: %v", err)
 	}
@@ -130,7 +143,7 @@ func TestAuthMiddleware_TokenVersionMismatch(t *testing.T) {
 		GetUserForAuthUser: &models.User{ID: 1, Username: "u", Nickname: "n", Type: 0, Status: 1, TokenVersion: 1},
 		GetUserForAuthErr:  nil,
 	}
-	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock})
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock, SessionService: &services.FakeSessionService{}})
 	r := gin.New()
 	r.Use(AuthMiddleware(a))
 	r.GET("/api/protected", func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) })
@@ -154,7 +167,7 @@ func TestAuthMiddleware_TokenVersionMismatch(t *testing.T) {
 func TestAuthMiddleware_UserDisabled(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	initTestJWT(t)
-	token, err := utils.GenerateToken(1, "u", "n", 0, false, []uint{1}, 0)
+	token, err := utils.GenerateToken(1, "u", "n", 0, false, []uint{1}, 0, "sess-1")
 	if err != nil {
 		t.Fatalf("generate token: %v", err)
 	}
@@ -162,7 +175,7 @@ func TestAuthMiddleware_UserDisabled(t *testing.T) {
 		GetUserForAuthUser: &models.User{ID: 1, Username: "u", Nickname: "n", Type: 0, Status: 0, TokenVersion: 0},
 		GetUserForAuthErr:  nil,
 	}
-	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock})
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock, SessionService: &services.FakeSessionService{}})
 	r := gin.New()
 	r.Use(AuthMiddleware(a))
 	r.GET("/api/protected", func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) })
@@ -186,15 +199,15 @@ func TestAuthMiddleware_UserDisabled(t *testing.T) {
 func TestAuthMiddleware_ValidToken(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	initTestJWT(t)
-	token, err := utils.GenerateToken(1, "testuser", "测试", 0, false, []uint{1, 2}, 0)
+	token, err := utils.GenerateToken(1, "testuser", "测试", 0, false, []uint{1, 2}, 0, "sess-1")
 	if err != nil {
 		t.Fatalf("generate token: %v", err)
 	}
 	userMock := &services.FakeUserService{
-		GetUserForAuthUser: &models.User{ID: 1, Username: "testuser", Nickname: "测试", Type: 0, Status: 1, TokenVersion: 0},
+		GetUserForAuthUser: &models.User{ID: 1, Username: "testuser", Nickname: "测试", Type: 0, Status: 1, TokenVersion: 0, Roles: []models.Role{{ID: 1}, {ID: 2}}},
 		GetUserForAuthErr:  nil,
 	}
-	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock})
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock, SessionService: &services.FakeSessionService{}})
 	r := gin.New()
 	r.Use(AuthMiddleware(a))
 	var gotUser models.User
@@ -223,3 +236,337 @@ func TestAuthMiddleware_ValidToken(t *testing.T) {
 		t.Errorf("claims = %+v", gotClaims)
 	}
 }
+
+// 角色按用户当前状态计算：Token 签发后被移除超级管理员角色、删除角色，下一次请求即生效
+func TestAuthMiddleware_UsesCurrentRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	initTestJWT(t)
+	token, err := utils.GenerateToken(1, "root", "", 0, true, []uint{1, 2}, 0, "sess-1")
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	userMock := &services.FakeUserService{GetUserForAuthUser: &models.User{ID: 1, Username: "root", Status: 1, Roles: []models.Role{{ID: 3}}}}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock, SessionService: &services.FakeSessionService{}})
+	r := gin.New()
+	r.Use(AuthMiddleware(a))
+	var gotClaims *utils.Claims
+	r.GET("/api/protected", func(c *gin.Context) {
+		gotClaims, _ = utils.ClaimsFromContext(c)
+		c.JSON(200, gin.H{"ok": true})
+	})
+
+	serve := func() {
+		req := httptest.NewRequest(http.MethodGet, "/api/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve()
+	if gotClaims == nil || gotClaims.IsSuperAdmin || len(gotClaims.RoleIDs) != 1 || gotClaims.RoleIDs[0] != 3 {
+		t.Errorf("claims = %+v, want current roles [3] without super admin", gotClaims)
+	}
+
+	// 重新授予超级管理员角色同样立即生效
+	userMock.GetUserForAuthUser = &models.User{ID: 1, Username: "root", Status: 1, Roles: []models.Role{{ID: 9, Code: models.RoleCodeSuperAdmin}}}
+	serve()
+	if gotClaims == nil || !gotClaims.IsSuperAdmin {
+		t.Errorf("claims = %+v, want super admin", gotClaims)
+	}
+}
+
+// 有效 token 但会话已被吊销（其他设备退出或管理员强制下线）→ 401
+func TestAuthMiddleware_SessionRevoked(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	initTestJWT(t)
+	token, err := utils.GenerateToken(1, "u", "n", 0, false, []uint{1}, 0, "sess-1")
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	userMock := &services.FakeUserService{
+		GetUserForAuthUser: &models.User{ID: 1, Username: "u", Nickname: "n", Type: 0, Status: 1, TokenVersion: 0},
+	}
+	sessionMock := &services.FakeSessionService{ValidateSessionErr: errors.UnauthorizedMsg("会话已失效")}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock, SessionService: sessionMock})
+	r := gin.New()
+	r.Use(AuthMiddleware(a))
+	r.GET("/api/protected", func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/protected", nil)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	var body struct {
+		Code int `json:"code"`
+	}
+	_ = json.NewDecoder(rec.Body).Decode(&body)
+	if body.Code != errors.CodeUnauthorized {
+		t.Errorf("code = %d, want %d (会话已失效)", body.Code, errors.CodeUnauthorized)
+	}
+}
+
+// 须修改密码的用户只能访问修改密码与退出接口，其他 API 返回 403，页面跳转到修改密码页
+func TestAuthMiddleware_MustChangePassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	initTestJWT(t)
+	token, err := utils.GenerateToken(1, "u", "n", 0, false, []uint{1}, 0, "sess-1")
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	userMock := &services.FakeUserService{
+		GetUserForAuthUser: &models.User{ID: 1, Username: "u", Status: 1, MustChangePassword: true},
+	}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock, SessionService: &services.FakeSessionService{}})
+	r := gin.New()
+	r.Use(AuthMiddleware(a))
+	ok := func(c *gin.Context) { c.JSON(200, gin.H{"code": 0}) }
+	r.GET("/admin/users", ok)
+	r.GET("/admin/api/users", ok)
+	r.PUT("/admin/api/profile/password", ok)
+	r.POST("/admin/api/logout", ok)
+
+	cases := []struct {
+		method, path, accept string
+		wantCode             int
+		wantStatus           int
+	}{
+		{http.MethodGet, "/admin/api/users", "application/json", errors.CodeForbidden, http.StatusOK},
+		{http.MethodPut, "/admin/api/profile/password", "application/json", 0, http.StatusOK},
+		{http.MethodPost, "/admin/api/logout", "application/json", 0, http.StatusOK},
+		{http.MethodGet, "/admin/users", "text/html", 0, http.StatusFound},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Accept", tc.accept)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != tc.wantStatus {
+			t.Errorf("%s %s status = %d, want %d", tc.method, tc.path, rec.Code, tc.wantStatus)
+			continue
+		}
+		if tc.wantStatus == http.StatusFound {
+			if loc := rec.Header().Get("Location"); loc != "/admin/password?must_change=1" {
+				t.Errorf("redirect = %q", loc)
+			}
+			continue
+		}
+		var body struct {
+			Code int `json:"code"`
+		}
+		_ = json.NewDecoder(rec.Body).Decode(&body)
+		if body.Code != tc.wantCode {
+			t.Errorf("%s %s code = %d, want %d", tc.method, tc.path, body.Code, tc.wantCode)
+		}
+	}
+}
+
+// API Key 认证：只能调用登记了权限的业务接口，不能访问个人中心（含 API Key 管理）、模拟登录等其他接口
+func TestAuthMiddleware_APIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	owner := &models.User{ID: 7, Username: "ci", Status: 1, Roles: []models.Role{{ID: 3, Name: "运维"}}}
+	keyMock := &services.FakeAPIKeyService{AuthKey: &models.APIKey{ID: 1, UserID: 7, User: owner}}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{APIKeyService: keyMock})
+	r := gin.New()
+	r.Use(AuthMiddleware(a))
+	var gotClaims *utils.Claims
+	routemeta.RegisterRoutePermission("GET", "/admin/api/users", "查询用户列表", "用户管理")
+	r.GET("/admin/api/users", func(c *gin.Context) {
+		cl, _ := c.Get("claims")
+		gotClaims = cl.(*utils.Claims)
+		c.JSON(200, gin.H{"ok": true})
+	})
+	r.POST("/admin/api/profile/api-keys", func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) })
+	r.POST("/admin/api/users/:id/impersonate", func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) })
+
+	decode := func(rec *httptest.ResponseRecorder) int {
+		var body struct {
+			Code int `json:"code"`
+		}
+		json.NewDecoder(rec.Body).Decode(&body)
+		return body.Code
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/users", nil)
+	req.Header.Set("Authorization", "Bearer "+services.APIKeyPrefix+"secret")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if gotClaims == nil || gotClaims.UserID != 7 || len(gotClaims.RoleIDs) != 1 || gotClaims.IsSuperAdmin {
+		t.Errorf("claims = %+v", gotClaims)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/api/profile/api-keys", nil)
+	req.Header.Set("Authorization", "Bearer "+services.APIKeyPrefix+"secret")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if code := decode(rec); code != errors.CodeForbidden {
+		t.Errorf("profile route with api key: code = %d, want %d", code, errors.CodeForbidden)
+	}
+
+	// 未登记权限的接口（不受 PermissionMiddleware 保护）即使在 /admin/api/ 下也不能访问
+	req = httptest.NewRequest(http.MethodPost, "/admin/api/users/2/impersonate", nil)
+	req.Header.Set("Authorization", "Bearer "+services.APIKeyPrefix+"secret")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if code := decode(rec); code != errors.CodeForbidden {
+		t.Errorf("impersonate with api key: code = %d, want %d", code, errors.CodeForbidden)
+	}
+
+	// 创建者须修改密码或已被禁用时拒绝
+	for _, blocked := range []models.User{
+		{ID: 7, Username: "ci", Status: 1, MustChangePassword: true},
+		{ID: 7, Username: "ci", Status: 0},
+	} {
+		blocked := blocked
+		keyMock.AuthKey = &models.APIKey{ID: 1, UserID: 7, User: &blocked}
+		req = httptest.NewRequest(http.MethodGet, "/admin/api/users", nil)
+		req.Header.Set("Authorization", "Bearer "+services.APIKeyPrefix+"secret")
+		rec = httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if code := decode(rec); code != errors.CodeForbidden {
+			t.Errorf("owner %+v: code = %d, want %d", blocked, code, errors.CodeForbidden)
+		}
+	}
+
+	keyMock.AuthKey, keyMock.AuthErr = nil, errors.UnauthorizedMsg("API Key无效")
+	req = httptest.NewRequest(http.MethodGet, "/admin/api/users", nil)
+	req.Header.Set("Authorization", "Bearer "+services.APIKeyPrefix+"wrong")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if code := decode(rec); code != errors.CodeUnauthorized {
+		t.Errorf("invalid api key: code = %d, want %d", code, errors.CodeUnauthorized)
+	}
+}
+
+// 默认来源不接受 ?token=，即使是有效的会话 Token
+func TestAuthMiddleware_DefaultRejectsQueryToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	initTestJWT(t)
+	token, _ := utils.GenerateToken(1, "testuser", "测试", 0, false, nil, 0, "sess-1")
+	userMock := &services.FakeUserService{GetUserForAuthUser: &models.User{ID: 1, Username: "testuser", Status: 1}}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock, SessionService: &services.FakeSessionService{}})
+	r := gin.New()
+	r.Use(AuthMiddleware(a))
+	r.GET("/api/protected", func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/protected?token="+token, nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	var body struct {
+		Code int `json:"code"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if body.Code != errors.CodeUnauthorized {
+		t.Errorf("code = %d, want %d", body.Code, errors.CodeUnauthorized)
+	}
+}
+
+// 显式接受查询参数的路由组只认为当前路径签发的查询 Token，不认会话 Token
+func TestAuthMiddleware_QueryTokenSource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	initTestJWT(t)
+	userMock := &services.FakeUserService{GetUserForAuthUser: &models.User{ID: 1, Username: "testuser", Status: 1}}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock, SessionService: &services.FakeSessionService{}})
+	r := gin.New()
+	r.Use(AuthMiddlewareWithConfig(a, AuthConfig{Sources: []TokenSource{TokenSourceQuery}}))
+	r.GET("/admin/download/:id", func(c *gin.Context) { c.JSON(200, gin.H{"code": 0}) })
+
+	session := &utils.Claims{UserID: 1, Username: "testuser"}
+	session.ID = "sess-1"
+	queryToken, err := utils.GenerateQueryToken(session, "/admin/download/1")
+	if err != nil {
+		t.Fatalf("generate query token: %v", err)
+	}
+	sessionToken, _ := utils.GenerateToken(1, "testuser", "测试", 0, false, nil, 0, "sess-1")
+
+	tests := []struct {
+		name string
+		url  string
+		want int
+	}{
+		{name: "query token for path", url: "/admin/download/1?token=" + queryToken, want: 0},
+		{name: "query token for other path", url: "/admin/download/2?token=" + queryToken, want: errors.CodeUnauthorized},
+		{name: "session token in query", url: "/admin/download/1?token=" + sessionToken, want: errors.CodeUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			var body struct {
+				Code int `json:"code"`
+			}
+			_ = json.Unmarshal(rec.Body.Bytes(), &body)
+			if body.Code != tt.want {
+				t.Errorf("code = %d, want %d", body.Code, tt.want)
+			}
+		})
+	}
+}
+
+// 模拟登录 Token：会话按发起人校验，凭证类接口被拒绝；发起人被禁用或不再是超级管理员时立即失效
+func TestAuthMiddleware_Impersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	initTestJWT(t)
+	token, err := utils.GenerateImpersonationToken(5, "ops", "运维", 0, []uint{2}, 0, "sess-root", 1, "root", time.Minute)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	superAdmin := models.Role{ID: 1, Code: models.RoleCodeSuperAdmin}
+	root := &models.User{ID: 1, Username: "root", Status: 1, Roles: []models.Role{superAdmin}}
+	userMock := &services.FakeUserService{GetUserForAuthUsers: map[uint]*models.User{
+		1: root,
+		5: {ID: 5, Username: "ops", Status: 1, MustChangePassword: true},
+	}}
+	sessionMock := &services.FakeSessionService{}
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{UserService: userMock, SessionService: sessionMock})
+	r := gin.New()
+	r.Use(AuthMiddleware(a))
+	var gotClaims *utils.Claims
+	handler := func(c *gin.Context) {
+		gotClaims, _ = utils.ClaimsFromContext(c)
+		c.JSON(200, gin.H{"code": 0})
+	}
+	r.GET("/admin/api/users", handler)
+	r.PUT("/admin/api/profile/password", handler)
+
+	serve := func(method, path string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		var body struct {
+			Code int `json:"code"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		return body.Code
+	}
+
+	if code := serve(http.MethodGet, "/admin/api/users"); code != 0 {
+		t.Fatalf("code = %d, want 0", code)
+	}
+	if sessionMock.ValidatedUserID != 1 {
+		t.Errorf("session validated for user %d, want impersonator 1", sessionMock.ValidatedUserID)
+	}
+	if gotClaims == nil || gotClaims.UserID != 5 || gotClaims.ImpersonatorID != 1 || gotClaims.IsSuperAdmin {
+		t.Errorf("claims = %+v", gotClaims)
+	}
+	if code := serve(http.MethodPut, "/admin/api/profile/password"); code != errors.CodeForbidden {
+		t.Errorf("change password while impersonating: code = %d, want %d", code, errors.CodeForbidden)
+	}
+
+	root.Roles = nil
+	if code := serve(http.MethodGet, "/admin/api/users"); code != errors.CodeUnauthorized {
+		t.Errorf("impersonator demoted: code = %d, want %d", code, errors.CodeUnauthorized)
+	}
+	root.Roles, root.Status = []models.Role{superAdmin}, 0
+	if code := serve(http.MethodGet, "/admin/api/users"); code != errors.CodeUnauthorized {
+		t.Errorf("impersonator disabled: code = %d, want %d", code, errors.CodeUnauthorized)
+	}
+}
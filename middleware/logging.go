@@ -3,6 +3,7 @@ package middleware
 import (
 	"bytes"
 	"io"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -23,7 +24,7 @@ func LoggingMiddleware(a *app.App) gin.HandlerFunc {
 
 		start := time.Now()
 		method := c.Request.Method
-		query := c.Request.URL.RawQuery
+		query := redactQuery(c.Request.URL.RawQuery)
 		clientIP := c.ClientIP()
 		isAPI := strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/admin/api/")
 
@@ -70,3 +71,28 @@ func LoggingMiddleware(a *app.App) gin.HandlerFunc {
 		}
 	}
 }
+
+// redactedQueryParams 不写入日志的查询参数
+var redactedQueryParams = []string{"token"}
+
+// redactQuery 把查询字符串中的凭证替换为 ***，避免 Token 落入访问日志
+func redactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "(unparsable query)"
+	}
+	redacted := false
+	for _, name := range redactedQueryParams {
+		if _, ok := values[name]; ok {
+			values.Set(name, "***")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return rawQuery
+	}
+	return values.Encode()
+}
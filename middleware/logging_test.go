@@ -139,3 +139,25 @@ func TestLoggingMiddleware_LogsAPIRequestWithBodies(t *testing.T) {
 		t.Errorf("resp_body = %v", m["resp_body"])
 	}
 }
+
+// 查询参数中的 token 不写入日志
+func TestLoggingMiddleware_RedactsQueryToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mock := &loggingMock{}
+	a := app.NewTestAppWithLogger(mock)
+	r := gin.New()
+	r.Use(LoggingMiddleware(a))
+	r.GET("/admin/download", func(c *gin.Context) { c.String(200, "ok") })
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/download?id=1&token=secret-value", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	calls := mock.getInfoCalls()
+	if len(calls) != 1 {
+		t.Fatalf("InfoContext 调用次数 = %d, want 1", len(calls))
+	}
+	query, _ := loggingArgsToMap(calls[0].Args)["query"].(string)
+	if strings.Contains(query, "secret-value") || !strings.Contains(query, "id=1") {
+		t.Errorf("query = %q, token 应被替换", query)
+	}
+}
@@ -18,7 +18,22 @@ import (
 // 操作记录的最大数据大小限制（50KB，MySQL TEXT 类型最大为 64KB）
 const maxOperationLogSize = 50 * 1024
 
-// OperationLogMiddleware 记录 /admin/api 下 PUT、DELETE、POST 的请求与响应
+// secretResponseRoutes 响应中含凭证原文的接口（"方法 路由"），操作日志不记录其响应，
+// 否则有查看或导出操作日志权限的人可以读到仍然有效的凭证
+var secretResponseRoutes = map[string]bool{
+	"POST /admin/api/profile/api-keys":         true, // API Key 原文
+	"POST /admin/api/profile/2fa/setup":        true, // 两步验证密钥
+	"POST /admin/api/profile/2fa/enable":       true, // 备用码
+	"POST /admin/api/profile/2fa/backup-codes": true, // 备用码
+	"POST /admin/api/profile/query-token":      true, // 下载链接的查询参数 Token
+	"POST /admin/api/users/:id/impersonate":    true, // 被模拟用户的访问 Token
+}
+
+// redactedResponse 替代不记录的响应内容
+const redactedResponse = "(响应含凭证，不记录)"
+
+// OperationLogMiddleware 记录 /admin/api 下 PUT、DELETE、POST 的请求与响应；模拟登录期间记录全部请求，
+// 同时记下被模拟用户与发起人
 func OperationLogMiddleware(a *app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if !strings.HasPrefix(c.Request.URL.Path, "/admin/api/") {
@@ -26,21 +41,23 @@ func OperationLogMiddleware(a *app.App) gin.HandlerFunc {
 			return
 		}
 
+		var userID, impersonatorID uint
+		var username, impersonatorName string
+		if claims, ok := utils.ClaimsFromContext(c); ok {
+			userID = claims.UserID
+			username = claims.Username
+			impersonatorID = claims.ImpersonatorID
+			impersonatorName = claims.ImpersonatorName
+		}
+
 		method := c.Request.Method
-		if method != "PUT" && method != "DELETE" && method != "POST" {
+		if method != "PUT" && method != "DELETE" && method != "POST" && impersonatorID == 0 {
 			c.Next()
 			return
 		}
 
 		startTime := time.Now()
 
-		var userID uint
-		var username string
-		if claims, ok := utils.ClaimsFromContext(c); ok {
-			userID = claims.UserID
-			username = claims.Username
-		}
-
 		var requestBody string
 		if c.Request.Body != nil {
 			bodyBytes, err := io.ReadAll(c.Request.Body)
@@ -70,21 +87,26 @@ func OperationLogMiddleware(a *app.App) gin.HandlerFunc {
 
 		formattedRequest := formatJSON(requestBody)
 		formattedResponse := formatJSON(responseBody)
+		if secretResponseRoutes[method+" "+c.FullPath()] {
+			formattedResponse = redactedResponse
+		}
 		routePermissionInfo := routemeta.GetRoutePermission(method, c.Request.URL.Path)
 		routeName := routePermissionInfo.Name
 
 		operationLog := models.OperationLog{
-			UserID:     userID,
-			Username:   username,
-			Method:     method,
-			Path:       c.Request.URL.Path,
-			RouteName:  routeName,
-			Request:    formattedRequest,
-			Response:   formattedResponse,
-			StatusCode: c.Writer.Status(),
-			IP:         c.ClientIP(),
-			UserAgent:  c.Request.UserAgent(),
-			Duration:   duration,
+			UserID:           userID,
+			Username:         username,
+			ImpersonatorID:   impersonatorID,
+			ImpersonatorName: impersonatorName,
+			Method:           method,
+			Path:             c.Request.URL.Path,
+			RouteName:        routeName,
+			Request:          formattedRequest,
+			Response:         formattedResponse,
+			StatusCode:       c.Writer.Status(),
+			IP:               c.ClientIP(),
+			UserAgent:        c.Request.UserAgent(),
+			Duration:         duration,
 		}
 
 		reqCtx := c.Copy()
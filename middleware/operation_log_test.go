@@ -114,3 +114,84 @@ func TestOperationLogMiddleware_RecordsLog(t *testing.T) {
 		t.Errorf("response should contain id, got %s", got.Response)
 	}
 }
+
+// 模拟登录期间 GET 也记录，且同时记下被模拟用户与发起人
+func TestOperationLogMiddleware_RecordsImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTestDB(t)
+	a := app.NewTestApp(db)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", &utils.Claims{UserID: 5, Username: "ops", ImpersonatorID: 1, ImpersonatorName: "root"})
+		c.Next()
+	})
+	r.Use(OperationLogMiddleware(a))
+	r.GET("/admin/api/users", func(c *gin.Context) { c.String(200, "[]") })
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/users", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	time.Sleep(100 * time.Millisecond)
+
+	var logs []models.OperationLog
+	if err := db.Find(&logs).Error; err != nil {
+		t.Fatalf("find logs: %v", err)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("expected 1 log, got %d", len(logs))
+	}
+	got := logs[0]
+	if got.Method != "GET" || got.UserID != 5 || got.Username != "ops" || got.ImpersonatorID != 1 || got.ImpersonatorName != "root" {
+		t.Errorf("log = %+v", got)
+	}
+}
+
+// 响应含凭证原文的接口只记录请求，不记录响应
+func TestOperationLogMiddleware_RedactsSecretResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTestDB(t)
+	// 日志异步写入且并发；内存库每个连接是独立的库，须共用一个连接
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	a := app.NewTestApp(db)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", &utils.Claims{UserID: 1, Username: "oploguser"})
+		c.Next()
+	})
+	r.Use(OperationLogMiddleware(a))
+	for route := range secretResponseRoutes {
+		method, path, _ := strings.Cut(route, " ")
+		r.Handle(method, path, func(c *gin.Context) {
+			c.JSON(200, gin.H{"secret": "live-credential"})
+		})
+	}
+
+	for route := range secretResponseRoutes {
+		method, path, _ := strings.Cut(route, " ")
+		req := httptest.NewRequest(method, strings.ReplaceAll(path, ":id", "1"), strings.NewReader(`{"name":"deploy"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if !strings.Contains(rec.Body.String(), "live-credential") {
+			t.Errorf("%s: client should still receive the response", route)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	var logs []models.OperationLog
+	if err := db.Find(&logs).Error; err != nil {
+		t.Fatalf("find logs: %v", err)
+	}
+	if len(logs) != len(secretResponseRoutes) {
+		t.Fatalf("expected %d logs, got %d", len(secretResponseRoutes), len(logs))
+	}
+	for _, l := range logs {
+		if strings.Contains(l.Response, "live-credential") || l.Response != redactedResponse {
+			t.Errorf("%s %s response = %q, want redacted", l.Method, l.Path, l.Response)
+		}
+		if !strings.Contains(l.Request, "deploy") {
+			t.Errorf("%s %s request should still be recorded, got %q", l.Method, l.Path, l.Request)
+		}
+	}
+}
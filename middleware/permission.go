@@ -1,12 +1,10 @@
 package middleware
 
 import (
-	"regexp"
-	"strings"
-
 	"github.com/lyuangg/gadmin/app"
 	"github.com/lyuangg/gadmin/errors"
 	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/services"
 	"github.com/lyuangg/gadmin/utils"
 
 	"github.com/gin-gonic/gin"
@@ -23,8 +21,9 @@ func PermissionMiddleware(a *app.App) gin.HandlerFunc {
 
 		isSuperAdmin := claims.IsSuperAdmin
 		roleIDs := claims.RoleIDs
+		apiKey, isAPIKey := apiKeyFromContext(c)
 
-		if isSuperAdmin {
+		if isSuperAdmin && !isAPIKey {
 			c.Next()
 			return
 		}
@@ -32,26 +31,31 @@ func PermissionMiddleware(a *app.App) gin.HandlerFunc {
 		path := c.Request.URL.Path
 		method := c.Request.Method
 
-		var permissions []models.Permission
-		if len(roleIDs) > 0 {
+		// 角色的权限按角色缓存并预编译，角色权限或权限变更时由 PermissionService 清空
+		var matchers []*services.PermissionMatcher
+		if !isSuperAdmin && len(roleIDs) > 0 {
 			var err error
-			permissions, err = a.GetPermissionService().GetPermissionsByRoleIDs(c, roleIDs)
+			matchers, err = a.GetPermissionService().GetMatchersByRoleIDs(c, roleIDs)
 			if err != nil {
 				a.Responder.RespondError(c, errors.InternalErrorMsg("查询权限失败"))
 				c.Abort()
 				return
 			}
 		}
-
-		hasPermission := false
-		for _, perm := range permissions {
-			if matchPermission(perm.Path, perm.Method, path, method) {
-				hasPermission = true
-				break
-			}
+		allowed := services.EvaluatePermissions(matchers, path, method)
+		if isAPIKey {
+			// Key 的授权范围与创建者当前的权限（含拒绝规则）须同时允许本次请求，按请求分别判断而不是比较两组规则；
+			// 创建者是超级管理员时只受 Key 的授权范围限制
+			allowed = (isSuperAdmin || allowed) &&
+				services.EvaluatePermissions(services.CompilePermissions(apiKey.Permissions), path, method)
 		}
 
-		if !hasPermission {
+		if !allowed {
+			reason := "没有权限访问此资源"
+			if isAPIKey {
+				reason = "API Key 授权范围外"
+			}
+			recordSecurityEvent(a, c, models.SecurityEventPermissionDenied, claims.UserID, claims.Username, reason)
 			a.Responder.RespondError(c, errors.ForbiddenMsg("没有权限访问此资源"))
 			c.Abort()
 			return
@@ -61,30 +65,12 @@ func PermissionMiddleware(a *app.App) gin.HandlerFunc {
 	}
 }
 
-func matchPermission(permPath, permMethod, reqPath, reqMethod string) bool {
-	if !strings.EqualFold(permMethod, reqMethod) {
-		return false
-	}
-	if permPath == reqPath {
-		return true
+// apiKeyFromContext 当前请求使用 API Key 认证时返回该 Key
+func apiKeyFromContext(c *gin.Context) (*models.APIKey, bool) {
+	val, ok := c.Get("api_key")
+	if !ok {
+		return nil, false
 	}
-	if strings.HasSuffix(permPath, "/*") {
-		prefix := strings.TrimSuffix(permPath, "/*")
-		if strings.HasPrefix(reqPath, prefix+"/") {
-			return true
-		}
-	}
-
-	// :id 等路径参数按正则匹配
-	pattern := regexp.QuoteMeta(permPath)
-	pattern = regexp.MustCompile(`:\\w+`).ReplaceAllString(pattern, `[^/]+`)
-	re, err := regexp.Compile("^" + pattern + "$")
-	if err != nil {
-		return false
-	}
-	if re.MatchString(reqPath) {
-		return true
-	}
-
-	return false
+	key, ok := val.(*models.APIKey)
+	return key, ok && key != nil
 }
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ipRateLimiter 按客户端 IP 的固定窗口计数器
+type ipRateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+	sweptAt time.Time
+}
+
+// rateWindow 单个 IP 当前窗口的开始时间与已放行次数
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func (l *ipRateLimiter) allow(ip string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 每个窗口清理一次已结束的计数，避免按 IP 无限增长
+	if now.Sub(l.sweptAt) >= l.window {
+		for key, w := range l.windows {
+			if now.Sub(w.start) >= l.window {
+				delete(l.windows, key)
+			}
+		}
+		l.sweptAt = now
+	}
+
+	w, ok := l.windows[ip]
+	if !ok || now.Sub(w.start) >= l.window {
+		l.windows[ip] = &rateWindow{start: now, count: 1}
+		return true
+	}
+	if w.count >= l.limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// RateLimitMiddleware 按客户端 IP 限制请求频率：每个 IP 在 window 内最多 limit 次，超出返回 429；limit 不大于 0 时不限制。
+// 计数保存在本实例内存中，多实例部署时每个实例分别计数
+func RateLimitMiddleware(a *app.App, limit int, window time.Duration) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	limiter := &ipRateLimiter{limit: limit, window: window, windows: make(map[string]*rateWindow)}
+	return func(c *gin.Context) {
+		if !limiter.allow(c.ClientIP(), time.Now()) {
+			a.Responder.RespondError(c, errors.TooManyRequestsMsg("请求过于频繁，请稍后再试"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
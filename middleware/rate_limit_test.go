@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 同一 IP 超出次数后返回 429，其他 IP 不受影响
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a := app.NewTestAppWithServiceMocks(&app.ServiceMocks{})
+	r := gin.New()
+	r.GET("/api/captcha", RateLimitMiddleware(a, 2, time.Minute), func(c *gin.Context) {
+		c.JSON(200, gin.H{"code": 0})
+	})
+
+	serve := func(ip string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/captcha", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		var body struct {
+			Code int `json:"code"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		return body.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := serve("10.0.0.1"); code != 0 {
+			t.Fatalf("request %d: code = %d, want 0", i+1, code)
+		}
+	}
+	if code := serve("10.0.0.1"); code != errors.CodeTooManyRequests {
+		t.Errorf("over limit: code = %d, want %d", code, errors.CodeTooManyRequests)
+	}
+	if code := serve("10.0.0.2"); code != 0 {
+		t.Errorf("other IP: code = %d, want 0", code)
+	}
+}
+
+// 窗口结束后重新计数，并清理已结束的窗口
+func TestIPRateLimiter_WindowResets(t *testing.T) {
+	l := &ipRateLimiter{limit: 1, window: time.Minute, windows: make(map[string]*rateWindow)}
+	now := time.Now()
+
+	if !l.allow("10.0.0.1", now) || l.allow("10.0.0.1", now.Add(time.Second)) {
+		t.Fatal("expected one request per window")
+	}
+	l.allow("10.0.0.2", now)
+	if !l.allow("10.0.0.1", now.Add(time.Minute)) {
+		t.Error("expected a new window after it ends")
+	}
+	if _, ok := l.windows["10.0.0.2"]; ok {
+		t.Error("ended windows should be swept")
+	}
+}
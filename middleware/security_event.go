@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordSecurityEvent 记录当前请求的安全事件（IP、User-Agent、方法与路径取自请求）
+func recordSecurityEvent(a *app.App, c *gin.Context, eventType string, userID uint, username, reason string) {
+	a.GetSecurityEventService().Record(c, &models.SecurityEvent{
+		EventType: eventType,
+		UserID:    userID,
+		Username:  username,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Reason:    reason,
+	})
+}
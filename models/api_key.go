@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey 个人访问令牌：供脚本、CI 等机器客户端调用 /admin/api/*，权限为创建者权限的子集；
+// 库中只保存摘要，原文仅在创建时返回一次
+type APIKey struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"` // 原文的前几位，用于在列表中辨认
+	KeyHash    string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at"` // 为空表示永不过期
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `gorm:"size:50" json:"last_used_ip"`
+
+	User        *User        `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Permissions []Permission `gorm:"many2many:api_key_permissions" json:"permissions"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// Captcha 图形验证码答案，captcha_store 为 db 时使用，供多实例共享；过期记录由定时任务清理
+type Captcha struct {
+	ID        string    `gorm:"primaryKey;size:64" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Answer    string    `gorm:"size:32;not null" json:"-"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+}
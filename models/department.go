@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Department 部门，通过 ParentID 组成树形结构，ParentID 为 0 表示顶级部门
+type Department struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	ParentID uint   `gorm:"index;default:0;not null" json:"parent_id"`
+	Name     string `gorm:"size:100;not null" json:"name"`
+	LeaderID uint   `gorm:"default:0;not null" json:"leader_id"` // 负责人用户 ID，0 表示未设置
+	Sort     int    `gorm:"default:0" json:"sort"`               // 排序，同级内数值越小越靠前
+	Status   int    `gorm:"default:1" json:"status"`             // 状态：0=禁用，1=启用；禁用的部门不能再分配用户
+
+	Leader   *User         `gorm:"foreignKey:LeaderID" json:"leader,omitempty"`
+	Children []*Department `gorm:"-" json:"children,omitempty"` // 树形查询时填充
+}
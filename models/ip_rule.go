@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// IP 规则动作
+const (
+	IPRuleAllow = "allow" // 配置了允许规则后，只有命中任一允许规则的 IP 可以访问
+	IPRuleDeny  = "deny"  // 命中即拒绝，优先于允许规则
+)
+
+// IPRule 后台访问的 IP 规则：UserID 为 0 时约束所有访问 /admin 的请求，否则只约束该用户
+type IPRule struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID uint   `gorm:"index;not null;default:0" json:"user_id"`
+	Action string `gorm:"size:10;not null" json:"action"`
+	CIDR   string `gorm:"column:cidr;size:64;not null" json:"cidr"` // 统一保存为网段形式，单个 IP 为 /32 或 /128
+	Remark string `gorm:"size:255" json:"remark"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// 登录锁定的维度
+const (
+	LoginLockScopeUser = "user" // 按用户名
+	LoginLockScopeIP   = "ip"   // 按客户端 IP
+)
+
+// LoginLock 登录失败计数与锁定状态，按 (scope, target) 唯一，target 为用户名或 IP
+type LoginLock struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Scope        string     `gorm:"size:10;not null;uniqueIndex:idx_login_lock_target" json:"scope"`
+	Target       string     `gorm:"size:100;not null;uniqueIndex:idx_login_lock_target" json:"target"`
+	Failures     int        `gorm:"default:0;not null" json:"failures"`   // 当前失败窗口内的连续失败次数
+	LockCount    int        `gorm:"default:0;not null" json:"lock_count"` // 连续被锁定的次数，用于指数退避
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `gorm:"index" json:"locked_until"`
+}
+
+// 登录锁定审计动作
+const (
+	LoginLockActionLock   = "lock"
+	LoginLockActionUnlock = "unlock"
+)
+
+// LoginLockEvent 登录锁定审计记录：自动锁定与管理员解锁
+type LoginLockEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	Scope       string     `gorm:"size:10;not null" json:"scope"`
+	Target      string     `gorm:"size:100;not null;index" json:"target"`
+	Action      string     `gorm:"size:10;not null" json:"action"`
+	Failures    int        `json:"failures"`     // 触发锁定时的失败次数
+	LockedUntil *time.Time `json:"locked_until"` // 锁定截止时间（解锁记录为空）
+	IP          string     `gorm:"size:50" json:"ip"`
+	OperatorID  uint       `json:"operator_id"` // 解锁的管理员 ID，自动锁定为 0
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 菜单类型
+const (
+	MenuTypeDirectory = "directory" // 目录：侧栏中的分组，本身不对应页面
+	MenuTypePage      = "page"      // 页面
+	MenuTypeButton    = "button"    // 按钮：页面内的操作，不在侧栏显示
+)
+
+// Menu 菜单，目录、页面与按钮通过 ParentID 组成树形结构，ParentID 为 0 表示顶级菜单
+// 绑定了权限的菜单只对拥有其中任一权限的用户可见，未绑定权限的菜单所有登录用户可见
+type Menu struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	ParentID uint   `gorm:"index;default:0;not null" json:"parent_id"`
+	Type     string `gorm:"size:20;not null" json:"type"`         // 类型：directory/page/button
+	Name     string `gorm:"size:50;not null" json:"name"`         // 显示名称
+	Code     string `gorm:"size:50" json:"code"`                  // 按钮标识，如 add、edit，同一页面内唯一
+	Path     string `gorm:"size:255" json:"path"`                 // 页面路径，如 /admin/users
+	Icon     string `gorm:"size:50" json:"icon"`                  // Element Plus 图标名
+	Sort     int    `gorm:"default:0" json:"sort"`                // 排序，同级内数值越小越靠前
+	Visible  bool   `gorm:"default:true;not null" json:"visible"` // 是否显示：隐藏的页面不出现在侧栏但仍可访问，隐藏的按钮不下发
+
+	Permissions []Permission `gorm:"many2many:menu_permissions" json:"permissions,omitempty"`
+	Children    []*Menu      `gorm:"-" json:"children,omitempty"` // 树形查询时填充
+}
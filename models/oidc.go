@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// OIDCLogin 一次进行中的单点登录：跳转 IdP 前保存 state、nonce 与 PKCE code_verifier；
+// 回调校验通过后写入 UserID 与一次性登录票据，由登录页换取正式 Token。库中只保存 state 与票据的摘要
+type OIDCLogin struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	StateHash    string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	Nonce        string     `gorm:"size:64;not null" json:"-"`
+	CodeVerifier string     `gorm:"size:128;not null" json:"-"`
+	CallbackAt   *time.Time `json:"callback_at"` // 非空表示 state 已在回调中使用过
+	UserID       uint       `gorm:"default:0;not null" json:"user_id"`
+	TicketHash   string     `gorm:"index;size:64" json:"-"`
+	ExpiresAt    time.Time  `gorm:"index" json:"expires_at"`
+}
+
+// UserIdentity 外部身份（OIDC issuer + subject）与本地用户的关联
+type UserIdentity struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID  uint   `gorm:"index;not null" json:"user_id"`
+	Issuer  string `gorm:"uniqueIndex:idx_user_identity_subject;size:255;not null" json:"issuer"`
+	Subject string `gorm:"uniqueIndex:idx_user_identity_subject;size:255;not null" json:"subject"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordHistory 用户曾使用过的密码（bcrypt 哈希），用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID       uint   `gorm:"index;not null" json:"user_id"`
+	PasswordHash string `gorm:"size:255;not null" json:"-"`
+}
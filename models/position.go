@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Position 岗位，一个用户可以担任多个岗位
+type Position struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Code   string `gorm:"uniqueIndex;size:64;not null" json:"code"` // 岗位编码，用于程序引用
+	Name   string `gorm:"size:100;not null" json:"name"`
+	Sort   int    `gorm:"default:0" json:"sort"`   // 排序，数值越小越靠前
+	Status int    `gorm:"default:1" json:"status"` // 状态：0=禁用，1=启用；禁用的岗位不能再分配给用户
+	Remark string `gorm:"size:255" json:"remark"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken 刷新 Token，库中只保存 SHA-256 摘要；每次刷新都会轮换出新 Token
+type RefreshToken struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	TokenHash string     `gorm:"uniqueIndex;size:64;not null" json:"-"` // Token 原文的 SHA-256 摘要
+	FamilyID  string     `gorm:"index;size:64;not null" json:"-"`       // 家族 ID 即会话 jti：同一次登录轮换出的 Token 共用，检测到重用时整族吊销
+	ExpiresAt time.Time  `gorm:"index" json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at"` // 已被轮换的时间，非空时再次使用视为重用
+	RevokedAt *time.Time `json:"revoked_at"` // 吊销时间（退出、修改密码或检测到重用）
+}
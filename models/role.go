@@ -6,16 +6,42 @@ import (
 	"gorm.io/gorm"
 )
 
+// RoleCodeSuperAdmin 超级管理员角色的系统编码，拥有全部权限
+const RoleCodeSuperAdmin = "super_admin"
+
+// 数据范围：决定拥有该角色的用户在列表查询中能看到哪些数据
+const (
+	DataScopeAll             = "all"               // 全部数据
+	DataScopeDept            = "dept"              // 本部门
+	DataScopeDeptAndChildren = "dept_and_children" // 本部门及下级部门
+	DataScopeSelf            = "self"              // 仅本人
+	DataScopeCustom          = "custom"            // 自定义部门
+)
+
 type Role struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
+	ParentID    uint   `gorm:"index;default:0;not null" json:"parent_id"` // 上级角色，继承其（及其所有上级）的权限；0 表示没有上级
 	Name        string `gorm:"uniqueIndex;size:100;not null" json:"name"`
+	Code        string `gorm:"size:50;index" json:"code"` // 系统角色编码，由初始化数据写入、不能通过接口修改；非空的角色不能改名或删除
 	Description string `gorm:"size:255" json:"description"`
+	Require2FA  bool   `gorm:"column:require_2fa;default:false" json:"require_2fa"` // 拥有该角色的用户登录时必须完成两步验证
+	DataScope   string `gorm:"size:20;default:all;not null" json:"data_scope"`      // 数据范围，见 DataScope* 常量
 
 	// 显式指定关联表名，NamingStrategy 的 TablePrefix 会作用到该名称
 	Users       []User       `gorm:"many2many:user_roles" json:"users,omitempty"`
-	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions,omitempty"`
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions,omitempty"` // 直接分配的权限
+	// InheritedPermissions 从上级角色继承、且未直接分配的权限，仅角色列表接口填充
+	InheritedPermissions []Permission `gorm:"-" json:"inherited_permissions,omitempty"`
+	Parent               *Role        `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
+	// DataScopeDepartments 数据范围为 custom 时可见的部门
+	DataScopeDepartments []Department `gorm:"many2many:role_data_scope_departments" json:"data_scope_departments,omitempty"`
+}
+
+// IsSystem 是否为系统内置角色
+func (r *Role) IsSystem() bool {
+	return r.Code != ""
 }
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// 安全事件类型
+const (
+	SecurityEventLoginSuccess       = "login_success"       // 登录成功（签发 Token）
+	SecurityEventLoginFailed        = "login_failed"        // 用户名或密码错误、单点登录失败
+	SecurityEventCaptchaFailed      = "captcha_failed"      // 验证码错误
+	SecurityEventLoginBlocked       = "login_blocked"       // 因连续失败被锁定而拒绝登录
+	SecurityEventTwoFactorFailed    = "two_factor_failed"   // 两步验证码错误
+	SecurityEventLogout             = "logout"              // 退出登录
+	SecurityEventRefreshTokenReuse  = "refresh_token_reuse" // 已轮换的刷新 Token 被重用
+	SecurityEventTokenRejected      = "token_rejected"      // 携带的 Token 或 API Key 被认证中间件拒绝
+	SecurityEventPermissionDenied   = "permission_denied"   // 权限中间件返回 403
+	SecurityEventImpersonationStart = "impersonation_start" // 超级管理员开始模拟登录其他用户
+	SecurityEventCSRFRejected       = "csrf_rejected"       // 以 cookie 认证的写请求缺少或带错 CSRF Token
+	SecurityEventIPDenied           = "ip_denied"           // 客户端 IP 被全局或用户 IP 规则拒绝
+)
+
+// SecurityEvent 登录与鉴权相关的安全事件，与操作日志分开存放
+type SecurityEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	EventType string `gorm:"size:32;not null;index" json:"event_type"`
+	UserID    uint   `gorm:"index" json:"user_id"`           // 能确定用户时记录，登录失败等为 0
+	Username  string `gorm:"size:100;index" json:"username"` // 登录失败时为提交的用户名
+	IP        string `gorm:"size:50;index" json:"ip"`
+	UserAgent string `gorm:"size:255" json:"user_agent"`
+	Method    string `gorm:"size:10" json:"method"`
+	Path      string `gorm:"size:255" json:"path"`
+	Reason    string `gorm:"size:255" json:"reason"`
+	TraceID   string `gorm:"size:64;index" json:"trace_id"`
+}
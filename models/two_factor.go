@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// UserBackupCode 两步验证备用码，一次性使用；库中只保存 SHA-256 摘要
+type UserBackupCode struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID   uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash string     `gorm:"size:64;not null" json:"-"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+// LoginChallenge 密码校验通过、等待两步验证的登录挑战；客户端持有原文，库中只保存摘要
+type LoginChallenge struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	TokenHash string    `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	Attempts  int       `gorm:"default:0;not null" json:"attempts"` // 已失败的验证次数，达到上限后挑战作废
+}
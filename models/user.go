@@ -21,9 +21,34 @@ type User struct {
 	TokenVersion uint   `gorm:"default:0;not null" json:"-"`
 	Remark       string `gorm:"size:500" json:"remark"`
 
-	Roles []Role `gorm:"many2many:user_roles" json:"roles,omitempty"`
+	// 两步验证（TOTP）：TOTPSecret 在绑定流程开始时写入，验证通过后 TOTPEnabled 才置为 true
+	TOTPSecret      string `gorm:"column:totp_secret;size:64" json:"-"`
+	TOTPEnabled     bool   `gorm:"column:totp_enabled;default:false" json:"totp_enabled"`
+	TOTPLastCounter int64  `gorm:"column:totp_last_counter;default:0" json:"-"` // 最近一次通过校验的时间步，防止验证码重放
+
+	// PasswordChangedAt 最近一次设置密码的时间，用于密码有效期检查；为空时按创建时间计算
+	PasswordChangedAt *time.Time `json:"password_changed_at"`
+	// MustChangePassword 管理员创建、重置密码或密码过期后置为 true，用户自行修改密码前只能访问修改密码与退出接口
+	MustChangePassword bool `gorm:"default:false" json:"must_change_password"`
+
+	// Source 账号来源：local 为本地密码，ldap / oidc 为首次通过 LDAP 或单点登录时自动创建（不保存密码）
+	Source string `gorm:"size:20;default:local;not null" json:"source"`
+
+	// DepartmentID 所属部门，0 表示未分配部门
+	DepartmentID uint `gorm:"index;default:0" json:"department_id"`
+
+	Roles      []Role      `gorm:"many2many:user_roles" json:"roles,omitempty"`
+	Department *Department `gorm:"foreignKey:DepartmentID" json:"department,omitempty"`
+	Positions  []Position  `gorm:"many2many:user_positions" json:"positions,omitempty"`
 }
 
+// 账号来源
+const (
+	UserSourceLocal = "local"
+	UserSourceLDAP  = "ldap"
+	UserSourceOIDC  = "oidc"
+)
+
 type UserRole struct {
 	UserID uint `gorm:"primaryKey"`
 	RoleID uint `gorm:"primaryKey"`
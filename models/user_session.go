@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// UserSession 一次登录产生的会话（一个设备/浏览器），以 JWT 的 jti 标识，可单独吊销
+type UserSession struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	JTI        string     `gorm:"column:jti;uniqueIndex;size:64;not null" json:"-"` // 访问 Token 的 jti，刷新后保持不变
+	IP         string     `gorm:"size:50" json:"ip"`                                // 最近一次访问的客户端 IP
+	UserAgent  string     `gorm:"size:255" json:"user_agent"`                       // 最近一次访问的用户代理
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"index" json:"expires_at"` // 与最新刷新 Token 同时过期
+	RevokedAt  *time.Time `json:"revoked_at"`
+
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
@@ -0,0 +1,194 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/config"
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/internal/testutil"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/services"
+	"github.com/lyuangg/gadmin/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const exportPath = "/admin/download/operation-logs"
+
+// setupDownloadRouter 用内存 DB 装配完整路由，创建两个用户：exporter 拥有导出权限，viewer 没有
+func setupDownloadRouter(t *testing.T) (*gin.Engine, *app.App) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	utils.InitJWT(&config.Config{JWTSecret: "download-test-secret-at-least-32-bytes"})
+
+	db := testutil.NewTestDB(t)
+	a := app.NewTestAppWithMocks(db, &services.FakeCaptchaProvider{VerifyResult: true}, nil)
+
+	perm := models.Permission{Name: "导出操作日志", Path: exportPath, Method: "GET", Effect: models.PermissionEffectAllow}
+	if err := db.Create(&perm).Error; err != nil {
+		t.Fatalf("create permission: %v", err)
+	}
+	role := models.Role{Name: "审计", Code: "auditor", Permissions: []models.Permission{perm}}
+	if err := db.Create(&role).Error; err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass123"), bcrypt.MinCost)
+	users := []models.User{
+		{Username: "exporter", Password: string(hashed), Status: 1, Roles: []models.Role{role}},
+		{Username: "viewer", Password: string(hashed), Status: 1},
+	}
+	for i := range users {
+		if err := db.Create(&users[i]).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	if err := db.Create(&models.OperationLog{UserID: users[0].ID, Username: "exporter", Method: "POST", Path: "/admin/api/users", StatusCode: 200}).Error; err != nil {
+		t.Fatalf("create log: %v", err)
+	}
+
+	// 开发模式按需解析模板，测试工作目录下没有 templates
+	a.Config.GinMode = "debug"
+	router := gin.New()
+	SetupRoutes(router, a)
+	return router, a
+}
+
+func loginAccessToken(t *testing.T, a *app.App, username string) string {
+	t.Helper()
+	result, err := a.GetAuthService().Login(context.Background(), username, "pass123", "cid", "val", services.ClientInfo{IP: "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("Login(%s): %v", username, err)
+	}
+	return result.Tokens.AccessToken
+}
+
+// requestQueryToken 走 POST /admin/api/profile/query-token 为导出路径签发查询参数 Token
+func requestQueryToken(t *testing.T, router *gin.Engine, accessToken, path string) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/profile/query-token", strings.NewReader(`{"path":"`+path+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var body struct {
+		Code int `json:"code"`
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Data.Token == "" {
+		t.Fatalf("query-token: status=%d body=%s", rec.Code, rec.Body.String())
+	}
+	return body.Data.Token
+}
+
+// errorCode 解析失败响应中的业务码（Responder 出错时 HTTP 状态仍为 200）
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) int {
+	t.Helper()
+	var body struct {
+		Code int `json:"code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("expected JSON error body, got status=%d body=%q", rec.Code, rec.Body.String())
+	}
+	return body.Code
+}
+
+func getDownload(router *gin.Engine, url string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDownload_QueryTokenExportsOperationLogs(t *testing.T) {
+	router, a := setupDownloadRouter(t)
+	accessToken := loginAccessToken(t, a, "exporter")
+	token := requestQueryToken(t, router, accessToken, exportPath)
+
+	rec := getDownload(router, exportPath+"?token="+token+"&method=POST")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200, body=%s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Content-Type = %q, want text/csv", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, "attachment") {
+		t.Errorf("Content-Disposition = %q, want attachment", cd)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "/admin/api/users") {
+		t.Errorf("csv body missing log row: %q", body)
+	}
+}
+
+func TestDownload_RejectsSessionTokenInQuery(t *testing.T) {
+	router, a := setupDownloadRouter(t)
+	accessToken := loginAccessToken(t, a, "exporter")
+
+	// 会话 Token 只能放在请求头，放进 URL 会被拒绝
+	if code := errorCode(t, getDownload(router, exportPath+"?token="+accessToken)); code != errors.CodeUnauthorized {
+		t.Errorf("session token in query: code = %d, want %d", code, errors.CodeUnauthorized)
+	}
+	// 请求头方式仍可用
+	req := httptest.NewRequest(http.MethodGet, exportPath, nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("header token: Content-Type = %q, want text/csv, body=%s", ct, rec.Body.String())
+	}
+}
+
+func TestDownload_QueryTokenBoundToPath(t *testing.T) {
+	router, a := setupDownloadRouter(t)
+	accessToken := loginAccessToken(t, a, "exporter")
+	token := requestQueryToken(t, router, accessToken, "/admin/download/other")
+
+	if code := errorCode(t, getDownload(router, exportPath+"?token="+token)); code != errors.CodeUnauthorized {
+		t.Errorf("token for another path: code = %d, want %d", code, errors.CodeUnauthorized)
+	}
+}
+
+// 脚本可用请求头中的 API Key 下载，Key 须在授权范围内
+func TestDownload_APIKey(t *testing.T) {
+	router, a := setupDownloadRouter(t)
+	var exporter models.User
+	a.DB().Where("username = ?", "exporter").First(&exporter)
+	var perm models.Permission
+	a.DB().Where("path = ?", exportPath).First(&perm)
+	_, rawKey, err := a.GetAPIKeyService().CreateKey(context.Background(), exporter.ID, "export", nil, []uint{perm.ID})
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, exportPath, nil)
+	req.Header.Set("Authorization", "Bearer "+rawKey)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("api key download: Content-Type = %q, body=%s", ct, rec.Body.String())
+	}
+
+	// API Key 只从请求头读取，放在 URL 中无效
+	if code := errorCode(t, getDownload(router, exportPath+"?token="+rawKey)); code != errors.CodeUnauthorized {
+		t.Errorf("api key in query: code = %d, want %d", code, errors.CodeUnauthorized)
+	}
+}
+
+func TestDownload_RequiresPermission(t *testing.T) {
+	router, a := setupDownloadRouter(t)
+	accessToken := loginAccessToken(t, a, "viewer")
+	token := requestQueryToken(t, router, accessToken, exportPath)
+
+	if code := errorCode(t, getDownload(router, exportPath+"?token="+token)); code != errors.CodeForbidden {
+		t.Errorf("without permission: code = %d, want %d", code, errors.CodeForbidden)
+	}
+}
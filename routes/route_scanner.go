@@ -5,8 +5,8 @@ import (
 	"strings"
 
 	"github.com/lyuangg/gadmin/app"
-	"github.com/lyuangg/gadmin/models"
 	"github.com/lyuangg/gadmin/routes/routemeta"
+	"github.com/lyuangg/gadmin/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,11 +20,14 @@ func NewRouteScanner(router *gin.Engine, a *app.App) *RouteScanner {
 	return &RouteScanner{router: router, app: a}
 }
 
-func (rs *RouteScanner) ScanAndImport() error {
-	routes := rs.router.Routes()
+// scannedPrefixes 需要权限校验的路由前缀：后台接口与文件下载
+var scannedPrefixes = []string{"/admin/api/", "/admin/download/"}
 
-	for _, route := range routes {
-		if !strings.HasPrefix(route.Path, "/admin/api/") {
+// Routes 返回已注册且配置了权限信息的后台接口与下载路由
+func (rs *RouteScanner) Routes() []services.RoutePermission {
+	var result []services.RoutePermission
+	for _, route := range rs.router.Routes() {
+		if !hasScannedPrefix(route.Path) {
 			continue
 		}
 
@@ -33,56 +36,32 @@ func (rs *RouteScanner) ScanAndImport() error {
 			continue
 		}
 
-		var permission models.Permission
-		result := rs.app.DB().Where("path = ? AND method = ?", route.Path, route.Method).First(&permission)
-
-		if result.Error != nil {
-			permission = models.Permission{
-				Path:       route.Path,
-				Method:     route.Method,
-				Name:       permissionInfo.Name,
-				Group:      permissionInfo.Group,
-				AutoImport: true,
-			}
+		result = append(result, services.RoutePermission{
+			Method: route.Method,
+			Path:   route.Path,
+			Name:   permissionInfo.Name,
+			Group:  permissionInfo.Group,
+		})
+	}
+	return result
+}
 
-			if err := rs.app.DB().Create(&permission).Error; err != nil {
-				rs.app.Logger().ErrorContext(context.Background(), "导入权限失败",
-					"method", route.Method,
-					"path", route.Path,
-					"error", err)
-				continue
-			}
+// Sync 对比路由与权限表，dryRun 时只返回差异报告，不修改数据库
+func (rs *RouteScanner) Sync(dryRun bool) (*services.PermissionSyncReport, error) {
+	return rs.app.GetPermissionService().SyncRoutePermissions(context.Background(), rs.Routes(), dryRun)
+}
 
-			rs.app.Logger().InfoContext(context.Background(), "自动导入权限",
-				"method", route.Method,
-				"path", route.Path,
-				"name", permission.Name,
-				"group", permission.Group)
-		} else {
-			needUpdate := false
-			if permission.Name != permissionInfo.Name {
-				permission.Name = permissionInfo.Name
-				needUpdate = true
-			}
-			if permission.Group != permissionInfo.Group {
-				permission.Group = permissionInfo.Group
-				needUpdate = true
-			}
-			if !permission.AutoImport {
-				permission.AutoImport = true
-				needUpdate = true
-			}
+// ScanAndImport 导入新路由的权限、更新名称与分组，并按 permission_orphan_policy 处理路由已不存在的权限
+func (rs *RouteScanner) ScanAndImport() error {
+	_, err := rs.Sync(false)
+	return err
+}
 
-			if needUpdate {
-				rs.app.DB().Save(&permission)
-				rs.app.Logger().InfoContext(context.Background(), "更新权限信息",
-					"method", route.Method,
-					"path", route.Path,
-					"name", permission.Name,
-					"group", permission.Group)
-			}
+func hasScannedPrefix(path string) bool {
+	for _, prefix := range scannedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
 		}
 	}
-
-	return nil
+	return false
 }
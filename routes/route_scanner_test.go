@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRouteScanner_Routes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/admin/api")
+	handler := func(c *gin.Context) {}
+
+	RegisterRouteWithPermission(group, "GET", "/route-scanner-test", "扫描测试", "测试分组", handler)
+	// 未配置权限信息的路由与 /admin/api 之外的路由不参与同步
+	group.GET("/route-scanner-test/plain", handler)
+	RegisterRouteWithPermission(router, "GET", "/route-scanner-test", "非后台接口", "测试分组", handler)
+
+	routes := NewRouteScanner(router, nil).Routes()
+	if len(routes) != 1 {
+		t.Fatalf("Routes() = %+v, want 1", routes)
+	}
+	r := routes[0]
+	if r.Method != "GET" || r.Path != "/admin/api/route-scanner-test" || r.Name != "扫描测试" || r.Group != "测试分组" {
+		t.Errorf("Routes()[0] = %+v", r)
+	}
+}
@@ -61,6 +61,14 @@ func GetRoutePermission(method, path string) RoutePermissionInfo {
 	return RoutePermissionInfo{}
 }
 
+// IsRegistered 路由是否通过 RegisterRoutePermission 登记；path 为注册时的完整路径（如 gin 的 FullPath），只做精确匹配
+func IsRegistered(method, path string) bool {
+	routePermissionMapMutex.RLock()
+	defer routePermissionMapMutex.RUnlock()
+	_, exists := routePermissionMap[getRouteKey(method, path)]
+	return exists
+}
+
 // matchRoutePath 匹配路由路径，支持路由参数
 // 例如：/users/:id 可以匹配 /users/123
 func matchRoutePath(routePath, actualPath string) bool {
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"html/template"
+	"time"
 
 	"github.com/lyuangg/gadmin/app"
 	"github.com/lyuangg/gadmin/controllers"
@@ -35,28 +36,39 @@ func createRenderer() multitemplate.Renderer {
 	loginTmpl := createTemplate("auth/login.html", "templates/auth/login.html")
 	r.Add("auth/login.html", loginTmpl)
 
-	adminPages := []string{
-		"admin/index.html",
-		"admin/users.html",
-		"admin/roles.html",
-		"admin/permissions.html",
-		"admin/dictionaries.html",
-		"admin/operation_logs.html",
-		"admin/password.html",
-		"admin/avatar.html",
-	}
-
 	for _, page := range adminPages {
-		pageTmpl := createTemplate(page,
+		pageTmpl := createTemplate(page.Template,
 			"templates/layouts/admin.html",
 			"templates/components/pagination.html",
-			"templates/"+page,
+			"templates/"+page.Template,
 		)
-		r.Add(page, pageTmpl)
+		r.Add(page.Template, pageTmpl)
 	}
 	return r
 }
 
+// adminPage 后台页面：/admin 下的路径、模板与标题。新增页面在此登记并提供模板，
+// 侧栏菜单与按钮在菜单管理中配置
+type adminPage struct {
+	Path     string
+	Template string
+	Title    string
+}
+
+var adminPages = []adminPage{
+	{"", "admin/index.html", ""},
+	{"/users", "admin/users.html", "用户管理"},
+	{"/roles", "admin/roles.html", "角色管理"},
+	{"/permissions", "admin/permissions.html", "权限管理"},
+	{"/menus", "admin/menus.html", "菜单管理"},
+	{"/dictionaries", "admin/dictionaries.html", "字典管理"},
+	{"/operation-logs", "admin/operation_logs.html", "操作日志"},
+	{"/security-events", "admin/security_events.html", "安全事件"},
+	{"/password", "admin/password.html", "修改密码"},
+	{"/avatar", "admin/avatar.html", "更换头像"},
+	{"/two-factor", "admin/two_factor.html", "两步验证"},
+}
+
 func SetupRoutes(router *gin.Engine, a *app.App) {
 	isDevMode := a.Config.GinMode == "debug"
 
@@ -66,6 +78,16 @@ func SetupRoutes(router *gin.Engine, a *app.App) {
 	permissionController := controllers.NewPermissionController(a)
 	dictionaryController := controllers.NewDictionaryController(a)
 	operationLogController := controllers.NewOperationLogController(a)
+	securityEventController := controllers.NewSecurityEventController(a)
+	sessionController := controllers.NewSessionController(a)
+	twoFactorController := controllers.NewTwoFactorController(a)
+	loginLockController := controllers.NewLoginLockController(a)
+	apiKeyController := controllers.NewAPIKeyController(a)
+	ipRuleController := controllers.NewIPRuleController(a)
+	departmentController := controllers.NewDepartmentController(a)
+	positionController := controllers.NewPositionController(a)
+	menuController := controllers.NewMenuController(a)
+	permissionSyncController := controllers.NewPermissionSyncController(a, NewRouteScanner(router, a).Routes)
 
 	if isDevMode {
 		router.HTMLRender = &devTemplateRenderer{app: a}
@@ -77,69 +99,70 @@ func SetupRoutes(router *gin.Engine, a *app.App) {
 	router.Use(middleware.LoggingMiddleware(a))
 	router.Static("/static", "./static")
 
+	router.GET("/.well-known/jwks.json", authController.JWKS)
+
 	router.GET("/login", func(c *gin.Context) {
-		c.HTML(200, "auth/login.html", nil)
+		c.HTML(200, "auth/login.html", gin.H{
+			"OIDCEnabled": a.GetOIDCService().Enabled(),
+		})
 	})
 
 	api := router.Group("/api")
 	api.Use(middleware.RecoveryMiddleware(a))
 	{
 		api.POST("/login", authController.Login)
-		api.GET("/captcha", authController.GetCaptcha)
+		api.POST("/login/2fa", authController.LoginTwoFactor)
+		api.POST("/login/2fa/setup", authController.LoginTwoFactorSetup)
+		// 匿名接口，验证码使用 db 存储时每次获取都会写一行记录，按 IP 限制频率
+		api.GET("/captcha", middleware.RateLimitMiddleware(a, a.Config.CaptchaRateLimit, time.Minute), authController.GetCaptcha)
+		api.POST("/token/refresh", authController.RefreshToken)
+		api.GET("/oidc/login", authController.OIDCLogin)
+		api.GET("/oidc/callback", authController.OIDCCallback)
+		api.POST("/oidc/exchange", authController.OIDCExchange)
 	}
 
 	admin := router.Group("/admin")
+	// 全局 IP 规则在认证前校验，用户 IP 规则在认证后校验
+	admin.Use(middleware.IPAccessMiddleware(a))
+	// 只接受请求头与 cookie 中的 Token；需要通过链接访问的下载类接口另建路由组，
+	// 用 AuthMiddlewareWithConfig 显式加入 TokenSourceQuery
 	admin.Use(middleware.AuthMiddleware(a))
+	admin.Use(middleware.UserIPAccessMiddleware(a))
 	{
-		admin.GET("", func(c *gin.Context) {
-			c.HTML(200, "admin/index.html", gin.H{
-				"PageTitle": "后台管理系统",
-			})
-		})
-		admin.GET("/users", func(c *gin.Context) {
-			c.HTML(200, "admin/users.html", gin.H{
-				"PageTitle": "用户管理 - 后台管理系统",
-			})
-		})
-		admin.GET("/roles", func(c *gin.Context) {
-			c.HTML(200, "admin/roles.html", gin.H{
-				"PageTitle": "角色管理 - 后台管理系统",
-			})
-		})
-		admin.GET("/permissions", func(c *gin.Context) {
-			c.HTML(200, "admin/permissions.html", gin.H{
-				"PageTitle": "权限管理 - 后台管理系统",
-			})
-		})
-		admin.GET("/dictionaries", func(c *gin.Context) {
-			c.HTML(200, "admin/dictionaries.html", gin.H{
-				"PageTitle": "字典管理 - 后台管理系统",
-			})
-		})
-		admin.GET("/operation-logs", func(c *gin.Context) {
-			c.HTML(200, "admin/operation_logs.html", gin.H{
-				"PageTitle": "操作日志 - 后台管理系统",
-			})
-		})
-		admin.GET("/password", func(c *gin.Context) {
-			c.HTML(200, "admin/password.html", gin.H{
-				"PageTitle": "修改密码 - 后台管理系统",
-			})
-		})
-		admin.GET("/avatar", func(c *gin.Context) {
-			c.HTML(200, "admin/avatar.html", gin.H{
-				"PageTitle": "更换头像 - 后台管理系统",
+		for _, page := range adminPages {
+			name, title := page.Template, "后台管理系统"
+			if page.Title != "" {
+				title = page.Title + " - " + title
+			}
+			admin.GET(page.Path, func(c *gin.Context) {
+				c.HTML(200, name, gin.H{
+					"PageTitle": title,
+				})
 			})
-		})
+		}
 
 		adminAPI := admin.Group("/api")
 		adminAPI.Use(middleware.RecoveryMiddleware(a))
+		adminAPI.Use(middleware.CSRFMiddleware(a))
 		adminAPI.Use(middleware.OperationLogMiddleware(a))
 		{
 			adminAPI.POST("/logout", authController.Logout)
 			adminAPI.PUT("/profile/password", authController.ChangePassword)
 			adminAPI.PUT("/profile/avatar", authController.UpdateAvatar)
+			adminAPI.POST("/profile/query-token", authController.CreateQueryToken)
+			adminAPI.POST("/users/:id/impersonate", authController.Impersonate)
 			adminAPI.GET("/user/permissions", authController.GetUserPermissions)
+			adminAPI.GET("/user/menus", menuController.GetUserMenus)
+			adminAPI.GET("/profile/sessions", sessionController.GetMySessions)
+			adminAPI.DELETE("/profile/sessions/:id", sessionController.RevokeMySession)
+			adminAPI.GET("/profile/2fa", twoFactorController.GetStatus)
+			adminAPI.POST("/profile/2fa/setup", twoFactorController.BeginSetup)
+			adminAPI.POST("/profile/2fa/enable", twoFactorController.Enable)
+			adminAPI.POST("/profile/2fa/disable", twoFactorController.Disable)
+			adminAPI.POST("/profile/2fa/backup-codes", twoFactorController.RegenerateBackupCodes)
+			adminAPI.GET("/profile/api-keys", apiKeyController.GetMyKeys)
+			adminAPI.POST("/profile/api-keys", apiKeyController.CreateMyKey)
+			adminAPI.DELETE("/profile/api-keys/:id", apiKeyController.RevokeMyKey)
 
 			adminAPIWithPermission := adminAPI.Group("").Use(middleware.PermissionMiddleware(a))
 			{
@@ -149,18 +172,45 @@ func SetupRoutes(router *gin.Engine, a *app.App) {
 				RegisterRouteWithPermission(adminAPIWithPermission, "DELETE", "/users/:id", "删除用户", "用户管理", userController.DeleteUser)
 				RegisterRouteWithPermission(adminAPIWithPermission, "POST", "/users/:id/reset-password", "重置用户密码", "用户管理", userController.ResetPassword)
 				RegisterRouteWithPermission(adminAPIWithPermission, "PUT", "/users/:id/toggle-status", "切换用户状态", "用户管理", userController.ToggleStatus)
+				RegisterRouteWithPermission(adminAPIWithPermission, "PUT", "/users/:id/organization", "设置用户部门与岗位", "用户管理", userController.AssignOrganization)
+				RegisterRouteWithPermission(adminAPIWithPermission, "DELETE", "/users/:id/sessions", "强制下线用户", "用户管理", sessionController.RevokeUserSessions)
+				RegisterRouteWithPermission(adminAPIWithPermission, "DELETE", "/users/:id/2fa", "重置用户两步验证", "用户管理", twoFactorController.ResetUser)
+				RegisterRouteWithPermission(adminAPIWithPermission, "POST", "/users/:id/unlock", "解锁用户", "用户管理", loginLockController.UnlockUser)
+
+				RegisterRouteWithPermission(adminAPIWithPermission, "GET", "/sessions", "查询在线会话", "会话管理", sessionController.GetSessions)
+				RegisterRouteWithPermission(adminAPIWithPermission, "DELETE", "/sessions/:id", "强制下线会话", "会话管理", sessionController.RevokeSession)
+
+				RegisterRouteWithPermission(adminAPIWithPermission, "GET", "/api-keys", "查询API Key", "API Key管理", apiKeyController.GetKeys)
+				RegisterRouteWithPermission(adminAPIWithPermission, "DELETE", "/api-keys/:id", "删除API Key", "API Key管理", apiKeyController.RevokeKey)
+
+				RegisterRouteWithPermission(adminAPIWithPermission, "GET", "/login-locks", "查询登录锁定", "登录安全", loginLockController.GetLocks)
+				RegisterRouteWithPermission(adminAPIWithPermission, "DELETE", "/login-locks/:id", "解除登录锁定", "登录安全", loginLockController.Unlock)
+				RegisterRouteWithPermission(adminAPIWithPermission, "GET", "/login-locks/events", "查询登录锁定记录", "登录安全", loginLockController.GetEvents)
+
+				RegisterRouteWithPermission(adminAPIWithPermission, "GET", "/ip-rules", "查询IP规则", "登录安全", ipRuleController.GetRules)
+				RegisterRouteWithPermission(adminAPIWithPermission, "POST", "/ip-rules", "创建IP规则", "登录安全", ipRuleController.CreateRule)
+				RegisterRouteWithPermission(adminAPIWithPermission, "PUT", "/ip-rules/:id", "更新IP规则", "登录安全", ipRuleController.UpdateRule)
+				RegisterRouteWithPermission(adminAPIWithPermission, "DELETE", "/ip-rules/:id", "删除IP规则", "登录安全", ipRuleController.DeleteRule)
 
 				RegisterRouteWithPermission(adminAPIWithPermission, "GET", "/roles", "查询角色列表", "角色管理", roleController.GetRoles)
 				RegisterRouteWithPermission(adminAPIWithPermission, "POST", "/roles", "创建角色", "角色管理", roleController.CreateRole)
 				RegisterRouteWithPermission(adminAPIWithPermission, "PUT", "/roles/:id", "更新角色", "角色管理", roleController.UpdateRole)
 				RegisterRouteWithPermission(adminAPIWithPermission, "DELETE", "/roles/:id", "删除角色", "角色管理", roleController.DeleteRole)
 				RegisterRouteWithPermission(adminAPIWithPermission, "PUT", "/roles/:id/permissions", "分配角色权限", "角色管理", roleController.AssignPermissions)
+				RegisterRouteWithPermission(adminAPIWithPermission, "PUT", "/roles/:id/require-2fa", "设置角色强制两步验证", "角色管理", roleController.SetRequire2FA)
+				RegisterRouteWithPermission(adminAPIWithPermission, "PUT", "/roles/:id/data-scope", "设置角色数据范围", "角色管理", roleController.SetDataScope)
 
 				RegisterRouteWithPermission(adminAPIWithPermission, "GET", "/permissions", "查询权限列表", "权限管理", permissionController.GetPermissions)
 				RegisterRouteWithPermission(adminAPIWithPermission, "POST", "/permissions", "创建权限", "权限管理", permissionController.CreatePermission)
 				RegisterRouteWithPermission(adminAPIWithPermission, "PUT", "/permissions/:id", "更新权限", "权限管理", permissionController.UpdatePermission)
 				RegisterRouteWithPermission(adminAPIWithPermission, "DELETE", "/permissions/:id", "删除权限", "权限管理", permissionController.DeletePermission)
 				RegisterRouteWithPermission(adminAPIWithPermission, "POST", "/permissions/batch-delete", "批量删除权限", "权限管理", permissionController.BatchDeletePermissions)
+				RegisterRouteWithPermission(adminAPIWithPermission, "GET", "/permissions/sync-preview", "预览路由权限同步", "权限管理", permissionSyncController.Preview)
+
+				RegisterRouteWithPermission(adminAPIWithPermission, "GET", "/menus/tree", "查询菜单树", "菜单管理", menuController.GetTree)
+				RegisterRouteWithPermission(adminAPIWithPermission, "POST", "/menus", "创建菜单", "菜单管理", menuController.CreateMenu)
+				RegisterRouteWithPermission(adminAPIWithPermission, "PUT", "/menus/:id", "更新菜单", "菜单管理", menuController.UpdateMenu)
+				RegisterRouteWithPermission(adminAPIWithPermission, "DELETE", "/menus/:id", "删除菜单", "菜单管理", menuController.DeleteMenu)
 
 				RegisterRouteWithPermission(adminAPIWithPermission, "GET", "/dictionaries/types", "查询字典类型列表", "字典管理", dictionaryController.GetTypes)
 				RegisterRouteWithPermission(adminAPIWithPermission, "POST", "/dictionaries/types", "创建字典类型", "字典管理", dictionaryController.CreateType)
@@ -172,11 +222,38 @@ func SetupRoutes(router *gin.Engine, a *app.App) {
 				RegisterRouteWithPermission(adminAPIWithPermission, "PUT", "/dictionaries/items/:id", "更新字典项", "字典管理", dictionaryController.UpdateItem)
 				RegisterRouteWithPermission(adminAPIWithPermission, "DELETE", "/dictionaries/items/:id", "删除字典项", "字典管理", dictionaryController.DeleteItem)
 
+				RegisterRouteWithPermission(adminAPIWithPermission, "GET", "/departments/tree", "查询部门树", "组织架构", departmentController.GetTree)
+				RegisterRouteWithPermission(adminAPIWithPermission, "POST", "/departments", "创建部门", "组织架构", departmentController.CreateDepartment)
+				RegisterRouteWithPermission(adminAPIWithPermission, "PUT", "/departments/:id", "更新部门", "组织架构", departmentController.UpdateDepartment)
+				RegisterRouteWithPermission(adminAPIWithPermission, "DELETE", "/departments/:id", "删除部门", "组织架构", departmentController.DeleteDepartment)
+				RegisterRouteWithPermission(adminAPIWithPermission, "GET", "/positions", "查询岗位列表", "组织架构", positionController.GetPositions)
+				RegisterRouteWithPermission(adminAPIWithPermission, "POST", "/positions", "创建岗位", "组织架构", positionController.CreatePosition)
+				RegisterRouteWithPermission(adminAPIWithPermission, "PUT", "/positions/:id", "更新岗位", "组织架构", positionController.UpdatePosition)
+				RegisterRouteWithPermission(adminAPIWithPermission, "DELETE", "/positions/:id", "删除岗位", "组织架构", positionController.DeletePosition)
+
 				RegisterRouteWithPermission(adminAPIWithPermission, "GET", "/operation-logs", "查询操作日志", "系统日志", operationLogController.GetOperationLogs)
+				RegisterRouteWithPermission(adminAPIWithPermission, "GET", "/security-events", "查询安全事件", "系统日志", securityEventController.GetEvents)
 			}
 		}
 	}
 
+	// 文件下载：浏览器直接打开链接，无法带 Authorization 头，接受 POST /admin/api/profile/query-token
+	// 为该路径签发的短期查询参数 Token；脚本可用请求头（含 API Key，路由须通过 RegisterRouteWithPermission 登记）。
+	// 不接受 cookie，GET 请求也不校验 CSRF
+	download := router.Group("/admin/download")
+	download.Use(middleware.RecoveryMiddleware(a))
+	download.Use(middleware.IPAccessMiddleware(a))
+	download.Use(middleware.AuthMiddlewareWithConfig(a, middleware.AuthConfig{
+		Sources: []middleware.TokenSource{middleware.TokenSourceHeader, middleware.TokenSourceQuery},
+	}))
+	download.Use(middleware.UserIPAccessMiddleware(a))
+	{
+		downloadWithPermission := download.Group("").Use(middleware.PermissionMiddleware(a))
+		{
+			RegisterRouteWithPermission(downloadWithPermission, "GET", "/operation-logs", "导出操作日志", "系统日志", operationLogController.ExportOperationLogs)
+		}
+	}
+
 	router.GET("/", func(c *gin.Context) {
 		c.Redirect(302, "/login")
 	})
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+	"time"
+
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/utils"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix API Key 原文的固定前缀，认证中间件据此区分 API Key 与登录 JWT
+const APIKeyPrefix = "gak_"
+
+const (
+	// apiKeyDisplayPrefixLen 列表中展示的原文前缀长度
+	apiKeyDisplayPrefixLen = 12
+	// apiKeyTouchInterval 最近使用时间的最小更新间隔，避免每个请求都写库
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyService API Key 服务：创建、查询、吊销与认证
+type APIKeyService struct {
+	ctx ServiceContext
+}
+
+// NewAPIKeyService 创建 API Key 服务实例
+func NewAPIKeyService(ctx ServiceContext) *APIKeyService {
+	return &APIKeyService{ctx: ctx}
+}
+
+// CreateKey 为用户创建 API Key，返回记录与原文（只在此时返回一次）；
+// permissionIDs 必须是用户当前拥有的权限，expiresAt 为空表示永不过期
+func (s *APIKeyService) CreateKey(ctx context.Context, userID uint, name string, expiresAt *time.Time, permissionIDs []uint) (*models.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", errors.BadRequestMsg("名称不能为空")
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", errors.BadRequestMsg("过期时间须晚于当前时间")
+	}
+	if len(permissionIDs) == 0 {
+		return nil, "", errors.BadRequestMsg("请至少选择一项权限")
+	}
+
+	var user models.User
+	if err := s.ctx.DB().Where("id = ?", userID).Preload("Roles").First(&user).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", errors.NotFoundMsg("用户不存在")
+		}
+		return nil, "", err
+	}
+
+	var permissions []models.Permission
+	if err := s.ctx.DB().Where("id IN ?", permissionIDs).Find(&permissions).Error; err != nil {
+		return nil, "", err
+	}
+	if len(permissions) != len(uniqueUints(permissionIDs)) {
+		return nil, "", errors.BadRequestMsg("权限不存在")
+	}
+	if !IsSuperAdmin(&user) {
+		roleIDs := make([]uint, 0, len(user.Roles))
+		for _, role := range user.Roles {
+			roleIDs = append(roleIDs, role.ID)
+		}
+		owned, err := s.ctx.GetPermissionService().GetMatchersByRoleIDs(ctx, roleIDs)
+		if err != nil {
+			return nil, "", err
+		}
+		ownedIDs := make(map[uint]bool, len(owned))
+		for _, m := range owned {
+			ownedIDs[m.Permission.ID] = true
+		}
+		for _, p := range permissions {
+			if !canGrantAPIKeyPermission(p, ownedIDs, owned) {
+				return nil, "", errors.ForbiddenMsg("不能授予自己没有的权限")
+			}
+		}
+	}
+
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	raw := APIKeyPrefix + token
+	key := models.APIKey{
+		UserID:      user.ID,
+		Name:        name,
+		Prefix:      raw[:apiKeyDisplayPrefixLen],
+		KeyHash:     utils.HashToken(raw),
+		ExpiresAt:   expiresAt,
+		Permissions: permissions,
+	}
+	if err := s.ctx.DB().Omit("Permissions.*").Create(&key).Error; err != nil {
+		return nil, "", err
+	}
+	return &key, raw, nil
+}
+
+// GetUserKeys 用户自己的 API Key 列表
+func (s *APIKeyService) GetUserKeys(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := s.ctx.DB().Where("user_id = ?", userID).
+		Preload("Permissions").
+		Order("id DESC").
+		Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetKeys 管理端查询 API Key 列表（分页和筛选），支持按 user_id、username 筛选
+func (s *APIKeyService) GetKeys(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.APIKey, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	var total int64
+	var keys []models.APIKey
+
+	query := s.ctx.DB().Model(&models.APIKey{})
+	if userID := filters["user_id"]; userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if username := filters["username"]; username != "" {
+		subQuery := s.ctx.DB().Model(&models.User{}).Select("id").Where("username LIKE ?", "%"+username+"%")
+		query = query.Where("user_id IN (?)", subQuery)
+	}
+	query = query.Order("id DESC")
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "username", "nickname")
+	}).Preload("Permissions").Offset(offset).Limit(pageSize).Find(&keys).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return keys, total, nil
+}
+
+// RevokeKey 删除 API Key，立即失效；userID 非 0 时只允许删除该用户自己的 Key
+func (s *APIKeyService) RevokeKey(ctx context.Context, keyID uint, userID uint) error {
+	query := s.ctx.DB().Where("id = ?", keyID)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	var key models.APIKey
+	if err := query.First(&key).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NotFoundMsg("API Key不存在")
+		}
+		return err
+	}
+	return s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&key).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&key).Error
+	})
+}
+
+// Authenticate 校验 API Key 原文，返回已预加载 Permissions 与 User（含 Roles）的记录，并按间隔记录最近使用信息
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey, ip string) (*models.APIKey, error) {
+	if !strings.HasPrefix(rawKey, APIKeyPrefix) {
+		return nil, errors.UnauthorizedMsg("API Key无效")
+	}
+	var key models.APIKey
+	if err := s.ctx.DB().Where("key_hash = ?", utils.HashToken(rawKey)).Preload("Permissions").First(&key).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.UnauthorizedMsg("API Key无效")
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, errors.UnauthorizedMsg("API Key已过期")
+	}
+
+	var user models.User
+	if err := s.ctx.DB().Where("id = ?", key.UserID).Preload("Roles").First(&user).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.UnauthorizedMsg("用户不存在")
+		}
+		return nil, err
+	}
+	if user.Status == 0 {
+		return nil, errors.ForbiddenMsg("用户已被禁用")
+	}
+	key.User = &user
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		updates := map[string]interface{}{"last_used_at": now}
+		if ip != "" {
+			updates["last_used_ip"] = ip
+		}
+		if err := s.ctx.DB().Model(&models.APIKey{}).Where("id = ?", key.ID).Updates(updates).Error; err != nil {
+			s.ctx.Logger().WarnContext(ctx, "更新 API Key 使用时间失败", "api_key_id", key.ID, "error", err)
+		}
+	}
+	return &key, nil
+}
+
+// canGrantAPIKeyPermission 创建 Key 时判断创建者能否授予权限 p：拒绝规则只会收窄范围，总可授予；
+// 创建者拥有同一条权限，或 p 是具体的路径与方法且创建者的规则允许访问。含通配或路径参数的权限不拿去匹配
+// 创建者的规则（:id 的正则同样能匹配 *），只能授予已拥有的。请求时 PermissionMiddleware 仍会同时校验 Key 与创建者的权限
+func canGrantAPIKeyPermission(p models.Permission, ownedIDs map[uint]bool, ownerMatchers []*PermissionMatcher) bool {
+	if p.Effect == models.PermissionEffectDeny || ownedIDs[p.ID] {
+		return true
+	}
+	if p.Method == models.PermissionMethodAny || strings.ContainsAny(p.Path, "*:") {
+		return false
+	}
+	return EvaluatePermissions(ownerMatchers, p.Path, p.Method)
+}
+
+func uniqueUints(ids []uint) map[uint]struct{} {
+	set := make(map[uint]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
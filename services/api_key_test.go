@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lyuangg/gadmin/models"
+)
+
+// newAPIKeyTestOwner 创建拥有一项权限的用户，另建一项该用户没有的权限
+func newAPIKeyTestOwner(t *testing.T, ctx ServiceContext) (models.User, models.Permission, models.Permission) {
+	t.Helper()
+	owned := models.Permission{Name: "查询用户列表", Path: "/admin/api/users", Method: "GET"}
+	other := models.Permission{Name: "删除用户", Path: "/admin/api/users/:id", Method: "DELETE"}
+	ctx.DB().Create(&owned)
+	ctx.DB().Create(&other)
+	role := models.Role{Name: "运维", Permissions: []models.Permission{owned}}
+	ctx.DB().Create(&role)
+	user := models.User{Username: "ci", Password: "x", Status: 1, Roles: []models.Role{role}}
+	ctx.DB().Create(&user)
+	return user, owned, other
+}
+
+func TestAPIKeyService_CreateAndAuthenticate(t *testing.T) {
+	ctx := NewTestServiceContext(t, NewTestDB(t))
+	svc := NewAPIKeyService(ctx)
+	bg := context.Background()
+	user, owned, other := newAPIKeyTestOwner(t, ctx)
+
+	if _, _, err := svc.CreateKey(bg, user.ID, "deploy", nil, []uint{owned.ID, other.ID}); err == nil {
+		t.Error("should not grant permissions the owner does not have")
+	}
+	past := time.Now().Add(-time.Hour)
+	if _, _, err := svc.CreateKey(bg, user.ID, "deploy", &past, []uint{owned.ID}); err == nil {
+		t.Error("expiry in the past should be rejected")
+	}
+
+	key, raw, err := svc.CreateKey(bg, user.ID, "deploy", nil, []uint{owned.ID})
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	if !strings.HasPrefix(raw, APIKeyPrefix) || !strings.HasPrefix(raw, key.Prefix) {
+		t.Errorf("raw = %q, prefix = %q", raw, key.Prefix)
+	}
+	var stored models.APIKey
+	ctx.DB().First(&stored, key.ID)
+	if stored.KeyHash == raw || strings.Contains(stored.KeyHash, raw) {
+		t.Error("raw key must not be stored")
+	}
+
+	got, err := svc.Authenticate(bg, raw, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if got.User == nil || got.User.ID != user.ID || len(got.User.Roles) != 1 || len(got.Permissions) != 1 {
+		t.Errorf("authenticated key = %+v", got)
+	}
+	ctx.DB().First(&stored, key.ID)
+	if stored.LastUsedAt == nil || stored.LastUsedIP != "10.0.0.1" {
+		t.Errorf("last used not recorded: %+v", stored)
+	}
+
+	if _, err := svc.Authenticate(bg, raw+"x", ""); err == nil {
+		t.Error("wrong key should be rejected")
+	}
+	ctx.DB().Model(&models.User{}).Where("id = ?", user.ID).Update("status", 0)
+	if _, err := svc.Authenticate(bg, raw, ""); err == nil {
+		t.Error("disabled owner should be rejected")
+	}
+}
+
+// 具体路径的权限按创建者的权限规则判断；含通配或路径参数的权限只能授予创建者已拥有的
+func TestAPIKeyService_CreateKeyWithWildcardOwner(t *testing.T) {
+	ctx := NewTestServiceContext(t, NewTestDB(t))
+	svc := NewAPIKeyService(ctx)
+	bg := context.Background()
+
+	wildcard := models.Permission{Name: "用户管理只读", Path: "/admin/api/users/*", Method: "GET"}
+	denyDetail := models.Permission{Name: "禁止查看敏感用户", Path: "/admin/api/users/1", Method: "*", Effect: models.PermissionEffectDeny}
+	updateUser := models.Permission{Name: "更新用户", Path: "/admin/api/users/:id", Method: "PUT"}
+	detail := models.Permission{Name: "查看用户 2", Path: "/admin/api/users/2", Method: "GET"}
+	sensitive := models.Permission{Name: "敏感用户详情", Path: "/admin/api/users/1", Method: "GET"}
+	remove := models.Permission{Name: "删除用户", Path: "/admin/api/users/:id", Method: "DELETE"}
+	allMethods := models.Permission{Name: "用户管理", Path: "/admin/api/users/*", Method: "*"}
+	// 创建者只有 PUT :id，:id 的正则也能匹配 *，不能据此授予 PUT /*
+	updateAll := models.Permission{Name: "更新用户下所有接口", Path: "/admin/api/users/*", Method: "PUT"}
+	for _, p := range []*models.Permission{&wildcard, &denyDetail, &updateUser, &detail, &sensitive, &remove, &allMethods, &updateAll} {
+		ctx.DB().Create(p)
+	}
+	role := models.Role{Name: "只读", Permissions: []models.Permission{wildcard, denyDetail, updateUser}}
+	ctx.DB().Create(&role)
+	user := models.User{Username: "reader", Password: "x", Status: 1, Roles: []models.Role{role}}
+	ctx.DB().Create(&user)
+
+	if _, _, err := svc.CreateKey(bg, user.ID, "detail", nil, []uint{detail.ID, updateUser.ID}); err != nil {
+		t.Errorf("owned or covered permissions should be grantable: %v", err)
+	}
+	for _, p := range []models.Permission{sensitive, remove, allMethods, updateAll} {
+		if _, _, err := svc.CreateKey(bg, user.ID, "deny", nil, []uint{p.ID}); err == nil {
+			t.Errorf("should not grant %s %s", p.Method, p.Path)
+		}
+	}
+}
+
+func TestAPIKeyService_ExpiredAndRevoked(t *testing.T) {
+	ctx := NewTestServiceContext(t, NewTestDB(t))
+	svc := NewAPIKeyService(ctx)
+	bg := context.Background()
+	user, owned, _ := newAPIKeyTestOwner(t, ctx)
+
+	soon := time.Now().Add(time.Hour)
+	key, raw, err := svc.CreateKey(bg, user.ID, "nightly", &soon, []uint{owned.ID})
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	ctx.DB().Model(&models.APIKey{}).Where("id = ?", key.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := svc.Authenticate(bg, raw, ""); err == nil {
+		t.Error("expired key should be rejected")
+	}
+
+	if err := svc.RevokeKey(bg, key.ID, user.ID+1); err == nil {
+		t.Error("other users cannot revoke the key")
+	}
+	if err := svc.RevokeKey(bg, key.ID, user.ID); err != nil {
+		t.Fatalf("RevokeKey: %v", err)
+	}
+	keys, total, err := svc.GetKeys(bg, 1, 10, map[string]string{"username": "ci"})
+	if err != nil || total != 0 || len(keys) != 0 {
+		t.Errorf("revoked key still listed: %+v total=%d err=%v", keys, total, err)
+	}
+}
@@ -3,46 +3,440 @@ package services
 import (
 	"context"
 	stderrors "errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/lyuangg/gadmin/errors"
 	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/utils"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// defaultRefreshTokenTTL 未配置 refresh_token_ttl_hours 时的刷新 Token 有效期
+const defaultRefreshTokenTTL = 7 * 24 * time.Hour
+
+// defaultImpersonationTTL 未配置 impersonation_ttl_minutes 时的模拟登录有效期
+const defaultImpersonationTTL = 30 * time.Minute
+
+const (
+	// loginChallengeTTL 密码校验通过后完成两步验证的时限
+	loginChallengeTTL = 5 * time.Minute
+	// loginChallengeMaxAttempts 单个登录挑战允许的验证码错误次数
+	loginChallengeMaxAttempts = 5
+)
+
+// refreshTokenReuseGrace 刷新 Token 轮换后的宽限期：期间同一旧 Token 的并发或重试请求（如多个标签页同时刷新）
+// 拿到本次轮换已签发的同一对 Token，不按重用处理
+const refreshTokenReuseGrace = 10 * time.Second
+
 type AuthService struct {
 	ctx ServiceContext
+
+	mu        sync.Mutex
+	rotations map[string]*refreshRotation // 按旧刷新 Token 摘要记录宽限期内的轮换结果
+}
+
+// refreshRotation 一次刷新 Token 轮换；done 关闭后 tokens、err 与 rotatedAt 才可读取
+type refreshRotation struct {
+	done      chan struct{}
+	tokens    *TokenPair
+	err       error
+	rotatedAt time.Time
 }
 
 func NewAuthService(ctx ServiceContext) *AuthService {
 	return &AuthService{ctx: ctx}
 }
 
-func (s *AuthService) Login(ctx context.Context, username, password, captchaID, captchaVal string) (*models.User, string, error) {
+// LoginResult 登录结果：无需两步验证时直接携带 Tokens；否则只返回 ChallengeToken，
+// 由 CompleteTwoFactorLogin 校验验证码后再签发 Token
+type LoginResult struct {
+	User               *models.User
+	Tokens             *TokenPair
+	ChallengeToken     string
+	ChallengeExpiresIn int64
+	SetupRequired      bool     // 角色要求两步验证但用户尚未绑定，需先完成绑定
+	BackupCodes        []string // 登录过程中完成绑定时生成的备用码，仅返回这一次
+	MustChangePassword bool     // 须先修改密码（管理员设置的初始或重置密码、密码过期），前端应跳转到修改密码页
+	PasswordExpired    bool     // 密码已超过有效期
+}
+
+func (s *AuthService) Login(ctx context.Context, username, password, captchaID, captchaVal string, client ClientInfo) (*LoginResult, error) {
 	if !s.ctx.GetCaptchaProvider().Verify(captchaID, captchaVal) {
-		return nil, "", errors.UnauthorizedMsg("验证码错误")
+		s.recordEvent(ctx, models.SecurityEventCaptchaFailed, 0, username, client, "验证码错误")
+		return nil, errors.UnauthorizedMsg("验证码错误")
+	}
+
+	lockService := s.ctx.GetLoginLockService()
+	if err := lockService.Check(ctx, username, client.IP); err != nil {
+		s.recordEvent(ctx, models.SecurityEventLoginBlocked, 0, username, client, err.Error())
+		return nil, err
+	}
+
+	user, err := s.authenticate(ctx, username, password)
+	if err != nil {
+		if stderrors.Is(err, ErrInvalidCredentials) {
+			s.recordLoginFailure(ctx, username, client.IP)
+			s.recordEvent(ctx, models.SecurityEventLoginFailed, 0, username, client, "用户名或密码错误")
+			return nil, errors.UnauthorizedMsg("用户名或密码错误")
+		}
+		s.recordEvent(ctx, models.SecurityEventLoginFailed, 0, username, client, err.Error())
+		return nil, err
+	}
+	return s.completeLogin(ctx, user, client)
+}
+
+// LoginWithOIDC 用单点登录回调签发的一次性票据登录，之后的两步验证与会话流程与密码登录相同
+func (s *AuthService) LoginWithOIDC(ctx context.Context, ticket string, client ClientInfo) (*LoginResult, error) {
+	user, err := s.ctx.GetOIDCService().ConsumeTicket(ctx, ticket)
+	if err != nil {
+		s.recordEvent(ctx, models.SecurityEventLoginFailed, 0, "", client, "单点登录: "+err.Error())
+		return nil, err
+	}
+	return s.completeLogin(ctx, user, client)
+}
+
+// completeLogin 身份确认后：需要两步验证时返回登录挑战，否则创建会话并签发 Token
+func (s *AuthService) completeLogin(ctx context.Context, user *models.User, client ClientInfo) (*LoginResult, error) {
+	if user.TOTPEnabled || requiresTwoFactor(user) {
+		token, err := s.createLoginChallenge(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{
+			User:               user,
+			ChallengeToken:     token,
+			ChallengeExpiresIn: int64(loginChallengeTTL.Seconds()),
+			SetupRequired:      !user.TOTPEnabled,
+		}, nil
+	}
+
+	pair, err := s.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	return s.loginSuccess(ctx, user, pair, nil), nil
+}
+
+// authenticate 按 auth_providers 的顺序尝试各认证方式，第一个成功的生效；
+// 全部失败且其中有认证服务出错（如 LDAP 不可达）时返回服务不可用，不计入登录失败次数
+func (s *AuthService) authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	authenticators := s.ctx.GetAuthenticators()
+	if len(authenticators) == 0 {
+		authenticators = []Authenticator{NewLocalAuthenticator(s.ctx)}
+	}
+
+	unavailable := false
+	for _, authenticator := range authenticators {
+		user, err := authenticator.Authenticate(ctx, username, password)
+		if err == nil {
+			return user, nil
+		}
+		if !stderrors.Is(err, ErrInvalidCredentials) {
+			unavailable = true
+			s.ctx.Logger().ErrorContext(ctx, "登录认证出错", "provider", authenticator.Name(), "username", username, "error", err)
+		}
+	}
+	if unavailable {
+		return nil, errors.InternalErrorMsg("认证服务暂时不可用，请稍后重试")
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// recordEvent 记录登录相关的安全事件
+func (s *AuthService) recordEvent(ctx context.Context, eventType string, userID uint, username string, client ClientInfo, reason string) {
+	s.ctx.GetSecurityEventService().Record(ctx, &models.SecurityEvent{
+		EventType: eventType,
+		UserID:    userID,
+		Username:  username,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Reason:    reason,
+	})
+}
+
+// recordLoginFailure 记录密码校验失败；计数出错只记日志，不影响本次登录的错误提示
+func (s *AuthService) recordLoginFailure(ctx context.Context, username, ip string) {
+	if err := s.ctx.GetLoginLockService().RecordFailure(ctx, username, ip); err != nil {
+		s.ctx.Logger().WarnContext(ctx, "记录登录失败次数失败", "username", username, "ip", ip, "error", err)
+	}
+}
+
+// BeginTwoFactorSetup 登录挑战阶段为尚未绑定、但被角色强制要求的用户生成绑定密钥
+func (s *AuthService) BeginTwoFactorSetup(ctx context.Context, challengeToken string) (*TwoFactorSetup, error) {
+	challenge, err := s.findLoginChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.ctx.GetTwoFactorService().BeginSetup(ctx, challenge.UserID)
+}
+
+// CompleteTwoFactorLogin 校验登录挑战的验证码（或备用码）后签发 Token；
+// 用户尚未绑定时，验证码用于确认绑定，并在结果中返回新生成的备用码
+func (s *AuthService) CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string, client ClientInfo) (*LoginResult, error) {
+	challenge, err := s.findLoginChallenge(challengeToken)
+	if err != nil {
+		return nil, err
 	}
 
 	var user models.User
-	if err := s.ctx.DB().Where("username = ?", username).Preload("Roles").First(&user).Error; err != nil {
+	if err := s.ctx.DB().Where("id = ?", challenge.UserID).Preload("Roles").First(&user).Error; err != nil {
 		if stderrors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, "", errors.UnauthorizedMsg("用户名或密码错误")
+			return nil, errors.UnauthorizedMsg("用户不存在")
 		}
-		return nil, "", err
+		return nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, "", errors.UnauthorizedMsg("用户名或密码错误")
+	// 两步验证的错误次数同样计入登录锁定，锁定期内不再校验
+	if err := s.ctx.GetLoginLockService().Check(ctx, user.Username, client.IP); err != nil {
+		s.recordEvent(ctx, models.SecurityEventLoginBlocked, user.ID, user.Username, client, err.Error())
+		return nil, err
 	}
 
-	isSuperAdmin := false
+	var backupCodes []string
+	if user.TOTPEnabled {
+		err = s.ctx.GetTwoFactorService().Verify(ctx, user.ID, code)
+	} else {
+		backupCodes, err = s.ctx.GetTwoFactorService().Enable(ctx, user.ID, code)
+	}
+	if err != nil {
+		var bizErr *errors.BizError
+		if stderrors.As(err, &bizErr) {
+			s.failLoginChallenge(ctx, challenge)
+			s.recordLoginFailure(ctx, user.Username, client.IP)
+			s.recordEvent(ctx, models.SecurityEventTwoFactorFailed, user.ID, user.Username, client, bizErr.Error())
+		}
+		return nil, err
+	}
+
+	// 挑战只能使用一次：删除成功者才能继续签发，防止并发重复提交
+	result := s.ctx.DB().Where("id = ?", challenge.ID).Delete(&models.LoginChallenge{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.UnauthorizedMsg("登录已失效，请重新登录")
+	}
+
+	pair, err := s.startSession(ctx, &user, client)
+	if err != nil {
+		return nil, err
+	}
+	return s.loginSuccess(ctx, &user, pair, backupCodes), nil
+}
+
+// loginSuccess 组装登录成功结果；密码过期时标记为须修改密码，由认证中间件限制其访问范围
+func (s *AuthService) loginSuccess(ctx context.Context, user *models.User, pair *TokenPair, backupCodes []string) *LoginResult {
+	expired := s.ctx.GetPasswordService().IsExpired(user)
+	if expired && !user.MustChangePassword {
+		if err := s.ctx.DB().Model(user).Update("must_change_password", true).Error; err != nil {
+			s.ctx.Logger().WarnContext(ctx, "标记密码过期失败", "user_id", user.ID, "error", err)
+		}
+		user.MustChangePassword = true
+	}
+	return &LoginResult{
+		User:               user,
+		Tokens:             pair,
+		BackupCodes:        backupCodes,
+		MustChangePassword: user.MustChangePassword,
+		PasswordExpired:    expired,
+	}
+}
+
+// startSession 创建登录会话并签发首个 Token 对；登录完成（含两步验证）后才清除失败计数，
+// 否则知道密码的人可以靠反复重新登录绕过两步验证的错误次数限制
+func (s *AuthService) startSession(ctx context.Context, user *models.User, client ClientInfo) (*TokenPair, error) {
+	session, err := s.ctx.GetSessionService().CreateSession(ctx, user.ID, client, time.Now().Add(s.refreshTokenTTL()))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ctx.GetLoginLockService().RecordSuccess(ctx, user.Username); err != nil {
+		s.ctx.Logger().WarnContext(ctx, "清除登录失败计数失败", "username", user.Username, "error", err)
+	}
+	s.recordEvent(ctx, models.SecurityEventLoginSuccess, user.ID, user.Username, client, "")
+	return s.issueTokens(user, session.JTI)
+}
+
+// createLoginChallenge 创建等待两步验证的登录挑战，返回挑战 Token 原文
+func (s *AuthService) createLoginChallenge(userID uint) (string, error) {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	challenge := models.LoginChallenge{
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(loginChallengeTTL),
+	}
+	if err := s.ctx.DB().Create(&challenge).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *AuthService) findLoginChallenge(token string) (*models.LoginChallenge, error) {
+	if token == "" {
+		return nil, errors.UnauthorizedMsg("登录已失效，请重新登录")
+	}
+	var challenge models.LoginChallenge
+	if err := s.ctx.DB().Where("token_hash = ?", utils.HashToken(token)).First(&challenge).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.UnauthorizedMsg("登录已失效，请重新登录")
+		}
+		return nil, err
+	}
+	if time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= loginChallengeMaxAttempts {
+		return nil, errors.UnauthorizedMsg("登录已失效，请重新登录")
+	}
+	return &challenge, nil
+}
+
+// failLoginChallenge 记录一次验证失败，达到上限后挑战作废，需重新输入密码
+func (s *AuthService) failLoginChallenge(ctx context.Context, challenge *models.LoginChallenge) {
+	if err := s.ctx.DB().Model(&models.LoginChallenge{}).Where("id = ?", challenge.ID).
+		Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+		s.ctx.Logger().WarnContext(ctx, "记录两步验证失败次数失败", "user_id", challenge.UserID, "error", err)
+	}
+}
+
+// RefreshToken 用刷新 Token 换取新的访问 Token，并轮换刷新 Token；会话 jti 保持不变。
+// 本实例轮换后 refreshTokenReuseGrace 内再次使用同一旧 Token，返回已签发的同一对 Token；
+// 超出宽限期后已轮换过的刷新 Token 再次出现视为泄露重用，吊销整个家族及其会话，迫使该次登录的所有持有者重新登录。
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, client ClientInfo) (*TokenPair, error) {
+	if refreshToken == "" {
+		return nil, errors.UnauthorizedMsg("未提供刷新Token")
+	}
+
+	tokenHash := utils.HashToken(refreshToken)
+	rotation, leader := s.joinRotation(tokenHash)
+	if !leader {
+		select {
+		case <-rotation.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if rotation.err != nil {
+			return nil, rotation.err
+		}
+		// 宽限期内可能已退出或被吊销，签发的 Token 随之失效
+		var count int64
+		if err := s.ctx.DB().Model(&models.RefreshToken{}).
+			Where("token_hash = ? AND revoked_at IS NULL", utils.HashToken(rotation.tokens.RefreshToken)).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, errors.UnauthorizedMsg("刷新Token已失效")
+		}
+		return rotation.tokens, nil
+	}
+
+	rotation.tokens, rotation.err = s.rotateRefreshToken(ctx, tokenHash, client)
+	rotation.rotatedAt = time.Now()
+	close(rotation.done)
+	if rotation.err != nil {
+		s.mu.Lock()
+		delete(s.rotations, tokenHash)
+		s.mu.Unlock()
+	}
+	return rotation.tokens, rotation.err
+}
+
+// joinRotation 返回 tokenHash 进行中或宽限期内的轮换；没有时登记一次新的轮换，leader 为 true 表示由调用方执行
+func (s *AuthService) joinRotation(tokenHash string) (rotation *refreshRotation, leader bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for hash, r := range s.rotations {
+		select {
+		case <-r.done:
+			if now.Sub(r.rotatedAt) >= refreshTokenReuseGrace {
+				delete(s.rotations, hash)
+			}
+		default:
+		}
+	}
+	if r, ok := s.rotations[tokenHash]; ok {
+		return r, false
+	}
+
+	if s.rotations == nil {
+		s.rotations = make(map[string]*refreshRotation)
+	}
+	rotation = &refreshRotation{done: make(chan struct{})}
+	s.rotations[tokenHash] = rotation
+	return rotation, true
+}
+
+// rotateRefreshToken 校验刷新 Token 并轮换出新的一对 Token
+func (s *AuthService) rotateRefreshToken(ctx context.Context, tokenHash string, client ClientInfo) (*TokenPair, error) {
+	var rt models.RefreshToken
+	if err := s.ctx.DB().Where("token_hash = ?", tokenHash).First(&rt).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.UnauthorizedMsg("刷新Token无效")
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if rt.RevokedAt != nil {
+		return nil, errors.UnauthorizedMsg("刷新Token已失效")
+	}
+	if rt.RotatedAt != nil {
+		// 宽限期内仍出现说明轮换发生在其他实例或本实例重启前，拿不到已签发的 Token，只拒绝本次请求、不吊销
+		if now.Sub(*rt.RotatedAt) < refreshTokenReuseGrace {
+			return nil, errors.UnauthorizedMsg("刷新Token已失效")
+		}
+		if err := s.ctx.GetSessionService().RevokeSessionByJTI(ctx, rt.FamilyID); err != nil {
+			return nil, err
+		}
+		s.ctx.Logger().WarnContext(ctx, "检测到刷新Token重用，已吊销整个Token家族", "user_id", rt.UserID, "refresh_token_id", rt.ID)
+		s.recordEvent(ctx, models.SecurityEventRefreshTokenReuse, rt.UserID, "", client, "已吊销该次登录的全部Token")
+		return nil, errors.UnauthorizedMsg("刷新Token已失效")
+	}
+	if now.After(rt.ExpiresAt) {
+		return nil, errors.UnauthorizedMsg("刷新Token已过期")
+	}
+
+	// 条件更新防止并发请求同时轮换同一个 Token
+	result := s.ctx.DB().Model(&models.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL", rt.ID).
+		Update("rotated_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.UnauthorizedMsg("刷新Token已失效")
+	}
+
+	var user models.User
+	if err := s.ctx.DB().Where("id = ?", rt.UserID).Preload("Roles").First(&user).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.UnauthorizedMsg("用户不存在")
+		}
+		return nil, err
+	}
+	if user.Status == 0 {
+		return nil, errors.ForbiddenMsg("用户已被禁用")
+	}
+
+	if err := s.ctx.GetSessionService().RenewSession(ctx, rt.FamilyID, client, now.Add(s.refreshTokenTTL())); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(&user, rt.FamilyID)
+}
+
+// issueTokens 按用户当前角色签发会话 jti 的访问 Token，并在该会话下创建新的刷新 Token
+func (s *AuthService) issueTokens(user *models.User, jti string) (*TokenPair, error) {
+	isSuperAdmin := IsSuperAdmin(user)
 	var roleIDs []uint
 	for _, role := range user.Roles {
 		roleIDs = append(roleIDs, role.ID)
-		if role.Name == "超级管理员" {
-			isSuperAdmin = true
-		}
 	}
 
 	token, err := s.ctx.GetTokenGenerator().GenerateToken(
@@ -53,19 +447,122 @@ func (s *AuthService) Login(ctx context.Context, username, password, captchaID,
 		isSuperAdmin,
 		roleIDs,
 		user.TokenVersion,
+		jti,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	refreshTTL := s.refreshTokenTTL()
+	rt := models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(refreshToken),
+		FamilyID:  jti,
+		ExpiresAt: time.Now().Add(refreshTTL),
+	}
+	if err := s.ctx.DB().Create(&rt).Error; err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL().Seconds()),
+		RefreshIn:    int64(refreshTTL.Seconds()),
+	}, nil
+}
+
+// IsSuperAdmin 用户（须已加载 Roles）是否拥有超级管理员角色
+func IsSuperAdmin(user *models.User) bool {
+	for _, role := range user.Roles {
+		if role.Code == models.RoleCodeSuperAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *AuthService) refreshTokenTTL() time.Duration {
+	if hours := s.ctx.GetConfig().RefreshTokenTTLHours; hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultRefreshTokenTTL
+}
+
+func (s *AuthService) impersonationTTL() time.Duration {
+	if minutes := s.ctx.GetConfig().ImpersonationTTLMinutes; minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultImpersonationTTL
+}
+
+// ImpersonationResult 模拟登录结果：被模拟的用户与其身份的短期访问 Token（无刷新 Token）
+type ImpersonationResult struct {
+	User      *models.User
+	Token     string
+	ExpiresIn int64
+}
+
+// Impersonate 超级管理员以 targetUserID 的身份登录，用于排查该用户的实际权限；
+// 不能在模拟期间再次发起，不能模拟自己、已禁用的用户或其他超级管理员
+func (s *AuthService) Impersonate(ctx context.Context, operator *utils.Claims, targetUserID uint, client ClientInfo) (*ImpersonationResult, error) {
+	if !operator.IsSuperAdmin || operator.ImpersonatorID != 0 {
+		return nil, errors.ForbiddenMsg("只有超级管理员可以模拟登录")
+	}
+	if targetUserID == operator.UserID {
+		return nil, errors.BadRequestMsg("不能模拟登录自己")
+	}
+
+	var user models.User
+	if err := s.ctx.DB().Where("id = ?", targetUserID).Preload("Roles").First(&user).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NotFoundMsg("用户不存在")
+		}
+		return nil, err
+	}
+	if user.Status == 0 {
+		return nil, errors.BadRequestMsg("用户已被禁用")
+	}
+	if IsSuperAdmin(&user) {
+		return nil, errors.BadRequestMsg("不能模拟登录超级管理员")
+	}
+
+	roleIDs := make([]uint, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		roleIDs = append(roleIDs, role.ID)
+	}
+	ttl := s.impersonationTTL()
+	token, err := s.ctx.GetTokenGenerator().GenerateImpersonationToken(
+		user.ID,
+		user.Username,
+		user.Nickname,
+		user.Type,
+		roleIDs,
+		user.TokenVersion,
+		operator.ID,
+		operator.UserID,
+		operator.Username,
+		ttl,
 	)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	return &user, token, nil
+	s.recordEvent(ctx, models.SecurityEventImpersonationStart, operator.UserID, operator.Username, client,
+		fmt.Sprintf("模拟登录用户 %s（ID %d）", user.Username, user.ID))
+	return &ImpersonationResult{User: &user, Token: token, ExpiresIn: int64(ttl.Seconds())}, nil
 }
 
 func (s *AuthService) GenerateCaptcha(ctx context.Context) (string, string, error) {
 	return s.ctx.GetCaptchaProvider().Generate(ctx)
 }
 
-func (s *AuthService) ChangePassword(ctx context.Context, userID uint, oldPassword, newPassword string) error {
+// ChangePassword 修改密码后吊销该用户其他设备的会话，currentJTI 对应的当前会话保留
+func (s *AuthService) ChangePassword(ctx context.Context, userID uint, currentJTI, oldPassword, newPassword string) error {
 	var user models.User
 	if err := s.ctx.DB().Where("id = ?", userID).First(&user).Error; err != nil {
 		if stderrors.Is(err, gorm.ErrRecordNotFound) {
@@ -78,20 +575,20 @@ func (s *AuthService) ChangePassword(ctx context.Context, userID uint, oldPasswo
 		return errors.UnauthorizedMsg("当前密码错误")
 	}
 
-	if len(newPassword) < 6 {
-		return errors.BadRequestMsg("新密码长度不能少于6位")
-	}
-
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
-	if err != nil {
-		return errors.InternalErrorMsg("密码加密失败")
+	passwords := s.ctx.GetPasswordService()
+	if err := passwords.SetPassword(ctx, &user, newPassword); err != nil {
+		return err
 	}
-
-	user.Password = string(hashedPassword)
-	user.TokenVersion++
+	user.MustChangePassword = false
 	if err := s.ctx.DB().Save(&user).Error; err != nil {
 		return err
 	}
+	if err := passwords.RecordHistory(ctx, &user); err != nil {
+		return err
+	}
+	if err := s.ctx.GetSessionService().RevokeUserSessions(ctx, user.ID, currentJTI); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -115,18 +612,31 @@ func (s *AuthService) UpdateAvatar(ctx context.Context, userID uint, avatarURL s
 	return nil
 }
 
-// Logout 递增用户 token_version，使已签发的 token 失效
-func (s *AuthService) Logout(ctx context.Context, userID uint) error {
-	var user models.User
-	if err := s.ctx.DB().Where("id = ?", userID).First(&user).Error; err != nil {
+// Logout 吊销当前会话及其刷新 Token，不影响该用户在其他设备上的登录
+func (s *AuthService) Logout(ctx context.Context, userID uint, jti string) error {
+	var session models.UserSession
+	if err := s.ctx.DB().Where("jti = ? AND user_id = ?", jti, userID).First(&session).Error; err != nil {
 		if stderrors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.NotFoundMsg("用户不存在")
+			return errors.NotFoundMsg("会话不存在")
 		}
 		return err
 	}
-	user.TokenVersion++
-	if err := s.ctx.DB().Save(&user).Error; err != nil {
-		return errors.InternalErrorMsg("更新Token版本失败")
+	if err := s.ctx.GetSessionService().RevokeSessionByJTI(ctx, jti); err != nil {
+		return errors.InternalErrorMsg("吊销会话失败")
 	}
 	return nil
 }
+
+// CleanExpired 删除已过期的刷新 Token、会话与两步验证登录挑战（过期后均已不可使用），返回删除的总行数
+func (s *AuthService) CleanExpired(ctx context.Context) (int64, error) {
+	now := time.Now()
+	var deleted int64
+	for _, model := range []any{&models.RefreshToken{}, &models.UserSession{}, &models.LoginChallenge{}} {
+		result := s.ctx.DB().Where("expires_at < ?", now).Delete(model)
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+	}
+	return deleted, nil
+}
@@ -2,9 +2,12 @@ package services
 
 import (
 	"context"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/utils"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -15,7 +18,7 @@ func TestAuthService_Login_VerifyCaptchaFail(t *testing.T) {
 	svc := NewAuthService(ctx)
 	bg := context.Background()
 
-	_, _, err := svc.Login(bg, "admin", "admin123", "cid", "val")
+	_, err := svc.Login(bg, "admin", "admin123", "cid", "val", ClientInfo{})
 	if err == nil {
 		t.Error("expected error when captcha fails")
 	}
@@ -27,7 +30,7 @@ func TestAuthService_Login_UserNotFound(t *testing.T) {
 	svc := NewAuthService(ctx)
 	bg := context.Background()
 
-	_, _, err := svc.Login(bg, "nonexistent", "any", "cid", "val")
+	_, err := svc.Login(bg, "nonexistent", "any", "cid", "val", ClientInfo{})
 	if err == nil {
 		t.Error("expected error when user not found")
 	}
@@ -53,12 +56,207 @@ func TestAuthService_Login_Success(t *testing.T) {
 	svc := NewAuthService(ctx)
 	bg := context.Background()
 
-	u, token, err := svc.Login(bg, "testuser", "pass123", "cid", "val")
+	result, err := svc.Login(bg, "testuser", "pass123", "cid", "val", ClientInfo{})
 	if err != nil {
 		t.Fatalf("Login: %v", err)
 	}
-	if u.Username != "testuser" || token != "my-token" {
-		t.Errorf("user=%s token=%s", u.Username, token)
+	u, tokens := result.User, result.Tokens
+	if result.ChallengeToken != "" {
+		t.Error("challenge should not be issued without 2FA")
+	}
+	if u.Username != "testuser" || tokens.AccessToken != "my-token" {
+		t.Errorf("user=%s token=%s", u.Username, tokens.AccessToken)
+	}
+	if tokens.RefreshToken == "" {
+		t.Error("expected refresh token")
+	}
+}
+
+// loginForRefreshTest 创建用户并登录，返回 service 与首个刷新 Token
+func loginForRefreshTest(t *testing.T) (*AuthService, *models.User, string) {
+	t.Helper()
+	db := NewTestDB(t)
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass123"), bcrypt.DefaultCost)
+	user := models.User{Username: "rtuser", Password: string(hashed), Status: 1}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	svc := NewAuthService(NewTestServiceContext(t, db))
+	result, err := svc.Login(context.Background(), "rtuser", "pass123", "cid", "val", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	return svc, &user, result.Tokens.RefreshToken
+}
+
+func TestAuthService_RefreshToken_Rotates(t *testing.T) {
+	svc, _, rt := loginForRefreshTest(t)
+	bg := context.Background()
+
+	tokens, err := svc.RefreshToken(bg, rt, ClientInfo{})
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if tokens.RefreshToken == "" || tokens.RefreshToken == rt {
+		t.Errorf("refresh token should rotate, got %q", tokens.RefreshToken)
+	}
+	if _, err := svc.RefreshToken(bg, tokens.RefreshToken, ClientInfo{}); err != nil {
+		t.Errorf("rotated refresh token should be usable: %v", err)
+	}
+}
+
+// endReuseGrace 让已轮换的刷新 Token 都超出重用宽限期
+func endReuseGrace(t *testing.T, svc *AuthService) {
+	t.Helper()
+	past := time.Now().Add(-refreshTokenReuseGrace - time.Second)
+	svc.ctx.DB().Model(&models.RefreshToken{}).Where("rotated_at IS NOT NULL").Update("rotated_at", past)
+	svc.mu.Lock()
+	for _, r := range svc.rotations {
+		r.rotatedAt = past
+	}
+	svc.mu.Unlock()
+}
+
+func TestAuthService_RefreshToken_ReuseRevokesFamily(t *testing.T) {
+	svc, _, rt := loginForRefreshTest(t)
+	bg := context.Background()
+
+	tokens, err := svc.RefreshToken(bg, rt, ClientInfo{})
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	endReuseGrace(t, svc)
+	// 超出宽限期后旧 Token 再次出现：视为重用
+	if _, err := svc.RefreshToken(bg, rt, ClientInfo{}); err == nil {
+		t.Fatal("expected error when reusing rotated refresh token")
+	}
+	// 整个家族已吊销，合法持有者的新 Token 也不可用
+	if _, err := svc.RefreshToken(bg, tokens.RefreshToken, ClientInfo{}); err == nil {
+		t.Error("expected family to be revoked after reuse")
+	}
+}
+
+// 宽限期内重复使用同一旧 Token 拿到同一对 Token，不吊销家族
+func TestAuthService_RefreshToken_ReuseWithinGrace(t *testing.T) {
+	svc, _, rt := loginForRefreshTest(t)
+	bg := context.Background()
+
+	first, err := svc.RefreshToken(bg, rt, ClientInfo{})
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	again, err := svc.RefreshToken(bg, rt, ClientInfo{})
+	if err != nil {
+		t.Fatalf("RefreshToken within grace: %v", err)
+	}
+	if again.RefreshToken != first.RefreshToken || again.AccessToken != first.AccessToken {
+		t.Error("expected the already-rotated pair within grace")
+	}
+	if _, err := svc.RefreshToken(bg, first.RefreshToken, ClientInfo{}); err != nil {
+		t.Errorf("family should not be revoked within grace: %v", err)
+	}
+}
+
+// 多个标签页同时用同一个刷新 Token 刷新，都拿到同一对 Token
+func TestAuthService_RefreshToken_Concurrent(t *testing.T) {
+	svc, _, rt := loginForRefreshTest(t)
+	// 内存库每个连接是独立的库，并发访问须共用一个连接
+	sqlDB, _ := svc.ctx.DB().DB()
+	sqlDB.SetMaxOpenConns(1)
+	bg := context.Background()
+
+	results := make([]*TokenPair, 5)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens, err := svc.RefreshToken(bg, rt, ClientInfo{})
+			if err != nil {
+				t.Errorf("RefreshToken: %v", err)
+				return
+			}
+			results[i] = tokens
+		}(i)
+	}
+	wg.Wait()
+	for _, tokens := range results {
+		if tokens != nil && tokens.RefreshToken != results[0].RefreshToken {
+			t.Errorf("concurrent refreshes got different pairs")
+		}
+	}
+	var count int64
+	svc.ctx.DB().Model(&models.RefreshToken{}).Where("revoked_at IS NULL AND rotated_at IS NULL").Count(&count)
+	if count != 1 {
+		t.Errorf("active refresh tokens = %d, want 1", count)
+	}
+}
+
+// 宽限期内已退出时，旧 Token 不再换回已签发的 Token
+func TestAuthService_RefreshToken_GraceAfterLogout(t *testing.T) {
+	svc, user, rt := loginForRefreshTest(t)
+	bg := context.Background()
+
+	if _, err := svc.RefreshToken(bg, rt, ClientInfo{}); err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if err := svc.Logout(bg, user.ID, sessionJTIs(t, svc)[0]); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, err := svc.RefreshToken(bg, rt, ClientInfo{}); err == nil {
+		t.Error("expected error after logout within grace")
+	}
+}
+
+func TestAuthService_RefreshToken_Invalid(t *testing.T) {
+	svc, _, _ := loginForRefreshTest(t)
+	if _, err := svc.RefreshToken(context.Background(), "not-a-token", ClientInfo{}); err == nil {
+		t.Error("expected error for unknown refresh token")
+	}
+}
+
+func TestAuthService_RefreshToken_Expired(t *testing.T) {
+	svc, _, rt := loginForRefreshTest(t)
+	svc.ctx.DB().Model(&models.RefreshToken{}).Where("1 = 1").Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := svc.RefreshToken(context.Background(), rt, ClientInfo{}); err == nil {
+		t.Error("expected error for expired refresh token")
+	}
+}
+
+func TestAuthService_Logout_RevokesRefreshTokens(t *testing.T) {
+	svc, user, rt := loginForRefreshTest(t)
+	bg := context.Background()
+	if err := svc.Logout(bg, user.ID, sessionJTIs(t, svc)[0]); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, err := svc.RefreshToken(bg, rt, ClientInfo{}); err == nil {
+		t.Error("expected refresh token to be revoked after logout")
+	}
+}
+
+func TestAuthService_CleanExpired(t *testing.T) {
+	svc, user, _ := loginForRefreshTest(t)
+	db := svc.ctx.DB()
+	past := time.Now().Add(-time.Minute)
+	db.Create(&models.RefreshToken{UserID: user.ID, TokenHash: "expired", FamilyID: "old", ExpiresAt: past})
+	db.Create(&models.UserSession{UserID: user.ID, JTI: "old", ExpiresAt: past})
+	db.Create(&models.LoginChallenge{UserID: user.ID, TokenHash: "expired", ExpiresAt: past})
+	db.Create(&models.LoginChallenge{UserID: user.ID, TokenHash: "live", ExpiresAt: time.Now().Add(time.Minute)})
+
+	deleted, err := svc.CleanExpired(context.Background())
+	if err != nil {
+		t.Fatalf("CleanExpired: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("deleted = %d, want 3", deleted)
+	}
+	// 登录时创建的会话与刷新 Token 未过期，保留
+	var tokens, sessions, challenges int64
+	db.Model(&models.RefreshToken{}).Count(&tokens)
+	db.Model(&models.UserSession{}).Count(&sessions)
+	db.Model(&models.LoginChallenge{}).Count(&challenges)
+	if tokens != 1 || sessions != 1 || challenges != 1 {
+		t.Errorf("remaining tokens=%d sessions=%d challenges=%d, want 1 each", tokens, sessions, challenges)
 	}
 }
 
@@ -78,7 +276,7 @@ func TestAuthService_Login_WrongPassword(t *testing.T) {
 	svc := NewAuthService(ctx)
 	bg := context.Background()
 
-	_, _, err := svc.Login(bg, "u2", "wrong", "cid", "val")
+	_, err := svc.Login(bg, "u2", "wrong", "cid", "val", ClientInfo{})
 	if err == nil {
 		t.Error("expected error for wrong password")
 	}
@@ -92,6 +290,7 @@ func TestAuthService_ChangePassword(t *testing.T) {
 	user.Password = string(hashed)
 	user.Type = 0
 	user.Status = 1
+	user.MustChangePassword = true
 	if err := db.Create(&user).Error; err != nil {
 		t.Fatalf("create user: %v", err)
 	}
@@ -100,7 +299,7 @@ func TestAuthService_ChangePassword(t *testing.T) {
 	svc := NewAuthService(ctx)
 	bg := context.Background()
 
-	err := svc.ChangePassword(bg, user.ID, "oldpass", "newpass6")
+	err := svc.ChangePassword(bg, user.ID, "", "oldpass", "NewPass#6")
 	if err != nil {
 		t.Fatalf("ChangePassword: %v", err)
 	}
@@ -108,9 +307,12 @@ func TestAuthService_ChangePassword(t *testing.T) {
 	if err := db.First(&u, user.ID).Error; err != nil {
 		t.Fatalf("find user: %v", err)
 	}
-	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte("newpass6")) != nil {
+	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte("NewPass#6")) != nil {
 		t.Error("password was not updated correctly")
 	}
+	if u.MustChangePassword {
+		t.Error("changing password should clear must_change_password")
+	}
 }
 
 func TestAuthService_ChangePassword_WrongOld(t *testing.T) {
@@ -126,7 +328,7 @@ func TestAuthService_ChangePassword_WrongOld(t *testing.T) {
 	svc := NewAuthService(ctx)
 	bg := context.Background()
 
-	err := svc.ChangePassword(bg, u.ID, "wrongold", "newpass")
+	err := svc.ChangePassword(bg, u.ID, "", "wrongold", "newpass")
 	if err == nil {
 		t.Error("expected error for wrong old password")
 	}
@@ -170,24 +372,137 @@ func TestAuthService_GenerateCaptcha(t *testing.T) {
 	}
 }
 
+// sessionJTIs 按创建顺序返回库中全部会话的 jti
+func sessionJTIs(t *testing.T, svc *AuthService) []string {
+	t.Helper()
+	var jtis []string
+	if err := svc.ctx.DB().Model(&models.UserSession{}).Order("id ASC").Pluck("jti", &jtis).Error; err != nil {
+		t.Fatalf("pluck jti: %v", err)
+	}
+	return jtis
+}
+
+// 退出只吊销当前会话，其他设备不受影响
 func TestAuthService_Logout(t *testing.T) {
-	db := NewTestDB(t)
-	if err := db.Create(&models.User{Username: "logoutuser", Password: "x", Type: 0, Status: 1, TokenVersion: 0}).Error; err != nil {
-		t.Fatal(err)
+	svc, user, _ := loginForRefreshTest(t)
+	bg := context.Background()
+	if _, err := svc.Login(bg, "rtuser", "pass123", "cid", "val", ClientInfo{}); err != nil {
+		t.Fatalf("second Login: %v", err)
+	}
+	jtis := sessionJTIs(t, svc)
+	if len(jtis) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(jtis))
+	}
+
+	if err := svc.Logout(bg, user.ID, jtis[0]); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+
+	sessions := svc.ctx.GetSessionService()
+	if err := sessions.ValidateSession(bg, user.ID, jtis[0], ClientInfo{}); err == nil {
+		t.Error("logged out session should be invalid")
+	}
+	if err := sessions.ValidateSession(bg, user.ID, jtis[1], ClientInfo{}); err != nil {
+		t.Errorf("other session should stay valid: %v", err)
 	}
 	var u models.User
-	db.Where("username = ?", "logoutuser").First(&u)
+	svc.ctx.DB().First(&u, user.ID)
+	if u.TokenVersion != 0 {
+		t.Errorf("logout should not bump TokenVersion, got %d", u.TokenVersion)
+	}
+}
 
-	ctx := NewTestServiceContext(t, db)
-	svc := NewAuthService(ctx)
+func TestAuthService_Logout_SessionNotFound(t *testing.T) {
+	svc, user, _ := loginForRefreshTest(t)
+	if err := svc.Logout(context.Background(), user.ID, "unknown-jti"); err == nil {
+		t.Error("expected error for unknown session")
+	}
+}
+
+// 修改密码吊销其他设备的会话，保留当前会话
+func TestAuthService_ChangePassword_RevokesOtherSessions(t *testing.T) {
+	svc, user, _ := loginForRefreshTest(t)
+	bg := context.Background()
+	if _, err := svc.Login(bg, "rtuser", "pass123", "cid", "val", ClientInfo{}); err != nil {
+		t.Fatalf("second Login: %v", err)
+	}
+	jtis := sessionJTIs(t, svc)
+
+	if err := svc.ChangePassword(bg, user.ID, jtis[1], "pass123", "NewPass#6"); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+
+	sessions := svc.ctx.GetSessionService()
+	if err := sessions.ValidateSession(bg, user.ID, jtis[0], ClientInfo{}); err == nil {
+		t.Error("other session should be revoked after password change")
+	}
+	if err := sessions.ValidateSession(bg, user.ID, jtis[1], ClientInfo{}); err != nil {
+		t.Errorf("current session should stay valid: %v", err)
+	}
+}
+
+func TestAuthService_Impersonate(t *testing.T) {
+	db := NewTestDB(t)
+	role := models.Role{Name: "运维"}
+	db.Create(&role)
+	target := models.User{Username: "ops", Password: "x", Nickname: "运维小王", Status: 1, TokenVersion: 2, Roles: []models.Role{role}}
+	disabled := models.User{Username: "gone", Password: "x", Status: 0}
+	admin := models.User{Username: "root", Password: "x", Status: 1, Roles: []models.Role{{Name: "超级管理员", Code: models.RoleCodeSuperAdmin}}}
+	for _, u := range []*models.User{&target, &disabled, &admin} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	// 状态 0 须显式更新，Create 会用默认值 1
+	db.Model(&disabled).Update("status", 0)
+
+	events := &FakeSecurityEventService{}
+	svc := NewAuthService(NewTestServiceContext(t, db,
+		WithTokenGenerator(&FakeTokenGenerator{Token: "imp-token"}),
+		WithSecurityEventService(events)))
+	operator := &utils.Claims{UserID: admin.ID, Username: "root", IsSuperAdmin: true}
+	operator.ID = "sess-root"
 	bg := context.Background()
 
-	err := svc.Logout(bg, u.ID)
+	result, err := svc.Impersonate(bg, operator, target.ID, ClientInfo{IP: "10.0.0.1"})
 	if err != nil {
-		t.Fatalf("Logout: %v", err)
+		t.Fatalf("Impersonate: %v", err)
 	}
-	db.First(&u, u.ID)
-	if u.TokenVersion != 1 {
-		t.Errorf("expected TokenVersion 1, got %d", u.TokenVersion)
+	if result.Token != "imp-token" || result.User.Username != "ops" || len(result.User.Roles) != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	if result.ExpiresIn != int64(defaultImpersonationTTL.Seconds()) {
+		t.Errorf("expires_in = %d", result.ExpiresIn)
+	}
+	if len(events.Recorded) != 1 || events.Recorded[0].EventType != models.SecurityEventImpersonationStart || events.Recorded[0].UserID != admin.ID {
+		t.Errorf("recorded events = %+v", events.Recorded)
+	}
+
+	cases := []struct {
+		name     string
+		operator *utils.Claims
+		target   uint
+	}{
+		{name: "not super admin", operator: &utils.Claims{UserID: target.ID}, target: admin.ID},
+		{name: "nested impersonation", operator: &utils.Claims{UserID: target.ID, IsSuperAdmin: true, ImpersonatorID: admin.ID}, target: disabled.ID},
+		{name: "self", operator: operator, target: admin.ID},
+		{name: "disabled user", operator: operator, target: disabled.ID},
+		{name: "missing user", operator: operator, target: 9999},
+	}
+	for _, tc := range cases {
+		if _, err := svc.Impersonate(bg, tc.operator, tc.target, ClientInfo{}); err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
+
+func TestAuthService_Impersonate_SuperAdminTarget(t *testing.T) {
+	db := NewTestDB(t)
+	other := models.User{Username: "root2", Password: "x", Status: 1, Roles: []models.Role{{Name: "超级管理员", Code: models.RoleCodeSuperAdmin}}}
+	db.Create(&other)
+	svc := NewAuthService(NewTestServiceContext(t, db))
+	operator := &utils.Claims{UserID: other.ID + 1, Username: "root", IsSuperAdmin: true}
+	if _, err := svc.Impersonate(context.Background(), operator, other.ID, ClientInfo{}); err == nil {
+		t.Error("expected error when impersonating another super admin")
 	}
 }
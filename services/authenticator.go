@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/lyuangg/gadmin/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 认证方式名称，对应配置 auth_providers
+const (
+	AuthProviderLocal = "local"
+	AuthProviderLDAP  = "ldap"
+)
+
+// ErrInvalidCredentials 用户不存在或密码错误，登录时按配置顺序继续尝试下一个认证方式
+var ErrInvalidCredentials = stderrors.New("invalid credentials")
+
+// Authenticator 用户名密码认证方式；认证成功返回已预加载 Roles 的本地用户
+type Authenticator interface {
+	Name() string
+	Authenticate(ctx context.Context, username, password string) (*models.User, error)
+}
+
+// NewAuthenticators 按配置 auth_providers 的顺序创建认证方式，未知名称忽略
+func NewAuthenticators(ctx ServiceContext) []Authenticator {
+	var list []Authenticator
+	for _, name := range ctx.GetConfig().AuthProviders {
+		switch name {
+		case AuthProviderLocal:
+			list = append(list, NewLocalAuthenticator(ctx))
+		case AuthProviderLDAP:
+			list = append(list, NewLDAPAuthenticator(ctx, nil))
+		default:
+			ctx.Logger().WarnContext(context.Background(), "未知的认证方式，已忽略", "provider", name)
+		}
+	}
+	return list
+}
+
+// localAuthenticator 本地账号：校验 bcrypt 密码，仅适用于来源为 local 的用户
+type localAuthenticator struct {
+	ctx ServiceContext
+}
+
+// NewLocalAuthenticator 创建本地密码认证
+func NewLocalAuthenticator(ctx ServiceContext) Authenticator {
+	return &localAuthenticator{ctx: ctx}
+}
+
+func (a *localAuthenticator) Name() string {
+	return AuthProviderLocal
+}
+
+func (a *localAuthenticator) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	var user models.User
+	err := a.ctx.DB().Where("username = ? AND source = ?", username, models.UserSourceLocal).
+		Preload("Roles").First(&user).Error
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &user, nil
+}
@@ -2,8 +2,38 @@ package services
 
 import (
 	"context"
+	"strings"
+	"time"
+
+	"github.com/lyuangg/gadmin/config"
 
 	"github.com/mojocn/base64Captcha"
+	"gorm.io/gorm"
+)
+
+// 验证码存储方式
+const (
+	CaptchaStoreMemory = "memory"
+	CaptchaStoreDB     = "db"
+)
+
+// 验证码类型
+const (
+	CaptchaTypeDigit        = "digit"
+	CaptchaTypeMath         = "math"
+	CaptchaTypeAlphanumeric = "alphanumeric"
+)
+
+// 未配置时的验证码默认值
+const (
+	defaultCaptchaWidth  = 240
+	defaultCaptchaHeight = 80
+	defaultCaptchaLength = 4
+	defaultCaptchaExpire = 5 * time.Minute
+	// captchaMemoryCollectNum 内存存储累计写入多少条后触发一次过期回收
+	captchaMemoryCollectNum = 10240
+	// captchaAlphanumericSource 字母数字验证码的字符集，去掉了易混淆字符；校验时不区分大小写
+	captchaAlphanumericSource = "23456789abcdefghjkmnpqrstuvwxyz"
 )
 
 // CaptchaProvider 验证码提供者，便于测试时替换为 mock
@@ -12,25 +42,82 @@ type CaptchaProvider interface {
 	Verify(id, answer string) bool
 }
 
-// realCaptcha 生产环境实现，使用 base64Captcha
+// CaptchaCleaner 需要定期清理过期验证码的提供者（如 db 存储）实现此接口
+type CaptchaCleaner interface {
+	CleanExpired(ctx context.Context) (int64, error)
+}
+
+// realCaptcha 生产环境实现，使用 base64Captcha，存储可替换
 type realCaptcha struct {
-	store base64Captcha.Store
+	store  base64Captcha.Store
+	driver base64Captcha.Driver
 }
 
-// NewRealCaptchaProvider 创建生产用验证码提供者
+// NewCaptchaProvider 按配置创建验证码提供者：captcha_store 为 db 时答案存入数据库，多实例间共享
+func NewCaptchaProvider(cfg *config.Config, db *gorm.DB) CaptchaProvider {
+	expire := defaultCaptchaExpire
+	if cfg.CaptchaExpireSeconds > 0 {
+		expire = time.Duration(cfg.CaptchaExpireSeconds) * time.Second
+	}
+
+	var store base64Captcha.Store
+	if cfg.CaptchaStore == CaptchaStoreDB && db != nil {
+		store = NewDBCaptchaStore(db, expire)
+	} else {
+		store = base64Captcha.NewMemoryStore(captchaMemoryCollectNum, expire)
+	}
+	return &realCaptcha{store: store, driver: newCaptchaDriver(cfg)}
+}
+
+// NewRealCaptchaProvider 创建使用默认配置与内存存储的验证码提供者
 func NewRealCaptchaProvider() CaptchaProvider {
-	return &realCaptcha{store: base64Captcha.DefaultMemStore}
+	return NewCaptchaProvider(&config.Config{}, nil)
 }
 
 func (c *realCaptcha) Generate(ctx context.Context) (id, b64s string, err error) {
-	driver := base64Captcha.NewDriverDigit(80, 240, 4, 0.7, 80)
-	captcha := base64Captcha.NewCaptcha(driver, c.store)
+	captcha := base64Captcha.NewCaptcha(c.driver, c.store)
 	id, b64s, _, err = captcha.Generate()
 	return id, b64s, err
 }
 
+// Verify 校验后立即作废该验证码，无论是否正确；不区分大小写
 func (c *realCaptcha) Verify(id, answer string) bool {
-	return c.store.Verify(id, answer, true)
+	if id == "" {
+		return false
+	}
+	expected := c.store.Get(id, true)
+	return expected != "" && strings.EqualFold(expected, strings.TrimSpace(answer))
+}
+
+// CleanExpired 清理过期验证码；内存存储会自行回收，返回 0
+func (c *realCaptcha) CleanExpired(ctx context.Context) (int64, error) {
+	if cleaner, ok := c.store.(CaptchaCleaner); ok {
+		return cleaner.CleanExpired(ctx)
+	}
+	return 0, nil
+}
+
+// newCaptchaDriver 按配置的类型与尺寸创建验证码图片驱动
+func newCaptchaDriver(cfg *config.Config) base64Captcha.Driver {
+	width, height, length := defaultCaptchaWidth, defaultCaptchaHeight, defaultCaptchaLength
+	if cfg.CaptchaWidth > 0 {
+		width = cfg.CaptchaWidth
+	}
+	if cfg.CaptchaHeight > 0 {
+		height = cfg.CaptchaHeight
+	}
+	if cfg.CaptchaLength > 0 {
+		length = cfg.CaptchaLength
+	}
+
+	switch cfg.CaptchaType {
+	case CaptchaTypeMath:
+		return base64Captcha.NewDriverMath(height, width, 0, base64Captcha.OptionShowHollowLine, nil, nil, nil)
+	case CaptchaTypeAlphanumeric:
+		return base64Captcha.NewDriverString(height, width, 0, base64Captcha.OptionShowHollowLine, length, captchaAlphanumericSource, nil, nil, nil)
+	default:
+		return base64Captcha.NewDriverDigit(height, width, length, 0.7, 80)
+	}
 }
 
 // FakeCaptchaProvider 单测用，Verify/Generate 行为可配置
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/lyuangg/gadmin/models"
+
+	"github.com/mojocn/base64Captcha"
+	"gorm.io/gorm"
+)
+
+// dbCaptchaStore 基于数据库的 base64Captcha.Store，多实例部署时任一节点生成的验证码可在其他节点校验
+type dbCaptchaStore struct {
+	db         *gorm.DB
+	expiration time.Duration
+}
+
+// NewDBCaptchaStore 创建数据库验证码存储，expiration 为验证码有效期
+func NewDBCaptchaStore(db *gorm.DB, expiration time.Duration) base64Captcha.Store {
+	return &dbCaptchaStore{db: db, expiration: expiration}
+}
+
+func (s *dbCaptchaStore) Set(id string, value string) error {
+	return s.db.Create(&models.Captcha{
+		ID:        id,
+		Answer:    value,
+		ExpiresAt: time.Now().Add(s.expiration),
+	}).Error
+}
+
+// Get 返回未过期的答案；clear 为 true 时删除记录，并发读取同一验证码时只有删除成功的一方拿到答案
+func (s *dbCaptchaStore) Get(id string, clear bool) string {
+	var captcha models.Captcha
+	if err := s.db.Where("id = ? AND expires_at > ?", id, time.Now()).First(&captcha).Error; err != nil {
+		return ""
+	}
+	if clear {
+		result := s.db.Where("id = ?", id).Delete(&models.Captcha{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return ""
+		}
+	}
+	return captcha.Answer
+}
+
+func (s *dbCaptchaStore) Verify(id, answer string, clear bool) bool {
+	expected := s.Get(id, clear)
+	return expected != "" && strings.EqualFold(expected, strings.TrimSpace(answer))
+}
+
+// CleanExpired 删除已过期的验证码
+func (s *dbCaptchaStore) CleanExpired(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&models.Captcha{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lyuangg/gadmin/config"
+	"github.com/lyuangg/gadmin/models"
+)
+
+func TestDBCaptchaStore_GetAndClear(t *testing.T) {
+	db := NewTestDB(t)
+	store := NewDBCaptchaStore(db, time.Minute)
+
+	if err := store.Set("cid", "AbC4"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got := store.Get("cid", false); got != "AbC4" {
+		t.Errorf("Get = %q, want AbC4", got)
+	}
+	if !store.Verify("cid", "abc4", true) {
+		t.Error("Verify should ignore case")
+	}
+	if store.Verify("cid", "abc4", true) {
+		t.Error("captcha should be cleared after verify")
+	}
+}
+
+func TestDBCaptchaStore_Expired(t *testing.T) {
+	db := NewTestDB(t)
+	store := NewDBCaptchaStore(db, time.Minute)
+	db.Create(&models.Captcha{ID: "old", Answer: "1234", ExpiresAt: time.Now().Add(-time.Second)})
+	store.Set("new", "5678")
+
+	if store.Get("old", false) != "" {
+		t.Error("expired captcha should not be returned")
+	}
+	deleted, err := store.(CaptchaCleaner).CleanExpired(context.Background())
+	if err != nil {
+		t.Fatalf("CleanExpired: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted = %d, want 1", deleted)
+	}
+	if store.Get("new", false) != "5678" {
+		t.Error("unexpired captcha should be kept")
+	}
+}
+
+// 两个实例共享同一数据库：一个实例生成，另一个实例校验
+func TestCaptchaProvider_DBStoreSharedAcrossInstances(t *testing.T) {
+	db := NewTestDB(t)
+	cfg := &config.Config{CaptchaStore: CaptchaStoreDB, CaptchaType: CaptchaTypeAlphanumeric, CaptchaLength: 5}
+	nodeA := NewCaptchaProvider(cfg, db)
+	nodeB := NewCaptchaProvider(cfg, db)
+
+	id, b64s, err := nodeA.Generate(context.Background())
+	if err != nil || id == "" || b64s == "" {
+		t.Fatalf("Generate: id=%q err=%v", id, err)
+	}
+	var captcha models.Captcha
+	if err := db.Where("id = ?", id).First(&captcha).Error; err != nil {
+		t.Fatalf("captcha not stored in db: %v", err)
+	}
+	if len(captcha.Answer) != 5 {
+		t.Errorf("answer length = %d, want 5", len(captcha.Answer))
+	}
+
+	if nodeB.Verify(id, "wrong") {
+		t.Error("wrong answer should fail")
+	}
+	// 校验失败同样作废，防止对同一验证码反复猜测
+	if nodeB.Verify(id, captcha.Answer) {
+		t.Error("captcha should be invalidated after a failed attempt")
+	}
+}
+
+func TestCaptchaProvider_Types(t *testing.T) {
+	for _, typ := range []string{CaptchaTypeDigit, CaptchaTypeMath, CaptchaTypeAlphanumeric} {
+		provider := NewCaptchaProvider(&config.Config{CaptchaType: typ, CaptchaWidth: 160, CaptchaHeight: 60}, nil)
+		id, b64s, err := provider.Generate(context.Background())
+		if err != nil || id == "" || b64s == "" {
+			t.Errorf("type %s: Generate id=%q err=%v", typ, id, err)
+		}
+	}
+}
@@ -13,9 +13,19 @@ type ServiceContext interface {
 	GetConfig() *config.Config
 	GetCaptchaProvider() CaptchaProvider
 	GetTokenGenerator() TokenGenerator
+	GetAuthenticators() []Authenticator
+	GetPermissionCacheBroadcaster() PermissionCacheBroadcaster
 	GetAuthService() IAuthService
+	GetSessionService() ISessionService
+	GetTwoFactorService() ITwoFactorService
+	GetLoginLockService() ILoginLockService
+	GetPasswordService() IPasswordService
+	GetOIDCService() IOIDCService
+	GetAPIKeyService() IAPIKeyService
 	GetUserService() IUserService
 	GetRoleService() IRoleService
 	GetPermissionService() IPermissionService
 	GetOperationLogService() IOperationLogService
+	GetSecurityEventService() ISecurityEventService
+	GetDataScopeService() IDataScopeService
 }
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/utils"
+
+	"gorm.io/gorm"
+)
+
+// DataScope 当前用户可见的数据范围，由用户所有角色的数据范围合并得到（取并集）
+type DataScope struct {
+	All           bool   // 可见全部数据
+	UserID        uint   // 当前用户，本人的数据始终可见
+	DepartmentIDs []uint // 可见的部门
+}
+
+// Scope 返回可直接用于 db.Scopes 的 GORM scope，列表查询通过它按数据范围过滤
+// deptColumn 为数据所属部门的列；没有部门列的表传空字符串，按 userColumn 对应用户的所在部门过滤
+// userColumn 为数据所属用户的列；All 时不做任何过滤，DataScope 为 nil 时不返回任何数据
+func (d *DataScope) Scope(deptColumn, userColumn string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if d == nil {
+			return db.Where("1 = 0")
+		}
+		if d.All {
+			return db
+		}
+
+		// 多个条件放在同一个 Where 中以 OR 连接，GORM 会为其加上括号，不影响调用方的其他条件
+		var conds []string
+		var args []interface{}
+		if len(d.DepartmentIDs) > 0 {
+			if deptColumn != "" {
+				conds = append(conds, deptColumn+" IN ?")
+				args = append(args, d.DepartmentIDs)
+			} else if userColumn != "" {
+				// 包含已删除的用户，其在职期间产生的数据仍归属原部门
+				sub := db.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&models.User{}).
+					Select("id").Where("department_id IN ?", d.DepartmentIDs)
+				conds = append(conds, userColumn+" IN (?)")
+				args = append(args, sub)
+			}
+		}
+		if userColumn != "" && d.UserID > 0 {
+			conds = append(conds, userColumn+" = ?")
+			args = append(args, d.UserID)
+		}
+		if len(conds) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where(strings.Join(conds, " OR "), args...)
+	}
+}
+
+type unrestrictedKey struct{}
+
+// Unrestricted 标记 ctx 不受数据范围限制，供定时任务、命令行等没有登录用户的内部调用显式使用；
+// 请求中的登录信息优先，标记对已登录的请求无效
+func Unrestricted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unrestrictedKey{}, true)
+}
+
+// DataScopeService 数据范围服务：根据请求上下文中的登录用户及其角色计算数据范围
+type DataScopeService struct {
+	ctx ServiceContext
+}
+
+// NewDataScopeService 创建数据范围服务实例
+func NewDataScopeService(ctx ServiceContext) *DataScopeService {
+	return &DataScopeService{ctx: ctx}
+}
+
+// Resolve 计算 ctx 中登录用户的数据范围
+// 超级管理员时为全部数据；任一角色为 all 即为全部数据，未知的数据范围按仅本人处理。
+// 没有登录信息时不可见任何数据，内部调用须用 Unrestricted 标记 ctx
+func (s *DataScopeService) Resolve(ctx context.Context) (*DataScope, error) {
+	claims, ok := utils.ClaimsFromContext(ctx)
+	if !ok {
+		if unrestricted, _ := ctx.Value(unrestrictedKey{}).(bool); unrestricted {
+			return &DataScope{All: true}, nil
+		}
+		return &DataScope{}, nil
+	}
+	if claims.IsSuperAdmin {
+		return &DataScope{All: true}, nil
+	}
+
+	scope := &DataScope{UserID: claims.UserID}
+	if len(claims.RoleIDs) == 0 {
+		return scope, nil
+	}
+
+	db := s.ctx.DB().WithContext(ctx)
+	var roles []models.Role
+	if err := db.Select("id", "data_scope").
+		Preload("DataScopeDepartments", func(tx *gorm.DB) *gorm.DB { return tx.Select("id") }).
+		Where("id IN ?", claims.RoleIDs).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	deptIDs := make(map[uint]struct{})
+	ownDept, ownDeptTree := false, false
+	for _, role := range roles {
+		switch role.DataScope {
+		case "", models.DataScopeAll:
+			return &DataScope{All: true}, nil
+		case models.DataScopeDept:
+			ownDept = true
+		case models.DataScopeDeptAndChildren:
+			ownDeptTree = true
+		case models.DataScopeCustom:
+			for _, dept := range role.DataScopeDepartments {
+				deptIDs[dept.ID] = struct{}{}
+			}
+		}
+	}
+
+	if ownDept || ownDeptTree {
+		var user models.User
+		if err := db.Select("id", "department_id").First(&user, claims.UserID).Error; err != nil {
+			return nil, err
+		}
+		if user.DepartmentID > 0 {
+			deptIDs[user.DepartmentID] = struct{}{}
+			if ownDeptTree {
+				subtree, err := departmentSubtree(db, []uint{user.DepartmentID})
+				if err != nil {
+					return nil, err
+				}
+				for _, id := range subtree {
+					deptIDs[id] = struct{}{}
+				}
+			}
+		}
+	}
+
+	for id := range deptIDs {
+		scope.DepartmentIDs = append(scope.DepartmentIDs, id)
+	}
+	sort.Slice(scope.DepartmentIDs, func(i, j int) bool { return scope.DepartmentIDs[i] < scope.DepartmentIDs[j] })
+	return scope, nil
+}
+
+// departmentSubtree 返回 roots 及其所有下级部门的 ID
+func departmentSubtree(db *gorm.DB, roots []uint) ([]uint, error) {
+	var depts []models.Department
+	if err := db.Select("id", "parent_id").Find(&depts).Error; err != nil {
+		return nil, err
+	}
+	children := make(map[uint][]uint)
+	for _, dept := range depts {
+		children[dept.ParentID] = append(children[dept.ParentID], dept.ID)
+	}
+
+	seen := make(map[uint]bool)
+	var ids []uint
+	queue := append([]uint(nil), roots...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+		queue = append(queue, children[id]...)
+	}
+	return ids, nil
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/utils"
+)
+
+// dataScopeFixture 部门树：总部(root) -> 研发(dev) -> 后端(backend)，另有独立的销售(sales)
+type dataScopeFixture struct {
+	root, dev, backend, sales models.Department
+	devUser, backendUser      models.User
+	salesUser                 models.User
+}
+
+func newDataScopeFixture(t *testing.T, svcCtx ServiceContext) *dataScopeFixture {
+	t.Helper()
+	db := svcCtx.DB()
+	f := &dataScopeFixture{}
+	f.root = models.Department{Name: "总部"}
+	db.Create(&f.root)
+	f.dev = models.Department{Name: "研发", ParentID: f.root.ID}
+	db.Create(&f.dev)
+	f.backend = models.Department{Name: "后端", ParentID: f.dev.ID}
+	db.Create(&f.backend)
+	f.sales = models.Department{Name: "销售", ParentID: f.root.ID}
+	db.Create(&f.sales)
+
+	f.devUser = models.User{Username: "dev", Password: "x", DepartmentID: f.dev.ID}
+	db.Create(&f.devUser)
+	f.backendUser = models.User{Username: "backend", Password: "x", DepartmentID: f.backend.ID}
+	db.Create(&f.backendUser)
+	f.salesUser = models.User{Username: "sales", Password: "x", DepartmentID: f.sales.ID}
+	db.Create(&f.salesUser)
+	return f
+}
+
+// withScopeRole 创建指定数据范围的角色，并返回以 user 身份、拥有该角色的请求上下文
+func withScopeRole(t *testing.T, svcCtx ServiceContext, user models.User, dataScope string, deptIDs ...uint) context.Context {
+	t.Helper()
+	role := models.Role{Name: "scope-" + dataScope + "-" + user.Username, DataScope: dataScope}
+	if err := svcCtx.DB().Create(&role).Error; err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+	if len(deptIDs) > 0 {
+		if err := NewRoleService(svcCtx).SetDataScope(context.Background(), role.ID, dataScope, deptIDs); err != nil {
+			t.Fatalf("SetDataScope: %v", err)
+		}
+	}
+	claims := &utils.Claims{UserID: user.ID, Username: user.Username, RoleIDs: []uint{role.ID}}
+	return context.WithValue(context.Background(), "claims", claims)
+}
+
+func TestDataScopeService_Resolve(t *testing.T) {
+	db := NewTestDB(t)
+	svcCtx := NewTestServiceContext(t, db)
+	svc := NewDataScopeService(svcCtx)
+	f := newDataScopeFixture(t, svcCtx)
+
+	tests := []struct {
+		name      string
+		dataScope string
+		deptIDs   []uint
+		wantAll   bool
+		wantDepts []uint
+	}{
+		{"全部数据", models.DataScopeAll, nil, true, nil},
+		{"本部门", models.DataScopeDept, nil, false, []uint{f.dev.ID}},
+		{"本部门及下级", models.DataScopeDeptAndChildren, nil, false, []uint{f.dev.ID, f.backend.ID}},
+		{"仅本人", models.DataScopeSelf, nil, false, nil},
+		{"自定义部门", models.DataScopeCustom, []uint{f.sales.ID}, false, []uint{f.sales.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope, err := svc.Resolve(withScopeRole(t, svcCtx, f.devUser, tt.dataScope, tt.deptIDs...))
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if scope.All != tt.wantAll || !reflect.DeepEqual(scope.DepartmentIDs, tt.wantDepts) {
+				t.Errorf("scope = %+v, want all=%v depts=%v", scope, tt.wantAll, tt.wantDepts)
+			}
+			if !tt.wantAll && scope.UserID != f.devUser.ID {
+				t.Errorf("UserID = %d, want %d", scope.UserID, f.devUser.ID)
+			}
+		})
+	}
+
+	// 无登录信息时不可见任何数据，须显式标记 Unrestricted；超级管理员为全部数据
+	if scope, _ := svc.Resolve(context.Background()); scope.All || scope.UserID != 0 || len(scope.DepartmentIDs) != 0 {
+		t.Errorf("expected empty scope without claims, got %+v", scope)
+	}
+	if scope, _ := svc.Resolve(Unrestricted(context.Background())); !scope.All {
+		t.Error("expected all data for unrestricted context")
+	}
+	// 已登录的请求不受 Unrestricted 标记影响
+	devCtx := Unrestricted(context.WithValue(context.Background(), "claims", &utils.Claims{UserID: f.devUser.ID}))
+	if scope, _ := svc.Resolve(devCtx); scope.All {
+		t.Error("Unrestricted must not widen a logged-in user's scope")
+	}
+	super := context.WithValue(context.Background(), "claims", &utils.Claims{UserID: f.devUser.ID, IsSuperAdmin: true})
+	if scope, _ := svc.Resolve(super); !scope.All {
+		t.Error("expected all data for super admin")
+	}
+}
+
+func TestDataScope_AppliedToLists(t *testing.T) {
+	db := NewTestDB(t)
+	svcCtx := NewTestServiceContext(t, db)
+	f := newDataScopeFixture(t, svcCtx)
+	userSvc := NewUserService(svcCtx)
+	logSvc := NewOperationLogService(svcCtx)
+
+	for _, u := range []models.User{f.devUser, f.backendUser, f.salesUser} {
+		db.Create(&models.OperationLog{UserID: u.ID, Username: u.Username, Method: "GET", Path: "/a", StatusCode: 200})
+	}
+
+	usernames := func(ctx context.Context) []string {
+		users, total, err := userSvc.GetUsers(ctx, 1, 10, map[string]string{"order_by": "id_asc"})
+		if err != nil {
+			t.Fatalf("GetUsers: %v", err)
+		}
+		if int(total) != len(users) {
+			t.Errorf("total = %d, len = %d", total, len(users))
+		}
+		var names []string
+		for _, u := range users {
+			names = append(names, u.Username)
+		}
+		return names
+	}
+	logUsernames := func(ctx context.Context) []string {
+		logs, _, err := logSvc.GetOperationLogs(ctx, 1, 10, map[string]string{"order_by": "id_asc"})
+		if err != nil {
+			t.Fatalf("GetOperationLogs: %v", err)
+		}
+		var names []string
+		for _, l := range logs {
+			names = append(names, l.Username)
+		}
+		return names
+	}
+
+	tree := withScopeRole(t, svcCtx, f.devUser, models.DataScopeDeptAndChildren)
+	if got, want := usernames(tree), []string{"dev", "backend"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("users in dept tree = %v, want %v", got, want)
+	}
+	if got, want := logUsernames(tree), []string{"dev", "backend"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("logs in dept tree = %v, want %v", got, want)
+	}
+
+	self := withScopeRole(t, svcCtx, f.backendUser, models.DataScopeSelf)
+	if got, want := usernames(self), []string{"backend"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("users for self = %v, want %v", got, want)
+	}
+	// 数据范围与其他筛选条件同时生效
+	logs, _, _ := logSvc.GetOperationLogs(self, 1, 10, map[string]string{"username": "sales"})
+	if len(logs) != 0 {
+		t.Errorf("expected no logs outside data scope, got %d", len(logs))
+	}
+
+	// 没有登录信息时列表为空（失败即关闭），内部调用显式标记后可见全部
+	if got := usernames(context.Background()); len(got) != 0 {
+		t.Errorf("expected no users without claims, got %v", got)
+	}
+	if got := logUsernames(context.Background()); len(got) != 0 {
+		t.Errorf("expected no logs without claims, got %v", got)
+	}
+	if got := usernames(Unrestricted(context.Background())); len(got) != 3 {
+		t.Errorf("expected all users for unrestricted context, got %v", got)
+	}
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"strconv"
+	"strings"
+
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+
+	"gorm.io/gorm"
+)
+
+// DepartmentService 部门服务
+type DepartmentService struct {
+	ctx ServiceContext
+}
+
+// NewDepartmentService 创建部门服务实例
+func NewDepartmentService(ctx ServiceContext) *DepartmentService {
+	return &DepartmentService{ctx: ctx}
+}
+
+// GetDepartmentTree 获取部门树，同级按 sort、id 升序
+// 支持按名称（模糊）与状态筛选，命中的部门会带上其所有上级，保持树形结构完整
+func (s *DepartmentService) GetDepartmentTree(ctx context.Context, filters map[string]string) ([]*models.Department, error) {
+	var depts []*models.Department
+	if err := s.ctx.DB().Preload("Leader", func(tx *gorm.DB) *gorm.DB {
+		return tx.Select("id", "username", "nickname")
+	}).Order("sort ASC, id ASC").Find(&depts).Error; err != nil {
+		return nil, err
+	}
+
+	name, status := filters["name"], filters["status"]
+	if name != "" || status != "" {
+		byID := make(map[uint]*models.Department, len(depts))
+		for _, d := range depts {
+			byID[d.ID] = d
+		}
+		keep := make(map[uint]bool)
+		for _, d := range depts {
+			if name != "" && !strings.Contains(d.Name, name) {
+				continue
+			}
+			if status != "" && status != strconv.Itoa(d.Status) {
+				continue
+			}
+			for p := d; p != nil && !keep[p.ID]; p = byID[p.ParentID] {
+				keep[p.ID] = true
+			}
+		}
+		filtered := depts[:0]
+		for _, d := range depts {
+			if keep[d.ID] {
+				filtered = append(filtered, d)
+			}
+		}
+		depts = filtered
+	}
+
+	return buildDepartmentTree(depts), nil
+}
+
+// CreateDepartment 创建部门，parentID 为 0 时为顶级部门
+func (s *DepartmentService) CreateDepartment(ctx context.Context, parentID uint, name string, leaderID uint, sort int, status int) (*models.Department, error) {
+	if err := s.checkParent(0, parentID); err != nil {
+		return nil, err
+	}
+	if err := s.checkLeader(leaderID); err != nil {
+		return nil, err
+	}
+
+	dept := models.Department{
+		ParentID: parentID,
+		Name:     name,
+		LeaderID: leaderID,
+		Sort:     sort,
+		Status:   status,
+	}
+	if err := s.ctx.DB().Create(&dept).Error; err != nil {
+		return nil, err
+	}
+	// status 为零值时 Create 会使用列默认值（启用），需单独更新
+	if status != dept.Status {
+		if err := s.ctx.DB().Model(&dept).Update("status", status).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &dept, nil
+}
+
+// UpdateDepartment 更新部门，只修改非 nil 的字段（name 为空时不修改）；上级部门不能是自己或自己的下级，
+// parentID 为 0 时移到顶级，leaderID 为 0 时清除负责人
+func (s *DepartmentService) UpdateDepartment(ctx context.Context, id uint, parentID *uint, name string, leaderID *uint, sort *int, status *int) (*models.Department, error) {
+	var dept models.Department
+	if err := s.ctx.DB().Where("id = ?", id).First(&dept).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NotFoundMsg("部门不存在")
+		}
+		return nil, err
+	}
+
+	if parentID != nil {
+		if err := s.checkParent(id, *parentID); err != nil {
+			return nil, err
+		}
+		dept.ParentID = *parentID
+	}
+	if leaderID != nil {
+		if err := s.checkLeader(*leaderID); err != nil {
+			return nil, err
+		}
+		dept.LeaderID = *leaderID
+	}
+	if name != "" {
+		dept.Name = name
+	}
+	if sort != nil {
+		dept.Sort = *sort
+	}
+	if status != nil {
+		dept.Status = *status
+	}
+
+	if err := s.ctx.DB().Save(&dept).Error; err != nil {
+		return nil, err
+	}
+	return &dept, nil
+}
+
+// DeleteDepartment 删除部门；有下级部门或仍有用户的部门不能删除
+func (s *DepartmentService) DeleteDepartment(ctx context.Context, id uint) error {
+	var dept models.Department
+	if err := s.ctx.DB().Where("id = ?", id).First(&dept).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NotFoundMsg("部门不存在")
+		}
+		return err
+	}
+
+	var count int64
+	if err := s.ctx.DB().Model(&models.Department{}).Where("parent_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.BadRequestMsg("请先删除下级部门")
+	}
+	if err := s.ctx.DB().Model(&models.User{}).Where("department_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.BadRequestMsg("部门下还有用户，不能删除")
+	}
+
+	return s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		// 从角色的自定义数据范围中移除
+		if err := tx.Table(tx.NamingStrategy.JoinTableName("role_data_scope_departments")).
+			Where("department_id = ?", id).Delete(map[string]interface{}{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&dept).Error
+	})
+}
+
+// checkParent 校验上级部门存在，且不是 id 自身或其下级（id 为 0 表示新建）
+func (s *DepartmentService) checkParent(id, parentID uint) error {
+	if parentID == 0 {
+		return nil
+	}
+	if id != 0 {
+		subtree, err := departmentSubtree(s.ctx.DB(), []uint{id})
+		if err != nil {
+			return err
+		}
+		for _, sub := range subtree {
+			if sub == parentID {
+				return errors.BadRequestMsg("上级部门不能是自己或自己的下级部门")
+			}
+		}
+	}
+	var count int64
+	if err := s.ctx.DB().Model(&models.Department{}).Where("id = ?", parentID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.BadRequestMsg("上级部门不存在")
+	}
+	return nil
+}
+
+func (s *DepartmentService) checkLeader(leaderID uint) error {
+	if leaderID == 0 {
+		return nil
+	}
+	var count int64
+	if err := s.ctx.DB().Model(&models.User{}).Where("id = ?", leaderID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.BadRequestMsg("负责人不存在")
+	}
+	return nil
+}
+
+// buildDepartmentTree 将已排序的部门列表组装为树，子节点保持原有顺序；上级不在列表中的部门作为根节点
+func buildDepartmentTree(depts []*models.Department) []*models.Department {
+	byID := make(map[uint]*models.Department, len(depts))
+	for _, d := range depts {
+		d.Children = nil
+		byID[d.ID] = d
+	}
+	roots := make([]*models.Department, 0)
+	for _, d := range depts {
+		if parent, ok := byID[d.ParentID]; ok && d.ParentID != d.ID {
+			parent.Children = append(parent.Children, d)
+		} else {
+			roots = append(roots, d)
+		}
+	}
+	return roots
+}
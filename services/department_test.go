@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lyuangg/gadmin/models"
+)
+
+func TestDepartmentService_Tree(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewDepartmentService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	leader := models.User{Username: "leader", Password: "x", Nickname: "负责人"}
+	db.Create(&leader)
+
+	root, err := svc.CreateDepartment(bg, 0, "总部", leader.ID, 0, 1)
+	if err != nil {
+		t.Fatalf("CreateDepartment: %v", err)
+	}
+	sales, _ := svc.CreateDepartment(bg, root.ID, "销售", 0, 2, 1)
+	dev, _ := svc.CreateDepartment(bg, root.ID, "研发", 0, 1, 1)
+	if _, err := svc.CreateDepartment(bg, dev.ID, "后端", 0, 0, 0); err != nil {
+		t.Fatalf("CreateDepartment child: %v", err)
+	}
+	if _, err := svc.CreateDepartment(bg, 999, "无效", 0, 0, 1); err == nil {
+		t.Error("expected error for missing parent")
+	}
+	if _, err := svc.CreateDepartment(bg, 0, "无效", 999, 0, 1); err == nil {
+		t.Error("expected error for missing leader")
+	}
+
+	tree, err := svc.GetDepartmentTree(bg, nil)
+	if err != nil {
+		t.Fatalf("GetDepartmentTree: %v", err)
+	}
+	if len(tree) != 1 || tree[0].ID != root.ID {
+		t.Fatalf("expected single root, got %+v", tree)
+	}
+	if tree[0].Leader == nil || tree[0].Leader.Nickname != "负责人" {
+		t.Errorf("expected leader preloaded, got %+v", tree[0].Leader)
+	}
+	children := tree[0].Children
+	if len(children) != 2 || children[0].ID != dev.ID || children[1].ID != sales.ID {
+		t.Fatalf("children should be ordered by sort: %+v", children)
+	}
+	if len(children[0].Children) != 1 || children[0].Children[0].Name != "后端" {
+		t.Errorf("expected nested child, got %+v", children[0].Children)
+	}
+
+	// 筛选时保留命中部门的上级
+	tree, _ = svc.GetDepartmentTree(bg, map[string]string{"status": "0"})
+	if len(tree) != 1 || len(tree[0].Children) != 1 || tree[0].Children[0].ID != dev.ID ||
+		len(tree[0].Children[0].Children) != 1 {
+		t.Errorf("filtered tree should keep ancestors: %+v", tree)
+	}
+}
+
+func TestDepartmentService_UpdateRejectsCycle(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewDepartmentService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	root, _ := svc.CreateDepartment(bg, 0, "总部", 0, 0, 1)
+	dev, _ := svc.CreateDepartment(bg, root.ID, "研发", 0, 0, 1)
+	backend, _ := svc.CreateDepartment(bg, dev.ID, "后端", 0, 0, 1)
+
+	if _, err := svc.UpdateDepartment(bg, dev.ID, &dev.ID, "", nil, nil, nil); err == nil {
+		t.Error("expected error when parent is itself")
+	}
+	if _, err := svc.UpdateDepartment(bg, dev.ID, &backend.ID, "", nil, nil, nil); err == nil {
+		t.Error("expected error when parent is a descendant")
+	}
+	sort := 5
+	updated, err := svc.UpdateDepartment(bg, backend.ID, &root.ID, "后端组", nil, &sort, nil)
+	if err != nil {
+		t.Fatalf("UpdateDepartment: %v", err)
+	}
+	if updated.ParentID != root.ID || updated.Name != "后端组" || updated.Sort != 5 || updated.Status != 1 {
+		t.Errorf("update result: %+v", updated)
+	}
+}
+
+func TestDepartmentService_PartialUpdate(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewDepartmentService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	leader := models.User{Username: "leader", Password: "x", Status: 1}
+	db.Create(&leader)
+	root, _ := svc.CreateDepartment(bg, 0, "总部", 0, 0, 1)
+	dev, err := svc.CreateDepartment(bg, root.ID, "研发", leader.ID, 0, 1)
+	if err != nil {
+		t.Fatalf("CreateDepartment: %v", err)
+	}
+
+	// 只改名称：上级与负责人保持不变
+	updated, err := svc.UpdateDepartment(bg, dev.ID, nil, "研发中心", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("UpdateDepartment: %v", err)
+	}
+	if updated.Name != "研发中心" || updated.ParentID != root.ID || updated.LeaderID != leader.ID {
+		t.Errorf("name-only update changed other fields: %+v", updated)
+	}
+
+	// 显式传 0 才会移到顶级、清除负责人
+	zero := uint(0)
+	updated, err = svc.UpdateDepartment(bg, dev.ID, &zero, "", &zero, nil, nil)
+	if err != nil {
+		t.Fatalf("UpdateDepartment: %v", err)
+	}
+	if updated.ParentID != 0 || updated.LeaderID != 0 || updated.Name != "研发中心" {
+		t.Errorf("explicit zero update: %+v", updated)
+	}
+	var saved models.Department
+	db.First(&saved, dev.ID)
+	if saved.ParentID != 0 || saved.LeaderID != 0 {
+		t.Errorf("saved department: %+v", saved)
+	}
+}
+
+func TestDepartmentService_Delete(t *testing.T) {
+	db := NewTestDB(t)
+	svcCtx := NewTestServiceContext(t, db)
+	svc := NewDepartmentService(svcCtx)
+	bg := context.Background()
+
+	root, _ := svc.CreateDepartment(bg, 0, "总部", 0, 0, 1)
+	dev, _ := svc.CreateDepartment(bg, root.ID, "研发", 0, 0, 1)
+	user := models.User{Username: "dev", Password: "x", DepartmentID: dev.ID}
+	db.Create(&user)
+
+	if err := svc.DeleteDepartment(bg, root.ID); err == nil {
+		t.Error("expected error when department has children")
+	}
+	if err := svc.DeleteDepartment(bg, dev.ID); err == nil {
+		t.Error("expected error when department has users")
+	}
+
+	db.Model(&user).Update("department_id", 0)
+	role := models.Role{Name: "自定义范围"}
+	db.Create(&role)
+	if err := NewRoleService(svcCtx).SetDataScope(bg, role.ID, models.DataScopeCustom, []uint{dev.ID}); err != nil {
+		t.Fatalf("SetDataScope: %v", err)
+	}
+	if err := svc.DeleteDepartment(bg, dev.ID); err != nil {
+		t.Fatalf("DeleteDepartment: %v", err)
+	}
+	if n := db.Model(&role).Association("DataScopeDepartments").Count(); n != 0 {
+		t.Errorf("expected data scope association removed, got %d", n)
+	}
+}
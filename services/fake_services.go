@@ -2,15 +2,26 @@ package services
 
 import (
 	"context"
+	"time"
 
 	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/utils"
 )
 
 // FakeAuthService 单测用 IAuthService mock，可配置各方法返回值
 type FakeAuthService struct {
-	LoginUser  *models.User
-	LoginToken string
-	LoginErr   error
+	LoginResult *LoginResult
+	LoginErr    error
+
+	TwoFactorSetupResult *TwoFactorSetup
+	TwoFactorSetupErr    error
+	TwoFactorLoginResult *LoginResult
+	TwoFactorLoginErr    error
+	OIDCLoginResult      *LoginResult
+	OIDCLoginErr         error
+
+	RefreshTokens *TokenPair
+	RefreshErr    error
 
 	GenerateID  string
 	GenerateB64 string
@@ -19,23 +30,259 @@ type FakeAuthService struct {
 	ChangePasswordErr error
 	UpdateAvatarErr   error
 	LogoutErr         error
+	LogoutUserID      uint // Logout 收到的会话所属用户
+
+	ImpersonateResult *ImpersonationResult
+	ImpersonateErr    error
 }
 
-func (f *FakeAuthService) Login(_ context.Context, _, _, _, _ string) (*models.User, string, error) {
-	return f.LoginUser, f.LoginToken, f.LoginErr
+func (f *FakeAuthService) Login(_ context.Context, _, _, _, _ string, _ ClientInfo) (*LoginResult, error) {
+	return f.LoginResult, f.LoginErr
+}
+func (f *FakeAuthService) BeginTwoFactorSetup(_ context.Context, _ string) (*TwoFactorSetup, error) {
+	return f.TwoFactorSetupResult, f.TwoFactorSetupErr
+}
+func (f *FakeAuthService) CompleteTwoFactorLogin(_ context.Context, _, _ string, _ ClientInfo) (*LoginResult, error) {
+	return f.TwoFactorLoginResult, f.TwoFactorLoginErr
+}
+func (f *FakeAuthService) LoginWithOIDC(_ context.Context, _ string, _ ClientInfo) (*LoginResult, error) {
+	return f.OIDCLoginResult, f.OIDCLoginErr
+}
+func (f *FakeAuthService) RefreshToken(_ context.Context, _ string, _ ClientInfo) (*TokenPair, error) {
+	return f.RefreshTokens, f.RefreshErr
 }
 func (f *FakeAuthService) GenerateCaptcha(_ context.Context) (string, string, error) {
 	return f.GenerateID, f.GenerateB64, f.GenerateErr
 }
-func (f *FakeAuthService) ChangePassword(_ context.Context, _ uint, _, _ string) error {
+func (f *FakeAuthService) ChangePassword(_ context.Context, _ uint, _, _, _ string) error {
 	return f.ChangePasswordErr
 }
 func (f *FakeAuthService) UpdateAvatar(_ context.Context, _ uint, _ string) error {
 	return f.UpdateAvatarErr
 }
-func (f *FakeAuthService) Logout(_ context.Context, _ uint) error {
+func (f *FakeAuthService) Logout(_ context.Context, userID uint, _ string) error {
+	f.LogoutUserID = userID
 	return f.LogoutErr
 }
+func (f *FakeAuthService) Impersonate(_ context.Context, _ *utils.Claims, _ uint, _ ClientInfo) (*ImpersonationResult, error) {
+	return f.ImpersonateResult, f.ImpersonateErr
+}
+func (f *FakeAuthService) CleanExpired(_ context.Context) (int64, error) {
+	return 0, nil
+}
+
+// FakeTwoFactorService 单测用 ITwoFactorService mock
+type FakeTwoFactorService struct {
+	GetStatusResult  *TwoFactorStatus
+	GetStatusErr     error
+	IsRequiredResult bool
+	IsRequiredErr    error
+
+	BeginSetupResult *TwoFactorSetup
+	BeginSetupErr    error
+	EnableCodes      []string
+	EnableErr        error
+	DisableErr       error
+	RegenerateCodes  []string
+	RegenerateErr    error
+	ResetErr         error
+	VerifyErr        error
+}
+
+func (f *FakeTwoFactorService) GetStatus(_ context.Context, _ uint) (*TwoFactorStatus, error) {
+	return f.GetStatusResult, f.GetStatusErr
+}
+func (f *FakeTwoFactorService) IsRequired(_ context.Context, _ uint) (bool, error) {
+	return f.IsRequiredResult, f.IsRequiredErr
+}
+func (f *FakeTwoFactorService) BeginSetup(_ context.Context, _ uint) (*TwoFactorSetup, error) {
+	return f.BeginSetupResult, f.BeginSetupErr
+}
+func (f *FakeTwoFactorService) Enable(_ context.Context, _ uint, _ string) ([]string, error) {
+	return f.EnableCodes, f.EnableErr
+}
+func (f *FakeTwoFactorService) Disable(_ context.Context, _ uint, _ string) error {
+	return f.DisableErr
+}
+func (f *FakeTwoFactorService) RegenerateBackupCodes(_ context.Context, _ uint, _ string) ([]string, error) {
+	return f.RegenerateCodes, f.RegenerateErr
+}
+func (f *FakeTwoFactorService) Reset(_ context.Context, _ uint) error {
+	return f.ResetErr
+}
+func (f *FakeTwoFactorService) Verify(_ context.Context, _ uint, _ string) error {
+	return f.VerifyErr
+}
+
+// FakeLoginLockService 单测用 ILoginLockService mock
+type FakeLoginLockService struct {
+	CheckErr         error
+	RecordFailureErr error
+	RecordSuccessErr error
+
+	GetLocksList   []models.LoginLock
+	GetLocksTotal  int64
+	GetLocksErr    error
+	GetEventsList  []models.LoginLockEvent
+	GetEventsTotal int64
+	GetEventsErr   error
+
+	UnlockErr     error
+	UnlockUserErr error
+}
+
+func (f *FakeLoginLockService) Check(_ context.Context, _, _ string) error {
+	return f.CheckErr
+}
+func (f *FakeLoginLockService) RecordFailure(_ context.Context, _, _ string) error {
+	return f.RecordFailureErr
+}
+func (f *FakeLoginLockService) RecordSuccess(_ context.Context, _ string) error {
+	return f.RecordSuccessErr
+}
+func (f *FakeLoginLockService) GetLocks(_ context.Context, _, _ int, _ map[string]string) ([]models.LoginLock, int64, error) {
+	return f.GetLocksList, f.GetLocksTotal, f.GetLocksErr
+}
+func (f *FakeLoginLockService) GetEvents(_ context.Context, _, _ int, _ map[string]string) ([]models.LoginLockEvent, int64, error) {
+	return f.GetEventsList, f.GetEventsTotal, f.GetEventsErr
+}
+func (f *FakeLoginLockService) Unlock(_ context.Context, _ uint, _ uint) error {
+	return f.UnlockErr
+}
+func (f *FakeLoginLockService) UnlockUser(_ context.Context, _ uint, _ uint) error {
+	return f.UnlockUserErr
+}
+
+// FakePasswordService 单测用 IPasswordService mock
+type FakePasswordService struct {
+	ValidateErr       error
+	SetPasswordErr    error
+	RecordHistoryErr  error
+	Expired           bool
+	GeneratedPassword string
+	GenerateErr       error
+}
+
+func (f *FakePasswordService) Validate(_ context.Context, _ *models.User, _ string) error {
+	return f.ValidateErr
+}
+func (f *FakePasswordService) SetPassword(_ context.Context, user *models.User, password string) error {
+	if f.SetPasswordErr != nil {
+		return f.SetPasswordErr
+	}
+	user.Password = password
+	return nil
+}
+func (f *FakePasswordService) RecordHistory(_ context.Context, _ *models.User) error {
+	return f.RecordHistoryErr
+}
+func (f *FakePasswordService) IsExpired(_ *models.User) bool {
+	return f.Expired
+}
+func (f *FakePasswordService) Generate() (string, error) {
+	return f.GeneratedPassword, f.GenerateErr
+}
+
+// FakeOIDCService 单测用 IOIDCService mock
+type FakeOIDCService struct {
+	EnabledResult bool
+	AuthURL       string
+	State         string
+	AuthURLErr    error
+	Ticket        string
+	CallbackErr   error
+	TicketUser    *models.User
+	TicketErr     error
+}
+
+func (f *FakeOIDCService) Enabled() bool {
+	return f.EnabledResult
+}
+func (f *FakeOIDCService) AuthCodeURL(_ context.Context) (string, string, error) {
+	return f.AuthURL, f.State, f.AuthURLErr
+}
+func (f *FakeOIDCService) HandleCallback(_ context.Context, _, _ string) (string, error) {
+	return f.Ticket, f.CallbackErr
+}
+func (f *FakeOIDCService) ConsumeTicket(_ context.Context, _ string) (*models.User, error) {
+	return f.TicketUser, f.TicketErr
+}
+
+// FakeAPIKeyService 单测用 IAPIKeyService mock
+type FakeAPIKeyService struct {
+	CreateResult *models.APIKey
+	CreateRaw    string
+	CreateErr    error
+	UserKeys     []models.APIKey
+	UserKeysErr  error
+	Keys         []models.APIKey
+	KeysTotal    int64
+	KeysErr      error
+	RevokeErr    error
+	AuthKey      *models.APIKey
+	AuthErr      error
+}
+
+func (f *FakeAPIKeyService) CreateKey(_ context.Context, _ uint, _ string, _ *time.Time, _ []uint) (*models.APIKey, string, error) {
+	return f.CreateResult, f.CreateRaw, f.CreateErr
+}
+func (f *FakeAPIKeyService) GetUserKeys(_ context.Context, _ uint) ([]models.APIKey, error) {
+	return f.UserKeys, f.UserKeysErr
+}
+func (f *FakeAPIKeyService) GetKeys(_ context.Context, _, _ int, _ map[string]string) ([]models.APIKey, int64, error) {
+	return f.Keys, f.KeysTotal, f.KeysErr
+}
+func (f *FakeAPIKeyService) RevokeKey(_ context.Context, _ uint, _ uint) error {
+	return f.RevokeErr
+}
+func (f *FakeAPIKeyService) Authenticate(_ context.Context, _, _ string) (*models.APIKey, error) {
+	return f.AuthKey, f.AuthErr
+}
+
+// FakeSessionService 单测用 ISessionService mock
+type FakeSessionService struct {
+	CreateSessionResult *models.UserSession
+	CreateSessionErr    error
+	ValidateSessionErr  error
+	ValidatedUserID     uint // 最近一次 ValidateSession 传入的用户 ID
+	RenewSessionErr     error
+
+	GetUserSessionsList []models.UserSession
+	GetUserSessionsErr  error
+	GetSessionsList     []models.UserSession
+	GetSessionsTotal    int64
+	GetSessionsErr      error
+
+	RevokeSessionErr      error
+	RevokeSessionByJTIErr error
+	RevokeUserSessionsErr error
+}
+
+func (f *FakeSessionService) CreateSession(_ context.Context, _ uint, _ ClientInfo, _ time.Time) (*models.UserSession, error) {
+	return f.CreateSessionResult, f.CreateSessionErr
+}
+func (f *FakeSessionService) ValidateSession(_ context.Context, userID uint, _ string, _ ClientInfo) error {
+	f.ValidatedUserID = userID
+	return f.ValidateSessionErr
+}
+func (f *FakeSessionService) RenewSession(_ context.Context, _ string, _ ClientInfo, _ time.Time) error {
+	return f.RenewSessionErr
+}
+func (f *FakeSessionService) GetUserSessions(_ context.Context, _ uint) ([]models.UserSession, error) {
+	return f.GetUserSessionsList, f.GetUserSessionsErr
+}
+func (f *FakeSessionService) GetSessions(_ context.Context, _, _ int, _ map[string]string) ([]models.UserSession, int64, error) {
+	return f.GetSessionsList, f.GetSessionsTotal, f.GetSessionsErr
+}
+func (f *FakeSessionService) RevokeSession(_ context.Context, _ uint, _ uint) error {
+	return f.RevokeSessionErr
+}
+func (f *FakeSessionService) RevokeSessionByJTI(_ context.Context, _ string) error {
+	return f.RevokeSessionByJTIErr
+}
+func (f *FakeSessionService) RevokeUserSessions(_ context.Context, _ uint, _ string) error {
+	return f.RevokeUserSessionsErr
+}
 
 // FakeUserService 单测用 IUserService mock
 type FakeUserService struct {
@@ -43,23 +290,28 @@ type FakeUserService struct {
 	GetUsersTotal int64
 	GetUsersErr   error
 
-	GetUserForAuthUser *models.User
-	GetUserForAuthErr  error
+	GetUserForAuthUser  *models.User
+	GetUserForAuthUsers map[uint]*models.User // 按用户 ID 返回，未命中时返回 GetUserForAuthUser
+	GetUserForAuthErr   error
 
 	CreateUserResult *models.User
 	CreateUserErr    error
 
-	UpdateUserErr   error
-	DeleteUserErr   error
-	ResetPasswordPw string
-	ResetPasswordErr error
-	ToggleStatusErr error
+	UpdateUserErr         error
+	DeleteUserErr         error
+	ResetPasswordPw       string
+	ResetPasswordErr      error
+	ToggleStatusErr       error
+	AssignOrganizationErr error
 }
 
 func (f *FakeUserService) GetUsers(_ context.Context, _, _ int, _ map[string]string) ([]models.User, int64, error) {
 	return f.GetUsersList, f.GetUsersTotal, f.GetUsersErr
 }
-func (f *FakeUserService) GetUserForAuth(_ context.Context, _ uint) (*models.User, error) {
+func (f *FakeUserService) GetUserForAuth(_ context.Context, userID uint) (*models.User, error) {
+	if user, ok := f.GetUserForAuthUsers[userID]; ok {
+		return user, nil
+	}
 	return f.GetUserForAuthUser, f.GetUserForAuthErr
 }
 func (f *FakeUserService) CreateUser(_ context.Context, _, _, _ string, _ int, _ string, _ []uint) (*models.User, error) {
@@ -77,6 +329,9 @@ func (f *FakeUserService) ResetPassword(_ context.Context, _ uint) (string, erro
 func (f *FakeUserService) ToggleStatus(_ context.Context, _ uint) error {
 	return f.ToggleStatusErr
 }
+func (f *FakeUserService) AssignOrganization(_ context.Context, _, _ uint, _ []uint) error {
+	return f.AssignOrganizationErr
+}
 
 // FakeRoleService 单测用 IRoleService mock
 type FakeRoleService struct {
@@ -84,21 +339,23 @@ type FakeRoleService struct {
 	GetRolesTotal int64
 	GetRolesErr   error
 
-	CreateRoleResult *models.Role
-	CreateRoleErr    error
-	UpdateRoleResult *models.Role
-	UpdateRoleErr    error
-	DeleteRoleErr    error
+	CreateRoleResult     *models.Role
+	CreateRoleErr        error
+	UpdateRoleResult     *models.Role
+	UpdateRoleErr        error
+	DeleteRoleErr        error
 	AssignPermissionsErr error
+	SetRequire2FAErr     error
+	SetDataScopeErr      error
 }
 
 func (f *FakeRoleService) GetRoles(_ context.Context, _, _ int, _ map[string]string) ([]models.Role, int64, error) {
 	return f.GetRolesList, f.GetRolesTotal, f.GetRolesErr
 }
-func (f *FakeRoleService) CreateRole(_ context.Context, _, _ string) (*models.Role, error) {
+func (f *FakeRoleService) CreateRole(_ context.Context, _, _ string, _ uint) (*models.Role, error) {
 	return f.CreateRoleResult, f.CreateRoleErr
 }
-func (f *FakeRoleService) UpdateRole(_ context.Context, _ uint, _, _ string) (*models.Role, error) {
+func (f *FakeRoleService) UpdateRole(_ context.Context, _ uint, _, _ string, _ *uint) (*models.Role, error) {
 	return f.UpdateRoleResult, f.UpdateRoleErr
 }
 func (f *FakeRoleService) DeleteRole(_ context.Context, _ uint) error {
@@ -107,6 +364,12 @@ func (f *FakeRoleService) DeleteRole(_ context.Context, _ uint) error {
 func (f *FakeRoleService) AssignPermissions(_ context.Context, _ uint, _ []uint) error {
 	return f.AssignPermissionsErr
 }
+func (f *FakeRoleService) SetRequire2FA(_ context.Context, _ uint, _ bool) error {
+	return f.SetRequire2FAErr
+}
+func (f *FakeRoleService) SetDataScope(_ context.Context, _ uint, _ string, _ []uint) error {
+	return f.SetDataScopeErr
+}
 
 // FakePermissionService 单测用 IPermissionService mock
 type FakePermissionService struct {
@@ -114,20 +377,23 @@ type FakePermissionService struct {
 	GetPermissionsTotal int64
 	GetPermissionsErr   error
 
-	CreatePermissionResult *models.Permission
-	CreatePermissionErr    error
-	UpdatePermissionResult *models.Permission
-	UpdatePermissionErr    error
-	DeletePermissionErr    error
-	BatchDeletePermissionsErr error
+	CreatePermissionResult      *models.Permission
+	CreatePermissionErr         error
+	UpdatePermissionResult      *models.Permission
+	UpdatePermissionErr         error
+	DeletePermissionErr         error
+	BatchDeletePermissionsErr   error
 	GetPermissionsByRoleIDsList []models.Permission
 	GetPermissionsByRoleIDsErr  error
+	Invalidated                 int // InvalidateCache 的调用次数
+	SyncReport                  *PermissionSyncReport
+	SyncErr                     error
 }
 
 func (f *FakePermissionService) GetPermissions(_ context.Context, _, _ int, _ map[string]string) ([]models.Permission, int64, error) {
 	return f.GetPermissionsList, f.GetPermissionsTotal, f.GetPermissionsErr
 }
-func (f *FakePermissionService) CreatePermission(_ context.Context, _, _, _, _, _ string) (*models.Permission, error) {
+func (f *FakePermissionService) CreatePermission(_ context.Context, _, _, _, _, _, _ string) (*models.Permission, error) {
 	return f.CreatePermissionResult, f.CreatePermissionErr
 }
 func (f *FakePermissionService) UpdatePermission(_ context.Context, _ uint, _, _, _ string) (*models.Permission, error) {
@@ -142,23 +408,59 @@ func (f *FakePermissionService) BatchDeletePermissions(_ context.Context, _ []ui
 func (f *FakePermissionService) GetPermissionsByRoleIDs(_ context.Context, _ []uint) ([]models.Permission, error) {
 	return f.GetPermissionsByRoleIDsList, f.GetPermissionsByRoleIDsErr
 }
+func (f *FakePermissionService) GetMatchersByRoleIDs(_ context.Context, _ []uint) ([]*PermissionMatcher, error) {
+	return CompilePermissions(f.GetPermissionsByRoleIDsList), f.GetPermissionsByRoleIDsErr
+}
+func (f *FakePermissionService) InvalidateCache(_ context.Context) {
+	f.Invalidated++
+}
+func (f *FakePermissionService) ClearCache() {}
+func (f *FakePermissionService) SyncRoutePermissions(_ context.Context, _ []RoutePermission, _ bool) (*PermissionSyncReport, error) {
+	return f.SyncReport, f.SyncErr
+}
 
 // FakeOperationLogService 单测用 IOperationLogService mock
 type FakeOperationLogService struct {
 	GetOperationLogsList  []models.OperationLog
 	GetOperationLogsTotal int64
 	GetOperationLogsErr   error
-	CleanOldLogsN        int64
-	CleanOldLogsErr      error
+	CleanOldLogsN         int64
+	CleanOldLogsErr       error
+	ExportList            []models.OperationLog
+	ExportErr             error
 }
 
 func (f *FakeOperationLogService) GetOperationLogs(_ context.Context, _, _ int, _ map[string]string) ([]models.OperationLog, int64, error) {
 	return f.GetOperationLogsList, f.GetOperationLogsTotal, f.GetOperationLogsErr
 }
+func (f *FakeOperationLogService) ExportOperationLogs(_ context.Context, _ map[string]string) ([]models.OperationLog, error) {
+	return f.ExportList, f.ExportErr
+}
 func (f *FakeOperationLogService) CleanOldLogs(_ context.Context, _ int) (int64, error) {
 	return f.CleanOldLogsN, f.CleanOldLogsErr
 }
 
+// FakeSecurityEventService 单测用 ISecurityEventService mock，Record 的事件追加到 Recorded 便于断言
+type FakeSecurityEventService struct {
+	Recorded []models.SecurityEvent
+
+	GetEventsList     []models.SecurityEvent
+	GetEventsTotal    int64
+	GetEventsErr      error
+	CleanOldEventsN   int64
+	CleanOldEventsErr error
+}
+
+func (f *FakeSecurityEventService) Record(_ context.Context, event *models.SecurityEvent) {
+	f.Recorded = append(f.Recorded, *event)
+}
+func (f *FakeSecurityEventService) GetEvents(_ context.Context, _, _ int, _ map[string]string) ([]models.SecurityEvent, int64, error) {
+	return f.GetEventsList, f.GetEventsTotal, f.GetEventsErr
+}
+func (f *FakeSecurityEventService) CleanOldEvents(_ context.Context, _ int) (int64, error) {
+	return f.CleanOldEventsN, f.CleanOldEventsErr
+}
+
 // FakeDictionaryService 单测用 IDictionaryService mock
 type FakeDictionaryService struct {
 	GetTypesList  []models.DictType
@@ -171,16 +473,16 @@ type FakeDictionaryService struct {
 	UpdateTypeErr    error
 	DeleteTypeErr    error
 
-	GetItemsList  []models.DictItem
-	GetItemsTotal int64
-	GetItemsErr   error
+	GetItemsList       []models.DictItem
+	GetItemsTotal      int64
+	GetItemsErr        error
 	GetItemsByCodeList []models.DictItem
 	GetItemsByCodeErr  error
-	CreateItemResult *models.DictItem
-	CreateItemErr    error
-	UpdateItemResult *models.DictItem
-	UpdateItemErr    error
-	DeleteItemErr    error
+	CreateItemResult   *models.DictItem
+	CreateItemErr      error
+	UpdateItemResult   *models.DictItem
+	UpdateItemErr      error
+	DeleteItemErr      error
 }
 
 func (f *FakeDictionaryService) GetTypes(_ context.Context, _, _ int, _ map[string]string) ([]models.DictType, int64, error) {
@@ -210,3 +512,137 @@ func (f *FakeDictionaryService) UpdateItem(_ context.Context, _ uint, _, _ strin
 func (f *FakeDictionaryService) DeleteItem(_ context.Context, _ uint) error {
 	return f.DeleteItemErr
 }
+
+// FakeIPRuleService 单测用 IIPRuleService mock，CheckAccessErrs 按 userID 指定校验结果
+type FakeIPRuleService struct {
+	CheckAccessErrs map[uint]error
+	Checked         []uint
+
+	GetRulesList  []models.IPRule
+	GetRulesTotal int64
+	GetRulesErr   error
+
+	CreateRuleResult *models.IPRule
+	CreateRuleErr    error
+	UpdateRuleResult *models.IPRule
+	UpdateRuleErr    error
+	DeleteRuleErr    error
+}
+
+func (f *FakeIPRuleService) CheckAccess(_ context.Context, userID uint, _ string) error {
+	f.Checked = append(f.Checked, userID)
+	return f.CheckAccessErrs[userID]
+}
+func (f *FakeIPRuleService) GetRules(_ context.Context, _, _ int, _ map[string]string) ([]models.IPRule, int64, error) {
+	return f.GetRulesList, f.GetRulesTotal, f.GetRulesErr
+}
+func (f *FakeIPRuleService) CreateRule(_ context.Context, _ uint, _, _, _ string, _ uint, _ string) (*models.IPRule, error) {
+	return f.CreateRuleResult, f.CreateRuleErr
+}
+func (f *FakeIPRuleService) UpdateRule(_ context.Context, _ uint, _, _, _ string, _ uint, _ string) (*models.IPRule, error) {
+	return f.UpdateRuleResult, f.UpdateRuleErr
+}
+func (f *FakeIPRuleService) DeleteRule(_ context.Context, _ uint, _ uint, _ string) error {
+	return f.DeleteRuleErr
+}
+
+// FakeDepartmentService 单测用 IDepartmentService mock
+type FakeDepartmentService struct {
+	GetDepartmentTreeList []*models.Department
+	GetDepartmentTreeErr  error
+
+	CreateDepartmentResult *models.Department
+	CreateDepartmentErr    error
+	UpdateDepartmentResult *models.Department
+	UpdateDepartmentErr    error
+	DeleteDepartmentErr    error
+}
+
+func (f *FakeDepartmentService) GetDepartmentTree(_ context.Context, _ map[string]string) ([]*models.Department, error) {
+	return f.GetDepartmentTreeList, f.GetDepartmentTreeErr
+}
+func (f *FakeDepartmentService) CreateDepartment(_ context.Context, _ uint, _ string, _ uint, _ int, _ int) (*models.Department, error) {
+	return f.CreateDepartmentResult, f.CreateDepartmentErr
+}
+func (f *FakeDepartmentService) UpdateDepartment(_ context.Context, _ uint, _ *uint, _ string, _ *uint, _ *int, _ *int) (*models.Department, error) {
+	return f.UpdateDepartmentResult, f.UpdateDepartmentErr
+}
+func (f *FakeDepartmentService) DeleteDepartment(_ context.Context, _ uint) error {
+	return f.DeleteDepartmentErr
+}
+
+// FakePositionService 单测用 IPositionService mock
+type FakePositionService struct {
+	GetPositionsList  []models.Position
+	GetPositionsTotal int64
+	GetPositionsErr   error
+
+	CreatePositionResult *models.Position
+	CreatePositionErr    error
+	UpdatePositionResult *models.Position
+	UpdatePositionErr    error
+	DeletePositionErr    error
+}
+
+func (f *FakePositionService) GetPositions(_ context.Context, _, _ int, _ map[string]string) ([]models.Position, int64, error) {
+	return f.GetPositionsList, f.GetPositionsTotal, f.GetPositionsErr
+}
+func (f *FakePositionService) CreatePosition(_ context.Context, _, _ string, _ int, _ int, _ string) (*models.Position, error) {
+	return f.CreatePositionResult, f.CreatePositionErr
+}
+func (f *FakePositionService) UpdatePosition(_ context.Context, _ uint, _, _ string, _ *int, _ *int, _ string) (*models.Position, error) {
+	return f.UpdatePositionResult, f.UpdatePositionErr
+}
+func (f *FakePositionService) DeletePosition(_ context.Context, _ uint) error {
+	return f.DeletePositionErr
+}
+
+// FakeDataScopeService 单测用 IDataScopeService mock，Scope 为 nil 时返回全部数据
+type FakeDataScopeService struct {
+	Scope *DataScope
+	Err   error
+}
+
+func (f *FakeDataScopeService) Resolve(_ context.Context) (*DataScope, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if f.Scope == nil {
+		return &DataScope{All: true}, nil
+	}
+	return f.Scope, nil
+}
+
+// FakeMenuService 单测用 IMenuService mock
+type FakeMenuService struct {
+	GetMenuTreeList []*models.Menu
+	GetMenuTreeErr  error
+	UserMenus       *UserMenus
+	UserMenusErr    error
+
+	CreateMenuResult *models.Menu
+	CreateMenuErr    error
+	UpdateMenuResult *models.Menu
+	UpdateMenuErr    error
+	DeleteMenuErr    error
+	InitErr          error
+}
+
+func (f *FakeMenuService) GetMenuTree(_ context.Context) ([]*models.Menu, error) {
+	return f.GetMenuTreeList, f.GetMenuTreeErr
+}
+func (f *FakeMenuService) GetUserMenus(_ context.Context, _ bool, _ []uint) (*UserMenus, error) {
+	return f.UserMenus, f.UserMenusErr
+}
+func (f *FakeMenuService) CreateMenu(_ context.Context, _ MenuInput) (*models.Menu, error) {
+	return f.CreateMenuResult, f.CreateMenuErr
+}
+func (f *FakeMenuService) UpdateMenu(_ context.Context, _ uint, _ MenuInput) (*models.Menu, error) {
+	return f.UpdateMenuResult, f.UpdateMenuErr
+}
+func (f *FakeMenuService) DeleteMenu(_ context.Context, _ uint) error {
+	return f.DeleteMenuErr
+}
+func (f *FakeMenuService) InitDefaultMenus(_ context.Context) error {
+	return f.InitErr
+}
@@ -2,50 +2,162 @@ package services
 
 import (
 	"context"
+	"time"
 
 	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/utils"
 )
 
 type IAuthService interface {
-	Login(ctx context.Context, username, password, captchaID, captchaVal string) (*models.User, string, error)
+	Login(ctx context.Context, username, password, captchaID, captchaVal string, client ClientInfo) (*LoginResult, error)
+	BeginTwoFactorSetup(ctx context.Context, challengeToken string) (*TwoFactorSetup, error)
+	CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string, client ClientInfo) (*LoginResult, error)
+	LoginWithOIDC(ctx context.Context, ticket string, client ClientInfo) (*LoginResult, error)
+	RefreshToken(ctx context.Context, refreshToken string, client ClientInfo) (*TokenPair, error)
 	GenerateCaptcha(ctx context.Context) (string, string, error)
-	ChangePassword(ctx context.Context, userID uint, oldPassword, newPassword string) error
+	ChangePassword(ctx context.Context, userID uint, currentJTI, oldPassword, newPassword string) error
 	UpdateAvatar(ctx context.Context, userID uint, avatarURL string) error
-	Logout(ctx context.Context, userID uint) error
+	Logout(ctx context.Context, userID uint, jti string) error
+	Impersonate(ctx context.Context, operator *utils.Claims, targetUserID uint, client ClientInfo) (*ImpersonationResult, error)
+	CleanExpired(ctx context.Context) (int64, error) // 定时任务用：删除已过期的刷新 Token、会话与登录挑战
+}
+
+type ISessionService interface {
+	CreateSession(ctx context.Context, userID uint, client ClientInfo, expiresAt time.Time) (*models.UserSession, error)
+	ValidateSession(ctx context.Context, userID uint, jti string, client ClientInfo) error // 认证中间件用：校验会话有效并刷新最近访问时间
+	RenewSession(ctx context.Context, jti string, client ClientInfo, expiresAt time.Time) error
+	GetUserSessions(ctx context.Context, userID uint) ([]models.UserSession, error)
+	GetSessions(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.UserSession, int64, error)
+	RevokeSession(ctx context.Context, sessionID uint, userID uint) error
+	RevokeSessionByJTI(ctx context.Context, jti string) error
+	RevokeUserSessions(ctx context.Context, userID uint, exceptJTI string) error
+}
+
+type ITwoFactorService interface {
+	GetStatus(ctx context.Context, userID uint) (*TwoFactorStatus, error)
+	IsRequired(ctx context.Context, userID uint) (bool, error)
+	BeginSetup(ctx context.Context, userID uint) (*TwoFactorSetup, error)
+	Enable(ctx context.Context, userID uint, code string) ([]string, error) // 返回新生成的备用码
+	Disable(ctx context.Context, userID uint, code string) error
+	RegenerateBackupCodes(ctx context.Context, userID uint, code string) ([]string, error)
+	Reset(ctx context.Context, userID uint) error // 管理员重置
+	Verify(ctx context.Context, userID uint, code string) error
+}
+
+type ILoginLockService interface {
+	Check(ctx context.Context, username, ip string) error // 用户名或 IP 被锁定时返回错误
+	RecordFailure(ctx context.Context, username, ip string) error
+	RecordSuccess(ctx context.Context, username string) error
+	GetLocks(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.LoginLock, int64, error)
+	GetEvents(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.LoginLockEvent, int64, error)
+	Unlock(ctx context.Context, lockID uint, operatorID uint) error
+	UnlockUser(ctx context.Context, userID uint, operatorID uint) error
+}
+
+type IPasswordService interface {
+	Validate(ctx context.Context, user *models.User, password string) error
+	SetPassword(ctx context.Context, user *models.User, password string) error // 校验并加密写入 user，不保存
+	RecordHistory(ctx context.Context, user *models.User) error
+	IsExpired(user *models.User) bool
+	Generate() (string, error)
+}
+
+type IOIDCService interface {
+	Enabled() bool
+	AuthCodeURL(ctx context.Context) (string, string, error)                // 返回授权地址与 state 原文
+	HandleCallback(ctx context.Context, state, code string) (string, error) // 返回一次性登录票据
+	ConsumeTicket(ctx context.Context, ticket string) (*models.User, error)
+}
+
+type IAPIKeyService interface {
+	CreateKey(ctx context.Context, userID uint, name string, expiresAt *time.Time, permissionIDs []uint) (*models.APIKey, string, error) // 返回记录与原文
+	GetUserKeys(ctx context.Context, userID uint) ([]models.APIKey, error)
+	GetKeys(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.APIKey, int64, error)
+	RevokeKey(ctx context.Context, keyID uint, userID uint) error
+	Authenticate(ctx context.Context, rawKey, ip string) (*models.APIKey, error) // 认证中间件用
 }
 
 type IUserService interface {
 	GetUsers(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.User, int64, error)
-	GetUserForAuth(ctx context.Context, userID uint) (*models.User, error) // 认证中间件用：按 ID 查用户（id, username, nickname, type, status, token_version）
+	GetUserForAuth(ctx context.Context, userID uint) (*models.User, error) // 认证中间件用：按 ID 查用户（id, username, nickname, type, status, token_version, must_change_password）
 	CreateUser(ctx context.Context, username, password, nickname string, userType int, remark string, roleIDs []uint) (*models.User, error)
 	UpdateUser(ctx context.Context, userID uint, nickname, password, remark string, roleIDs []uint) error
 	DeleteUser(ctx context.Context, userID uint) error
 	ResetPassword(ctx context.Context, userID uint) (string, error)
 	ToggleStatus(ctx context.Context, userID uint) error
+	AssignOrganization(ctx context.Context, userID, departmentID uint, positionIDs []uint) error
 }
 
 type IRoleService interface {
 	GetRoles(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.Role, int64, error)
-	CreateRole(ctx context.Context, name, description string) (*models.Role, error)
-	UpdateRole(ctx context.Context, roleID uint, name, description string) (*models.Role, error)
+	CreateRole(ctx context.Context, name, description string, parentID uint) (*models.Role, error)
+	UpdateRole(ctx context.Context, roleID uint, name, description string, parentID *uint) (*models.Role, error) // parentID 为 nil 时不修改上级
 	DeleteRole(ctx context.Context, roleID uint) error
 	AssignPermissions(ctx context.Context, roleID uint, permissionIDs []uint) error
+	SetRequire2FA(ctx context.Context, roleID uint, required bool) error
+	SetDataScope(ctx context.Context, roleID uint, dataScope string, departmentIDs []uint) error
 }
 
 type IPermissionService interface {
 	GetPermissions(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.Permission, int64, error)
-	CreatePermission(ctx context.Context, path, method, name, group, description string) (*models.Permission, error)
+	CreatePermission(ctx context.Context, path, method, name, group, description, effect string) (*models.Permission, error)
 	UpdatePermission(ctx context.Context, permissionID uint, name, group, description string) (*models.Permission, error)
 	DeletePermission(ctx context.Context, permissionID uint) error
 	BatchDeletePermissions(ctx context.Context, ids []uint) error
 	GetPermissionsByRoleIDs(ctx context.Context, roleIDs []uint) ([]models.Permission, error)
+	GetMatchersByRoleIDs(ctx context.Context, roleIDs []uint) ([]*PermissionMatcher, error) // 权限中间件用，按角色缓存
+	InvalidateCache(ctx context.Context)                                                    // 清空本实例缓存并广播给其他实例
+	ClearCache()                                                                            // 只清空本实例缓存
+	SyncRoutePermissions(ctx context.Context, routes []RoutePermission, dryRun bool) (*PermissionSyncReport, error)
 }
 
 type IOperationLogService interface {
 	GetOperationLogs(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.OperationLog, int64, error)
+	ExportOperationLogs(ctx context.Context, filters map[string]string) ([]models.OperationLog, error) // 条件同列表，不分页，有条数上限
 	CleanOldLogs(ctx context.Context, retain int) (int64, error)
 }
 
+type ISecurityEventService interface {
+	Record(ctx context.Context, event *models.SecurityEvent) // 写入失败只记日志
+	GetEvents(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.SecurityEvent, int64, error)
+	CleanOldEvents(ctx context.Context, retain int) (int64, error)
+}
+
+type IDepartmentService interface {
+	GetDepartmentTree(ctx context.Context, filters map[string]string) ([]*models.Department, error)
+	CreateDepartment(ctx context.Context, parentID uint, name string, leaderID uint, sort int, status int) (*models.Department, error)
+	UpdateDepartment(ctx context.Context, id uint, parentID *uint, name string, leaderID *uint, sort *int, status *int) (*models.Department, error)
+	DeleteDepartment(ctx context.Context, id uint) error
+}
+
+type IPositionService interface {
+	GetPositions(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.Position, int64, error)
+	CreatePosition(ctx context.Context, code, name string, sort int, status int, remark string) (*models.Position, error)
+	UpdatePosition(ctx context.Context, id uint, code, name string, sort *int, status *int, remark string) (*models.Position, error)
+	DeletePosition(ctx context.Context, id uint) error
+}
+
+type IMenuService interface {
+	GetMenuTree(ctx context.Context) ([]*models.Menu, error)
+	GetUserMenus(ctx context.Context, isSuperAdmin bool, roleIDs []uint) (*UserMenus, error)
+	CreateMenu(ctx context.Context, in MenuInput) (*models.Menu, error)
+	UpdateMenu(ctx context.Context, id uint, in MenuInput) (*models.Menu, error)
+	DeleteMenu(ctx context.Context, id uint) error
+	InitDefaultMenus(ctx context.Context) error // 菜单表为空时写入内置菜单
+}
+
+type IDataScopeService interface {
+	Resolve(ctx context.Context) (*DataScope, error) // 按 ctx 中的登录用户计算，无登录信息时为空（内部调用见 Unrestricted）
+}
+
+type IIPRuleService interface {
+	CheckAccess(ctx context.Context, userID uint, ip string) error // IP 访问中间件用，userID 为 0 时校验全局规则
+	GetRules(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.IPRule, int64, error)
+	CreateRule(ctx context.Context, userID uint, action, cidr, remark string, operatorID uint, operatorIP string) (*models.IPRule, error)
+	UpdateRule(ctx context.Context, id uint, action, cidr, remark string, operatorID uint, operatorIP string) (*models.IPRule, error)
+	DeleteRule(ctx context.Context, id uint, operatorID uint, operatorIP string) error
+}
+
 type IDictionaryService interface {
 	GetTypes(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.DictType, int64, error)
 	CreateType(ctx context.Context, code, name, remark string) (*models.DictType, error)
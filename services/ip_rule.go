@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+
+	"gorm.io/gorm"
+)
+
+// ipRuleCacheTTL 规则在内存中的缓存时间：本实例修改规则时立即失效，多实例部署时其他实例最多延迟这么久生效
+const ipRuleCacheTTL = 30 * time.Second
+
+// compiledIPRule 解析后的规则，用于逐请求匹配
+type compiledIPRule struct {
+	action string
+	prefix netip.Prefix
+}
+
+// IPRuleService 后台访问 IP 规则：全局规则在认证前校验，用户规则在认证后校验；
+// 命中禁止规则即拒绝，配置了允许规则时只放行命中任一允许规则的 IP
+type IPRuleService struct {
+	ctx ServiceContext
+
+	mu         sync.RWMutex
+	rules      map[uint][]compiledIPRule // 按 UserID 分组，0 为全局规则；nil 表示需要重新加载
+	loadedAt   time.Time
+	generation uint64 // 每次失效加一；加载期间规则被修改时，加载结果不写入缓存
+}
+
+// NewIPRuleService 创建 IP 规则服务实例
+func NewIPRuleService(ctx ServiceContext) *IPRuleService {
+	return &IPRuleService{ctx: ctx}
+}
+
+// CheckAccess 按 userID 的规则（0 为全局规则）校验 ip，被拒绝时返回 403 及原因
+func (s *IPRuleService) CheckAccess(ctx context.Context, userID uint, ip string) error {
+	rules, err := s.loadRules()
+	if err != nil {
+		return err
+	}
+	if reason := matchIPRules(rules[userID], ip); reason != "" {
+		return errors.ForbiddenMsg(reason)
+	}
+	return nil
+}
+
+// GetRules 获取 IP 规则列表（分页和筛选）
+// 支持按范围（global 全局 / user 用户）、用户 ID、动作、网段筛选
+func (s *IPRuleService) GetRules(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.IPRule, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	var total int64
+	var rules []models.IPRule
+
+	query := s.ctx.DB().Model(&models.IPRule{})
+
+	switch filters["scope"] {
+	case "global":
+		query = query.Where("user_id = ?", 0)
+	case "user":
+		query = query.Where("user_id <> ?", 0)
+	}
+	if userID := filters["user_id"]; userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if action := filters["action"]; action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if cidr := filters["cidr"]; cidr != "" {
+		query = query.Where("cidr LIKE ?", "%"+cidr+"%")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("user_id ASC, id ASC").Offset(offset).Limit(pageSize).Find(&rules).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return rules, total, nil
+}
+
+// CreateRule 创建规则，userID 为 0 时为全局规则；保存后操作者当前 IP 将无法访问时拒绝，避免把自己锁在外面
+func (s *IPRuleService) CreateRule(ctx context.Context, userID uint, action, cidr, remark string, operatorID uint, operatorIP string) (*models.IPRule, error) {
+	rule := models.IPRule{UserID: userID, Remark: remark}
+	if err := setIPRule(&rule, action, cidr); err != nil {
+		return nil, err
+	}
+	if userID != 0 {
+		var count int64
+		if err := s.ctx.DB().Model(&models.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, errors.NotFoundMsg("用户不存在")
+		}
+	}
+
+	if err := s.checkLockout(userID, operatorID, operatorIP, func(rules []models.IPRule) []models.IPRule {
+		return append(rules, rule)
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.ctx.DB().Create(&rule).Error; err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return &rule, nil
+}
+
+// UpdateRule 修改规则的动作、网段与备注，所属用户不能修改
+func (s *IPRuleService) UpdateRule(ctx context.Context, id uint, action, cidr, remark string, operatorID uint, operatorIP string) (*models.IPRule, error) {
+	rule, err := s.getRule(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := setIPRule(rule, action, cidr); err != nil {
+		return nil, err
+	}
+	rule.Remark = remark
+
+	if err := s.checkLockout(rule.UserID, operatorID, operatorIP, func(rules []models.IPRule) []models.IPRule {
+		for i := range rules {
+			if rules[i].ID == rule.ID {
+				rules[i] = *rule
+			}
+		}
+		return rules
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.ctx.DB().Save(rule).Error; err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return rule, nil
+}
+
+// DeleteRule 删除规则；删除允许规则同样可能使当前 IP 不在剩余的允许范围内，一并校验
+func (s *IPRuleService) DeleteRule(ctx context.Context, id uint, operatorID uint, operatorIP string) error {
+	rule, err := s.getRule(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkLockout(rule.UserID, operatorID, operatorIP, func(rules []models.IPRule) []models.IPRule {
+		kept := rules[:0]
+		for _, r := range rules {
+			if r.ID != rule.ID {
+				kept = append(kept, r)
+			}
+		}
+		return kept
+	}); err != nil {
+		return err
+	}
+
+	if err := s.ctx.DB().Delete(rule).Error; err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+func (s *IPRuleService) getRule(id uint) (*models.IPRule, error) {
+	var rule models.IPRule
+	if err := s.ctx.DB().Where("id = ?", id).First(&rule).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NotFoundMsg("IP 规则不存在")
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// checkLockout 规则变更影响操作者本人（全局规则或操作者自己的规则）时，按变更后的规则校验操作者当前 IP
+func (s *IPRuleService) checkLockout(scopeUserID, operatorID uint, operatorIP string, change func([]models.IPRule) []models.IPRule) error {
+	if scopeUserID != 0 && scopeUserID != operatorID {
+		return nil
+	}
+	var rules []models.IPRule
+	if err := s.ctx.DB().Where("user_id = ?", scopeUserID).Find(&rules).Error; err != nil {
+		return err
+	}
+	if matchIPRules(compileIPRules(change(rules)), operatorIP) != "" {
+		return errors.BadRequestMsg("保存后当前 IP（" + operatorIP + "）将无法访问后台，请先添加包含该 IP 的允许规则")
+	}
+	return nil
+}
+
+// loadRules 返回按用户分组的规则，缓存过期或被修改后重新从数据库加载
+func (s *IPRuleService) loadRules() (map[uint][]compiledIPRule, error) {
+	s.mu.RLock()
+	if s.rules != nil && time.Since(s.loadedAt) < ipRuleCacheTTL {
+		rules := s.rules
+		s.mu.RUnlock()
+		return rules, nil
+	}
+	generation := s.generation
+	s.mu.RUnlock()
+
+	var list []models.IPRule
+	if err := s.ctx.DB().Find(&list).Error; err != nil {
+		return nil, err
+	}
+	grouped := make(map[uint][]models.IPRule)
+	for _, rule := range list {
+		grouped[rule.UserID] = append(grouped[rule.UserID], rule)
+	}
+	rules := make(map[uint][]compiledIPRule, len(grouped))
+	for userID, group := range grouped {
+		rules[userID] = compileIPRules(group)
+	}
+
+	s.mu.Lock()
+	// 查询期间规则被修改时，查到的可能是修改前的数据，只用于本次请求，不写入缓存
+	if s.generation == generation {
+		s.rules = rules
+		s.loadedAt = time.Now()
+	}
+	s.mu.Unlock()
+	return rules, nil
+}
+
+func (s *IPRuleService) invalidate() {
+	s.mu.Lock()
+	s.rules = nil
+	s.generation++
+	s.mu.Unlock()
+}
+
+// setIPRule 校验动作并把网段规范化后写入 rule
+func setIPRule(rule *models.IPRule, action, cidr string) error {
+	if action != models.IPRuleAllow && action != models.IPRuleDeny {
+		return errors.BadRequestMsg("规则动作只能是 allow 或 deny")
+	}
+	prefix, err := parseIPPrefix(cidr)
+	if err != nil {
+		return errors.BadRequestMsg("无效的 IP 或网段: " + cidr)
+	}
+	rule.Action = action
+	rule.CIDR = prefix.String()
+	return nil
+}
+
+// parseIPPrefix 解析网段（如 10.0.0.0/8）或单个 IP（按 /32、/128 处理），主机位清零
+func parseIPPrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// compileIPRules 解析规则网段，无法解析的规则（只可能来自直接修改数据库）跳过
+func compileIPRules(rules []models.IPRule) []compiledIPRule {
+	compiled := make([]compiledIPRule, 0, len(rules))
+	for _, rule := range rules {
+		prefix, err := parseIPPrefix(rule.CIDR)
+		if err != nil {
+			continue
+		}
+		compiled = append(compiled, compiledIPRule{action: rule.Action, prefix: prefix})
+	}
+	return compiled
+}
+
+// matchIPRules 按规则校验 ip，允许时返回空字符串，否则返回拒绝原因
+func matchIPRules(rules []compiledIPRule, ip string) string {
+	if len(rules) == 0 {
+		return ""
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "无法识别客户端 IP"
+	}
+	addr = addr.Unmap()
+
+	hasAllow, allowed := false, false
+	for _, rule := range rules {
+		if rule.action == models.IPRuleAllow {
+			hasAllow = true
+		}
+		if !rule.prefix.Contains(addr) {
+			continue
+		}
+		if rule.action == models.IPRuleDeny {
+			return "IP " + ip + " 已被禁止访问"
+		}
+		allowed = true
+	}
+	if hasAllow && !allowed {
+		return "IP " + ip + " 不在允许访问的范围内"
+	}
+	return ""
+}
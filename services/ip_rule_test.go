@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+
+	"gorm.io/gorm"
+)
+
+func TestMatchIPRules(t *testing.T) {
+	compile := func(rules ...models.IPRule) []compiledIPRule { return compileIPRules(rules) }
+	office := models.IPRule{Action: models.IPRuleAllow, CIDR: "10.0.0.0/8"}
+	vpn := models.IPRule{Action: models.IPRuleAllow, CIDR: "2001:db8::/32"}
+	banned := models.IPRule{Action: models.IPRuleDeny, CIDR: "10.1.2.3/32"}
+
+	tests := []struct {
+		name    string
+		rules   []compiledIPRule
+		ip      string
+		allowed bool
+	}{
+		{"无规则放行", nil, "8.8.8.8", true},
+		{"命中允许规则", compile(office), "10.9.9.9", true},
+		{"不在允许范围", compile(office), "192.168.1.1", false},
+		{"IPv6 允许规则", compile(office, vpn), "2001:db8::1", true},
+		{"IPv4 映射地址按 IPv4 匹配", compile(office), "::ffff:10.0.0.1", true},
+		{"禁止规则优先", compile(office, banned), "10.1.2.3", false},
+		{"只有禁止规则时其他 IP 放行", compile(banned), "8.8.8.8", true},
+		{"无法解析的 IP 拒绝", compile(office), "unknown", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchIPRules(tt.rules, tt.ip) == ""; got != tt.allowed {
+				t.Errorf("allowed = %v, want %v", got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestIPRuleService_CreateRule_Normalizes(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewIPRuleService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	rule, err := svc.CreateRule(bg, 0, models.IPRuleDeny, " 192.168.1.77/24 ", "", 1, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+	if rule.CIDR != "192.168.1.0/24" {
+		t.Errorf("CIDR = %q, want 192.168.1.0/24", rule.CIDR)
+	}
+	rule, _ = svc.CreateRule(bg, 0, models.IPRuleDeny, "2001:db8::1", "", 1, "10.0.0.1")
+	if rule == nil || rule.CIDR != "2001:db8::1/128" {
+		t.Errorf("single IPv6 rule = %+v", rule)
+	}
+
+	if _, err := svc.CreateRule(bg, 0, models.IPRuleDeny, "10.0.0.300", "", 1, "10.0.0.1"); err == nil {
+		t.Error("expected error for invalid IP")
+	}
+	if _, err := svc.CreateRule(bg, 0, "block", "10.0.0.0/8", "", 1, "10.0.0.1"); err == nil {
+		t.Error("expected error for invalid action")
+	}
+	if _, err := svc.CreateRule(bg, 999, models.IPRuleAllow, "10.0.0.0/8", "", 1, "10.0.0.1"); err == nil {
+		t.Error("expected error for missing user")
+	}
+}
+
+// 会让操作者当前 IP 无法访问的变更被拒绝：新增允许规则、禁止自己、删除唯一覆盖自己的允许规则
+func TestIPRuleService_PreventsLockout(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewIPRuleService(NewTestServiceContext(t, db))
+	bg := context.Background()
+	const operatorIP = "10.0.0.5"
+
+	if _, err := svc.CreateRule(bg, 0, models.IPRuleAllow, "192.168.0.0/16", "", 1, operatorIP); err == nil {
+		t.Error("expected error when allow rule excludes operator IP")
+	}
+	if _, err := svc.CreateRule(bg, 0, models.IPRuleDeny, operatorIP, "", 1, operatorIP); err == nil {
+		t.Error("expected error when denying operator IP")
+	}
+
+	office, err := svc.CreateRule(bg, 0, models.IPRuleAllow, "10.0.0.0/8", "", 1, operatorIP)
+	if err != nil {
+		t.Fatalf("CreateRule office: %v", err)
+	}
+	vpn, err := svc.CreateRule(bg, 0, models.IPRuleAllow, "192.168.0.0/16", "", 1, operatorIP)
+	if err != nil {
+		t.Fatalf("CreateRule vpn: %v", err)
+	}
+	if err := svc.DeleteRule(bg, office.ID, 1, operatorIP); err == nil {
+		t.Error("expected error when deleting the allow rule covering operator IP")
+	}
+	if _, err := svc.UpdateRule(bg, office.ID, models.IPRuleAllow, "172.16.0.0/12", "", 1, operatorIP); err == nil {
+		t.Error("expected error when narrowing the allow rule covering operator IP")
+	}
+	if err := svc.DeleteRule(bg, vpn.ID, 1, operatorIP); err != nil {
+		t.Errorf("DeleteRule vpn: %v", err)
+	}
+
+	// 其他用户的规则不影响操作者
+	other := models.User{Username: "bob", Status: 1}
+	db.Create(&other)
+	if _, err := svc.CreateRule(bg, other.ID, models.IPRuleAllow, "192.168.0.0/16", "", other.ID+1, operatorIP); err != nil {
+		t.Errorf("CreateRule for other user: %v", err)
+	}
+}
+
+func TestIPRuleService_CheckAccess(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewIPRuleService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	user := models.User{Username: "alice", Status: 1}
+	db.Create(&user)
+
+	if err := svc.CheckAccess(bg, 0, "8.8.8.8"); err != nil {
+		t.Fatalf("no rules: %v", err)
+	}
+
+	// 修改规则后缓存立即失效
+	if _, err := svc.CreateRule(bg, user.ID, models.IPRuleAllow, "10.0.0.0/8", "", user.ID+1, "8.8.8.8"); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+	if err := svc.CheckAccess(bg, user.ID, "8.8.8.8"); err == nil {
+		t.Error("expected user rule to reject IP outside range")
+	} else if bizErr, ok := err.(*errors.BizError); !ok || bizErr.Code != errors.CodeForbidden {
+		t.Errorf("err = %v, want forbidden", err)
+	}
+	if err := svc.CheckAccess(bg, user.ID, "10.1.1.1"); err != nil {
+		t.Errorf("IP inside user range: %v", err)
+	}
+	if err := svc.CheckAccess(bg, 0, "8.8.8.8"); err != nil {
+		t.Errorf("global rules should not include user rules: %v", err)
+	}
+}
+
+func TestIPRuleService_InvalidatedDuringLoad(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewIPRuleService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	// 模拟加载查询进行中时其他请求修改了规则
+	invalidated := false
+	db.Callback().Query().After("gorm:query").Register("test:invalidate", func(*gorm.DB) {
+		if !invalidated {
+			invalidated = true
+			svc.invalidate()
+		}
+	})
+
+	if err := svc.CheckAccess(bg, 0, "8.8.8.8"); err != nil {
+		t.Fatalf("CheckAccess: %v", err)
+	}
+	svc.mu.RLock()
+	cached := svc.rules != nil
+	svc.mu.RUnlock()
+	if cached {
+		t.Error("rules loaded across an invalidation should not be cached")
+	}
+
+	if err := svc.CheckAccess(bg, 0, "8.8.8.8"); err != nil {
+		t.Fatalf("CheckAccess: %v", err)
+	}
+	svc.mu.RLock()
+	cached = svc.rules != nil
+	svc.mu.RUnlock()
+	if !cached {
+		t.Error("expected rules to be cached")
+	}
+}
+
+func TestIPRuleService_GetRules(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewIPRuleService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	user := models.User{Username: "alice", Status: 1}
+	db.Create(&user)
+	svc.CreateRule(bg, 0, models.IPRuleDeny, "1.2.3.4", "", 1, "10.0.0.1")
+	svc.CreateRule(bg, user.ID, models.IPRuleAllow, "10.0.0.0/8", "", 1, "10.0.0.1")
+
+	rules, total, err := svc.GetRules(bg, 1, 10, map[string]string{"scope": "user"})
+	if err != nil {
+		t.Fatalf("GetRules: %v", err)
+	}
+	if total != 1 || len(rules) != 1 || rules[0].UserID != user.ID {
+		t.Errorf("user rules = %+v (total %d)", rules, total)
+	}
+	_, total, _ = svc.GetRules(bg, 1, 10, map[string]string{"action": models.IPRuleDeny})
+	if total != 1 {
+		t.Errorf("deny rules total = %d, want 1", total)
+	}
+}
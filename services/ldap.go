@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+
+	"github.com/go-ldap/ldap/v3"
+	"gorm.io/gorm"
+)
+
+// ldapDialTimeout 连接 LDAP 服务器的超时时间
+const ldapDialTimeout = 10 * time.Second
+
+// LDAPConn LDAP 连接中认证用到的操作，*ldap.Conn 满足此接口；单测可替换为进程内的目录实现
+type LDAPConn interface {
+	StartTLS(config *tls.Config) error
+	Bind(username, password string) error
+	Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// LDAPDialer 建立 LDAP 连接
+type LDAPDialer func(url string, tlsConfig *tls.Config) (LDAPConn, error)
+
+func dialLDAP(url string, tlsConfig *tls.Config) (LDAPConn, error) {
+	return ldap.DialURL(url,
+		ldap.DialWithTLSConfig(tlsConfig),
+		ldap.DialWithDialer(&net.Dialer{Timeout: ldapDialTimeout}))
+}
+
+// ldapAuthenticator LDAP / Active Directory 认证：用服务账号查到用户 DN 后以用户密码绑定，
+// 首次登录自动创建本地用户，并按 ldap_group_roles 将所属组映射为角色
+type ldapAuthenticator struct {
+	ctx  ServiceContext
+	dial LDAPDialer
+}
+
+// NewLDAPAuthenticator 创建 LDAP 认证；dial 为 nil 时连接真实的 LDAP 服务器
+func NewLDAPAuthenticator(ctx ServiceContext, dial LDAPDialer) Authenticator {
+	if dial == nil {
+		dial = dialLDAP
+	}
+	return &ldapAuthenticator{ctx: ctx, dial: dial}
+}
+
+func (a *ldapAuthenticator) Name() string {
+	return AuthProviderLDAP
+}
+
+func (a *ldapAuthenticator) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	// 空密码在 LDAP 中是匿名绑定，会直接成功，必须拒绝
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	cfg := a.ctx.GetConfig()
+	if cfg.LDAPURL == "" {
+		return nil, stderrors.New("未配置 ldap_url")
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.LDAPSkipVerify}
+	conn, err := a.dial(cfg.LDAPURL, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("连接 LDAP 失败: %w", err)
+	}
+	defer conn.Close()
+
+	if cfg.LDAPStartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			return nil, fmt.Errorf("LDAP StartTLS 失败: %w", err)
+		}
+	}
+	if cfg.LDAPBindDN != "" {
+		if err := conn.Bind(cfg.LDAPBindDN, cfg.LDAPBindPassword); err != nil {
+			return nil, fmt.Errorf("LDAP 服务账号绑定失败: %w", err)
+		}
+	}
+
+	entry, err := a.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP 用户绑定失败: %w", err)
+	}
+
+	return a.provision(ctx, username, entry)
+}
+
+// findUser 在 ldap_base_dn 下按 ldap_user_filter 查找唯一的用户条目
+func (a *ldapAuthenticator) findUser(conn LDAPConn, username string) (*ldap.Entry, error) {
+	cfg := a.ctx.GetConfig()
+	filter := cfg.LDAPUserFilter
+	if filter == "" {
+		filter = "(uid=%s)"
+	}
+	req := ldap.NewSearchRequest(
+		cfg.LDAPBaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(ldapDialTimeout.Seconds()), false,
+		fmt.Sprintf(filter, ldap.EscapeFilter(username)),
+		[]string{"dn", a.nicknameAttr(), a.groupAttr()},
+		nil,
+	)
+	result, err := conn.Search(req)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP 查询用户失败: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return result.Entries[0], nil
+}
+
+// provision 首次登录创建本地用户，之后同步昵称；配置了组映射时按组重新分配角色，
+// 但不会移除最后一个启用的超级管理员的超级管理员角色
+func (a *ldapAuthenticator) provision(ctx context.Context, username string, entry *ldap.Entry) (*models.User, error) {
+	db := a.ctx.DB()
+	nickname := entry.GetAttributeValue(a.nicknameAttr())
+	if nickname == "" {
+		nickname = username
+	}
+
+	var user models.User
+	err := db.Where("username = ?", username).First(&user).Error
+	switch {
+	case stderrors.Is(err, gorm.ErrRecordNotFound):
+		user = models.User{
+			Username: username,
+			Nickname: nickname,
+			Status:   1,
+			Source:   models.UserSourceLDAP,
+		}
+		if err := db.Create(&user).Error; err != nil {
+			return nil, err
+		}
+		a.ctx.Logger().InfoContext(ctx, "LDAP 用户首次登录，已创建本地账号", "username", username, "dn", entry.DN)
+	case err != nil:
+		return nil, err
+	case user.Source != models.UserSourceLDAP:
+		// 不允许目录中的同名账号接管本地账号
+		a.ctx.Logger().WarnContext(ctx, "LDAP 用户与本地账号同名，拒绝登录", "username", username, "dn", entry.DN)
+		return nil, ErrInvalidCredentials
+	case user.Nickname != nickname:
+		if err := db.Model(&user).Update("nickname", nickname).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if mapping := a.ctx.GetConfig().LDAPGroupRoles; len(mapping) > 0 {
+		names := ldapGroupRoleNames(entry.GetAttributeValues(a.groupAttr()), mapping)
+		var roles []models.Role
+		if len(names) > 0 {
+			if err := db.Where("name IN ?", names).Find(&roles).Error; err != nil {
+				return nil, err
+			}
+		}
+		if !IsSuperAdmin(&models.User{Roles: roles}) {
+			if err := ensureNotLastSuperAdmin(db, &user); err != nil {
+				var bizErr *errors.BizError
+				if !stderrors.As(err, &bizErr) {
+					return nil, err
+				}
+				// 目录中的组已不再映射到超级管理员，但移除后系统将无人能管理角色与权限：保留该角色
+				var superAdmin models.Role
+				if err := db.Where("code = ?", models.RoleCodeSuperAdmin).First(&superAdmin).Error; err != nil {
+					return nil, err
+				}
+				roles = append(roles, superAdmin)
+				a.ctx.Logger().WarnContext(ctx, "LDAP 组映射将移除最后一个启用的超级管理员，已保留其超级管理员角色", "username", username)
+			}
+		}
+		if err := db.Model(&user).Association("Roles").Replace(roles); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := db.Where("id = ?", user.ID).Preload("Roles").First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (a *ldapAuthenticator) nicknameAttr() string {
+	if attr := a.ctx.GetConfig().LDAPNicknameAttr; attr != "" {
+		return attr
+	}
+	return "cn"
+}
+
+func (a *ldapAuthenticator) groupAttr() string {
+	if attr := a.ctx.GetConfig().LDAPGroupAttr; attr != "" {
+		return attr
+	}
+	return "memberOf"
+}
+
+// ldapGroupRoleNames 将用户所属组映射为角色名；映射的键可以是组的完整 DN 或 CN，不区分大小写
+func ldapGroupRoleNames(groups []string, mapping map[string]string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, group := range groups {
+		cn := ldapGroupCN(group)
+		for key, role := range mapping {
+			if (strings.EqualFold(key, group) || strings.EqualFold(key, cn)) && !seen[role] {
+				seen[role] = true
+				names = append(names, role)
+			}
+		}
+	}
+	return names
+}
+
+// ldapGroupCN 取组 DN 第一段的 cn 值，不是 DN 时原样返回
+func ldapGroupCN(group string) string {
+	dn, err := ldap.ParseDN(group)
+	if err != nil || len(dn.RDNs) == 0 {
+		return group
+	}
+	for _, attr := range dn.RDNs[0].Attributes {
+		if strings.EqualFold(attr.Type, "cn") {
+			return attr.Value
+		}
+	}
+	return group
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	stderrors "errors"
+	"strings"
+	"testing"
+
+	"github.com/lyuangg/gadmin/config"
+	"github.com/lyuangg/gadmin/models"
+
+	"github.com/go-ldap/ldap/v3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	testLDAPBaseDN   = "ou=people,dc=example,dc=com"
+	testLDAPBindDN   = "cn=svc,dc=example,dc=com"
+	testLDAPBindPass = "svc-secret"
+)
+
+// fakeLDAPEntry 进程内目录中的一个用户
+type fakeLDAPEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// fakeDirectory 进程内 LDAP 目录：支持服务账号与用户绑定、按 (uid=...) 查询
+type fakeDirectory struct {
+	entries map[string]*fakeLDAPEntry // uid -> 条目
+	down    bool
+	binds   []string
+}
+
+func newFakeDirectory() *fakeDirectory {
+	return &fakeDirectory{entries: map[string]*fakeLDAPEntry{
+		"alice": {
+			dn:       "uid=alice," + testLDAPBaseDN,
+			password: "alice-ldap-pass",
+			attrs: map[string][]string{
+				"cn":       {"Alice Liddell"},
+				"memberOf": {"cn=ops,ou=groups,dc=example,dc=com", "cn=dev,ou=groups,dc=example,dc=com"},
+			},
+		},
+	}}
+}
+
+func (d *fakeDirectory) dial(url string, _ *tls.Config) (LDAPConn, error) {
+	if d.down {
+		return nil, stderrors.New("connection refused")
+	}
+	return &fakeLDAPConn{dir: d}, nil
+}
+
+type fakeLDAPConn struct {
+	dir *fakeDirectory
+}
+
+func (c *fakeLDAPConn) StartTLS(*tls.Config) error { return nil }
+func (c *fakeLDAPConn) Close() error               { return nil }
+
+func (c *fakeLDAPConn) Bind(dn, password string) error {
+	c.dir.binds = append(c.dir.binds, dn)
+	if dn == testLDAPBindDN && password == testLDAPBindPass {
+		return nil
+	}
+	for _, e := range c.dir.entries {
+		if e.dn == dn && e.password == password {
+			return nil
+		}
+	}
+	return ldap.NewError(ldap.LDAPResultInvalidCredentials, stderrors.New("invalid credentials"))
+}
+
+func (c *fakeLDAPConn) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	result := &ldap.SearchResult{}
+	for uid, e := range c.dir.entries {
+		if req.Filter == "(uid="+ldap.EscapeFilter(uid)+")" && strings.HasSuffix(e.dn, req.BaseDN) {
+			result.Entries = append(result.Entries, ldap.NewEntry(e.dn, e.attrs))
+		}
+	}
+	return result, nil
+}
+
+func newLDAPTestAuthService(t *testing.T, dir *fakeDirectory, providers ...string) (*AuthService, ServiceContext) {
+	t.Helper()
+	db := NewTestDB(t)
+	db.Create(&models.Role{Name: "运维"})
+	db.Create(&models.Role{Name: "开发"})
+	cfg := &config.Config{
+		AuthProviders:    providers,
+		LDAPURL:          "ldap://directory.test:389",
+		LDAPBindDN:       testLDAPBindDN,
+		LDAPBindPassword: testLDAPBindPass,
+		LDAPBaseDN:       testLDAPBaseDN,
+		LDAPGroupRoles: map[string]string{
+			"ops":                                "运维",
+			"CN=dev,OU=groups,DC=example,DC=com": "开发",
+		},
+	}
+	ctx := NewTestServiceContext(t, db, WithConfig(cfg), WithAuthenticators(func(ctx ServiceContext) []Authenticator {
+		var list []Authenticator
+		for _, p := range providers {
+			if p == AuthProviderLDAP {
+				list = append(list, NewLDAPAuthenticator(ctx, dir.dial))
+			} else {
+				list = append(list, NewLocalAuthenticator(ctx))
+			}
+		}
+		return list
+	}))
+	return NewAuthService(ctx), ctx
+}
+
+func TestAuthService_Login_LDAPProvisionsUser(t *testing.T) {
+	dir := newFakeDirectory()
+	svc, ctx := newLDAPTestAuthService(t, dir, AuthProviderLocal, AuthProviderLDAP)
+	bg := context.Background()
+
+	result, err := svc.Login(bg, "alice", "alice-ldap-pass", "cid", "val", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	user := result.User
+	if user.Source != models.UserSourceLDAP || user.Nickname != "Alice Liddell" || user.MustChangePassword {
+		t.Errorf("provisioned user = %+v", user)
+	}
+	if len(user.Roles) != 2 {
+		t.Errorf("roles = %+v, want 运维 and 开发", user.Roles)
+	}
+
+	// 组变化后再次登录，角色随之同步
+	dir.entries["alice"].attrs["memberOf"] = []string{"cn=ops,ou=groups,dc=example,dc=com"}
+	if _, err := svc.Login(bg, "alice", "alice-ldap-pass", "cid", "val", ClientInfo{}); err != nil {
+		t.Fatalf("second Login: %v", err)
+	}
+	var count int64
+	ctx.DB().Model(&models.User{}).Where("username = ?", "alice").Count(&count)
+	var reloaded models.User
+	ctx.DB().Preload("Roles").Where("username = ?", "alice").First(&reloaded)
+	if count != 1 || len(reloaded.Roles) != 1 || reloaded.Roles[0].Name != "运维" {
+		t.Errorf("count=%d roles=%+v", count, reloaded.Roles)
+	}
+
+	if err := svc.ChangePassword(bg, reloaded.ID, "", "alice-ldap-pass", "New#Passw0rd"); err == nil {
+		t.Error("ldap user should not change password locally")
+	}
+}
+
+// 组映射不再包含超级管理员时，最后一个启用的超级管理员保留该角色
+func TestAuthService_Login_LDAPKeepsLastSuperAdmin(t *testing.T) {
+	dir := newFakeDirectory()
+	dir.entries["alice"].attrs["memberOf"] = []string{"cn=admins,ou=groups,dc=example,dc=com", "cn=ops,ou=groups,dc=example,dc=com"}
+	svc, ctx := newLDAPTestAuthService(t, dir, AuthProviderLDAP)
+	superAdmin := models.Role{Name: "超级管理员", Code: models.RoleCodeSuperAdmin}
+	ctx.DB().Create(&superAdmin)
+	ctx.GetConfig().LDAPGroupRoles["admins"] = superAdmin.Name
+	bg := context.Background()
+
+	result, err := svc.Login(bg, "alice", "alice-ldap-pass", "cid", "val", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !IsSuperAdmin(result.User) {
+		t.Fatalf("roles = %+v, want super admin", result.User.Roles)
+	}
+
+	dir.entries["alice"].attrs["memberOf"] = []string{"cn=ops,ou=groups,dc=example,dc=com"}
+	result, err = svc.Login(bg, "alice", "alice-ldap-pass", "cid", "val", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login after leaving admins: %v", err)
+	}
+	if !IsSuperAdmin(result.User) || len(result.User.Roles) != 2 {
+		t.Errorf("last super admin should keep the role, got %+v", result.User.Roles)
+	}
+
+	// 还有其他启用的超级管理员时照常按组同步
+	other := models.User{Username: "root", Status: 1, Roles: []models.Role{superAdmin}}
+	ctx.DB().Create(&other)
+	result, err = svc.Login(bg, "alice", "alice-ldap-pass", "cid", "val", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if IsSuperAdmin(result.User) || len(result.User.Roles) != 1 {
+		t.Errorf("roles = %+v, want only 运维", result.User.Roles)
+	}
+}
+
+func TestAuthService_Login_LDAPWrongPassword(t *testing.T) {
+	dir := newFakeDirectory()
+	svc, ctx := newLDAPTestAuthService(t, dir, AuthProviderLDAP)
+	bg := context.Background()
+
+	for _, password := range []string{"wrong", ""} {
+		_, err := svc.Login(bg, "alice", password, "cid", "val", ClientInfo{})
+		if err == nil || err.Error() != "用户名或密码错误" {
+			t.Errorf("password %q: err = %v", password, err)
+		}
+	}
+	var lock models.LoginLock
+	if err := ctx.DB().Where("target = ?", "alice").First(&lock).Error; err != nil || lock.Failures != 2 {
+		t.Errorf("failures should be recorded: %+v err=%v", lock, err)
+	}
+}
+
+// 目录中的同名账号不能接管本地账号；按顺序回退到本地密码认证
+func TestAuthService_Login_LDAPDoesNotTakeOverLocalUser(t *testing.T) {
+	dir := newFakeDirectory()
+	svc, ctx := newLDAPTestAuthService(t, dir, AuthProviderLDAP, AuthProviderLocal)
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("local-pass"), bcrypt.DefaultCost)
+	ctx.DB().Create(&models.User{Username: "alice", Password: string(hashed), Status: 1})
+	bg := context.Background()
+
+	if _, err := svc.Login(bg, "alice", "alice-ldap-pass", "cid", "val", ClientInfo{}); err == nil {
+		t.Error("ldap login must not take over a local account")
+	}
+	result, err := svc.Login(bg, "alice", "local-pass", "cid", "val", ClientInfo{})
+	if err != nil || result.User.Source != models.UserSourceLocal {
+		t.Errorf("local login should still work: %v", err)
+	}
+}
+
+func TestAuthService_Login_LDAPUnavailable(t *testing.T) {
+	dir := newFakeDirectory()
+	dir.down = true
+	svc, ctx := newLDAPTestAuthService(t, dir, AuthProviderLDAP)
+
+	_, err := svc.Login(context.Background(), "alice", "alice-ldap-pass", "cid", "val", ClientInfo{})
+	if err == nil || err.Error() == "用户名或密码错误" {
+		t.Errorf("directory outage should not look like bad credentials: %v", err)
+	}
+	var count int64
+	ctx.DB().Model(&models.LoginLock{}).Count(&count)
+	if count != 0 {
+		t.Error("directory outage should not count as a login failure")
+	}
+}
+
+func TestLDAPGroupRoleNames(t *testing.T) {
+	mapping := map[string]string{"admins": "超级管理员", "cn=ops,dc=x": "运维"}
+	got := ldapGroupRoleNames([]string{"CN=Admins,OU=Groups,DC=x", "cn=ops,dc=x", "plain"}, mapping)
+	if len(got) != 2 {
+		t.Errorf("got %v", got)
+	}
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 未配置时的登录锁定策略默认值
+const (
+	defaultLoginMaxFailures      = 5
+	defaultLoginMaxFailuresPerIP = 20
+	defaultLoginFailureWindow    = 15 * time.Minute
+	defaultLoginLockout          = 5 * time.Minute
+	defaultLoginLockoutMax       = 24 * time.Hour
+)
+
+// loginLockPolicy 登录锁定阈值与时长
+type loginLockPolicy struct {
+	maxFailures      int
+	maxFailuresPerIP int
+	window           time.Duration
+	lockout          time.Duration
+	lockoutMax       time.Duration
+}
+
+// LoginLockService 登录防暴力破解：按用户名与客户端 IP 统计连续失败次数，超过阈值后按指数退避临时锁定
+type LoginLockService struct {
+	ctx ServiceContext
+}
+
+// NewLoginLockService 创建登录锁定服务实例
+func NewLoginLockService(ctx ServiceContext) *LoginLockService {
+	return &LoginLockService{ctx: ctx}
+}
+
+// Check 用户名或 IP 处于锁定期内时返回错误，登录校验密码前调用
+func (s *LoginLockService) Check(ctx context.Context, username, ip string) error {
+	now := time.Now()
+	var locks []models.LoginLock
+	query := s.ctx.DB().Where("locked_until > ?", now).
+		Where(s.ctx.DB().Where("scope = ? AND target = ?", models.LoginLockScopeUser, username).
+			Or("scope = ? AND target = ?", models.LoginLockScopeIP, ip))
+	if err := query.Find(&locks).Error; err != nil {
+		return err
+	}
+
+	var until time.Time
+	for _, lock := range locks {
+		if lock.LockedUntil.After(until) {
+			until = *lock.LockedUntil
+		}
+	}
+	if until.IsZero() {
+		return nil
+	}
+	minutes := int(math.Ceil(until.Sub(now).Minutes()))
+	return errors.TooManyRequestsMsg(fmt.Sprintf("登录失败次数过多，请 %d 分钟后重试", minutes))
+}
+
+// RecordFailure 记录一次登录失败（用户名不存在也计数，避免暴露账户是否存在）
+func (s *LoginLockService) RecordFailure(ctx context.Context, username, ip string) error {
+	policy := s.policy()
+	if username != "" {
+		if err := s.fail(ctx, models.LoginLockScopeUser, username, policy.maxFailures, ip, policy); err != nil {
+			return err
+		}
+	}
+	if ip != "" {
+		if err := s.fail(ctx, models.LoginLockScopeIP, ip, policy.maxFailuresPerIP, ip, policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordSuccess 登录成功后清除该用户名的失败计数与退避级别；IP 维度的计数保留，按窗口自然过期
+func (s *LoginLockService) RecordSuccess(ctx context.Context, username string) error {
+	return s.ctx.DB().Where("scope = ? AND target = ?", models.LoginLockScopeUser, username).
+		Delete(&models.LoginLock{}).Error
+}
+
+// GetLocks 查询当前处于锁定期内的记录（分页），支持按 scope、target 筛选
+func (s *LoginLockService) GetLocks(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.LoginLock, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	query := s.ctx.DB().Model(&models.LoginLock{}).Where("locked_until > ?", time.Now())
+	if scope := filters["scope"]; scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+	if target := filters["target"]; target != "" {
+		query = query.Where("target LIKE ?", "%"+target+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var locks []models.LoginLock
+	if err := query.Order("locked_until DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&locks).Error; err != nil {
+		return nil, 0, err
+	}
+	return locks, total, nil
+}
+
+// GetEvents 查询锁定/解锁审计记录（分页），支持按 scope、target、action 筛选
+func (s *LoginLockService) GetEvents(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.LoginLockEvent, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	query := s.ctx.DB().Model(&models.LoginLockEvent{})
+	if scope := filters["scope"]; scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+	if target := filters["target"]; target != "" {
+		query = query.Where("target LIKE ?", "%"+target+"%")
+	}
+	if action := filters["action"]; action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var events []models.LoginLockEvent
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// Unlock 管理员按记录 ID 解除锁定（用户名或 IP），并清零退避级别
+func (s *LoginLockService) Unlock(ctx context.Context, lockID uint, operatorID uint) error {
+	var lock models.LoginLock
+	if err := s.ctx.DB().Where("id = ?", lockID).First(&lock).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NotFoundMsg("锁定记录不存在")
+		}
+		return err
+	}
+	return s.unlock(ctx, &lock, operatorID)
+}
+
+// UnlockUser 管理员解锁指定用户的账户
+func (s *LoginLockService) UnlockUser(ctx context.Context, userID uint, operatorID uint) error {
+	var user models.User
+	if err := s.ctx.DB().Select("id", "username").Where("id = ?", userID).First(&user).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NotFoundMsg("用户不存在")
+		}
+		return err
+	}
+	var lock models.LoginLock
+	if err := s.ctx.DB().Where("scope = ? AND target = ?", models.LoginLockScopeUser, user.Username).First(&lock).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.unlock(ctx, &lock, operatorID)
+}
+
+func (s *LoginLockService) unlock(ctx context.Context, lock *models.LoginLock, operatorID uint) error {
+	err := s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.LoginLock{}, lock.ID).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.LoginLockEvent{
+			Scope:      lock.Scope,
+			Target:     lock.Target,
+			Action:     models.LoginLockActionUnlock,
+			OperatorID: operatorID,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.ctx.Logger().InfoContext(ctx, "管理员解除登录锁定", "scope", lock.Scope, "target", lock.Target, "operator_id", operatorID)
+	return nil
+}
+
+// fail 累加一个维度的失败次数，达到阈值时锁定：锁定时长 = 首次时长 × 2^已锁定次数，不超过上限。
+// 计数在数据库中原子累加，并发的失败请求不会读到同一个旧值而少计；锁定按累加后的记录计算，只有一个请求能触发
+func (s *LoginLockService) fail(ctx context.Context, scope, target string, threshold int, ip string, policy loginLockPolicy) error {
+	now := time.Now()
+	var event *models.LoginLockEvent
+	err := s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		// 首次失败时建行，并发的首次失败由唯一索引去重
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.LoginLock{Scope: scope, Target: target, LastFailedAt: now}).Error; err != nil {
+			return err
+		}
+		row := func() *gorm.DB {
+			return tx.Model(&models.LoginLock{}).Where("scope = ? AND target = ?", scope, target)
+		}
+		// 长时间没有失败则不再累积退避级别，超过统计窗口则重新计数
+		if err := row().Where("last_failed_at < ?", now.Add(-policy.lockoutMax)).Update("lock_count", 0).Error; err != nil {
+			return err
+		}
+		if err := row().Where("last_failed_at < ?", now.Add(-policy.window)).Update("failures", 0).Error; err != nil {
+			return err
+		}
+		if err := row().Updates(map[string]interface{}{
+			"failures":       gorm.Expr("failures + 1"),
+			"last_failed_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		var lock models.LoginLock
+		if err := tx.Where("scope = ? AND target = ?", scope, target).First(&lock).Error; err != nil {
+			return err
+		}
+		if lock.Failures < threshold {
+			return nil
+		}
+		duration := policy.lockout << uint(min(lock.LockCount, 30))
+		if duration <= 0 || duration > policy.lockoutMax {
+			duration = policy.lockoutMax
+		}
+		until := now.Add(duration)
+		// 以读到的失败次数为条件，同一轮失败只会锁定一次
+		result := row().Where("failures = ?", lock.Failures).Updates(map[string]interface{}{
+			"failures":     0,
+			"lock_count":   gorm.Expr("lock_count + 1"),
+			"locked_until": until,
+		})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		event = &models.LoginLockEvent{
+			Scope:       scope,
+			Target:      target,
+			Action:      models.LoginLockActionLock,
+			Failures:    lock.Failures,
+			LockedUntil: &until,
+			IP:          ip,
+		}
+		return tx.Create(event).Error
+	})
+	if err != nil {
+		return err
+	}
+	if event != nil {
+		s.ctx.Logger().WarnContext(ctx, "登录失败次数过多，已临时锁定", "scope", scope, "target", target, "ip", ip, "locked_until", event.LockedUntil)
+	}
+	return nil
+}
+
+func (s *LoginLockService) policy() loginLockPolicy {
+	cfg := s.ctx.GetConfig()
+	p := loginLockPolicy{
+		maxFailures:      defaultLoginMaxFailures,
+		maxFailuresPerIP: defaultLoginMaxFailuresPerIP,
+		window:           defaultLoginFailureWindow,
+		lockout:          defaultLoginLockout,
+		lockoutMax:       defaultLoginLockoutMax,
+	}
+	if cfg.LoginMaxFailures > 0 {
+		p.maxFailures = cfg.LoginMaxFailures
+	}
+	if cfg.LoginMaxFailuresPerIP > 0 {
+		p.maxFailuresPerIP = cfg.LoginMaxFailuresPerIP
+	}
+	if cfg.LoginFailureWindowMinutes > 0 {
+		p.window = time.Duration(cfg.LoginFailureWindowMinutes) * time.Minute
+	}
+	if cfg.LoginLockoutMinutes > 0 {
+		p.lockout = time.Duration(cfg.LoginLockoutMinutes) * time.Minute
+	}
+	if cfg.LoginLockoutMaxMinutes > 0 {
+		p.lockoutMax = time.Duration(cfg.LoginLockoutMaxMinutes) * time.Minute
+	}
+	return p
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lyuangg/gadmin/config"
+	"github.com/lyuangg/gadmin/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func newLoginLockTestService(t *testing.T) (*LoginLockService, ServiceContext) {
+	t.Helper()
+	db := NewTestDB(t)
+	ctx := NewTestServiceContext(t, db, WithConfig(&config.Config{
+		LoginMaxFailures:      3,
+		LoginMaxFailuresPerIP: 10,
+		LoginLockoutMinutes:   5,
+	}))
+	return NewLoginLockService(ctx), ctx
+}
+
+func TestLoginLockService_LocksAfterThreshold(t *testing.T) {
+	svc, ctx := newLoginLockTestService(t)
+	bg := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := svc.RecordFailure(bg, "alice", "10.0.0.1"); err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+	}
+	if err := svc.Check(bg, "alice", "10.0.0.1"); err != nil {
+		t.Fatalf("should not be locked before threshold: %v", err)
+	}
+	svc.RecordFailure(bg, "alice", "10.0.0.1")
+	if err := svc.Check(bg, "alice", "10.0.0.2"); err == nil {
+		t.Error("username should be locked after threshold")
+	}
+	if err := svc.Check(bg, "bob", "10.0.0.1"); err != nil {
+		t.Errorf("ip below its own threshold should not be locked: %v", err)
+	}
+
+	var events []models.LoginLockEvent
+	ctx.DB().Find(&events)
+	if len(events) != 1 || events[0].Action != models.LoginLockActionLock || events[0].Target != "alice" {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+// 并发的失败请求逐个计数，不会因读到同一个旧值而少计；达到阈值只锁定一次
+func TestLoginLockService_ConcurrentFailures(t *testing.T) {
+	db := NewTestDB(t)
+	// 内存库每个连接是独立的库，并发访问须共用一个连接
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	// 每次查询后让出执行权，放大“读后写”之间被其他请求插入的窗口
+	db.Callback().Query().After("gorm:query").Register("test:yield", func(*gorm.DB) { time.Sleep(time.Millisecond) })
+	ctx := NewTestServiceContext(t, db, WithConfig(&config.Config{LoginMaxFailures: 8, LoginMaxFailuresPerIP: 100}))
+	svc := NewLoginLockService(ctx)
+	bg := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := svc.RecordFailure(bg, "alice", "10.0.0.1"); err != nil {
+				t.Errorf("RecordFailure: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var ipLock, userLock models.LoginLock
+	db.Where("scope = ? AND target = ?", models.LoginLockScopeIP, "10.0.0.1").First(&ipLock)
+	if ipLock.Failures != 10 {
+		t.Errorf("ip failures = %d, want 10", ipLock.Failures)
+	}
+	db.Where("scope = ? AND target = ?", models.LoginLockScopeUser, "alice").First(&userLock)
+	if userLock.LockCount != 1 || userLock.Failures != 2 || userLock.LockedUntil == nil {
+		t.Errorf("user lock = %+v, want locked once with 2 failures after", userLock)
+	}
+	var events int64
+	db.Model(&models.LoginLockEvent{}).Count(&events)
+	if events != 1 {
+		t.Errorf("lock events = %d, want 1", events)
+	}
+}
+
+func TestLoginLockService_ExponentialBackoff(t *testing.T) {
+	svc, ctx := newLoginLockTestService(t)
+	bg := context.Background()
+
+	lockOnce := func() time.Duration {
+		// 模拟上一次锁定已过期
+		ctx.DB().Model(&models.LoginLock{}).Where("target = ?", "alice").Update("locked_until", time.Now().Add(-time.Second))
+		for i := 0; i < 3; i++ {
+			svc.RecordFailure(bg, "alice", "")
+		}
+		var lock models.LoginLock
+		ctx.DB().Where("scope = ? AND target = ?", models.LoginLockScopeUser, "alice").First(&lock)
+		return time.Until(*lock.LockedUntil)
+	}
+
+	first := lockOnce()
+	second := lockOnce()
+	if first > 5*time.Minute || first < 4*time.Minute {
+		t.Errorf("first lockout = %v, want ~5m", first)
+	}
+	if second < 9*time.Minute || second > 10*time.Minute {
+		t.Errorf("second lockout = %v, want ~10m", second)
+	}
+}
+
+func TestLoginLockService_RecordSuccessResets(t *testing.T) {
+	svc, _ := newLoginLockTestService(t)
+	bg := context.Background()
+
+	svc.RecordFailure(bg, "alice", "")
+	svc.RecordFailure(bg, "alice", "")
+	if err := svc.RecordSuccess(bg, "alice"); err != nil {
+		t.Fatalf("RecordSuccess: %v", err)
+	}
+	svc.RecordFailure(bg, "alice", "")
+	if err := svc.Check(bg, "alice", ""); err != nil {
+		t.Errorf("counter should restart after success: %v", err)
+	}
+}
+
+func TestLoginLockService_UnlockUser(t *testing.T) {
+	svc, ctx := newLoginLockTestService(t)
+	bg := context.Background()
+	user := models.User{Username: "alice", Password: "x", Status: 1}
+	ctx.DB().Create(&user)
+
+	for i := 0; i < 3; i++ {
+		svc.RecordFailure(bg, "alice", "")
+	}
+	if err := svc.UnlockUser(bg, user.ID, 1); err != nil {
+		t.Fatalf("UnlockUser: %v", err)
+	}
+	if err := svc.Check(bg, "alice", ""); err != nil {
+		t.Errorf("should be unlocked: %v", err)
+	}
+
+	events, total, err := svc.GetEvents(bg, 1, 10, map[string]string{"action": models.LoginLockActionUnlock})
+	if err != nil {
+		t.Fatalf("GetEvents: %v", err)
+	}
+	if total != 1 || events[0].OperatorID != 1 {
+		t.Errorf("unlock events = %+v", events)
+	}
+	if err := svc.UnlockUser(bg, 9999, 1); err == nil {
+		t.Error("unknown user should return error")
+	}
+}
+
+func TestAuthService_Login_LockedAfterFailures(t *testing.T) {
+	db := NewTestDB(t)
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass123"), bcrypt.DefaultCost)
+	db.Create(&models.User{Username: "alice", Password: string(hashed), Status: 1})
+	ctx := NewTestServiceContext(t, db, WithConfig(&config.Config{LoginMaxFailures: 2}))
+	svc := NewAuthService(ctx)
+	bg := context.Background()
+	client := ClientInfo{IP: "10.0.0.1"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Login(bg, "alice", "wrong", "cid", "val", client); err == nil {
+			t.Fatal("wrong password should fail")
+		}
+	}
+	_, err := svc.Login(bg, "alice", "pass123", "cid", "val", client)
+	if err == nil {
+		t.Fatal("locked account should be rejected even with correct password")
+	}
+	if err.Error() == "用户名或密码错误" {
+		t.Errorf("expected lockout error, got %v", err)
+	}
+}
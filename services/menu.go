@@ -0,0 +1,406 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+
+	"gorm.io/gorm"
+)
+
+// MenuInput 创建或更新菜单的字段，更新时整体替换
+type MenuInput struct {
+	ParentID      uint
+	Type          string
+	Name          string
+	Code          string // 按钮标识，按钮必填
+	Path          string // 页面路径，页面必填
+	Icon          string
+	Sort          int
+	Visible       bool
+	PermissionIDs []uint
+}
+
+// UserMenus 当前用户可见的菜单
+type UserMenus struct {
+	Menus   []*models.Menu      `json:"menus"`   // 侧栏菜单树，只含目录与页面；没有可见页面的目录不返回
+	Buttons map[string][]string `json:"buttons"` // 页面路径 -> 可用的按钮标识
+}
+
+// MenuService 菜单服务
+type MenuService struct {
+	ctx ServiceContext
+}
+
+// NewMenuService 创建菜单服务实例
+func NewMenuService(ctx ServiceContext) *MenuService {
+	return &MenuService{ctx: ctx}
+}
+
+// GetMenuTree 获取完整的菜单树（含按钮与绑定的权限），同级按 sort、id 升序
+func (s *MenuService) GetMenuTree(ctx context.Context) ([]*models.Menu, error) {
+	var menus []*models.Menu
+	if err := s.ctx.DB().Preload("Permissions").Order("sort ASC, id ASC").Find(&menus).Error; err != nil {
+		return nil, err
+	}
+	return buildMenuTree(menus), nil
+}
+
+// GetUserMenus 按用户权限过滤菜单：绑定了权限的菜单需拥有其中任一权限（按允许/拒绝规则判断），
+// 上级不可见时下级也不可见；超级管理员可见全部菜单
+func (s *MenuService) GetUserMenus(ctx context.Context, isSuperAdmin bool, roleIDs []uint) (*UserMenus, error) {
+	var menus []*models.Menu
+	// 绑定的权限被删除后菜单仍按原权限判断，不会因此变为所有人可见
+	if err := s.ctx.DB().Preload("Permissions", func(tx *gorm.DB) *gorm.DB {
+		return tx.Unscoped()
+	}).Order("sort ASC, id ASC").Find(&menus).Error; err != nil {
+		return nil, err
+	}
+
+	var matchers []*PermissionMatcher
+	if !isSuperAdmin && len(roleIDs) > 0 {
+		var err error
+		if matchers, err = s.ctx.GetPermissionService().GetMatchersByRoleIDs(ctx, roleIDs); err != nil {
+			return nil, err
+		}
+	}
+	allowed := func(menu *models.Menu) bool {
+		if isSuperAdmin || len(menu.Permissions) == 0 {
+			return true
+		}
+		for _, p := range menu.Permissions {
+			if permissionGranted(matchers, p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := &UserMenus{Buttons: make(map[string][]string)}
+	byID := make(map[uint]*models.Menu, len(menus))
+	for _, m := range menus {
+		byID[m.ID] = m
+	}
+	for _, m := range menus {
+		if m.Type != models.MenuTypeButton || !m.Visible || !allowed(m) {
+			continue
+		}
+		if page, ok := byID[m.ParentID]; ok && page.Type == models.MenuTypePage && allowed(page) {
+			result.Buttons[page.Path] = append(result.Buttons[page.Path], m.Code)
+		}
+	}
+
+	result.Menus = filterMenuTree(buildMenuTree(menus), func(m *models.Menu) bool {
+		return m.Type != models.MenuTypeButton && m.Visible && allowed(m)
+	})
+	return result, nil
+}
+
+// CreateMenu 创建菜单
+func (s *MenuService) CreateMenu(ctx context.Context, in MenuInput) (*models.Menu, error) {
+	permissions, err := s.checkMenu(0, &in)
+	if err != nil {
+		return nil, err
+	}
+
+	menu := models.Menu{
+		ParentID:    in.ParentID,
+		Type:        in.Type,
+		Name:        in.Name,
+		Code:        in.Code,
+		Path:        in.Path,
+		Icon:        in.Icon,
+		Sort:        in.Sort,
+		Visible:     in.Visible,
+		Permissions: permissions,
+	}
+	err = s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Permissions.*").Create(&menu).Error; err != nil {
+			return err
+		}
+		// visible 为 false 时 Create 会使用列默认值（显示），需单独更新
+		if in.Visible != menu.Visible {
+			return tx.Model(&menu).Update("visible", in.Visible).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	menu.Visible = in.Visible
+	return &menu, nil
+}
+
+// UpdateMenu 更新菜单；上级不能是自己或自己的下级，已有下级的菜单不能改为按钮
+func (s *MenuService) UpdateMenu(ctx context.Context, id uint, in MenuInput) (*models.Menu, error) {
+	var menu models.Menu
+	if err := s.ctx.DB().Where("id = ?", id).First(&menu).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NotFoundMsg("菜单不存在")
+		}
+		return nil, err
+	}
+
+	permissions, err := s.checkMenu(id, &in)
+	if err != nil {
+		return nil, err
+	}
+	if in.Type != menu.Type {
+		var children []models.Menu
+		if err := s.ctx.DB().Select("id", "type").Where("parent_id = ?", id).Find(&children).Error; err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			if in.Type == models.MenuTypeButton ||
+				(child.Type == models.MenuTypeButton) != (in.Type == models.MenuTypePage) {
+				return nil, errors.BadRequestMsg("菜单类型与其下级菜单不匹配")
+			}
+		}
+	}
+
+	menu.ParentID = in.ParentID
+	menu.Type = in.Type
+	menu.Name = in.Name
+	menu.Code = in.Code
+	menu.Path = in.Path
+	menu.Icon = in.Icon
+	menu.Sort = in.Sort
+	menu.Visible = in.Visible
+
+	err = s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Permissions").Save(&menu).Error; err != nil {
+			return err
+		}
+		return tx.Model(&menu).Association("Permissions").Replace(permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+	menu.Permissions = permissions
+	return &menu, nil
+}
+
+// DeleteMenu 删除菜单；有下级菜单的不能删除
+func (s *MenuService) DeleteMenu(ctx context.Context, id uint) error {
+	var menu models.Menu
+	if err := s.ctx.DB().Where("id = ?", id).First(&menu).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NotFoundMsg("菜单不存在")
+		}
+		return err
+	}
+
+	var count int64
+	if err := s.ctx.DB().Model(&models.Menu{}).Where("parent_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.BadRequestMsg("请先删除下级菜单")
+	}
+
+	return s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&menu).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&menu).Error
+	})
+}
+
+// checkMenu 校验菜单字段并返回要绑定的权限（id 为 0 表示新建）：
+// 目录与页面只能挂在目录下或作为顶级菜单，按钮必须挂在页面下且标识在该页面内唯一
+func (s *MenuService) checkMenu(id uint, in *MenuInput) ([]models.Permission, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	in.Code = strings.TrimSpace(in.Code)
+	in.Path = strings.TrimSpace(in.Path)
+	if in.Name == "" {
+		return nil, errors.BadRequestMsg("菜单名称不能为空")
+	}
+	switch in.Type {
+	case models.MenuTypeDirectory:
+		in.Code, in.Path = "", ""
+	case models.MenuTypePage:
+		if !strings.HasPrefix(in.Path, "/") {
+			return nil, errors.BadRequestMsg("页面路径须以 / 开头")
+		}
+		in.Code = ""
+	case models.MenuTypeButton:
+		if in.Code == "" {
+			return nil, errors.BadRequestMsg("按钮标识不能为空")
+		}
+		in.Path, in.Icon = "", ""
+	default:
+		return nil, errors.BadRequestMsg("无效的菜单类型")
+	}
+
+	if in.ParentID == 0 {
+		if in.Type == models.MenuTypeButton {
+			return nil, errors.BadRequestMsg("按钮必须属于一个页面")
+		}
+	} else {
+		if id != 0 {
+			subtree, err := menuSubtree(s.ctx.DB(), id)
+			if err != nil {
+				return nil, err
+			}
+			for _, sub := range subtree {
+				if sub == in.ParentID {
+					return nil, errors.BadRequestMsg("上级菜单不能是自己或自己的下级菜单")
+				}
+			}
+		}
+		var parent models.Menu
+		if err := s.ctx.DB().Select("id", "type").Where("id = ?", in.ParentID).First(&parent).Error; err != nil {
+			if stderrors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.BadRequestMsg("上级菜单不存在")
+			}
+			return nil, err
+		}
+		if in.Type == models.MenuTypeButton && parent.Type != models.MenuTypePage {
+			return nil, errors.BadRequestMsg("按钮必须属于一个页面")
+		}
+		if in.Type != models.MenuTypeButton && parent.Type != models.MenuTypeDirectory {
+			return nil, errors.BadRequestMsg("上级菜单必须是目录")
+		}
+	}
+
+	if in.Type == models.MenuTypeButton {
+		var count int64
+		if err := s.ctx.DB().Model(&models.Menu{}).
+			Where("parent_id = ? AND type = ? AND code = ? AND id != ?", in.ParentID, models.MenuTypeButton, in.Code, id).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, errors.BadRequestMsg("按钮标识已存在")
+		}
+	}
+
+	permissions := []models.Permission{}
+	if len(in.PermissionIDs) > 0 {
+		if err := s.ctx.DB().Where("id IN ?", in.PermissionIDs).Find(&permissions).Error; err != nil {
+			return nil, err
+		}
+		if len(permissions) != len(uniqueUints(in.PermissionIDs)) {
+			return nil, errors.BadRequestMsg("权限不存在")
+		}
+	}
+	return permissions, nil
+}
+
+// InitDefaultMenus 菜单表为空时写入内置菜单，需在路由权限导入之后调用，按 方法+路径 绑定导入的权限
+func (s *MenuService) InitDefaultMenus(ctx context.Context) error {
+	var count int64
+	if err := s.ctx.DB().Unscoped().Model(&models.Menu{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var permissions []models.Permission
+	if err := s.ctx.DB().Where("effect = ?", models.PermissionEffectAllow).Find(&permissions).Error; err != nil {
+		return err
+	}
+	byRoute := make(map[string]models.Permission, len(permissions))
+	for _, p := range permissions {
+		byRoute[p.Method+" "+p.Path] = p
+	}
+
+	var create func(tx *gorm.DB, parentID uint, defs []defaultMenu) error
+	create = func(tx *gorm.DB, parentID uint, defs []defaultMenu) error {
+		for i, def := range defs {
+			menu := models.Menu{
+				ParentID: parentID,
+				Type:     def.Type,
+				Name:     def.Name,
+				Code:     def.Code,
+				Path:     def.Path,
+				Icon:     def.Icon,
+				Sort:     i + 1,
+				Visible:  true,
+			}
+			for _, route := range def.Permissions {
+				if p, ok := byRoute[route]; ok {
+					menu.Permissions = append(menu.Permissions, p)
+				}
+			}
+			if err := tx.Omit("Permissions.*").Create(&menu).Error; err != nil {
+				return err
+			}
+			if err := create(tx, menu.ID, def.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		return create(tx, 0, defaultMenus)
+	}); err != nil {
+		return err
+	}
+	s.ctx.Logger().InfoContext(ctx, "初始化默认菜单")
+	return nil
+}
+
+// menuSubtree 返回 id 及其所有下级菜单的 ID
+func menuSubtree(db *gorm.DB, id uint) ([]uint, error) {
+	var menus []models.Menu
+	if err := db.Select("id", "parent_id").Find(&menus).Error; err != nil {
+		return nil, err
+	}
+	children := make(map[uint][]uint)
+	for _, m := range menus {
+		children[m.ParentID] = append(children[m.ParentID], m.ID)
+	}
+
+	seen := map[uint]bool{id: true}
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// buildMenuTree 将已排序的菜单列表组装为树，子节点保持原有顺序；上级不在列表中的菜单作为根节点
+func buildMenuTree(menus []*models.Menu) []*models.Menu {
+	byID := make(map[uint]*models.Menu, len(menus))
+	for _, m := range menus {
+		m.Children = nil
+		byID[m.ID] = m
+	}
+	roots := make([]*models.Menu, 0)
+	for _, m := range menus {
+		if parent, ok := byID[m.ParentID]; ok && m.ParentID != m.ID {
+			parent.Children = append(parent.Children, m)
+		} else {
+			roots = append(roots, m)
+		}
+	}
+	return roots
+}
+
+// filterMenuTree 保留 keep 为 true 的节点（节点被去掉时其下级一并去掉），并去掉没有剩余下级的目录；
+// 返回的节点不带绑定的权限
+func filterMenuTree(nodes []*models.Menu, keep func(*models.Menu) bool) []*models.Menu {
+	result := make([]*models.Menu, 0, len(nodes))
+	for _, n := range nodes {
+		if !keep(n) {
+			continue
+		}
+		n.Children = filterMenuTree(n.Children, keep)
+		if n.Type == models.MenuTypeDirectory && len(n.Children) == 0 {
+			continue
+		}
+		n.Permissions = nil
+		result = append(result, n)
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package services
+
+import "github.com/lyuangg/gadmin/models"
+
+// defaultMenu 内置菜单定义，Permissions 为 "方法 路径"，对应路由导入的权限
+type defaultMenu struct {
+	Type        string
+	Name        string
+	Code        string
+	Path        string
+	Icon        string
+	Permissions []string
+	Children    []defaultMenu
+}
+
+func pageButton(code, name, permission string) defaultMenu {
+	return defaultMenu{Type: models.MenuTypeButton, Name: name, Code: code, Permissions: []string{permission}}
+}
+
+// defaultMenus 首次启动时写入的菜单，之后以数据库中的菜单为准，可在菜单管理中调整
+var defaultMenus = []defaultMenu{
+	{Type: models.MenuTypePage, Name: "首页", Path: "/admin", Icon: "House"},
+	{Type: models.MenuTypeDirectory, Name: "系统管理", Icon: "Setting", Children: []defaultMenu{
+		{Type: models.MenuTypePage, Name: "用户管理", Path: "/admin/users", Icon: "User",
+			Permissions: []string{"GET /admin/api/users"}, Children: []defaultMenu{
+				pageButton("add", "新增", "POST /admin/api/users"),
+				pageButton("edit", "编辑", "PUT /admin/api/users/:id"),
+				pageButton("delete", "删除", "DELETE /admin/api/users/:id"),
+				pageButton("organization", "部门岗位", "PUT /admin/api/users/:id/organization"),
+				pageButton("toggleStatus", "启用/禁用", "PUT /admin/api/users/:id/toggle-status"),
+				pageButton("resetPassword", "重置密码", "POST /admin/api/users/:id/reset-password"),
+				pageButton("resetTwoFactor", "重置两步验证", "DELETE /admin/api/users/:id/2fa"),
+				pageButton("unlock", "解锁", "POST /admin/api/users/:id/unlock"),
+			}},
+		{Type: models.MenuTypePage, Name: "角色管理", Path: "/admin/roles", Icon: "Avatar",
+			Permissions: []string{"GET /admin/api/roles"}, Children: []defaultMenu{
+				pageButton("add", "新增", "POST /admin/api/roles"),
+				pageButton("edit", "编辑", "PUT /admin/api/roles/:id"),
+				pageButton("delete", "删除", "DELETE /admin/api/roles/:id"),
+				pageButton("assignPermissions", "分配权限", "PUT /admin/api/roles/:id/permissions"),
+				pageButton("require2FA", "强制两步验证", "PUT /admin/api/roles/:id/require-2fa"),
+				pageButton("dataScope", "数据范围", "PUT /admin/api/roles/:id/data-scope"),
+			}},
+		{Type: models.MenuTypePage, Name: "权限管理", Path: "/admin/permissions", Icon: "Lock",
+			Permissions: []string{"GET /admin/api/permissions"}, Children: []defaultMenu{
+				pageButton("add", "新增", "POST /admin/api/permissions"),
+				pageButton("edit", "编辑", "PUT /admin/api/permissions/:id"),
+				pageButton("delete", "删除", "DELETE /admin/api/permissions/:id"),
+				pageButton("syncPreview", "同步预览", "GET /admin/api/permissions/sync-preview"),
+			}},
+		{Type: models.MenuTypePage, Name: "菜单管理", Path: "/admin/menus", Icon: "Menu",
+			Permissions: []string{"GET /admin/api/menus/tree"}, Children: []defaultMenu{
+				pageButton("add", "新增", "POST /admin/api/menus"),
+				pageButton("edit", "编辑", "PUT /admin/api/menus/:id"),
+				pageButton("delete", "删除", "DELETE /admin/api/menus/:id"),
+			}},
+		{Type: models.MenuTypePage, Name: "字典管理", Path: "/admin/dictionaries", Icon: "Collection",
+			Permissions: []string{"GET /admin/api/dictionaries/types"}, Children: []defaultMenu{
+				pageButton("add", "新增类型", "POST /admin/api/dictionaries/types"),
+				pageButton("edit", "编辑类型", "PUT /admin/api/dictionaries/types/:id"),
+				pageButton("delete", "删除类型", "DELETE /admin/api/dictionaries/types/:id"),
+				pageButton("addItem", "新增字典项", "POST /admin/api/dictionaries/items"),
+				pageButton("editItem", "编辑字典项", "PUT /admin/api/dictionaries/items/:id"),
+				pageButton("deleteItem", "删除字典项", "DELETE /admin/api/dictionaries/items/:id"),
+			}},
+	}},
+	{Type: models.MenuTypeDirectory, Name: "日志审计", Icon: "Tickets", Children: []defaultMenu{
+		{Type: models.MenuTypePage, Name: "操作日志", Path: "/admin/operation-logs", Icon: "Document",
+			Permissions: []string{"GET /admin/api/operation-logs"}, Children: []defaultMenu{
+				pageButton("export", "导出", "GET /admin/download/operation-logs"),
+			}},
+		{Type: models.MenuTypePage, Name: "安全事件", Path: "/admin/security-events", Icon: "Warning",
+			Permissions: []string{"GET /admin/api/security-events"}},
+	}},
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+)
+
+func TestMenuService_CRUD(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewMenuService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	perm := models.Permission{Path: "/admin/api/users", Method: "GET"}
+	db.Create(&perm)
+
+	dir, err := svc.CreateMenu(bg, MenuInput{Type: models.MenuTypeDirectory, Name: "系统管理", Path: "/ignored", Visible: true})
+	if err != nil || dir.Path != "" {
+		t.Fatalf("create directory: %+v, %v", dir, err)
+	}
+	page, err := svc.CreateMenu(bg, MenuInput{ParentID: dir.ID, Type: models.MenuTypePage, Name: "用户管理", Path: "/admin/users",
+		Visible: false, PermissionIDs: []uint{perm.ID, perm.ID}})
+	if err != nil {
+		t.Fatalf("create page: %v", err)
+	}
+	var stored models.Menu
+	db.Preload("Permissions").First(&stored, page.ID)
+	if stored.Visible || len(stored.Permissions) != 1 {
+		t.Errorf("stored page = %+v", stored)
+	}
+	button, err := svc.CreateMenu(bg, MenuInput{ParentID: page.ID, Type: models.MenuTypeButton, Name: "新增", Code: "add", Visible: true})
+	if err != nil {
+		t.Fatalf("create button: %v", err)
+	}
+
+	invalid := []struct {
+		name string
+		in   MenuInput
+	}{
+		{"无效类型", MenuInput{Type: "link", Name: "x"}},
+		{"名称为空", MenuInput{Type: models.MenuTypeDirectory, Name: " "}},
+		{"页面路径", MenuInput{Type: models.MenuTypePage, Name: "x", Path: "admin"}},
+		{"按钮缺少标识", MenuInput{ParentID: page.ID, Type: models.MenuTypeButton, Name: "x"}},
+		{"顶级按钮", MenuInput{Type: models.MenuTypeButton, Name: "x", Code: "x"}},
+		{"按钮挂在目录下", MenuInput{ParentID: dir.ID, Type: models.MenuTypeButton, Name: "x", Code: "x"}},
+		{"页面挂在页面下", MenuInput{ParentID: page.ID, Type: models.MenuTypePage, Name: "x", Path: "/x"}},
+		{"按钮标识重复", MenuInput{ParentID: page.ID, Type: models.MenuTypeButton, Name: "x", Code: "add"}},
+		{"上级不存在", MenuInput{ParentID: 999, Type: models.MenuTypePage, Name: "x", Path: "/x"}},
+		{"权限不存在", MenuInput{Type: models.MenuTypePage, Name: "x", Path: "/x", PermissionIDs: []uint{999}}},
+	}
+	for _, tt := range invalid {
+		if _, err := svc.CreateMenu(bg, tt.in); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+
+	// 更新：整体替换字段与绑定的权限；不能挂到自己的下级，有按钮的页面不能改为目录
+	updated, err := svc.UpdateMenu(bg, page.ID, MenuInput{ParentID: dir.ID, Type: models.MenuTypePage, Name: "用户", Path: "/admin/users", Visible: true})
+	if err != nil || updated.Name != "用户" || !updated.Visible || len(updated.Permissions) != 0 {
+		t.Fatalf("update page: %+v, %v", updated, err)
+	}
+	if _, err := svc.UpdateMenu(bg, button.ID, MenuInput{ParentID: page.ID, Type: models.MenuTypeButton, Name: "添加", Code: "add"}); err != nil {
+		t.Errorf("update button keeping its code: %v", err)
+	}
+	sub, _ := svc.CreateMenu(bg, MenuInput{ParentID: dir.ID, Type: models.MenuTypeDirectory, Name: "子目录"})
+	if _, err := svc.UpdateMenu(bg, dir.ID, MenuInput{ParentID: sub.ID, Type: models.MenuTypeDirectory, Name: "系统管理"}); err == nil {
+		t.Error("expected error when moving under own child")
+	}
+	if _, err := svc.UpdateMenu(bg, page.ID, MenuInput{ParentID: dir.ID, Type: models.MenuTypeDirectory, Name: "用户"}); err == nil {
+		t.Error("expected error when page with buttons becomes directory")
+	}
+	if _, err := svc.UpdateMenu(bg, 999, MenuInput{Type: models.MenuTypeDirectory, Name: "x"}); err == nil {
+		t.Error("expected error when updating missing menu")
+	} else if bizErr, ok := err.(*errors.BizError); !ok || bizErr.Code != errors.CodeNotFound {
+		t.Errorf("update missing menu: %v", err)
+	}
+
+	// 删除：有下级的不能删除
+	if err := svc.DeleteMenu(bg, page.ID); err == nil {
+		t.Error("expected error when deleting menu with children")
+	}
+	if err := svc.DeleteMenu(bg, button.ID); err != nil {
+		t.Fatalf("delete button: %v", err)
+	}
+	if err := svc.DeleteMenu(bg, page.ID); err != nil {
+		t.Fatalf("delete page: %v", err)
+	}
+
+	tree, err := svc.GetMenuTree(bg)
+	if err != nil || len(tree) != 1 || len(tree[0].Children) != 1 || tree[0].Children[0].ID != sub.ID {
+		t.Errorf("tree = %+v, %v", tree, err)
+	}
+}
+
+func TestMenuService_GetUserMenus(t *testing.T) {
+	db := NewTestDB(t)
+	sc := NewTestServiceContext(t, db)
+	svc := NewMenuService(sc)
+	bg := context.Background()
+
+	listUsers := models.Permission{Path: "/admin/api/users", Method: "GET"}
+	addUser := models.Permission{Path: "/admin/api/users", Method: "POST"}
+	deleteUser := models.Permission{Path: "/admin/api/users/:id", Method: "DELETE"}
+	listRoles := models.Permission{Path: "/admin/api/roles", Method: "GET"}
+	listLogs := models.Permission{Path: "/admin/api/operation-logs", Method: "GET"}
+	allUsers := models.Permission{Path: "/admin/api/users/*", Method: "*"}
+	denyDelete := models.Permission{Path: "/admin/api/users/:id", Method: "DELETE", Effect: models.PermissionEffectDeny}
+	for _, p := range []*models.Permission{&listUsers, &addUser, &deleteUser, &listRoles, &listLogs, &allUsers, &denyDelete} {
+		db.Create(p)
+	}
+	role := models.Role{Name: "用户管理员", Permissions: []models.Permission{listUsers, addUser, allUsers, denyDelete}}
+	db.Create(&role)
+
+	create := func(in MenuInput) *models.Menu {
+		t.Helper()
+		m, err := svc.CreateMenu(bg, in)
+		if err != nil {
+			t.Fatalf("CreateMenu(%s): %v", in.Name, err)
+		}
+		return m
+	}
+	create(MenuInput{Type: models.MenuTypePage, Name: "首页", Path: "/admin", Sort: 0, Visible: true})
+	system := create(MenuInput{Type: models.MenuTypeDirectory, Name: "系统管理", Sort: 1, Visible: true})
+	users := create(MenuInput{ParentID: system.ID, Type: models.MenuTypePage, Name: "用户管理", Path: "/admin/users", Sort: 2, Visible: true,
+		PermissionIDs: []uint{listUsers.ID}})
+	create(MenuInput{ParentID: users.ID, Type: models.MenuTypeButton, Name: "新增", Code: "add", Visible: true, PermissionIDs: []uint{addUser.ID}})
+	create(MenuInput{ParentID: users.ID, Type: models.MenuTypeButton, Name: "删除", Code: "delete", Visible: true, PermissionIDs: []uint{deleteUser.ID}})
+	create(MenuInput{ParentID: users.ID, Type: models.MenuTypeButton, Name: "导出", Code: "export", Visible: false})
+	create(MenuInput{ParentID: system.ID, Type: models.MenuTypePage, Name: "角色管理", Path: "/admin/roles", Sort: 1, Visible: true,
+		PermissionIDs: []uint{listRoles.ID}})
+	audit := create(MenuInput{Type: models.MenuTypeDirectory, Name: "日志审计", Sort: 2, Visible: true})
+	create(MenuInput{ParentID: audit.ID, Type: models.MenuTypePage, Name: "操作日志", Path: "/admin/operation-logs", Visible: true,
+		PermissionIDs: []uint{listLogs.ID}})
+	profile := create(MenuInput{Type: models.MenuTypePage, Name: "个人中心", Path: "/admin/profile", Sort: 3, Visible: false})
+	create(MenuInput{ParentID: profile.ID, Type: models.MenuTypeButton, Name: "保存", Code: "save", Visible: true})
+
+	names := func(menus []*models.Menu) []string {
+		var result []string
+		var walk func([]*models.Menu, string)
+		walk = func(nodes []*models.Menu, prefix string) {
+			for _, n := range nodes {
+				result = append(result, prefix+n.Name)
+				walk(n.Children, prefix+n.Name+"/")
+			}
+		}
+		walk(menus, "")
+		return result
+	}
+
+	// 普通用户：没有权限的页面与没有可见页面的目录不返回；删除按钮被拒绝规则挡住；隐藏页面的按钮仍下发
+	got, err := svc.GetUserMenus(bg, false, []uint{role.ID})
+	if err != nil {
+		t.Fatalf("GetUserMenus: %v", err)
+	}
+	if want := []string{"首页", "系统管理", "系统管理/用户管理"}; !reflect.DeepEqual(names(got.Menus), want) {
+		t.Errorf("menus = %v, want %v", names(got.Menus), want)
+	}
+	wantButtons := map[string][]string{"/admin/users": {"add"}, "/admin/profile": {"save"}}
+	if !reflect.DeepEqual(got.Buttons, wantButtons) {
+		t.Errorf("buttons = %v, want %v", got.Buttons, wantButtons)
+	}
+	if got.Menus[1].Children[0].Permissions != nil {
+		t.Error("expected permissions stripped from user menus")
+	}
+
+	// 超级管理员：全部可见菜单，按同级排序
+	got, _ = svc.GetUserMenus(bg, true, nil)
+	want := []string{"首页", "系统管理", "系统管理/角色管理", "系统管理/用户管理", "日志审计", "日志审计/操作日志"}
+	if !reflect.DeepEqual(names(got.Menus), want) {
+		t.Errorf("super admin menus = %v, want %v", names(got.Menus), want)
+	}
+	if !reflect.DeepEqual(got.Buttons["/admin/users"], []string{"add", "delete"}) {
+		t.Errorf("super admin buttons = %v", got.Buttons)
+	}
+
+	// 没有角色时只有未绑定权限的菜单；绑定的权限被删除后菜单不会变为所有人可见
+	if err := NewPermissionService(sc).DeletePermission(bg, listLogs.ID); err != nil {
+		t.Fatalf("DeletePermission: %v", err)
+	}
+	got, _ = svc.GetUserMenus(bg, false, nil)
+	if want := []string{"首页"}; !reflect.DeepEqual(names(got.Menus), want) {
+		t.Errorf("menus without roles = %v, want %v", names(got.Menus), want)
+	}
+}
+
+func TestMenuService_GetUserMenus_WildcardMethod(t *testing.T) {
+	db := NewTestDB(t)
+	sc := NewTestServiceContext(t, db)
+	svc := NewMenuService(sc)
+	bg := context.Background()
+
+	anyLogs := models.Permission{Path: "/admin/api/operation-logs", Method: "*"}
+	listLogs := models.Permission{Path: "/admin/api/operation-logs", Method: "GET"}
+	denyAll := models.Permission{Path: "/admin/api/*", Method: "*", Effect: models.PermissionEffectDeny}
+	listUsers := models.Permission{Path: "/admin/api/users", Method: "GET"}
+	for _, p := range []*models.Permission{&anyLogs, &listLogs, &denyAll, &listUsers} {
+		db.Create(p)
+	}
+	reader := models.Role{Name: "只读", Permissions: []models.Permission{listLogs}}
+	blocked := models.Role{Name: "禁用", Permissions: []models.Permission{listLogs, denyAll}}
+	other := models.Role{Name: "其他", Permissions: []models.Permission{listUsers}}
+	for _, r := range []*models.Role{&reader, &blocked, &other} {
+		db.Create(r)
+	}
+
+	if _, err := svc.CreateMenu(bg, MenuInput{Type: models.MenuTypePage, Name: "操作日志", Path: "/admin/operation-logs", Visible: true,
+		PermissionIDs: []uint{anyLogs.ID}}); err != nil {
+		t.Fatalf("CreateMenu: %v", err)
+	}
+
+	// 绑定方法为 * 的权限：任一方法被允许且未被拒绝即可见
+	tests := []struct {
+		name string
+		role models.Role
+		want int
+	}{
+		{"GET allowed", reader, 1},
+		{"denied by wildcard rule", blocked, 0},
+		{"unrelated permission", other, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.GetUserMenus(bg, false, []uint{tt.role.ID})
+			if err != nil {
+				t.Fatalf("GetUserMenus: %v", err)
+			}
+			if len(got.Menus) != tt.want {
+				t.Errorf("menus = %d, want %d", len(got.Menus), tt.want)
+			}
+		})
+	}
+}
+
+func TestMenuService_InitDefaultMenus(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewMenuService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	listUsers := models.Permission{Path: "/admin/api/users", Method: "GET"}
+	denyUsers := models.Permission{Path: "/admin/api/users", Method: "GET", Effect: models.PermissionEffectDeny}
+	db.Create(&listUsers)
+	db.Create(&denyUsers)
+
+	if err := svc.InitDefaultMenus(bg); err != nil {
+		t.Fatalf("InitDefaultMenus: %v", err)
+	}
+	var page models.Menu
+	if err := db.Preload("Permissions").Where("path = ?", "/admin/users").First(&page).Error; err != nil {
+		t.Fatalf("users page: %v", err)
+	}
+	if len(page.Permissions) != 1 || page.Permissions[0].ID != listUsers.ID {
+		t.Errorf("users page permissions = %+v", page.Permissions)
+	}
+	var buttons int64
+	db.Model(&models.Menu{}).Where("parent_id = ? AND type = ?", page.ID, models.MenuTypeButton).Count(&buttons)
+	if buttons == 0 {
+		t.Error("expected default buttons under users page")
+	}
+
+	// 已有菜单时不再写入
+	var before, after int64
+	db.Model(&models.Menu{}).Count(&before)
+	if err := svc.InitDefaultMenus(bg); err != nil {
+		t.Fatalf("InitDefaultMenus again: %v", err)
+	}
+	db.Model(&models.Menu{}).Count(&after)
+	if before != after {
+		t.Errorf("menus count %d -> %d, expected unchanged", before, after)
+	}
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/utils"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+const (
+	// oidcLoginTTL 跳转 IdP 后完成回调的时限
+	oidcLoginTTL = 10 * time.Minute
+	// oidcTicketTTL 回调成功后登录页用票据换取 Token 的时限
+	oidcTicketTTL = time.Minute
+	// oidcRequestTimeout 请求 IdP（discovery、换取 Token）的超时时间
+	oidcRequestTimeout = 10 * time.Second
+)
+
+// OIDCService OpenID Connect 单点登录：授权码 + PKCE 流程，ID Token 按 discovery 文档与 JWKS 校验，
+// 通过后关联或创建本地用户，并签发一次性登录票据，由 AuthService.LoginWithOIDC 换取正式 Token
+type OIDCService struct {
+	ctx ServiceContext
+
+	mu       sync.Mutex
+	provider *oidc.Provider // discovery 结果，首次使用时获取，失败下次重试
+}
+
+// NewOIDCService 创建单点登录服务实例
+func NewOIDCService(ctx ServiceContext) *OIDCService {
+	return &OIDCService{ctx: ctx}
+}
+
+// Enabled 是否配置了单点登录
+func (s *OIDCService) Enabled() bool {
+	cfg := s.ctx.GetConfig()
+	return cfg.OIDCIssuer != "" && cfg.OIDCClientID != ""
+}
+
+// AuthCodeURL 生成 state、nonce 与 PKCE code_verifier 并保存，返回跳转 IdP 的授权地址和 state 原文
+func (s *OIDCService) AuthCodeURL(ctx context.Context) (string, string, error) {
+	if !s.Enabled() {
+		return "", "", errors.BadRequestMsg("未启用单点登录")
+	}
+	provider, err := s.getProvider(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := utils.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	now := time.Now()
+	// 顺带清理过期未完成的登录记录
+	if err := s.ctx.DB().Where("expires_at < ?", now).Delete(&models.OIDCLogin{}).Error; err != nil {
+		s.ctx.Logger().WarnContext(ctx, "清理过期单点登录记录失败", "error", err)
+	}
+	login := models.OIDCLogin{
+		StateHash:    utils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    now.Add(oidcLoginTTL),
+	}
+	if err := s.ctx.DB().Create(&login).Error; err != nil {
+		return "", "", err
+	}
+
+	authURL := s.oauth2Config(provider).AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	return authURL, state, nil
+}
+
+// HandleCallback 处理 IdP 回调：state 只能使用一次，用 code 与 code_verifier 换取 Token 并校验 ID Token，
+// 关联或创建本地用户后返回一次性登录票据
+func (s *OIDCService) HandleCallback(ctx context.Context, state, code string) (string, error) {
+	if state == "" || code == "" {
+		return "", errors.UnauthorizedMsg("单点登录已失效，请重试")
+	}
+	var login models.OIDCLogin
+	if err := s.ctx.DB().Where("state_hash = ?", utils.HashToken(state)).First(&login).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return "", errors.UnauthorizedMsg("单点登录已失效，请重试")
+		}
+		return "", err
+	}
+	now := time.Now()
+	if now.After(login.ExpiresAt) {
+		return "", errors.UnauthorizedMsg("单点登录已失效，请重试")
+	}
+	result := s.ctx.DB().Model(&models.OIDCLogin{}).
+		Where("id = ? AND callback_at IS NULL", login.ID).
+		Update("callback_at", now)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", errors.UnauthorizedMsg("单点登录已失效，请重试")
+	}
+
+	provider, err := s.getProvider(ctx)
+	if err != nil {
+		return "", err
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, oidcRequestTimeout)
+	defer cancel()
+
+	token, err := s.oauth2Config(provider).Exchange(reqCtx, code, oauth2.VerifierOption(login.CodeVerifier))
+	if err != nil {
+		s.ctx.Logger().WarnContext(ctx, "单点登录换取 Token 失败", "error", err)
+		return "", errors.UnauthorizedMsg("单点登录失败，请重试")
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return "", errors.UnauthorizedMsg("单点登录失败：IdP 未返回 ID Token")
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: s.ctx.GetConfig().OIDCClientID}).Verify(reqCtx, rawIDToken)
+	if err != nil {
+		s.ctx.Logger().WarnContext(ctx, "单点登录 ID Token 校验失败", "error", err)
+		return "", errors.UnauthorizedMsg("单点登录失败：ID Token 无效")
+	}
+	if idToken.Nonce != login.Nonce {
+		s.ctx.Logger().WarnContext(ctx, "单点登录 ID Token nonce 不匹配", "subject", idToken.Subject)
+		return "", errors.UnauthorizedMsg("单点登录失败：ID Token 无效")
+	}
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return "", err
+	}
+
+	user, err := s.resolveUser(ctx, idToken.Issuer, idToken.Subject, claims)
+	if err != nil {
+		return "", err
+	}
+	if user.Status == 0 {
+		return "", errors.ForbiddenMsg("用户已被禁用")
+	}
+
+	ticket, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	err = s.ctx.DB().Model(&models.OIDCLogin{}).Where("id = ?", login.ID).Updates(map[string]interface{}{
+		"user_id":     user.ID,
+		"ticket_hash": utils.HashToken(ticket),
+		"expires_at":  now.Add(oidcTicketTTL),
+	}).Error
+	if err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+// ConsumeTicket 校验并作废一次性登录票据，返回已预加载 Roles 的用户
+func (s *OIDCService) ConsumeTicket(ctx context.Context, ticket string) (*models.User, error) {
+	if ticket == "" {
+		return nil, errors.UnauthorizedMsg("单点登录已失效，请重试")
+	}
+	var login models.OIDCLogin
+	err := s.ctx.DB().Where("ticket_hash = ? AND user_id > 0", utils.HashToken(ticket)).First(&login).Error
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.UnauthorizedMsg("单点登录已失效，请重试")
+		}
+		return nil, err
+	}
+	if time.Now().After(login.ExpiresAt) {
+		return nil, errors.UnauthorizedMsg("单点登录已失效，请重试")
+	}
+	// 票据只能使用一次：删除成功者才能继续签发
+	result := s.ctx.DB().Where("id = ?", login.ID).Delete(&models.OIDCLogin{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.UnauthorizedMsg("单点登录已失效，请重试")
+	}
+
+	var user models.User
+	if err := s.ctx.DB().Where("id = ?", login.UserID).Preload("Roles").First(&user).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.UnauthorizedMsg("用户不存在")
+		}
+		return nil, err
+	}
+	if user.Status == 0 {
+		return nil, errors.ForbiddenMsg("用户已被禁用")
+	}
+	return &user, nil
+}
+
+// resolveUser 按 issuer + subject 查找已关联的用户；首次登录时按用户名 claim 关联同名账号，不存在则创建。
+// 同名账号须是单点登录创建的；本地、LDAP 账号只在开启 oidc_link_local 时关联，超级管理员始终不关联，
+// 否则 IdP 中同名的用户可绕过本地密码与两步验证接管该账号
+func (s *OIDCService) resolveUser(ctx context.Context, issuer, subject string, claims map[string]interface{}) (*models.User, error) {
+	db := s.ctx.DB()
+	nickname := oidcClaimString(claims, "name")
+
+	var identity models.UserIdentity
+	err := db.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	if err == nil {
+		var user models.User
+		if err := db.Where("id = ?", identity.UserID).First(&user).Error; err != nil {
+			if stderrors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.UnauthorizedMsg("用户不存在")
+			}
+			return nil, err
+		}
+		if user.Source == models.UserSourceOIDC && nickname != "" && user.Nickname != nickname {
+			if err := db.Model(&user).Update("nickname", nickname).Error; err != nil {
+				return nil, err
+			}
+		}
+		return &user, nil
+	}
+	if !stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	username := oidcClaimString(claims, s.usernameClaim())
+	if username == "" {
+		s.ctx.Logger().WarnContext(ctx, "单点登录 ID Token 缺少用户名 claim", "claim", s.usernameClaim(), "subject", subject)
+		return nil, errors.UnauthorizedMsg("单点登录失败：IdP 未返回用户名")
+	}
+	if nickname == "" {
+		nickname = username
+	}
+
+	var user models.User
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Preload("Roles").Where("username = ?", username).First(&user).Error
+		switch {
+		case stderrors.Is(err, gorm.ErrRecordNotFound):
+			user = models.User{
+				Username: username,
+				Nickname: nickname,
+				Status:   1,
+				Source:   models.UserSourceOIDC,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			s.ctx.Logger().InfoContext(ctx, "单点登录用户首次登录，已创建本地账号", "username", username, "subject", subject)
+		case err != nil:
+			return err
+		case user.Source != models.UserSourceOIDC && (!s.ctx.GetConfig().OIDCLinkLocal || IsSuperAdmin(&user)):
+			s.ctx.Logger().WarnContext(ctx, "单点登录用户与本地账号同名，拒绝关联", "username", username, "source", user.Source, "subject", subject)
+			return errors.UnauthorizedMsg("单点登录失败：存在同名的本地账号，请联系管理员")
+		default:
+			// 同名账号已关联同一 IdP 的其他身份时不能再次关联
+			var count int64
+			if err := tx.Model(&models.UserIdentity{}).Where("user_id = ? AND issuer = ?", user.ID, issuer).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				s.ctx.Logger().WarnContext(ctx, "单点登录用户名已关联其他身份，拒绝登录", "username", username, "subject", subject)
+				return errors.UnauthorizedMsg("单点登录失败：账号已关联其他身份")
+			}
+			s.ctx.Logger().InfoContext(ctx, "单点登录身份已关联到已有账号", "username", username, "subject", subject)
+		}
+		return tx.Create(&models.UserIdentity{UserID: user.ID, Issuer: issuer, Subject: subject}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// getProvider 获取并缓存 IdP 的 discovery 文档（含 JWKS 地址）
+func (s *OIDCService) getProvider(ctx context.Context) (*oidc.Provider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.provider != nil {
+		return s.provider, nil
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, oidcRequestTimeout)
+	defer cancel()
+	provider, err := oidc.NewProvider(reqCtx, s.ctx.GetConfig().OIDCIssuer)
+	if err != nil {
+		s.ctx.Logger().ErrorContext(ctx, "获取 OIDC discovery 文档失败", "issuer", s.ctx.GetConfig().OIDCIssuer, "error", err)
+		return nil, errors.InternalErrorMsg("单点登录服务暂时不可用，请稍后重试")
+	}
+	s.provider = provider
+	return provider, nil
+}
+
+func (s *OIDCService) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	cfg := s.ctx.GetConfig()
+	scopes := cfg.OIDCScopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	if !slices.Contains(scopes, oidc.ScopeOpenID) {
+		scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+	}
+	return &oauth2.Config{
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  cfg.OIDCRedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       scopes,
+	}
+}
+
+func (s *OIDCService) usernameClaim() string {
+	if claim := s.ctx.GetConfig().OIDCUsernameClaim; claim != "" {
+		return claim
+	}
+	return "preferred_username"
+}
+
+func oidcClaimString(claims map[string]interface{}, name string) string {
+	v, _ := claims[name].(string)
+	return v
+}
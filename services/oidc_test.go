@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/lyuangg/gadmin/config"
+	"github.com/lyuangg/gadmin/models"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+const testOIDCClientID = "gadmin-test"
+
+// mockIdPCode IdP 签发的授权码及其对应的 PKCE challenge 与 ID Token 内容
+type mockIdPCode struct {
+	challenge string
+	nonce     string
+}
+
+// mockIdP 本地 OIDC IdP：提供 discovery、JWKS 与 token 接口，校验 PKCE 后签发 RS256 ID Token
+type mockIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	codes  map[string]mockIdPCode
+
+	subject  string
+	username string
+	nonce    string // 非空时覆盖 ID Token 中的 nonce
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	idp := &mockIdP{t: t, key: key, codes: map[string]mockIdPCode{}, subject: "sub-alice", username: "alice"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &idp.key.PublicKey, KeyID: "test-key", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", idp.handleToken)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize 模拟用户在 IdP 完成登录：校验授权地址参数，返回回调中的 state 与 code
+func (idp *mockIdP) authorize(authURL string) (string, string) {
+	idp.t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		idp.t.Fatalf("parse auth url: %v", err)
+	}
+	q := u.Query()
+	if q.Get("client_id") != testOIDCClientID || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		idp.t.Fatalf("unexpected auth request: %s", authURL)
+	}
+	code := "code-" + q.Get("state")[:8]
+	idp.codes[code] = mockIdPCode{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	return q.Get("state"), code
+}
+
+func (idp *mockIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	code, ok := idp.codes[r.PostForm.Get("code")]
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+	delete(idp.codes, r.PostForm.Get("code"))
+
+	nonce := code.nonce
+	if idp.nonce != "" {
+		nonce = idp.nonce
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "idp-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token": idp.sign(map[string]interface{}{
+			"iss":                idp.server.URL,
+			"sub":                idp.subject,
+			"aud":                testOIDCClientID,
+			"iat":                time.Now().Unix(),
+			"exp":                time.Now().Add(5 * time.Minute).Unix(),
+			"nonce":              nonce,
+			"preferred_username": idp.username,
+			"name":               "Alice Liddell",
+		}),
+	})
+}
+
+func (idp *mockIdP) sign(claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: idp.key, KeyID: "test-key"}},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	if err != nil {
+		idp.t.Fatalf("new signer: %v", err)
+	}
+	payload, _ := json.Marshal(claims)
+	sig, err := signer.Sign(payload)
+	if err != nil {
+		idp.t.Fatalf("sign: %v", err)
+	}
+	token, _ := sig.CompactSerialize()
+	return token
+}
+
+func newOIDCTestContext(t *testing.T, idp *mockIdP, opts ...func(*config.Config)) ServiceContext {
+	t.Helper()
+	cfg := &config.Config{
+		OIDCIssuer:      idp.server.URL,
+		OIDCClientID:    testOIDCClientID,
+		OIDCRedirectURL: "http://gadmin.test/api/oidc/callback",
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return NewTestServiceContext(t, NewTestDB(t), WithConfig(cfg))
+}
+
+// ssoLogin 走完一次跳转 IdP、回调的流程，返回登录票据
+func ssoLogin(t *testing.T, svc IOIDCService, idp *mockIdP) (string, error) {
+	t.Helper()
+	authURL, state, err := svc.AuthCodeURL(context.Background())
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	gotState, code := idp.authorize(authURL)
+	if gotState != state {
+		t.Fatalf("state = %q, want %q", gotState, state)
+	}
+	return svc.HandleCallback(context.Background(), state, code)
+}
+
+func TestOIDCService_LoginProvisionsUser(t *testing.T) {
+	idp := newMockIdP(t)
+	ctx := newOIDCTestContext(t, idp)
+	auth := NewAuthService(ctx)
+	bg := context.Background()
+
+	ticket, err := ssoLogin(t, ctx.GetOIDCService(), idp)
+	if err != nil {
+		t.Fatalf("HandleCallback: %v", err)
+	}
+	result, err := auth.LoginWithOIDC(bg, ticket, ClientInfo{IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("LoginWithOIDC: %v", err)
+	}
+	if result.Tokens == nil || result.Tokens.AccessToken != "fake-token" {
+		t.Errorf("tokens = %+v", result.Tokens)
+	}
+	if result.User.Username != "alice" || result.User.Source != models.UserSourceOIDC || result.User.Nickname != "Alice Liddell" {
+		t.Errorf("provisioned user = %+v", result.User)
+	}
+	if _, err := auth.LoginWithOIDC(bg, ticket, ClientInfo{}); err == nil {
+		t.Error("ticket should be single use")
+	}
+
+	// 再次登录按 issuer + subject 找到同一用户
+	ticket, err = ssoLogin(t, ctx.GetOIDCService(), idp)
+	if err != nil {
+		t.Fatalf("second HandleCallback: %v", err)
+	}
+	second, err := auth.LoginWithOIDC(bg, ticket, ClientInfo{})
+	if err != nil || second.User.ID != result.User.ID {
+		t.Errorf("second login user = %+v, err = %v", second, err)
+	}
+	var identities int64
+	ctx.DB().Model(&models.UserIdentity{}).Count(&identities)
+	if identities != 1 {
+		t.Errorf("identities = %d, want 1", identities)
+	}
+}
+
+// 默认不关联同名的本地账号，IdP 中同名用户不能绕过本地密码与两步验证登录
+func TestOIDCService_RejectsLocalAccountTakeover(t *testing.T) {
+	idp := newMockIdP(t)
+	ctx := newOIDCTestContext(t, idp)
+	idp.username = "admin"
+	admin := models.User{Username: "admin", Password: "x", Status: 1, Roles: []models.Role{{Name: "超级管理员", Code: models.RoleCodeSuperAdmin}}}
+	ctx.DB().Create(&admin)
+
+	if _, err := ssoLogin(t, ctx.GetOIDCService(), idp); err == nil {
+		t.Fatal("SSO login must not take over a local account")
+	}
+	var identities int64
+	ctx.DB().Model(&models.UserIdentity{}).Count(&identities)
+	if identities != 0 {
+		t.Errorf("identities = %d, want 0", identities)
+	}
+
+	// 开启 oidc_link_local 后超级管理员仍不关联
+	ctx = newOIDCTestContext(t, idp, func(cfg *config.Config) { cfg.OIDCLinkLocal = true })
+	admin = models.User{Username: "admin", Password: "x", Status: 1, Roles: []models.Role{{Name: "超级管理员", Code: models.RoleCodeSuperAdmin}}}
+	ctx.DB().Create(&admin)
+	if _, err := ssoLogin(t, ctx.GetOIDCService(), idp); err == nil {
+		t.Error("SSO login must never take over a super admin")
+	}
+}
+
+func TestOIDCService_LinksExistingUser(t *testing.T) {
+	idp := newMockIdP(t)
+	ctx := newOIDCTestContext(t, idp, func(cfg *config.Config) { cfg.OIDCLinkLocal = true })
+	local := models.User{Username: "alice", Password: "x", Status: 1}
+	ctx.DB().Create(&local)
+
+	ticket, err := ssoLogin(t, ctx.GetOIDCService(), idp)
+	if err != nil {
+		t.Fatalf("HandleCallback: %v", err)
+	}
+	user, err := ctx.GetOIDCService().ConsumeTicket(context.Background(), ticket)
+	if err != nil || user.ID != local.ID || user.Source != models.UserSourceLocal {
+		t.Fatalf("should link existing user: %+v err=%v", user, err)
+	}
+
+	// 同名但来自同一 IdP 的另一个身份不能再关联到该账号
+	idp.subject = "sub-impostor"
+	if _, err := ssoLogin(t, ctx.GetOIDCService(), idp); err == nil {
+		t.Error("second identity must not take over a linked account")
+	}
+}
+
+func TestOIDCService_RejectsInvalidCallback(t *testing.T) {
+	idp := newMockIdP(t)
+	ctx := newOIDCTestContext(t, idp)
+	svc := ctx.GetOIDCService()
+	bg := context.Background()
+
+	if _, err := svc.HandleCallback(bg, "unknown-state", "code"); err == nil {
+		t.Error("unknown state should be rejected")
+	}
+
+	// state 只能使用一次
+	authURL, state, _ := svc.AuthCodeURL(bg)
+	_, code := idp.authorize(authURL)
+	if _, err := svc.HandleCallback(bg, state, code); err != nil {
+		t.Fatalf("HandleCallback: %v", err)
+	}
+	if _, err := svc.HandleCallback(bg, state, code); err == nil {
+		t.Error("state replay should be rejected")
+	}
+
+	// ID Token 中的 nonce 与发起时不一致
+	idp.nonce = "forged-nonce"
+	if _, err := ssoLogin(t, svc, idp); err == nil {
+		t.Error("nonce mismatch should be rejected")
+	}
+	var users int64
+	ctx.DB().Model(&models.User{}).Where("username = ?", "alice").Count(&users)
+	if users != 1 {
+		t.Errorf("users = %d, want only the one from the valid callback", users)
+	}
+}
+
+func TestOIDCService_Disabled(t *testing.T) {
+	ctx := NewTestServiceContext(t, NewTestDB(t))
+	if ctx.GetOIDCService().Enabled() {
+		t.Error("should be disabled without issuer")
+	}
+	if _, _, err := ctx.GetOIDCService().AuthCodeURL(context.Background()); err == nil {
+		t.Error("AuthCodeURL should fail when disabled")
+	}
+}
@@ -5,8 +5,13 @@ import (
 	"time"
 
 	"github.com/lyuangg/gadmin/models"
+
+	"gorm.io/gorm"
 )
 
+// operationLogExportLimit 单次导出的最大条数
+const operationLogExportLimit = 10000
+
 // OperationLogService 操作日志服务
 type OperationLogService struct {
 	ctx ServiceContext
@@ -34,10 +39,51 @@ func (s *OperationLogService) GetOperationLogs(
 		pageSize = 100
 	}
 
+	query, err := s.filteredQuery(ctx, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	var total int64
 	var logs []models.OperationLog
 
-	query := s.ctx.DB().Model(&models.OperationLog{})
+	// 总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	s.fillLogNicknames(logs)
+	return logs, total, nil
+}
+
+// ExportOperationLogs 按与列表相同的筛选条件与数据范围查询操作日志，最多 operationLogExportLimit 条
+func (s *OperationLogService) ExportOperationLogs(ctx context.Context, filters map[string]string) ([]models.OperationLog, error) {
+	query, err := s.filteredQuery(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	var logs []models.OperationLog
+	if err := query.Limit(operationLogExportLimit).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	s.fillLogNicknames(logs)
+	return logs, nil
+}
+
+// filteredQuery 按当前用户的数据范围与筛选条件构造查询（含排序）
+func (s *OperationLogService) filteredQuery(ctx context.Context, filters map[string]string) (*gorm.DB, error) {
+	// 操作日志没有部门列，按操作人所在部门过滤
+	scope, err := s.ctx.GetDataScopeService().Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := s.ctx.DB().Model(&models.OperationLog{}).Scopes(scope.Scope("", "user_id"))
 
 	// 时间范围筛选（created_at）
 	if startStr, ok := filters["start_time"]; ok && startStr != "" {
@@ -56,8 +102,9 @@ func (s *OperationLogService) GetOperationLogs(
 	}
 
 	// 其他筛选
+	// 用户名同时匹配模拟登录的发起人，按管理员查询时包含其模拟期间的操作
 	if username, ok := filters["username"]; ok && username != "" {
-		query = query.Where("username LIKE ?", "%"+username+"%")
+		query = query.Where("username LIKE ? OR impersonator_name LIKE ?", "%"+username+"%", "%"+username+"%")
 	}
 	if method, ok := filters["method"]; ok && method != "" {
 		query = query.Where("method = ?", method)
@@ -83,19 +130,7 @@ func (s *OperationLogService) GetOperationLogs(
 		query = query.Order("id DESC")
 	}
 
-	// 总数
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	// 分页查询
-	offset := (page - 1) * pageSize
-	if err := query.Offset(offset).Limit(pageSize).Find(&logs).Error; err != nil {
-		return nil, 0, err
-	}
-
-	s.fillLogNicknames(logs)
-	return logs, total, nil
+	return query, nil
 }
 
 // CleanOldLogs 清理旧操作日志，仅保留最近 retain 条（按 id 倒序）。返回删除行数。
@@ -11,7 +11,7 @@ func TestOperationLogService_GetOperationLogs(t *testing.T) {
 	db := NewTestDB(t)
 	ctx := NewTestServiceContext(t, db)
 	svc := NewOperationLogService(ctx)
-	bg := context.Background()
+	bg := Unrestricted(context.Background())
 
 	logs, total, err := svc.GetOperationLogs(bg, 1, 10, nil)
 	if err != nil {
@@ -47,7 +47,7 @@ func TestOperationLogService_GetOperationLogs_Filter(t *testing.T) {
 	db := NewTestDB(t)
 	ctx := NewTestServiceContext(t, db)
 	svc := NewOperationLogService(ctx)
-	bg := context.Background()
+	bg := Unrestricted(context.Background())
 
 	_ = db.Create(&models.OperationLog{UserID: 0, Username: "a", Method: "GET", Path: "/a", StatusCode: 200}).Error
 	_ = db.Create(&models.OperationLog{UserID: 0, Username: "b", Method: "POST", Path: "/b", StatusCode: 201}).Error
@@ -59,6 +59,41 @@ func TestOperationLogService_GetOperationLogs_Filter(t *testing.T) {
 	if total != 1 || len(logs) != 1 || logs[0].Method != "POST" {
 		t.Errorf("filter by method: total=%d len=%d", total, len(logs))
 	}
+
+	// 用户名同时匹配模拟登录的发起人；与其他条件组合时 OR 不能吞掉其他条件
+	_ = db.Create(&models.OperationLog{UserID: 5, Username: "ops", ImpersonatorID: 1, ImpersonatorName: "root", Method: "GET", Path: "/c", StatusCode: 200}).Error
+	_ = db.Create(&models.OperationLog{UserID: 1, Username: "root", Method: "POST", Path: "/d", StatusCode: 200}).Error
+	logs, total, err = svc.GetOperationLogs(bg, 1, 10, map[string]string{"username": "root", "method": "GET"})
+	if err != nil {
+		t.Fatalf("GetOperationLogs filter: %v", err)
+	}
+	if total != 1 || len(logs) != 1 || logs[0].Username != "ops" {
+		t.Errorf("filter by impersonator: total=%d len=%d", total, len(logs))
+	}
+}
+
+func TestOperationLogService_ExportOperationLogs(t *testing.T) {
+	db := NewTestDB(t)
+	ctx := NewTestServiceContext(t, db)
+	svc := NewOperationLogService(ctx)
+	bg := Unrestricted(context.Background())
+
+	for _, method := range []string{"POST", "DELETE", "POST"} {
+		if err := db.Create(&models.OperationLog{UserID: 1, Username: "test", Method: method, Path: "/api/test", StatusCode: 200}).Error; err != nil {
+			t.Fatalf("create log: %v", err)
+		}
+	}
+
+	logs, err := svc.ExportOperationLogs(bg, map[string]string{"method": "POST"})
+	if err != nil {
+		t.Fatalf("ExportOperationLogs: %v", err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("expected 2 POST logs, got %d", len(logs))
+	}
+	if logs[0].ID < logs[1].ID {
+		t.Errorf("expected newest first, got ids %d, %d", logs[0].ID, logs[1].ID)
+	}
 }
 
 func TestOperationLogService_CleanOldLogs(t *testing.T) {
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 未配置时的密码策略默认值
+const (
+	defaultPasswordMinLength  = 8
+	defaultPasswordMinClasses = 3
+	defaultPasswordHistory    = 5
+	// generatedPasswordLength 重置密码时随机生成的最小长度
+	generatedPasswordLength = 12
+)
+
+// commonPasswords 内置的常见弱密码，比较时不区分大小写
+var commonPasswords = []string{
+	"123456", "12345678", "123456789", "1234567890", "111111", "000000", "888888", "666666",
+	"123123", "654321", "112233", "abc123", "abcd1234", "a123456", "a1234567", "qwerty",
+	"qwerty123", "qwe123", "1qaz2wsx", "1q2w3e4r", "zxcvbnm", "asdfghjkl", "password", "password1",
+	"password123", "passw0rd", "p@ssw0rd", "p@ssword", "admin", "admin123", "admin@123", "admin888",
+	"root", "root123", "iloveyou", "welcome", "welcome1", "letmein", "monkey", "dragon",
+	"woaini1314", "aa123456", "qq123456", "test123", "changeme",
+}
+
+// 随机密码使用的字符集，去掉了 0/O、1/l/I 等易混淆字符
+const (
+	passwordLowerChars  = "abcdefghijkmnpqrstuvwxyz"
+	passwordUpperChars  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	passwordDigitChars  = "23456789"
+	passwordSymbolChars = "!@#$%^&*-_=+?"
+)
+
+// passwordPolicy 密码复杂度、历史与有效期要求
+type passwordPolicy struct {
+	minLength  int
+	minClasses int
+	denyList   map[string]struct{}
+	history    int
+	maxAge     time.Duration
+}
+
+// PasswordService 密码策略：所有设置密码的入口（创建用户、修改密码、管理员改密、重置密码）统一经此校验与加密
+type PasswordService struct {
+	ctx ServiceContext
+}
+
+// NewPasswordService 创建密码策略服务实例
+func NewPasswordService(ctx ServiceContext) *PasswordService {
+	return &PasswordService{ctx: ctx}
+}
+
+// Validate 校验密码是否满足策略；user.ID 为 0 表示新建用户，不检查历史密码
+func (s *PasswordService) Validate(ctx context.Context, user *models.User, password string) error {
+	switch user.Source {
+	case models.UserSourceLDAP:
+		return errors.BadRequestMsg("LDAP 账号的密码由目录服务管理，不能在此修改")
+	case models.UserSourceOIDC:
+		return errors.BadRequestMsg("单点登录账号没有本地密码，不能在此修改")
+	}
+	policy := s.policy()
+
+	if len([]rune(password)) < policy.minLength {
+		return errors.BadRequestMsg(fmt.Sprintf("密码长度不能少于%d位", policy.minLength))
+	}
+	if passwordClasses(password) < policy.minClasses {
+		return errors.BadRequestMsg(fmt.Sprintf("密码须至少包含大写字母、小写字母、数字、符号中的%d类", policy.minClasses))
+	}
+	lower := strings.ToLower(password)
+	if _, ok := policy.denyList[lower]; ok {
+		return errors.BadRequestMsg("密码过于常见，请更换")
+	}
+	if user.Username != "" && lower == strings.ToLower(user.Username) {
+		return errors.BadRequestMsg("密码不能与用户名相同")
+	}
+	if user.ID == 0 || policy.history == 0 {
+		return nil
+	}
+
+	// 当前密码也算作最近使用过的密码（升级前设置的密码不在历史表中）
+	hashes := []string{user.Password}
+	var history []models.PasswordHistory
+	if err := s.ctx.DB().Where("user_id = ?", user.ID).Order("id DESC").Limit(policy.history).Find(&history).Error; err != nil {
+		return err
+	}
+	for _, h := range history {
+		hashes = append(hashes, h.PasswordHash)
+	}
+	for _, hash := range hashes {
+		if hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return errors.BadRequestMsg(fmt.Sprintf("不能使用最近%d次用过的密码", policy.history))
+		}
+	}
+	return nil
+}
+
+// SetPassword 校验通过后加密写入 user.Password 并更新修改时间，调用方负责保存 user，保存后调用 RecordHistory
+func (s *PasswordService) SetPassword(ctx context.Context, user *models.User, password string) error {
+	if err := s.Validate(ctx, user, password); err != nil {
+		return err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.InternalErrorMsg("密码加密失败")
+	}
+	now := time.Now()
+	user.Password = string(hashed)
+	user.PasswordChangedAt = &now
+	return nil
+}
+
+// RecordHistory 将用户当前密码写入历史，只保留最近 N 条
+func (s *PasswordService) RecordHistory(ctx context.Context, user *models.User) error {
+	policy := s.policy()
+	if policy.history == 0 {
+		// 不限制重复使用时不保留历史
+		return s.ctx.DB().Where("user_id = ?", user.ID).Delete(&models.PasswordHistory{}).Error
+	}
+	if err := s.ctx.DB().Create(&models.PasswordHistory{UserID: user.ID, PasswordHash: user.Password}).Error; err != nil {
+		return err
+	}
+	var keepIDs []uint
+	err := s.ctx.DB().Model(&models.PasswordHistory{}).Where("user_id = ?", user.ID).
+		Order("id DESC").Limit(policy.history).Pluck("id", &keepIDs).Error
+	if err != nil {
+		return err
+	}
+	return s.ctx.DB().Where("user_id = ? AND id NOT IN ?", user.ID, keepIDs).Delete(&models.PasswordHistory{}).Error
+}
+
+// IsExpired 密码超过有效期时返回 true；未配置有效期或 LDAP、单点登录账号始终为 false
+func (s *PasswordService) IsExpired(user *models.User) bool {
+	maxAge := s.policy().maxAge
+	if maxAge <= 0 || user.Source == models.UserSourceLDAP || user.Source == models.UserSourceOIDC {
+		return false
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return time.Since(changedAt) > maxAge
+}
+
+// Generate 使用 crypto/rand 生成满足策略的随机密码：四类字符各至少一个
+func (s *PasswordService) Generate() (string, error) {
+	length := max(s.policy().minLength, generatedPasswordLength)
+	sets := []string{passwordLowerChars, passwordUpperChars, passwordDigitChars, passwordSymbolChars}
+	all := strings.Join(sets, "")
+
+	out := make([]byte, 0, length)
+	for i := 0; i < length; i++ {
+		chars := all
+		if i < len(sets) {
+			chars = sets[i]
+		}
+		c, err := randomChar(chars)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, c)
+	}
+	// 打乱顺序，避免固定位置的字符类别
+	for i := len(out) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		out[i], out[j.Int64()] = out[j.Int64()], out[i]
+	}
+	return string(out), nil
+}
+
+func (s *PasswordService) policy() passwordPolicy {
+	cfg := s.ctx.GetConfig()
+	p := passwordPolicy{
+		minLength:  defaultPasswordMinLength,
+		minClasses: defaultPasswordMinClasses,
+		denyList:   make(map[string]struct{}, len(commonPasswords)+len(cfg.PasswordDenyList)),
+		history:    defaultPasswordHistory,
+	}
+	if cfg.PasswordMinLength > 0 {
+		p.minLength = cfg.PasswordMinLength
+	}
+	if cfg.PasswordMinClasses != nil {
+		p.minClasses = min(max(*cfg.PasswordMinClasses, 0), 4)
+	}
+	if cfg.PasswordHistory != nil {
+		p.history = max(*cfg.PasswordHistory, 0)
+	}
+	if cfg.PasswordMaxAgeDays > 0 {
+		p.maxAge = time.Duration(cfg.PasswordMaxAgeDays) * 24 * time.Hour
+	}
+	for _, pwd := range commonPasswords {
+		p.denyList[pwd] = struct{}{}
+	}
+	for _, pwd := range cfg.PasswordDenyList {
+		if pwd = strings.TrimSpace(pwd); pwd != "" {
+			p.denyList[strings.ToLower(pwd)] = struct{}{}
+		}
+	}
+	return p
+}
+
+// passwordClasses 统计密码包含的字符类别数：大写字母、小写字母、数字、其他符号
+func passwordClasses(password string) int {
+	var upper, lower, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return upper + lower + digit + symbol
+}
+
+func randomChar(chars string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+	if err != nil {
+		return 0, err
+	}
+	return chars[n.Int64()], nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lyuangg/gadmin/config"
+	"github.com/lyuangg/gadmin/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordService_Validate(t *testing.T) {
+	db := NewTestDB(t)
+	ctx := NewTestServiceContext(t, db, WithConfig(&config.Config{PasswordDenyList: []string{"Company#2024"}}))
+	svc := NewPasswordService(ctx)
+	bg := context.Background()
+	user := &models.User{Username: "Alice#2024"}
+
+	cases := []struct {
+		password string
+		ok       bool
+	}{
+		{"Ab1#", false},         // 长度不足
+		{"abcdefgh12", false},   // 只有两类字符
+		{"P@ssw0rd", false},     // 内置弱密码（不区分大小写）
+		{"company#2024", false}, // 配置的禁用密码
+		{"alice#2024", false},   // 与用户名相同
+		{"Tiger#Lake9", true},
+		{"tigerlake#9", true},
+	}
+	for _, tc := range cases {
+		err := svc.Validate(bg, user, tc.password)
+		if (err == nil) != tc.ok {
+			t.Errorf("Validate(%q) err = %v, want ok=%v", tc.password, err, tc.ok)
+		}
+	}
+}
+
+func TestPasswordService_RejectsRecentPasswords(t *testing.T) {
+	db := NewTestDB(t)
+	ctx := NewTestServiceContext(t, db, WithConfig(&config.Config{PasswordHistory: intPtr(2)}))
+	svc := NewPasswordService(ctx)
+	bg := context.Background()
+
+	user := &models.User{Username: "alice", Status: 1}
+	change := func(password string) error {
+		if err := svc.SetPassword(bg, user, password); err != nil {
+			return err
+		}
+		if err := db.Save(user).Error; err != nil {
+			t.Fatalf("save user: %v", err)
+		}
+		return svc.RecordHistory(bg, user)
+	}
+
+	for _, p := range []string{"First#Pass1", "Second#Pass2", "Third#Pass3"} {
+		if err := change(p); err != nil {
+			t.Fatalf("change to %s: %v", p, err)
+		}
+	}
+	if err := change("Second#Pass2"); err == nil {
+		t.Error("password within history should be rejected")
+	}
+	if err := change("First#Pass1"); err != nil {
+		t.Errorf("password older than history should be allowed: %v", err)
+	}
+
+	var count int64
+	db.Model(&models.PasswordHistory{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 2 {
+		t.Errorf("history count = %d, want 2", count)
+	}
+}
+
+// 显式配置为 0 时关闭对应要求，而不是回到默认值
+func TestPasswordService_ZeroDisablesClassesAndHistory(t *testing.T) {
+	db := NewTestDB(t)
+	ctx := NewTestServiceContext(t, db, WithConfig(&config.Config{PasswordMinClasses: intPtr(0), PasswordHistory: intPtr(0)}))
+	svc := NewPasswordService(ctx)
+	bg := context.Background()
+
+	user := &models.User{Username: "alice", Status: 1}
+	db.Create(user)
+	for i := 0; i < 2; i++ {
+		if err := svc.SetPassword(bg, user, "onlylowercase"); err != nil {
+			t.Fatalf("SetPassword #%d: %v", i+1, err)
+		}
+		db.Save(user)
+		if err := svc.RecordHistory(bg, user); err != nil {
+			t.Fatalf("RecordHistory: %v", err)
+		}
+	}
+
+	var count int64
+	db.Model(&models.PasswordHistory{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("history count = %d, want 0", count)
+	}
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestPasswordService_IsExpired(t *testing.T) {
+	db := NewTestDB(t)
+	ctx := NewTestServiceContext(t, db)
+	if NewPasswordService(ctx).IsExpired(&models.User{CreatedAt: time.Now().AddDate(-1, 0, 0)}) {
+		t.Error("max age disabled by default")
+	}
+
+	svc := NewPasswordService(NewTestServiceContext(t, db, WithConfig(&config.Config{PasswordMaxAgeDays: 30})))
+	old := time.Now().AddDate(0, 0, -31)
+	recent := time.Now().AddDate(0, 0, -1)
+	if !svc.IsExpired(&models.User{CreatedAt: old}) {
+		t.Error("password never changed since creation should expire")
+	}
+	if svc.IsExpired(&models.User{CreatedAt: old, PasswordChangedAt: &recent}) {
+		t.Error("recently changed password should not expire")
+	}
+}
+
+func TestPasswordService_Generate(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewPasswordService(NewTestServiceContext(t, db, WithConfig(&config.Config{PasswordMinClasses: intPtr(4)})))
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		p, err := svc.Generate()
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		if err := svc.Validate(context.Background(), &models.User{}, p); err != nil {
+			t.Errorf("generated %q violates policy: %v", p, err)
+		}
+		seen[p] = true
+	}
+	if len(seen) < 20 {
+		t.Error("generated passwords should not repeat")
+	}
+}
+
+func TestAuthService_Login_PasswordExpired(t *testing.T) {
+	db := NewTestDB(t)
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass123"), bcrypt.DefaultCost)
+	changedAt := time.Now().AddDate(0, 0, -100)
+	db.Create(&models.User{Username: "alice", Password: string(hashed), Status: 1, PasswordChangedAt: &changedAt})
+	ctx := NewTestServiceContext(t, db, WithConfig(&config.Config{PasswordMaxAgeDays: 90}))
+
+	result, err := NewAuthService(ctx).Login(context.Background(), "alice", "pass123", "cid", "val", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !result.PasswordExpired || !result.MustChangePassword || result.Tokens == nil {
+		t.Errorf("expired password should still log in and be flagged: %+v", result)
+	}
+	var user models.User
+	db.Where("username = ?", "alice").First(&user)
+	if !user.MustChangePassword {
+		t.Error("expired password should set must_change_password")
+	}
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"context"
 	stderrors "errors"
+	"sync"
+	"time"
 
 	"github.com/lyuangg/gadmin/errors"
 	"github.com/lyuangg/gadmin/models"
@@ -13,6 +15,16 @@ import (
 // PermissionService 权限服务
 type PermissionService struct {
 	ctx ServiceContext
+
+	mu         sync.RWMutex
+	matchers   map[uint]roleMatchers // 按角色缓存的预编译权限，权限中间件逐请求使用
+	generation uint64                // 每次清空缓存加一；加载期间发生过清空时，加载结果不写入缓存
+}
+
+// roleMatchers 单个角色的预编译权限及加载时间
+type roleMatchers struct {
+	matchers []*PermissionMatcher
+	loadedAt time.Time
 }
 
 // NewPermissionService 创建权限服务实例
@@ -20,18 +32,18 @@ func NewPermissionService(ctx ServiceContext) *PermissionService {
 	return &PermissionService{ctx: ctx}
 }
 
-// GetPermissionsByRoleIDs 根据角色 ID 列表查询合并去重后的权限（使用模型 + Preload，表名由 NamingStrategy 统一处理）
+// GetPermissionsByRoleIDs 根据角色 ID 列表查询合并去重后的权限，包含从上级角色继承的权限（使用模型 + Preload，表名由 NamingStrategy 统一处理）
 func (s *PermissionService) GetPermissionsByRoleIDs(ctx context.Context, roleIDs []uint) ([]models.Permission, error) {
 	if len(roleIDs) == 0 {
 		return nil, nil
 	}
-	var roles []models.Role
-	if err := s.ctx.DB().Where("id IN ?", roleIDs).Preload("Permissions").Find(&roles).Error; err != nil {
+	byRole, err := permissionsWithInherited(s.ctx.DB(), roleIDs)
+	if err != nil {
 		return nil, err
 	}
 	permMap := make(map[uint]models.Permission)
-	for _, r := range roles {
-		for _, p := range r.Permissions {
+	for _, perms := range byRole {
+		for _, p := range perms {
 			permMap[p.ID] = p
 		}
 	}
@@ -42,6 +54,87 @@ func (s *PermissionService) GetPermissionsByRoleIDs(ctx context.Context, roleIDs
 	return permissions, nil
 }
 
+// GetMatchersByRoleIDs 返回角色权限（含继承）的预编译匹配器（按权限 ID 去重），缓存未命中或过期的角色才查询数据库
+func (s *PermissionService) GetMatchersByRoleIDs(ctx context.Context, roleIDs []uint) ([]*PermissionMatcher, error) {
+	if len(roleIDs) == 0 {
+		return nil, nil
+	}
+	ttl := time.Duration(s.ctx.GetConfig().PermissionCacheSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+
+	cached := make(map[uint][]*PermissionMatcher, len(roleIDs))
+	var missing []uint
+	s.mu.RLock()
+	generation := s.generation
+	for _, roleID := range roleIDs {
+		if entry, ok := s.matchers[roleID]; ok && time.Since(entry.loadedAt) < ttl {
+			cached[roleID] = entry.matchers
+		} else {
+			missing = append(missing, roleID)
+		}
+	}
+	s.mu.RUnlock()
+
+	if len(missing) > 0 {
+		// 已删除的角色同样缓存为空，避免反复查询
+		byRole, err := permissionsWithInherited(s.ctx.DB(), missing)
+		if err != nil {
+			return nil, err
+		}
+		loaded := make(map[uint][]*PermissionMatcher, len(missing))
+		for _, roleID := range missing {
+			loaded[roleID] = CompilePermissions(byRole[roleID])
+		}
+
+		now := time.Now()
+		s.mu.Lock()
+		// 查询期间缓存被清空（权限已变更）时，查到的可能是变更前的数据，只用于本次请求，不写入缓存
+		store := s.generation == generation
+		if store && s.matchers == nil {
+			s.matchers = make(map[uint]roleMatchers)
+		}
+		for roleID, matchers := range loaded {
+			if store {
+				s.matchers[roleID] = roleMatchers{matchers: matchers, loadedAt: now}
+			}
+			cached[roleID] = matchers
+		}
+		s.mu.Unlock()
+	}
+
+	seen := make(map[uint]bool)
+	var result []*PermissionMatcher
+	for _, roleID := range roleIDs {
+		for _, m := range cached[roleID] {
+			if !seen[m.Permission.ID] {
+				seen[m.Permission.ID] = true
+				result = append(result, m)
+			}
+		}
+	}
+	return result, nil
+}
+
+// InvalidateCache 角色权限、权限或路由导入变更后调用：清空本实例缓存，并通过 PermissionCacheBroadcaster 通知其他实例
+func (s *PermissionService) InvalidateCache(ctx context.Context) {
+	s.ClearCache()
+	if b := s.ctx.GetPermissionCacheBroadcaster(); b != nil {
+		if err := b.Publish(ctx); err != nil {
+			s.ctx.Logger().WarnContext(ctx, "广播权限缓存失效失败", "error", err)
+		}
+	}
+}
+
+// ClearCache 只清空本实例缓存，供 PermissionCacheBroadcaster 收到其他实例的通知时调用
+func (s *PermissionService) ClearCache() {
+	s.mu.Lock()
+	s.matchers = nil
+	s.generation++
+	s.mu.Unlock()
+}
+
 // GetPermissions 获取权限列表（分页和筛选）
 func (s *PermissionService) GetPermissions(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.Permission, int64, error) {
 	if page < 1 {
@@ -100,11 +193,18 @@ func (s *PermissionService) GetPermissions(ctx context.Context, page, pageSize i
 	return permissions, total, nil
 }
 
-// CreatePermission 创建权限
-func (s *PermissionService) CreatePermission(ctx context.Context, path, method, name, group, description string) (*models.Permission, error) {
-	// 检查权限是否已存在
+// CreatePermission 创建权限，effect 为空时为允许规则；method 为 * 时匹配所有方法
+func (s *PermissionService) CreatePermission(ctx context.Context, path, method, name, group, description, effect string) (*models.Permission, error) {
+	if effect == "" {
+		effect = models.PermissionEffectAllow
+	}
+	if effect != models.PermissionEffectAllow && effect != models.PermissionEffectDeny {
+		return nil, errors.BadRequestMsg("无效的规则效果")
+	}
+
+	// 检查权限是否已存在（同一路径与方法可以同时有允许和拒绝规则）
 	var existingPermission models.Permission
-	if err := s.ctx.DB().Where("path = ? AND method = ?", path, method).First(&existingPermission).Error; err != nil {
+	if err := s.ctx.DB().Where("path = ? AND method = ? AND effect = ?", path, method, effect).First(&existingPermission).Error; err != nil {
 		if !stderrors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, err
 		}
@@ -119,11 +219,13 @@ func (s *PermissionService) CreatePermission(ctx context.Context, path, method,
 		Group:       group,
 		Description: description,
 		AutoImport:  false,
+		Effect:      effect,
 	}
 
 	if err := s.ctx.DB().Create(&permission).Error; err != nil {
 		return nil, err
 	}
+	s.InvalidateCache(ctx)
 
 	return &permission, nil
 }
@@ -153,6 +255,7 @@ func (s *PermissionService) UpdatePermission(ctx context.Context, permissionID u
 	if err := s.ctx.DB().Save(&permission).Error; err != nil {
 		return nil, err
 	}
+	s.InvalidateCache(ctx)
 
 	return &permission, nil
 }
@@ -174,6 +277,7 @@ func (s *PermissionService) DeletePermission(ctx context.Context, permissionID u
 	if err := s.ctx.DB().Delete(&permission).Error; err != nil {
 		return err
 	}
+	s.InvalidateCache(ctx)
 
 	return nil
 }
@@ -209,6 +313,7 @@ func (s *PermissionService) BatchDeletePermissions(ctx context.Context, ids []ui
 	if err := s.ctx.DB().Where("id IN ?", ids).Delete(&models.Permission{}).Error; err != nil {
 		return err
 	}
+	s.InvalidateCache(ctx)
 
 	return nil
 }
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/lyuangg/gadmin/models"
+)
+
+// pathParamPattern 权限路径中的路径参数，如 /admin/api/users/:id 中的 :id
+var pathParamPattern = regexp.MustCompile(`:\w+`)
+
+// PermissionMatcher 预编译的权限匹配器：/* 前缀与 :param 路径参数的正则只在构造时处理一次，逐请求只做比较
+type PermissionMatcher struct {
+	Permission models.Permission
+
+	prefix string         // 路径以 /* 结尾时的前缀（不含结尾的 /）
+	re     *regexp.Regexp // 路径含 :param 时的匹配正则
+}
+
+// NewPermissionMatcher 编译单条权限
+func NewPermissionMatcher(permission models.Permission) *PermissionMatcher {
+	m := &PermissionMatcher{Permission: permission}
+	if strings.HasSuffix(permission.Path, "/*") {
+		m.prefix = strings.TrimSuffix(permission.Path, "/*")
+	}
+	if strings.Contains(permission.Path, ":") {
+		pattern := pathParamPattern.ReplaceAllString(regexp.QuoteMeta(permission.Path), `[^/]+`)
+		m.re = regexp.MustCompile("^" + pattern + "$")
+	}
+	return m
+}
+
+// CompilePermissions 编译一组权限
+func CompilePermissions(permissions []models.Permission) []*PermissionMatcher {
+	matchers := make([]*PermissionMatcher, len(permissions))
+	for i, p := range permissions {
+		matchers[i] = NewPermissionMatcher(p)
+	}
+	return matchers
+}
+
+// Match 请求方法与路径是否命中该权限，不考虑规则效果
+// 方法不区分大小写，* 匹配所有方法；路径支持精确匹配、/* 前缀匹配或路径参数匹配
+func (m *PermissionMatcher) Match(reqPath, reqMethod string) bool {
+	if m.Permission.Method != models.PermissionMethodAny && !strings.EqualFold(m.Permission.Method, reqMethod) {
+		return false
+	}
+	if m.Permission.Path == reqPath {
+		return true
+	}
+	if m.prefix != "" && strings.HasPrefix(reqPath, m.prefix+"/") {
+		return true
+	}
+	return m.re != nil && m.re.MatchString(reqPath)
+}
+
+// Deny 是否为拒绝规则；Effect 为空的历史数据按允许处理
+func (m *PermissionMatcher) Deny() bool {
+	return m.Permission.Effect == models.PermissionEffectDeny
+}
+
+// EvaluatePermissions 判断一组权限是否允许访问请求：命中任一拒绝规则即拒绝（deny 优先），
+// 否则命中任一允许规则才允许，未命中任何规则时拒绝。结果与规则顺序、规则来自哪个角色无关
+func EvaluatePermissions(matchers []*PermissionMatcher, reqPath, reqMethod string) bool {
+	allowed := false
+	for _, m := range matchers {
+		if !m.Match(reqPath, reqMethod) {
+			continue
+		}
+		if m.Deny() {
+			return false
+		}
+		allowed = true
+	}
+	return allowed
+}
+
+// anyMethods 权限方法为 * 时逐个判断的请求方法
+var anyMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// permissionGranted 判断一组权限是否允许访问权限 p 描述的接口；p 的方法为 * 时，
+// 任一方法被允许（且未被拒绝）即可，不要求拥有同样为 * 的权限
+func permissionGranted(matchers []*PermissionMatcher, p models.Permission) bool {
+	if p.Method != models.PermissionMethodAny {
+		return EvaluatePermissions(matchers, p.Path, p.Method)
+	}
+	for _, method := range anyMethods {
+		if EvaluatePermissions(matchers, p.Path, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionCacheBroadcaster 多实例部署时在实例间同步权限缓存失效，例如基于 Redis Pub/Sub 实现：
+// 本实例修改角色权限、权限或导入路由后调用 Publish；实现方收到其他实例的消息时调用 IPermissionService.ClearCache
+type PermissionCacheBroadcaster interface {
+	Publish(ctx context.Context) error
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/lyuangg/gadmin/models"
+	"gorm.io/gorm"
+)
+
+func TestPermissionMatcher_Match(t *testing.T) {
+	tests := []struct {
+		name      string
+		perm      models.Permission
+		reqPath   string
+		reqMethod string
+		want      bool
+	}{
+		{"精确匹配", models.Permission{Path: "/admin/api/users", Method: "GET"}, "/admin/api/users", "GET", true},
+		{"方法不区分大小写", models.Permission{Path: "/admin/api/users", Method: "get"}, "/admin/api/users", "GET", true},
+		{"方法不同", models.Permission{Path: "/admin/api/users", Method: "GET"}, "/admin/api/users", "POST", false},
+		{"前缀通配", models.Permission{Path: "/admin/api/users/*", Method: "GET"}, "/admin/api/users/1/roles", "GET", true},
+		{"前缀通配不含自身", models.Permission{Path: "/admin/api/users/*", Method: "GET"}, "/admin/api/users", "GET", false},
+		{"路径参数", models.Permission{Path: "/admin/api/roles/:id/permissions", Method: "PUT"}, "/admin/api/roles/3/permissions", "PUT", true},
+		{"路径参数不跨段", models.Permission{Path: "/admin/api/roles/:id", Method: "GET"}, "/admin/api/roles/1/extra", "GET", false},
+		{"路径中的点不作为通配", models.Permission{Path: "/admin/api/files/:name.txt", Method: "GET"}, "/admin/api/files/axtxt", "GET", false},
+		{"方法通配", models.Permission{Path: "/admin/api/users", Method: "*"}, "/admin/api/users", "DELETE", true},
+		{"方法通配仍校验路径", models.Permission{Path: "/admin/api/users", Method: "*"}, "/admin/api/roles", "GET", false},
+		{"拒绝规则同样命中", models.Permission{Path: "/admin/api/users", Method: "GET", Effect: "deny"}, "/admin/api/users", "GET", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewPermissionMatcher(tt.perm).Match(tt.reqPath, tt.reqMethod); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.reqPath, tt.reqMethod, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluatePermissions(t *testing.T) {
+	allowUsersAll := models.Permission{Path: "/admin/api/users/*", Method: "*"}
+	allowUsersList := models.Permission{Path: "/admin/api/users", Method: "*"}
+	denyUserDelete := models.Permission{Path: "/admin/api/users/:id", Method: "DELETE", Effect: "deny"}
+	denyUsersAll := models.Permission{Path: "/admin/api/users/*", Method: "*", Effect: "deny"}
+	allowUserGet := models.Permission{Path: "/admin/api/users/:id", Method: "GET"}
+	denyRoles := models.Permission{Path: "/admin/api/roles", Method: "post", Effect: "deny"}
+	allowRoles := models.Permission{Path: "/admin/api/roles", Method: "POST", Effect: "allow"}
+
+	tests := []struct {
+		name      string
+		perms     []models.Permission
+		reqPath   string
+		reqMethod string
+		want      bool
+	}{
+		{"无规则拒绝", nil, "/admin/api/users", "GET", false},
+		{"未命中任何规则拒绝", []models.Permission{allowUserGet}, "/admin/api/roles", "GET", false},
+		{"Effect 为空按允许处理", []models.Permission{{Path: "/admin/api/users", Method: "GET"}}, "/admin/api/users", "GET", true},
+		{"通配允许下的 GET", []models.Permission{allowUsersAll, denyUserDelete}, "/admin/api/users/1", "GET", true},
+		{"通配允许下的 PUT", []models.Permission{allowUsersAll, denyUserDelete}, "/admin/api/users/1", "PUT", true},
+		{"除 DELETE 外：DELETE 被拒绝", []models.Permission{allowUsersAll, denyUserDelete}, "/admin/api/users/1", "DELETE", false},
+		{"拒绝规则的参数不跨段", []models.Permission{allowUsersAll, denyUserDelete}, "/admin/api/users/1/roles", "DELETE", true},
+		{"前缀拒绝覆盖更具体的允许", []models.Permission{allowUserGet, denyUsersAll}, "/admin/api/users/1", "GET", false},
+		{"前缀拒绝不含前缀自身", []models.Permission{allowUsersList, denyUsersAll}, "/admin/api/users", "GET", true},
+		{"同一路径方法的允许与拒绝", []models.Permission{allowRoles, denyRoles}, "/admin/api/roles", "POST", false},
+		{"拒绝方法不区分大小写", []models.Permission{allowRoles, denyRoles}, "/admin/api/roles", "Post", false},
+		{"只有拒绝规则", []models.Permission{denyUserDelete}, "/admin/api/users/1", "GET", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 结果与规则顺序无关：正序与逆序都应得到相同结果
+			reversed := make([]models.Permission, len(tt.perms))
+			for i, p := range tt.perms {
+				reversed[len(tt.perms)-1-i] = p
+			}
+			for _, perms := range [][]models.Permission{tt.perms, reversed} {
+				if got := EvaluatePermissions(CompilePermissions(perms), tt.reqPath, tt.reqMethod); got != tt.want {
+					t.Errorf("EvaluatePermissions(%v, %q, %q) = %v, want %v", perms, tt.reqPath, tt.reqMethod, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+type countingBroadcaster struct {
+	published int
+}
+
+func (b *countingBroadcaster) Publish(context.Context) error {
+	b.published++
+	return nil
+}
+
+// 缓存命中时不再读取数据库；分配权限、权限增删改后失效并广播
+func TestPermissionService_GetMatchersByRoleIDs_Cache(t *testing.T) {
+	db := NewTestDB(t)
+	broadcaster := &countingBroadcaster{}
+	sc := NewTestServiceContext(t, db, WithPermissionCacheBroadcaster(broadcaster))
+	svc := sc.GetPermissionService()
+	roleSvc := NewRoleService(sc)
+	bg := context.Background()
+
+	users := models.Permission{Path: "/admin/api/users", Method: "GET"}
+	roles := models.Permission{Path: "/admin/api/roles", Method: "GET"}
+	db.Create(&users)
+	db.Create(&roles)
+	role := models.Role{Name: "运维", Permissions: []models.Permission{users}}
+	db.Create(&role)
+
+	matchers, err := svc.GetMatchersByRoleIDs(bg, []uint{role.ID, 999})
+	if err != nil {
+		t.Fatalf("GetMatchersByRoleIDs: %v", err)
+	}
+	if len(matchers) != 1 || !matchers[0].Match("/admin/api/users", "GET") {
+		t.Fatalf("matchers = %+v", matchers)
+	}
+
+	// 绕过服务直接改库，缓存未失效时仍是旧结果
+	db.Model(&role).Association("Permissions").Append(&roles)
+	matchers, _ = svc.GetMatchersByRoleIDs(bg, []uint{role.ID})
+	if len(matchers) != 1 {
+		t.Errorf("expected cached result, got %d matchers", len(matchers))
+	}
+
+	if err := roleSvc.AssignPermissions(bg, role.ID, []uint{users.ID, roles.ID}); err != nil {
+		t.Fatalf("AssignPermissions: %v", err)
+	}
+	matchers, _ = svc.GetMatchersByRoleIDs(bg, []uint{role.ID})
+	if len(matchers) != 2 {
+		t.Errorf("after AssignPermissions got %d matchers, want 2", len(matchers))
+	}
+
+	if err := svc.DeletePermission(bg, roles.ID); err != nil {
+		t.Fatalf("DeletePermission: %v", err)
+	}
+	matchers, _ = svc.GetMatchersByRoleIDs(bg, []uint{role.ID})
+	if len(matchers) != 1 {
+		t.Errorf("after DeletePermission got %d matchers, want 1", len(matchers))
+	}
+	if broadcaster.published != 2 {
+		t.Errorf("published = %d, want 2", broadcaster.published)
+	}
+}
+
+func TestPermissionService_GetMatchersByRoleIDs_InvalidatedDuringLoad(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewPermissionService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	users := models.Permission{Path: "/admin/api/users", Method: "GET"}
+	db.Create(&users)
+	role := models.Role{Name: "运维", Permissions: []models.Permission{users}}
+	db.Create(&role)
+
+	// 模拟加载查询进行中时其他请求修改了权限并清空缓存
+	cleared := false
+	db.Callback().Query().After("gorm:query").Register("test:invalidate", func(*gorm.DB) {
+		if !cleared {
+			cleared = true
+			svc.ClearCache()
+		}
+	})
+
+	matchers, err := svc.GetMatchersByRoleIDs(bg, []uint{role.ID})
+	if err != nil {
+		t.Fatalf("GetMatchersByRoleIDs: %v", err)
+	}
+	if len(matchers) != 1 {
+		t.Fatalf("matchers = %+v, want 1", matchers)
+	}
+	if _, ok := svc.matchers[role.ID]; ok {
+		t.Error("matchers loaded across an invalidation should not be cached")
+	}
+
+	// 未发生清空的加载照常写入缓存
+	if _, err := svc.GetMatchersByRoleIDs(bg, []uint{role.ID}); err != nil {
+		t.Fatalf("GetMatchersByRoleIDs: %v", err)
+	}
+	if _, ok := svc.matchers[role.ID]; !ok {
+		t.Error("expected matchers to be cached")
+	}
+}
+
+// legacyMatchPermission 预编译之前的匹配方式：每次请求为每条权限编译正则，仅供基准对比
+func legacyMatchPermission(permPath, permMethod, reqPath, reqMethod string) bool {
+	if !strings.EqualFold(permMethod, reqMethod) {
+		return false
+	}
+	if permPath == reqPath {
+		return true
+	}
+	if strings.HasSuffix(permPath, "/*") && strings.HasPrefix(reqPath, strings.TrimSuffix(permPath, "/*")+"/") {
+		return true
+	}
+	pattern := regexp.QuoteMeta(permPath)
+	pattern = regexp.MustCompile(`:\w+`).ReplaceAllString(pattern, `[^/]+`)
+	re, err := regexp.Compile("^" + pattern + "$")
+	return err == nil && re.MatchString(reqPath)
+}
+
+// benchmarkPermissionSetup 一个拥有 60 条权限的角色，请求命中最后一条
+func benchmarkPermissionSetup(b *testing.B) (*PermissionService, []uint, string) {
+	db := NewTestDB(b)
+	var perms []models.Permission
+	for i := 0; i < 60; i++ {
+		perms = append(perms, models.Permission{Path: fmt.Sprintf("/admin/api/resource%d/:id", i), Method: "PUT"})
+	}
+	role := models.Role{Name: "bench", Permissions: perms}
+	db.Create(&role)
+	svc := NewPermissionService(NewTestServiceContext(b, db))
+	return svc, []uint{role.ID}, "/admin/api/resource59/42"
+}
+
+// 每次请求查询数据库并逐条编译正则（原 PermissionMiddleware 的做法）
+func BenchmarkPermissionCheck_Uncached(b *testing.B) {
+	svc, roleIDs, path := benchmarkPermissionSetup(b)
+	bg := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		perms, err := svc.GetPermissionsByRoleIDs(bg, roleIDs)
+		if err != nil {
+			b.Fatal(err)
+		}
+		matched := false
+		for _, p := range perms {
+			if legacyMatchPermission(p.Path, p.Method, path, "PUT") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.Fatal("expected match")
+		}
+	}
+}
+
+// 按角色缓存的预编译匹配器
+func BenchmarkPermissionCheck_Cached(b *testing.B) {
+	svc, roleIDs, path := benchmarkPermissionSetup(b)
+	bg := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		matchers, err := svc.GetMatchersByRoleIDs(bg, roleIDs)
+		if err != nil {
+			b.Fatal(err)
+		}
+		matched := false
+		for _, m := range matchers {
+			if m.Match(path, "PUT") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.Fatal("expected match")
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lyuangg/gadmin/models"
+
+	"gorm.io/gorm"
+)
+
+// 路由已不存在的自动导入权限（孤儿权限）的处理策略，见配置 permission_orphan_policy
+const (
+	PermissionOrphanMark   = "mark"   // 标记为失效，保留角色分配；路由恢复后自动取消标记
+	PermissionOrphanDelete = "delete" // 解除角色分配后删除
+)
+
+// RoutePermission 路由扫描得到的、配置了权限信息的接口
+type RoutePermission struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Name   string `json:"name"`
+	Group  string `json:"group"`
+}
+
+// PermissionSyncItem 同步报告中的一条权限
+type PermissionSyncItem struct {
+	ID        uint     `json:"id,omitempty"` // 新增的权限在预览时为 0
+	Method    string   `json:"method"`
+	Path      string   `json:"path"`
+	Name      string   `json:"name"`
+	Group     string   `json:"group"`
+	Changes   []string `json:"changes,omitempty"`    // 变更说明，仅 changed
+	RoleCount int64    `json:"role_count,omitempty"` // 仍分配给的角色数，仅 orphaned
+	Marked    bool     `json:"marked,omitempty"`     // 此前已标记为失效，仅 orphaned
+}
+
+// PermissionSyncReport 路由与权限表的差异
+type PermissionSyncReport struct {
+	DryRun   bool                 `json:"dry_run"`
+	Policy   string               `json:"policy"`   // 孤儿权限的处理策略
+	Added    []PermissionSyncItem `json:"added"`    // 新路由，将创建权限
+	Changed  []PermissionSyncItem `json:"changed"`  // 名称、分组变化，或由手动创建转为自动导入、路由恢复
+	Orphaned []PermissionSyncItem `json:"orphaned"` // 自动导入但路由已不存在
+}
+
+// SyncRoutePermissions 对比路由与权限表（只比较允许规则），dryRun 时只返回差异；否则创建、更新权限，
+// 并按 permission_orphan_policy 处理孤儿权限。手动创建且不对应路由的权限（如通配规则）不算孤儿
+func (s *PermissionService) SyncRoutePermissions(ctx context.Context, routes []RoutePermission, dryRun bool) (*PermissionSyncReport, error) {
+	policy := s.ctx.GetConfig().PermissionOrphanPolicy
+	if policy != PermissionOrphanDelete {
+		policy = PermissionOrphanMark
+	}
+	report := &PermissionSyncReport{
+		DryRun:   dryRun,
+		Policy:   policy,
+		Added:    []PermissionSyncItem{},
+		Changed:  []PermissionSyncItem{},
+		Orphaned: []PermissionSyncItem{},
+	}
+
+	var existing []models.Permission
+	if err := s.ctx.DB().Where("effect = ?", models.PermissionEffectAllow).Order("id ASC").Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*models.Permission, len(existing))
+	for i := range existing {
+		key := routeKey(existing[i].Method, existing[i].Path)
+		if _, ok := byKey[key]; !ok {
+			byKey[key] = &existing[i]
+		}
+	}
+
+	routeKeys := make(map[string]bool, len(routes))
+	var creates, updates []models.Permission
+	for _, route := range routes {
+		key := routeKey(route.Method, route.Path)
+		if routeKeys[key] {
+			continue
+		}
+		routeKeys[key] = true
+
+		p, ok := byKey[key]
+		if !ok {
+			report.Added = append(report.Added, PermissionSyncItem{Method: route.Method, Path: route.Path, Name: route.Name, Group: route.Group})
+			creates = append(creates, models.Permission{
+				Path:       route.Path,
+				Method:     route.Method,
+				Name:       route.Name,
+				Group:      route.Group,
+				AutoImport: true,
+				Effect:     models.PermissionEffectAllow,
+			})
+			continue
+		}
+
+		var changes []string
+		if p.Name != route.Name {
+			changes = append(changes, fmt.Sprintf("名称: %s → %s", p.Name, route.Name))
+		}
+		if p.Group != route.Group {
+			changes = append(changes, fmt.Sprintf("分组: %s → %s", p.Group, route.Group))
+		}
+		if !p.AutoImport {
+			changes = append(changes, "转为自动导入")
+		}
+		if p.Orphaned {
+			changes = append(changes, "路由已恢复，取消失效标记")
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		report.Changed = append(report.Changed, PermissionSyncItem{ID: p.ID, Method: p.Method, Path: p.Path, Name: route.Name, Group: route.Group, Changes: changes})
+		updated := *p
+		updated.Name, updated.Group, updated.AutoImport, updated.Orphaned = route.Name, route.Group, true, false
+		updates = append(updates, updated)
+	}
+
+	var orphans []models.Permission
+	for _, p := range existing {
+		if !p.AutoImport || routeKeys[routeKey(p.Method, p.Path)] {
+			continue
+		}
+		roleCount := s.ctx.DB().Model(&p).Association("Roles").Count()
+		report.Orphaned = append(report.Orphaned, PermissionSyncItem{ID: p.ID, Method: p.Method, Path: p.Path, Name: p.Name, Group: p.Group, RoleCount: roleCount, Marked: p.Orphaned})
+		orphans = append(orphans, p)
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	err := s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		for i := range creates {
+			if err := tx.Create(&creates[i]).Error; err != nil {
+				return err
+			}
+		}
+		for _, p := range updates {
+			if err := tx.Model(&models.Permission{}).Where("id = ?", p.ID).Updates(map[string]interface{}{
+				"name":        p.Name,
+				"group":       p.Group,
+				"auto_import": true,
+				"orphaned":    false,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		for i := range orphans {
+			p := &orphans[i]
+			if policy == PermissionOrphanMark {
+				if !p.Orphaned {
+					if err := tx.Model(p).Update("orphaned", true).Error; err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			// 菜单的权限绑定保留：菜单按未删除的权限判断可见，绑定的权限全部删除后菜单对普通用户隐藏，而不是变成所有人可见
+			if err := tx.Model(p).Association("Roles").Clear(); err != nil {
+				return err
+			}
+			if err := tx.Delete(p).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range report.Added {
+		s.ctx.Logger().InfoContext(ctx, "自动导入权限", "method", item.Method, "path", item.Path, "name", item.Name, "group", item.Group)
+	}
+	for _, item := range report.Changed {
+		s.ctx.Logger().InfoContext(ctx, "更新权限信息", "method", item.Method, "path", item.Path, "name", item.Name, "group", item.Group, "changes", item.Changes)
+	}
+	for _, item := range report.Orphaned {
+		if item.Marked && policy == PermissionOrphanMark {
+			continue
+		}
+		s.ctx.Logger().WarnContext(ctx, "权限对应的路由已不存在", "method", item.Method, "path", item.Path, "name", item.Name, "role_count", item.RoleCount, "policy", policy)
+	}
+
+	// 导入、更新或删除了权限，清空按角色缓存的权限匹配器
+	s.InvalidateCache(ctx)
+
+	return report, nil
+}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lyuangg/gadmin/config"
+	"github.com/lyuangg/gadmin/models"
+
+	"gorm.io/gorm"
+)
+
+// seedSyncPermissions 准备各类已有权限：名称变化的自动导入权限、与路由同路径的手动权限、
+// 路由已删除且分配给角色的自动导入权限、不对应路由的手动权限和拒绝规则
+func seedSyncPermissions(t *testing.T, db *gorm.DB, ctx ServiceContext) (renamed, manual, orphan, custom, deny models.Permission, roleID uint) {
+	t.Helper()
+	renamed = models.Permission{Path: "/admin/api/users", Method: "GET", Name: "用户列表", Group: "用户管理", AutoImport: true}
+	manual = models.Permission{Path: "/admin/api/roles", Method: "GET", Name: "查询角色列表", Group: "角色管理"}
+	orphan = models.Permission{Path: "/admin/api/legacy", Method: "POST", Name: "旧接口", Group: "旧分组", AutoImport: true}
+	custom = models.Permission{Path: "/admin/api/reports/*", Method: "*", Name: "报表"}
+	deny = models.Permission{Path: "/admin/api/users", Method: "DELETE", Name: "禁止删除用户", Effect: models.PermissionEffectDeny}
+	for _, p := range []*models.Permission{&renamed, &manual, &orphan, &custom, &deny} {
+		if err := db.Create(p).Error; err != nil {
+			t.Fatalf("create permission: %v", err)
+		}
+	}
+	role, err := NewRoleService(ctx).CreateRole(context.Background(), "r1", "", 0)
+	if err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := NewRoleService(ctx).AssignPermissions(context.Background(), role.ID, []uint{renamed.ID, orphan.ID}); err != nil {
+		t.Fatalf("AssignPermissions: %v", err)
+	}
+	return renamed, manual, orphan, custom, deny, role.ID
+}
+
+var syncRoutes = []RoutePermission{
+	{Method: "GET", Path: "/admin/api/users", Name: "查询用户列表", Group: "用户管理"},
+	{Method: "GET", Path: "/admin/api/roles", Name: "查询角色列表", Group: "角色管理"},
+	{Method: "POST", Path: "/admin/api/users", Name: "创建用户", Group: "用户管理"},
+	{Method: "DELETE", Path: "/admin/api/users", Name: "删除用户", Group: "用户管理"},
+}
+
+func TestPermissionService_SyncRoutePermissions_DryRun(t *testing.T) {
+	db := NewTestDB(t)
+	ctx := NewTestServiceContext(t, db)
+	svc := NewPermissionService(ctx)
+	renamed, manual, orphan, _, _, _ := seedSyncPermissions(t, db, ctx)
+
+	var before int64
+	db.Model(&models.Permission{}).Count(&before)
+
+	report, err := svc.SyncRoutePermissions(context.Background(), syncRoutes, true)
+	if err != nil {
+		t.Fatalf("SyncRoutePermissions: %v", err)
+	}
+	if !report.DryRun || report.Policy != PermissionOrphanMark {
+		t.Errorf("DryRun=%v Policy=%q, want true mark", report.DryRun, report.Policy)
+	}
+
+	// 拒绝规则不参与对比，同路径的允许规则仍需新增
+	if len(report.Added) != 2 || report.Added[0].Path != "/admin/api/users" || report.Added[0].Method != "POST" || report.Added[1].Method != "DELETE" {
+		t.Errorf("Added = %+v, want POST and DELETE /admin/api/users", report.Added)
+	}
+	if len(report.Changed) != 2 {
+		t.Fatalf("Changed = %+v, want 2", report.Changed)
+	}
+	if report.Changed[0].ID != renamed.ID || report.Changed[0].Name != "查询用户列表" || len(report.Changed[0].Changes) != 1 {
+		t.Errorf("Changed[0] = %+v, want rename of %d", report.Changed[0], renamed.ID)
+	}
+	if report.Changed[1].ID != manual.ID || report.Changed[1].Changes[0] != "转为自动导入" {
+		t.Errorf("Changed[1] = %+v, want %d 转为自动导入", report.Changed[1], manual.ID)
+	}
+	// 手动创建、不对应路由的权限不算孤儿
+	if len(report.Orphaned) != 1 || report.Orphaned[0].ID != orphan.ID || report.Orphaned[0].RoleCount != 1 || report.Orphaned[0].Marked {
+		t.Errorf("Orphaned = %+v, want %d with 1 role", report.Orphaned, orphan.ID)
+	}
+
+	var after int64
+	db.Model(&models.Permission{}).Count(&after)
+	var p models.Permission
+	db.First(&p, renamed.ID)
+	if after != before || p.Name != "用户列表" {
+		t.Errorf("dry run changed database: count %d → %d, name %q", before, after, p.Name)
+	}
+}
+
+func TestPermissionService_SyncRoutePermissions_Mark(t *testing.T) {
+	db := NewTestDB(t)
+	ctx := NewTestServiceContext(t, db)
+	svc := NewPermissionService(ctx)
+	bg := context.Background()
+	renamed, manual, orphan, custom, _, roleID := seedSyncPermissions(t, db, ctx)
+
+	report, err := svc.SyncRoutePermissions(bg, syncRoutes, false)
+	if err != nil {
+		t.Fatalf("SyncRoutePermissions: %v", err)
+	}
+	if report.DryRun || len(report.Added) != 2 || len(report.Changed) != 2 || len(report.Orphaned) != 1 {
+		t.Errorf("report = %+v", report)
+	}
+
+	var added models.Permission
+	if err := db.Where("path = ? AND method = ? AND effect = ?", "/admin/api/users", "DELETE", models.PermissionEffectAllow).First(&added).Error; err != nil {
+		t.Fatalf("added permission not found: %v", err)
+	}
+	if !added.AutoImport || added.Name != "删除用户" {
+		t.Errorf("added = %+v", added)
+	}
+	load := func(id uint) models.Permission {
+		var p models.Permission
+		db.First(&p, id)
+		return p
+	}
+	if p := load(renamed.ID); p.Name != "查询用户列表" {
+		t.Errorf("renamed.Name = %q", p.Name)
+	}
+	if p := load(manual.ID); !p.AutoImport {
+		t.Error("manual permission should become auto imported")
+	}
+	if p := load(custom.ID); p.Orphaned {
+		t.Error("manual permission without route should not be marked")
+	}
+
+	// 孤儿权限标记为失效，角色分配保留
+	if p := load(orphan.ID); !p.Orphaned {
+		t.Error("orphan should be marked")
+	}
+	perms, _ := svc.GetPermissionsByRoleIDs(bg, []uint{roleID})
+	if len(perms) != 2 {
+		t.Errorf("role permissions = %d, want 2", len(perms))
+	}
+
+	// 再次同步：已标记的孤儿仍出现在报告中，其余无差异
+	report, err = svc.SyncRoutePermissions(bg, syncRoutes, false)
+	if err != nil {
+		t.Fatalf("SyncRoutePermissions again: %v", err)
+	}
+	if len(report.Added) != 0 || len(report.Changed) != 0 || len(report.Orphaned) != 1 || !report.Orphaned[0].Marked {
+		t.Errorf("second report = %+v", report)
+	}
+
+	// 路由恢复后取消标记
+	routes := append([]RoutePermission{{Method: "POST", Path: "/admin/api/legacy", Name: "旧接口", Group: "旧分组"}}, syncRoutes...)
+	report, err = svc.SyncRoutePermissions(bg, routes, false)
+	if err != nil {
+		t.Fatalf("SyncRoutePermissions restore: %v", err)
+	}
+	if len(report.Changed) != 1 || report.Changed[0].ID != orphan.ID || len(report.Orphaned) != 0 {
+		t.Errorf("restore report = %+v", report)
+	}
+	if p := load(orphan.ID); p.Orphaned {
+		t.Error("restored route should clear the mark")
+	}
+}
+
+func TestPermissionService_SyncRoutePermissions_Delete(t *testing.T) {
+	db := NewTestDB(t)
+	ctx := NewTestServiceContext(t, db, WithConfig(&config.Config{PermissionOrphanPolicy: PermissionOrphanDelete}))
+	svc := NewPermissionService(ctx)
+	bg := context.Background()
+	renamed, _, orphan, _, _, roleID := seedSyncPermissions(t, db, ctx)
+
+	// 缓存角色权限，同步后应失效
+	if _, err := svc.GetMatchersByRoleIDs(bg, []uint{roleID}); err != nil {
+		t.Fatalf("GetMatchersByRoleIDs: %v", err)
+	}
+
+	report, err := svc.SyncRoutePermissions(bg, syncRoutes, false)
+	if err != nil {
+		t.Fatalf("SyncRoutePermissions: %v", err)
+	}
+	if report.Policy != PermissionOrphanDelete || len(report.Orphaned) != 1 {
+		t.Errorf("report = %+v", report)
+	}
+
+	var p models.Permission
+	if err := db.First(&p, orphan.ID).Error; err == nil {
+		t.Error("orphan should be deleted")
+	}
+	var count int64
+	db.Table("role_permissions").Where("permission_id = ?", orphan.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("orphan role assignments = %d, want 0", count)
+	}
+	matchers, _ := svc.GetMatchersByRoleIDs(bg, []uint{roleID})
+	if len(matchers) != 1 || matchers[0].Permission.ID != renamed.ID {
+		t.Errorf("matchers after sync = %d, want only %d", len(matchers), renamed.ID)
+	}
+}
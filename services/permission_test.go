@@ -21,7 +21,7 @@ func TestPermissionService_GetPermissions(t *testing.T) {
 		t.Errorf("expected 0 permissions, got total=%d len=%d", total, len(list))
 	}
 
-	_, _ = svc.CreatePermission(bg, "/api/users", "GET", "用户列表", "用户管理", "")
+	_, _ = svc.CreatePermission(bg, "/api/users", "GET", "用户列表", "用户管理", "", "")
 	list, total, err = svc.GetPermissions(bg, 1, 10, nil)
 	if err != nil {
 		t.Fatalf("GetPermissions: %v", err)
@@ -37,7 +37,7 @@ func TestPermissionService_CreatePermission(t *testing.T) {
 	svc := NewPermissionService(ctx)
 	bg := context.Background()
 
-	p, err := svc.CreatePermission(bg, "/api/roles", "POST", "创建角色", "角色管理", "描述")
+	p, err := svc.CreatePermission(bg, "/api/roles", "POST", "创建角色", "角色管理", "描述", "")
 	if err != nil {
 		t.Fatalf("CreatePermission: %v", err)
 	}
@@ -45,10 +45,25 @@ func TestPermissionService_CreatePermission(t *testing.T) {
 		t.Errorf("CreatePermission result: %+v", p)
 	}
 
-	_, err = svc.CreatePermission(bg, "/api/roles", "POST", "重复", "", "")
+	_, err = svc.CreatePermission(bg, "/api/roles", "POST", "重复", "", "", "")
 	if err == nil {
 		t.Error("expected error for duplicate path+method")
 	}
+	if p.Effect != models.PermissionEffectAllow {
+		t.Errorf("default effect = %q, want allow", p.Effect)
+	}
+
+	// 同一路径与方法可以另有一条拒绝规则，但不能重复
+	deny, err := svc.CreatePermission(bg, "/api/roles", "POST", "禁止创建角色", "", "", models.PermissionEffectDeny)
+	if err != nil || deny.Effect != models.PermissionEffectDeny {
+		t.Fatalf("create deny: %+v, %v", deny, err)
+	}
+	if _, err := svc.CreatePermission(bg, "/api/roles", "POST", "", "", "", models.PermissionEffectDeny); err == nil {
+		t.Error("expected error for duplicate deny rule")
+	}
+	if _, err := svc.CreatePermission(bg, "/api/users", "*", "", "", "", "block"); err == nil {
+		t.Error("expected error for invalid effect")
+	}
 }
 
 func TestPermissionService_UpdatePermission(t *testing.T) {
@@ -57,7 +72,7 @@ func TestPermissionService_UpdatePermission(t *testing.T) {
 	svc := NewPermissionService(ctx)
 	bg := context.Background()
 
-	created, _ := svc.CreatePermission(bg, "/update", "PUT", "旧名", "旧组", "")
+	created, _ := svc.CreatePermission(bg, "/update", "PUT", "旧名", "旧组", "", "")
 	updated, err := svc.UpdatePermission(bg, created.ID, "新名", "新组", "新描述")
 	if err != nil {
 		t.Fatalf("UpdatePermission: %v", err)
@@ -78,7 +93,7 @@ func TestPermissionService_DeletePermission(t *testing.T) {
 	svc := NewPermissionService(ctx)
 	bg := context.Background()
 
-	created, _ := svc.CreatePermission(bg, "/del", "DELETE", "待删", "", "")
+	created, _ := svc.CreatePermission(bg, "/del", "DELETE", "待删", "", "", "")
 	err := svc.DeletePermission(bg, created.ID)
 	if err != nil {
 		t.Fatalf("DeletePermission: %v", err)
@@ -105,8 +120,8 @@ func TestPermissionService_GetPermissionsByRoleIDs(t *testing.T) {
 	}
 
 	// 创建角色和权限并关联
-	role, _ := NewRoleService(ctx).CreateRole(bg, "r1", "")
-	p1, _ := svc.CreatePermission(bg, "/p1", "GET", "P1", "", "")
+	role, _ := NewRoleService(ctx).CreateRole(bg, "r1", "", 0)
+	p1, _ := svc.CreatePermission(bg, "/p1", "GET", "P1", "", "", "")
 	_ = NewRoleService(ctx).AssignPermissions(bg, role.ID, []uint{p1.ID})
 
 	perms, err = svc.GetPermissionsByRoleIDs(bg, []uint{role.ID})
@@ -124,8 +139,8 @@ func TestPermissionService_BatchDeletePermissions(t *testing.T) {
 	svc := NewPermissionService(ctx)
 	bg := context.Background()
 
-	p1, _ := svc.CreatePermission(bg, "/b1", "GET", "B1", "", "")
-	p2, _ := svc.CreatePermission(bg, "/b2", "GET", "B2", "", "")
+	p1, _ := svc.CreatePermission(bg, "/b1", "GET", "B1", "", "", "")
+	p2, _ := svc.CreatePermission(bg, "/b2", "GET", "B2", "", "", "")
 
 	err := svc.BatchDeletePermissions(bg, []uint{})
 	if err == nil {
@@ -142,3 +157,55 @@ func TestPermissionService_BatchDeletePermissions(t *testing.T) {
 		t.Errorf("expected 0 remaining, got %d", count)
 	}
 }
+
+func TestPermissionService_InheritedPermissions(t *testing.T) {
+	db := NewTestDB(t)
+	ctx := NewTestServiceContext(t, db)
+	svc := ctx.GetPermissionService()
+	roles := NewRoleService(ctx)
+	bg := context.Background()
+
+	auditor, _ := roles.CreateRole(bg, "审计员", "", 0)
+	senior, _ := roles.CreateRole(bg, "高级审计员", "", auditor.ID)
+	other, _ := roles.CreateRole(bg, "其他", "", 0)
+	pView, _ := NewPermissionService(ctx).CreatePermission(bg, "/logs", "GET", "查看日志", "", "", "")
+	pExport, _ := NewPermissionService(ctx).CreatePermission(bg, "/logs/export", "GET", "导出日志", "", "", "")
+	_ = roles.AssignPermissions(bg, auditor.ID, []uint{pView.ID})
+	_ = roles.AssignPermissions(bg, senior.ID, []uint{pExport.ID})
+
+	perms, err := svc.GetPermissionsByRoleIDs(bg, []uint{senior.ID})
+	if err != nil {
+		t.Fatalf("GetPermissionsByRoleIDs: %v", err)
+	}
+	if len(perms) != 2 {
+		t.Errorf("expected direct and inherited permissions, got %+v", perms)
+	}
+
+	matches := func(roleID uint) bool {
+		matchers, err := svc.GetMatchersByRoleIDs(bg, []uint{roleID})
+		if err != nil {
+			t.Fatalf("GetMatchersByRoleIDs: %v", err)
+		}
+		for _, m := range matchers {
+			if m.Match("/logs", "GET") {
+				return true
+			}
+		}
+		return false
+	}
+	if !matches(senior.ID) {
+		t.Error("expected senior auditor to inherit /logs")
+	}
+	if matches(other.ID) {
+		t.Error("unrelated role should not match /logs")
+	}
+
+	// 调整上级后缓存失效，立即按新的继承关系生效
+	parent := other.ID
+	if _, err := roles.UpdateRole(bg, senior.ID, "", "", &parent); err != nil {
+		t.Fatalf("UpdateRole parent: %v", err)
+	}
+	if matches(senior.ID) {
+		t.Error("expected inherited permission removed after changing parent")
+	}
+}
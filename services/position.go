@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+
+	"gorm.io/gorm"
+)
+
+// PositionService 岗位服务
+type PositionService struct {
+	ctx ServiceContext
+}
+
+// NewPositionService 创建岗位服务实例
+func NewPositionService(ctx ServiceContext) *PositionService {
+	return &PositionService{ctx: ctx}
+}
+
+// GetPositions 获取岗位列表（分页和筛选），默认按 sort、id 升序
+func (s *PositionService) GetPositions(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.Position, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	var total int64
+	var list []models.Position
+
+	query := s.ctx.DB().Model(&models.Position{})
+	if code := filters["code"]; code != "" {
+		query = query.Where("code LIKE ?", "%"+code+"%")
+	}
+	if name := filters["name"]; name != "" {
+		query = query.Where("name LIKE ?", "%"+name+"%")
+	}
+	if status := filters["status"]; status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	switch filters["order_by"] {
+	case "id", "id_asc":
+		query = query.Order("id ASC")
+	case "id_desc":
+		query = query.Order("id DESC")
+	default:
+		query = query.Order("sort ASC, id ASC")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return list, total, nil
+}
+
+// CreatePosition 创建岗位
+func (s *PositionService) CreatePosition(ctx context.Context, code, name string, sort int, status int, remark string) (*models.Position, error) {
+	var existing models.Position
+	if err := s.ctx.DB().Where("code = ?", code).First(&existing).Error; err != nil {
+		if !stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	} else {
+		return nil, errors.BadRequestMsg("岗位编码已存在")
+	}
+
+	position := models.Position{
+		Code:   code,
+		Name:   name,
+		Sort:   sort,
+		Status: status,
+		Remark: remark,
+	}
+	if err := s.ctx.DB().Create(&position).Error; err != nil {
+		return nil, err
+	}
+	// status 为零值时 Create 会使用列默认值（启用），需单独更新
+	if status != position.Status {
+		if err := s.ctx.DB().Model(&position).Update("status", status).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &position, nil
+}
+
+// UpdatePosition 更新岗位
+func (s *PositionService) UpdatePosition(ctx context.Context, id uint, code, name string, sort *int, status *int, remark string) (*models.Position, error) {
+	var position models.Position
+	if err := s.ctx.DB().Where("id = ?", id).First(&position).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NotFoundMsg("岗位不存在")
+		}
+		return nil, err
+	}
+
+	if code != "" {
+		var other models.Position
+		if err := s.ctx.DB().Where("code = ? AND id != ?", code, id).First(&other).Error; err != nil {
+			if !stderrors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+		} else {
+			return nil, errors.BadRequestMsg("岗位编码已存在")
+		}
+		position.Code = code
+	}
+	if name != "" {
+		position.Name = name
+	}
+	if sort != nil {
+		position.Sort = *sort
+	}
+	if status != nil {
+		position.Status = *status
+	}
+	position.Remark = remark
+
+	if err := s.ctx.DB().Save(&position).Error; err != nil {
+		return nil, err
+	}
+	return &position, nil
+}
+
+// DeletePosition 删除岗位，同时解除用户与该岗位的关联
+func (s *PositionService) DeletePosition(ctx context.Context, id uint) error {
+	var position models.Position
+	if err := s.ctx.DB().Where("id = ?", id).First(&position).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NotFoundMsg("岗位不存在")
+		}
+		return err
+	}
+
+	return s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(tx.NamingStrategy.JoinTableName("user_positions")).
+			Where("position_id = ?", id).Delete(map[string]interface{}{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&position).Error
+	})
+}
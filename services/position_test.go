@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lyuangg/gadmin/models"
+)
+
+func TestPositionService_CRUD(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewPositionService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	pm, err := svc.CreatePosition(bg, "pm", "产品经理", 2, 1, "")
+	if err != nil {
+		t.Fatalf("CreatePosition: %v", err)
+	}
+	if _, err := svc.CreatePosition(bg, "dev", "开发", 1, 1, ""); err != nil {
+		t.Fatalf("CreatePosition: %v", err)
+	}
+	if _, err := svc.CreatePosition(bg, "pm", "重复", 0, 1, ""); err == nil {
+		t.Error("expected error for duplicate code")
+	}
+
+	list, total, err := svc.GetPositions(bg, 1, 10, nil)
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	if total != 2 || list[0].Code != "dev" {
+		t.Errorf("expected 2 positions ordered by sort, got total=%d list=%+v", total, list)
+	}
+
+	if _, err := svc.UpdatePosition(bg, pm.ID, "dev", "", nil, nil, ""); err == nil {
+		t.Error("expected error when updating to an existing code")
+	}
+	status := 0
+	updated, err := svc.UpdatePosition(bg, pm.ID, "", "产品", nil, &status, "备注")
+	if err != nil {
+		t.Fatalf("UpdatePosition: %v", err)
+	}
+	if updated.Name != "产品" || updated.Status != 0 || updated.Code != "pm" {
+		t.Errorf("update result: %+v", updated)
+	}
+
+	user := models.User{Username: "u", Password: "x"}
+	db.Create(&user)
+	db.Model(&user).Association("Positions").Append(pm)
+	if err := svc.DeletePosition(bg, pm.ID); err != nil {
+		t.Fatalf("DeletePosition: %v", err)
+	}
+	if n := db.Model(&user).Association("Positions").Count(); n != 0 {
+		t.Errorf("expected user position removed, got %d", n)
+	}
+}
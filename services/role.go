@@ -56,15 +56,40 @@ func (s *RoleService) GetRoles(ctx context.Context, page, pageSize int, filters
 
 	// 分页查询
 	offset := (page - 1) * pageSize
-	if err := query.Preload("Permissions").Offset(offset).Limit(pageSize).Find(&roles).Error; err != nil {
+	if err := query.Preload("Permissions").Preload("DataScopeDepartments").
+		Preload("Parent", func(tx *gorm.DB) *gorm.DB { return tx.Select("id", "name") }).
+		Offset(offset).Limit(pageSize).Find(&roles).Error; err != nil {
 		return nil, 0, err
 	}
 
+	// 区分直接分配与从上级继承的权限
+	roleIDs := make([]uint, len(roles))
+	for i, r := range roles {
+		roleIDs[i] = r.ID
+	}
+	if len(roleIDs) > 0 {
+		byRole, err := permissionsWithInherited(s.ctx.DB(), roleIDs)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i := range roles {
+			direct := make(map[uint]bool, len(roles[i].Permissions))
+			for _, p := range roles[i].Permissions {
+				direct[p.ID] = true
+			}
+			for _, p := range byRole[roles[i].ID] {
+				if !direct[p.ID] {
+					roles[i].InheritedPermissions = append(roles[i].InheritedPermissions, p)
+				}
+			}
+		}
+	}
+
 	return roles, total, nil
 }
 
-// CreateRole 创建角色
-func (s *RoleService) CreateRole(ctx context.Context, name, description string) (*models.Role, error) {
+// CreateRole 创建角色，parentID 为 0 时没有上级角色
+func (s *RoleService) CreateRole(ctx context.Context, name, description string, parentID uint) (*models.Role, error) {
 	// 检查角色名是否已存在
 	var existingRole models.Role
 	if err := s.ctx.DB().Where("name = ?", name).First(&existingRole).Error; err != nil {
@@ -75,7 +100,12 @@ func (s *RoleService) CreateRole(ctx context.Context, name, description string)
 		return nil, errors.BadRequestMsg("角色名已存在")
 	}
 
+	if err := s.checkParent(0, parentID); err != nil {
+		return nil, err
+	}
+
 	role := models.Role{
+		ParentID:    parentID,
 		Name:        name,
 		Description: description,
 	}
@@ -87,8 +117,8 @@ func (s *RoleService) CreateRole(ctx context.Context, name, description string)
 	return &role, nil
 }
 
-// UpdateRole 更新角色
-func (s *RoleService) UpdateRole(ctx context.Context, roleID uint, name, description string) (*models.Role, error) {
+// UpdateRole 更新角色；parentID 为 nil 时不修改上级角色，指向 0 时取消上级
+func (s *RoleService) UpdateRole(ctx context.Context, roleID uint, name, description string, parentID *uint) (*models.Role, error) {
 	var role models.Role
 	if err := s.ctx.DB().Where("id = ?", roleID).First(&role).Error; err != nil {
 		if stderrors.Is(err, gorm.ErrRecordNotFound) {
@@ -97,7 +127,10 @@ func (s *RoleService) UpdateRole(ctx context.Context, roleID uint, name, descrip
 		return nil, err
 	}
 
-	if name != "" {
+	if name != "" && name != role.Name {
+		if role.IsSystem() {
+			return nil, errors.BadRequestMsg("系统角色不能改名")
+		}
 		// 检查角色名是否与其他角色冲突
 		var existingRole models.Role
 		if err := s.ctx.DB().Where("name = ? AND id != ?", name, roleID).First(&existingRole).Error; err != nil {
@@ -114,13 +147,59 @@ func (s *RoleService) UpdateRole(ctx context.Context, roleID uint, name, descrip
 		role.Description = description
 	}
 
+	parentChanged := parentID != nil && *parentID != role.ParentID
+	if parentChanged {
+		if role.IsSystem() {
+			return nil, errors.BadRequestMsg("系统角色不能设置上级角色")
+		}
+		if err := s.checkParent(role.ID, *parentID); err != nil {
+			return nil, err
+		}
+		role.ParentID = *parentID
+	}
+
 	if err := s.ctx.DB().Save(&role).Error; err != nil {
 		return nil, err
 	}
+	if parentChanged {
+		s.ctx.GetPermissionService().InvalidateCache(ctx)
+	}
 
 	return &role, nil
 }
 
+// checkParent 校验上级角色存在、不是超级管理员角色，且不会形成环（roleID 为 0 表示新建）
+func (s *RoleService) checkParent(roleID, parentID uint) error {
+	if parentID == 0 {
+		return nil
+	}
+	var parent models.Role
+	if err := s.ctx.DB().Where("id = ?", parentID).First(&parent).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.BadRequestMsg("上级角色不存在")
+		}
+		return err
+	}
+	// 超级管理员按角色编码放行全部权限，不能通过继承获得
+	if parent.Code == models.RoleCodeSuperAdmin {
+		return errors.BadRequestMsg("超级管理员角色不能作为上级角色")
+	}
+	if roleID == 0 {
+		return nil
+	}
+
+	parents, err := roleParents(s.ctx.DB())
+	if err != nil {
+		return err
+	}
+	for _, id := range roleLineage(parents, parentID) {
+		if id == roleID {
+			return errors.BadRequestMsg("上级角色不能是自己或自己的下级角色")
+		}
+	}
+	return nil
+}
+
 // DeleteRole 删除角色
 func (s *RoleService) DeleteRole(ctx context.Context, roleID uint) error {
 	var role models.Role
@@ -130,15 +209,27 @@ func (s *RoleService) DeleteRole(ctx context.Context, roleID uint) error {
 		}
 		return err
 	}
+	if role.IsSystem() {
+		return errors.BadRequestMsg("系统角色不能删除")
+	}
+	var children int64
+	if err := s.ctx.DB().Model(&models.Role{}).Where("parent_id = ?", roleID).Count(&children).Error; err != nil {
+		return err
+	}
+	if children > 0 {
+		return errors.BadRequestMsg("请先调整下级角色的上级角色")
+	}
 
 	// 清除关联关系
 	s.ctx.DB().Model(&role).Association("Users").Clear()
 	s.ctx.DB().Model(&role).Association("Permissions").Clear()
+	s.ctx.DB().Model(&role).Association("DataScopeDepartments").Clear()
 
 	// 删除角色
 	if err := s.ctx.DB().Delete(&role).Error; err != nil {
 		return err
 	}
+	s.ctx.GetPermissionService().InvalidateCache(ctx)
 
 	return nil
 }
@@ -163,6 +254,56 @@ func (s *RoleService) AssignPermissions(ctx context.Context, roleID uint, permis
 
 	// 分配权限
 	s.ctx.DB().Model(&role).Association("Permissions").Replace(permissions)
+	s.ctx.GetPermissionService().InvalidateCache(ctx)
 
 	return nil
 }
+
+// SetRequire2FA 设置角色是否强制要求两步验证；已开启的用户不受影响，未绑定的用户下次登录时须先完成绑定
+func (s *RoleService) SetRequire2FA(ctx context.Context, roleID uint, required bool) error {
+	var role models.Role
+	if err := s.ctx.DB().Where("id = ?", roleID).First(&role).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NotFoundMsg("角色不存在")
+		}
+		return err
+	}
+	return s.ctx.DB().Model(&role).Update("require_2fa", required).Error
+}
+
+// SetDataScope 设置角色的数据范围；custom 时 departmentIDs 为可见的部门，其他范围会清空已选部门
+func (s *RoleService) SetDataScope(ctx context.Context, roleID uint, dataScope string, departmentIDs []uint) error {
+	switch dataScope {
+	case models.DataScopeAll, models.DataScopeDept, models.DataScopeDeptAndChildren, models.DataScopeSelf, models.DataScopeCustom:
+	default:
+		return errors.BadRequestMsg("无效的数据范围")
+	}
+
+	var role models.Role
+	if err := s.ctx.DB().Where("id = ?", roleID).First(&role).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NotFoundMsg("角色不存在")
+		}
+		return err
+	}
+
+	var departments []models.Department
+	if dataScope == models.DataScopeCustom {
+		if len(departmentIDs) == 0 {
+			return errors.BadRequestMsg("自定义数据范围至少选择一个部门")
+		}
+		if err := s.ctx.DB().Where("id IN ?", departmentIDs).Find(&departments).Error; err != nil {
+			return err
+		}
+		if len(departments) != len(uniqueUints(departmentIDs)) {
+			return errors.BadRequestMsg("部门不存在")
+		}
+	}
+
+	return s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&role).Update("data_scope", dataScope).Error; err != nil {
+			return err
+		}
+		return tx.Model(&role).Association("DataScopeDepartments").Replace(departments)
+	})
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/lyuangg/gadmin/models"
+
+	"gorm.io/gorm"
+)
+
+// roleParents 返回所有角色的上级角色（角色 ID -> 上级角色 ID），角色数量有限，一次性加载
+func roleParents(db *gorm.DB) (map[uint]uint, error) {
+	var roles []models.Role
+	if err := db.Select("id", "parent_id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	parents := make(map[uint]uint, len(roles))
+	for _, r := range roles {
+		parents[r.ID] = r.ParentID
+	}
+	return parents, nil
+}
+
+// roleLineage 返回 roleID 及其所有上级角色的 ID（由近及远）；数据中存在环时在重复处停止
+func roleLineage(parents map[uint]uint, roleID uint) []uint {
+	lineage := []uint{roleID}
+	seen := map[uint]bool{roleID: true}
+	for id := parents[roleID]; id != 0 && !seen[id]; id = parents[id] {
+		seen[id] = true
+		lineage = append(lineage, id)
+	}
+	return lineage
+}
+
+// permissionsWithInherited 返回每个角色直接拥有与从上级角色继承的全部权限（按权限 ID 去重、升序）
+// 已删除或不存在的角色对应为空
+func permissionsWithInherited(db *gorm.DB, roleIDs []uint) (map[uint][]models.Permission, error) {
+	parents, err := roleParents(db)
+	if err != nil {
+		return nil, err
+	}
+
+	lineages := make(map[uint][]uint, len(roleIDs))
+	var allIDs []uint
+	seen := make(map[uint]bool)
+	for _, roleID := range roleIDs {
+		lineages[roleID] = roleLineage(parents, roleID)
+		for _, id := range lineages[roleID] {
+			if !seen[id] {
+				seen[id] = true
+				allIDs = append(allIDs, id)
+			}
+		}
+	}
+
+	var roles []models.Role
+	if err := db.Where("id IN ?", allIDs).Preload("Permissions").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	direct := make(map[uint][]models.Permission, len(roles))
+	for _, r := range roles {
+		direct[r.ID] = r.Permissions
+	}
+
+	result := make(map[uint][]models.Permission, len(roleIDs))
+	for roleID, lineage := range lineages {
+		permMap := make(map[uint]models.Permission)
+		for _, id := range lineage {
+			for _, p := range direct[id] {
+				permMap[p.ID] = p
+			}
+		}
+		perms := make([]models.Permission, 0, len(permMap))
+		for _, p := range permMap {
+			perms = append(perms, p)
+		}
+		sort.Slice(perms, func(i, j int) bool { return perms[i].ID < perms[j].ID })
+		result[roleID] = perms
+	}
+	return result, nil
+}
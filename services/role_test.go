@@ -23,7 +23,7 @@ func TestRoleService_GetRoles(t *testing.T) {
 	}
 
 	// 创建一条再查
-	created, err := svc.CreateRole(bg, "测试角色", "描述")
+	created, err := svc.CreateRole(bg, "测试角色", "描述", 0)
 	if err != nil {
 		t.Fatalf("CreateRole: %v", err)
 	}
@@ -45,7 +45,7 @@ func TestRoleService_CreateRole(t *testing.T) {
 	svc := NewRoleService(ctx)
 	bg := context.Background()
 
-	role, err := svc.CreateRole(bg, "管理员", "系统管理员")
+	role, err := svc.CreateRole(bg, "管理员", "系统管理员", 0)
 	if err != nil {
 		t.Fatalf("CreateRole: %v", err)
 	}
@@ -54,7 +54,7 @@ func TestRoleService_CreateRole(t *testing.T) {
 	}
 
 	// 同名应失败
-	_, err = svc.CreateRole(bg, "管理员", "")
+	_, err = svc.CreateRole(bg, "管理员", "", 0)
 	if err == nil {
 		t.Error("expected error for duplicate name")
 	}
@@ -66,8 +66,8 @@ func TestRoleService_UpdateRole(t *testing.T) {
 	svc := NewRoleService(ctx)
 	bg := context.Background()
 
-	created, _ := svc.CreateRole(bg, "旧名", "旧描述")
-	updated, err := svc.UpdateRole(bg, created.ID, "新名", "新描述")
+	created, _ := svc.CreateRole(bg, "旧名", "旧描述", 0)
+	updated, err := svc.UpdateRole(bg, created.ID, "新名", "新描述", nil)
 	if err != nil {
 		t.Fatalf("UpdateRole: %v", err)
 	}
@@ -76,7 +76,7 @@ func TestRoleService_UpdateRole(t *testing.T) {
 	}
 
 	// 不存在的 ID
-	_, err = svc.UpdateRole(bg, 99999, "x", "")
+	_, err = svc.UpdateRole(bg, 99999, "x", "", nil)
 	if err == nil {
 		t.Error("expected error for non-existent role")
 	}
@@ -88,7 +88,7 @@ func TestRoleService_DeleteRole(t *testing.T) {
 	svc := NewRoleService(ctx)
 	bg := context.Background()
 
-	created, _ := svc.CreateRole(bg, "待删", "")
+	created, _ := svc.CreateRole(bg, "待删", "", 0)
 	err := svc.DeleteRole(bg, created.ID)
 	if err != nil {
 		t.Fatalf("DeleteRole: %v", err)
@@ -99,6 +99,29 @@ func TestRoleService_DeleteRole(t *testing.T) {
 	}
 }
 
+func TestRoleService_SystemRoleProtected(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewRoleService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	role := models.Role{Name: "超级管理员", Code: models.RoleCodeSuperAdmin}
+	db.Create(&role)
+
+	if _, err := svc.UpdateRole(bg, role.ID, "普通角色", "", nil); err == nil {
+		t.Error("expected error when renaming system role")
+	}
+	updated, err := svc.UpdateRole(bg, role.ID, "超级管理员", "新描述", nil)
+	if err != nil {
+		t.Fatalf("UpdateRole description: %v", err)
+	}
+	if updated.Code != models.RoleCodeSuperAdmin || updated.Description != "新描述" {
+		t.Errorf("UpdateRole result: %+v", updated)
+	}
+	if err := svc.DeleteRole(bg, role.ID); err == nil {
+		t.Error("expected error when deleting system role")
+	}
+}
+
 func TestRoleService_AssignPermissions(t *testing.T) {
 	db := NewTestDB(t)
 	ctx := NewTestServiceContext(t, db)
@@ -114,7 +137,7 @@ func TestRoleService_AssignPermissions(t *testing.T) {
 		t.Fatalf("create permission: %v", err)
 	}
 
-	role, _ := svc.CreateRole(bg, "有权限角色", "")
+	role, _ := svc.CreateRole(bg, "有权限角色", "", 0)
 	err := svc.AssignPermissions(bg, role.ID, []uint{perm.ID})
 	if err != nil {
 		t.Fatalf("AssignPermissions: %v", err)
@@ -124,3 +147,111 @@ func TestRoleService_AssignPermissions(t *testing.T) {
 		t.Errorf("expected 1 permission, got %d", count)
 	}
 }
+
+func TestRoleService_SetDataScope(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewRoleService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	role, _ := svc.CreateRole(bg, "部门主管", "", 0)
+	var created models.Role
+	db.First(&created, role.ID)
+	if created.DataScope != models.DataScopeAll {
+		t.Errorf("default data scope = %q, want %q", created.DataScope, models.DataScopeAll)
+	}
+	dept := models.Department{Name: "研发"}
+	db.Create(&dept)
+
+	if err := svc.SetDataScope(bg, role.ID, "unknown", nil); err == nil {
+		t.Error("expected error for invalid data scope")
+	}
+	if err := svc.SetDataScope(bg, role.ID, models.DataScopeCustom, nil); err == nil {
+		t.Error("expected error for custom scope without departments")
+	}
+	if err := svc.SetDataScope(bg, role.ID, models.DataScopeCustom, []uint{dept.ID, dept.ID + 100}); err == nil {
+		t.Error("expected error for missing department")
+	}
+	if err := svc.SetDataScope(bg, role.ID, models.DataScopeCustom, []uint{dept.ID}); err != nil {
+		t.Fatalf("SetDataScope custom: %v", err)
+	}
+	if n := db.Model(&models.Role{ID: role.ID}).Association("DataScopeDepartments").Count(); n != 1 {
+		t.Errorf("expected 1 department, got %d", n)
+	}
+
+	// 切换为其他范围时清空已选部门
+	if err := svc.SetDataScope(bg, role.ID, models.DataScopeSelf, []uint{dept.ID}); err != nil {
+		t.Fatalf("SetDataScope self: %v", err)
+	}
+	var updated models.Role
+	db.First(&updated, role.ID)
+	if updated.DataScope != models.DataScopeSelf {
+		t.Errorf("data scope = %q, want %q", updated.DataScope, models.DataScopeSelf)
+	}
+	if n := db.Model(&models.Role{ID: role.ID}).Association("DataScopeDepartments").Count(); n != 0 {
+		t.Errorf("expected departments cleared, got %d", n)
+	}
+}
+
+func TestRoleService_Hierarchy(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewRoleService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	super := models.Role{Name: "超级管理员", Code: models.RoleCodeSuperAdmin}
+	db.Create(&super)
+	if _, err := svc.CreateRole(bg, "继承超管", "", super.ID); err == nil {
+		t.Error("expected error when parent is super admin")
+	}
+	if _, err := svc.CreateRole(bg, "无效上级", "", 99999); err == nil {
+		t.Error("expected error for missing parent")
+	}
+
+	a, _ := svc.CreateRole(bg, "A", "", 0)
+	b, err := svc.CreateRole(bg, "B", "", a.ID)
+	if err != nil {
+		t.Fatalf("CreateRole with parent: %v", err)
+	}
+	c, _ := svc.CreateRole(bg, "C", "", b.ID)
+
+	// 环：A 的上级不能是自己或下级
+	for _, parentID := range []uint{a.ID, b.ID, c.ID} {
+		id := parentID
+		if _, err := svc.UpdateRole(bg, a.ID, "", "", &id); err == nil {
+			t.Errorf("expected cycle error for parent %d", parentID)
+		}
+	}
+	if _, err := svc.UpdateRole(bg, super.ID, "", "", &a.ID); err == nil {
+		t.Error("expected error when setting parent of system role")
+	}
+
+	if err := svc.DeleteRole(bg, a.ID); err == nil {
+		t.Error("expected error when deleting role with children")
+	}
+
+	// 角色列表区分直接与继承的权限
+	p1 := models.Permission{Path: "/p1", Method: "GET", Name: "P1"}
+	p2 := models.Permission{Path: "/p2", Method: "GET", Name: "P2"}
+	db.Create(&p1)
+	db.Create(&p2)
+	_ = svc.AssignPermissions(bg, a.ID, []uint{p1.ID, p2.ID})
+	_ = svc.AssignPermissions(bg, c.ID, []uint{p2.ID})
+
+	list, _, err := svc.GetRoles(bg, 1, 10, nil)
+	if err != nil {
+		t.Fatalf("GetRoles: %v", err)
+	}
+	for _, r := range list {
+		if r.ID != c.ID {
+			continue
+		}
+		if len(r.Permissions) != 1 || r.Permissions[0].ID != p2.ID {
+			t.Errorf("direct permissions = %+v", r.Permissions)
+		}
+		if len(r.InheritedPermissions) != 1 || r.InheritedPermissions[0].ID != p1.ID {
+			t.Errorf("inherited permissions = %+v", r.InheritedPermissions)
+		}
+		if r.Parent == nil || r.Parent.Name != "B" {
+			t.Errorf("parent = %+v", r.Parent)
+		}
+	}
+}
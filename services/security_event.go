@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/lyuangg/gadmin/models"
+)
+
+// traceIDContextKey TraceIDMiddleware 写入请求上下文的 trace id 键
+const traceIDContextKey = "trace_id"
+
+// SecurityEventService 安全事件服务：记录登录、退出与鉴权失败等事件，供审计查询
+type SecurityEventService struct {
+	ctx ServiceContext
+}
+
+// NewSecurityEventService 创建安全事件服务实例
+func NewSecurityEventService(ctx ServiceContext) *SecurityEventService {
+	return &SecurityEventService{ctx: ctx}
+}
+
+// Record 写入一条安全事件，TraceID 为空时从 ctx 中读取；写入失败只记日志，不影响业务流程
+func (s *SecurityEventService) Record(ctx context.Context, event *models.SecurityEvent) {
+	if event.TraceID == "" {
+		if traceID, ok := ctx.Value(traceIDContextKey).(string); ok {
+			event.TraceID = traceID
+		}
+	}
+	event.Username = truncate(event.Username, 100)
+	event.UserAgent = truncate(event.UserAgent, 255)
+	event.Path = truncate(event.Path, 255)
+	event.Reason = truncate(event.Reason, 255)
+	if err := s.ctx.DB().Create(event).Error; err != nil {
+		s.ctx.Logger().WarnContext(ctx, "记录安全事件失败", "event_type", event.EventType, "username", event.Username, "error", err)
+	}
+}
+
+// GetEvents 获取安全事件列表（分页和筛选）
+// 支持按时间范围、事件类型、用户 ID、用户名、IP、trace id 筛选
+func (s *SecurityEventService) GetEvents(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.SecurityEvent, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	var total int64
+	var events []models.SecurityEvent
+
+	query := s.ctx.DB().Model(&models.SecurityEvent{})
+
+	if startStr := filters["start_time"]; startStr != "" {
+		if startTime, err := time.Parse(time.RFC3339, startStr); err == nil {
+			query = query.Where("created_at >= ?", startTime)
+		} else {
+			s.ctx.Logger().WarnContext(ctx, "解析安全事件开始时间失败", "start_time", startStr, "error", err)
+		}
+	}
+	if endStr := filters["end_time"]; endStr != "" {
+		if endTime, err := time.Parse(time.RFC3339, endStr); err == nil {
+			query = query.Where("created_at <= ?", endTime)
+		} else {
+			s.ctx.Logger().WarnContext(ctx, "解析安全事件结束时间失败", "end_time", endStr, "error", err)
+		}
+	}
+	if eventType := filters["event_type"]; eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+	if userID := filters["user_id"]; userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if username := filters["username"]; username != "" {
+		query = query.Where("username LIKE ?", "%"+username+"%")
+	}
+	if ip := filters["ip"]; ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+	if traceID := filters["trace_id"]; traceID != "" {
+		query = query.Where("trace_id = ?", traceID)
+	}
+	query = query.Order("id DESC")
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// CleanOldEvents 清理旧安全事件，仅保留最近 retain 条（按 id 倒序）。返回删除行数。
+func (s *SecurityEventService) CleanOldEvents(ctx context.Context, retain int) (int64, error) {
+	if retain <= 0 {
+		return 0, nil
+	}
+	var minIDToKeep uint
+	err := s.ctx.DB().Model(&models.SecurityEvent{}).Order("id DESC").Offset(retain-1).Limit(1).Pluck("id", &minIDToKeep).Error
+	if err != nil {
+		return 0, err
+	}
+	result := s.ctx.DB().Where("id < ?", minIDToKeep).Delete(&models.SecurityEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lyuangg/gadmin/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestSecurityEventService_RecordAndFilter(t *testing.T) {
+	db := NewTestDB(t)
+	ctx := NewTestServiceContext(t, db)
+	svc := NewSecurityEventService(ctx)
+	bg := context.WithValue(context.Background(), traceIDContextKey, "trace-1")
+
+	svc.Record(bg, &models.SecurityEvent{EventType: models.SecurityEventLoginFailed, Username: "alice", IP: "10.0.0.1"})
+	svc.Record(bg, &models.SecurityEvent{EventType: models.SecurityEventPermissionDenied, UserID: 2, Username: "bob", IP: "10.0.0.2", TraceID: "trace-2"})
+
+	events, total, err := svc.GetEvents(bg, 1, 10, nil)
+	if err != nil || total != 2 || len(events) != 2 {
+		t.Fatalf("GetEvents = %d events, total %d, err %v", len(events), total, err)
+	}
+	if events[1].TraceID != "trace-1" || events[0].TraceID != "trace-2" {
+		t.Errorf("trace ids = %q, %q", events[1].TraceID, events[0].TraceID)
+	}
+
+	events, total, _ = svc.GetEvents(bg, 1, 10, map[string]string{"event_type": models.SecurityEventLoginFailed, "ip": "10.0.0.1"})
+	if total != 1 || events[0].Username != "alice" {
+		t.Errorf("filter by type and ip: total=%d events=%+v", total, events)
+	}
+	if _, total, _ = svc.GetEvents(bg, 1, 10, map[string]string{"username": "bo"}); total != 1 {
+		t.Errorf("filter by username: total=%d, want 1", total)
+	}
+}
+
+func TestSecurityEventService_CleanOldEvents(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewSecurityEventService(NewTestServiceContext(t, db))
+	bg := context.Background()
+	for i := 0; i < 5; i++ {
+		svc.Record(bg, &models.SecurityEvent{EventType: models.SecurityEventLogout})
+	}
+
+	deleted, err := svc.CleanOldEvents(bg, 2)
+	if err != nil || deleted != 3 {
+		t.Fatalf("CleanOldEvents = %d, %v; want 3", deleted, err)
+	}
+	var remaining int64
+	db.Model(&models.SecurityEvent{}).Count(&remaining)
+	if remaining != 2 {
+		t.Errorf("remaining = %d, want 2", remaining)
+	}
+}
+
+func TestAuthService_Login_RecordsSecurityEvents(t *testing.T) {
+	db := NewTestDB(t)
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("right"), bcrypt.DefaultCost)
+	db.Create(&models.User{Username: "u1", Password: string(hashed), Status: 1})
+	events := &FakeSecurityEventService{}
+	ctx := NewTestServiceContext(t, db, WithSecurityEventService(events))
+	svc := NewAuthService(ctx)
+	bg := context.Background()
+	client := ClientInfo{IP: "10.0.0.9", UserAgent: "curl/8"}
+
+	svc.Login(bg, "u1", "wrong", "cid", "val", client)
+	if _, err := svc.Login(bg, "u1", "right", "cid", "val", client); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	if len(events.Recorded) != 2 {
+		t.Fatalf("recorded %d events, want 2: %+v", len(events.Recorded), events.Recorded)
+	}
+	failed, success := events.Recorded[0], events.Recorded[1]
+	if failed.EventType != models.SecurityEventLoginFailed || failed.Username != "u1" || failed.UserID != 0 || failed.IP != "10.0.0.9" {
+		t.Errorf("failed event = %+v", failed)
+	}
+	if success.EventType != models.SecurityEventLoginSuccess || success.UserID == 0 || success.UserAgent != "curl/8" {
+		t.Errorf("success event = %+v", success)
+	}
+}
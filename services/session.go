@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/utils"
+
+	"gorm.io/gorm"
+)
+
+// sessionTouchInterval 会话最近访问时间的最小更新间隔，避免每个请求都写库
+const sessionTouchInterval = time.Minute
+
+// ClientInfo 发起请求的客户端信息，由 controller / middleware 从请求中提取
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// SessionService 登录会话服务：按设备记录、校验与吊销会话
+type SessionService struct {
+	ctx ServiceContext
+}
+
+// NewSessionService 创建会话服务实例
+func NewSessionService(ctx ServiceContext) *SessionService {
+	return &SessionService{ctx: ctx}
+}
+
+// CreateSession 为用户新建一个会话，jti 随机生成
+func (s *SessionService) CreateSession(ctx context.Context, userID uint, client ClientInfo, expiresAt time.Time) (*models.UserSession, error) {
+	jti, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := models.UserSession{
+		UserID:     userID,
+		JTI:        jti,
+		IP:         client.IP,
+		UserAgent:  truncate(client.UserAgent, 255),
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.ctx.DB().Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ValidateSession 校验 jti 对应的会话属于该用户且未吊销、未过期，并按间隔刷新最近访问信息
+func (s *SessionService) ValidateSession(ctx context.Context, userID uint, jti string, client ClientInfo) error {
+	if jti == "" {
+		return errors.UnauthorizedMsg("Token格式错误")
+	}
+	var session models.UserSession
+	if err := s.ctx.DB().Where("jti = ?", jti).First(&session).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.UnauthorizedMsg("会话不存在")
+		}
+		return err
+	}
+	if session.UserID != userID || session.RevokedAt != nil {
+		return errors.UnauthorizedMsg("会话已失效")
+	}
+	now := time.Now()
+	if now.After(session.ExpiresAt) {
+		return errors.UnauthorizedMsg("会话已过期")
+	}
+
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		updates := map[string]interface{}{"last_seen_at": now}
+		if client.IP != "" {
+			updates["ip"] = client.IP
+		}
+		if client.UserAgent != "" {
+			updates["user_agent"] = truncate(client.UserAgent, 255)
+		}
+		if err := s.ctx.DB().Model(&models.UserSession{}).Where("id = ?", session.ID).Updates(updates).Error; err != nil {
+			s.ctx.Logger().WarnContext(ctx, "更新会话访问时间失败", "session_id", session.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// RenewSession 刷新 Token 时延长会话有效期并记录最近访问信息
+func (s *SessionService) RenewSession(ctx context.Context, jti string, client ClientInfo, expiresAt time.Time) error {
+	var session models.UserSession
+	if err := s.ctx.DB().Where("jti = ?", jti).First(&session).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.UnauthorizedMsg("会话不存在")
+		}
+		return err
+	}
+	if session.RevokedAt != nil {
+		return errors.UnauthorizedMsg("会话已失效")
+	}
+	updates := map[string]interface{}{
+		"last_seen_at": time.Now(),
+		"expires_at":   expiresAt,
+	}
+	if client.IP != "" {
+		updates["ip"] = client.IP
+	}
+	if client.UserAgent != "" {
+		updates["user_agent"] = truncate(client.UserAgent, 255)
+	}
+	return s.ctx.DB().Model(&models.UserSession{}).Where("id = ?", session.ID).Updates(updates).Error
+}
+
+// GetUserSessions 获取用户当前有效的会话，按最近访问时间倒序
+func (s *SessionService) GetUserSessions(ctx context.Context, userID uint) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	err := s.ctx.DB().
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// GetSessions 管理端查询有效会话列表（分页和筛选），支持按 user_id、username 筛选
+func (s *SessionService) GetSessions(ctx context.Context, page, pageSize int, filters map[string]string) ([]models.UserSession, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	var total int64
+	var sessions []models.UserSession
+
+	query := s.ctx.DB().Model(&models.UserSession{}).
+		Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+	if userID := filters["user_id"]; userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if username := filters["username"]; username != "" {
+		subQuery := s.ctx.DB().Model(&models.User{}).Select("id").Where("username LIKE ?", "%"+username+"%")
+		query = query.Where("user_id IN (?)", subQuery)
+	}
+	query = query.Order("last_seen_at DESC")
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "username", "nickname")
+	}).Offset(offset).Limit(pageSize).Find(&sessions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return sessions, total, nil
+}
+
+// RevokeSession 吊销指定会话及其刷新 Token；userID 非 0 时只允许吊销该用户自己的会话
+func (s *SessionService) RevokeSession(ctx context.Context, sessionID uint, userID uint) error {
+	query := s.ctx.DB().Where("id = ?", sessionID)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	var session models.UserSession
+	if err := query.First(&session).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NotFoundMsg("会话不存在")
+		}
+		return err
+	}
+	return s.revoke(s.ctx.DB().Where("id = ?", session.ID))
+}
+
+// RevokeSessionByJTI 按 jti 吊销会话（退出登录、刷新 Token 重用时使用）
+func (s *SessionService) RevokeSessionByJTI(ctx context.Context, jti string) error {
+	if jti == "" {
+		return nil
+	}
+	return s.revoke(s.ctx.DB().Where("jti = ?", jti))
+}
+
+// RevokeUserSessions 吊销用户的全部会话；exceptJTI 非空时保留该会话（如修改密码时保留当前设备）
+func (s *SessionService) RevokeUserSessions(ctx context.Context, userID uint, exceptJTI string) error {
+	query := s.ctx.DB().Where("user_id = ?", userID)
+	if exceptJTI != "" {
+		query = query.Where("jti <> ?", exceptJTI)
+	}
+	return s.revoke(query)
+}
+
+// revoke 吊销 query 命中的会话，并同时吊销以其 jti 为家族的刷新 Token
+func (s *SessionService) revoke(query *gorm.DB) error {
+	var jtis []string
+	if err := query.Model(&models.UserSession{}).Where("revoked_at IS NULL").Pluck("jti", &jtis).Error; err != nil {
+		return err
+	}
+	if len(jtis) == 0 {
+		return nil
+	}
+	now := time.Now()
+	return s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserSession{}).Where("jti IN ? AND revoked_at IS NULL", jtis).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).Where("family_id IN ? AND revoked_at IS NULL", jtis).Update("revoked_at", now).Error
+	})
+}
+
+// truncate 按字节截断字符串以适配列长度（不切断 UTF-8 字符）
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && s[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return s[:cut]
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lyuangg/gadmin/models"
+)
+
+func TestSessionService_CreateAndValidate(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewSessionService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	s, err := svc.CreateSession(bg, 1, ClientInfo{IP: "10.0.0.1", UserAgent: "ua"}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if s.JTI == "" || s.IP != "10.0.0.1" {
+		t.Errorf("session = %+v", s)
+	}
+	if err := svc.ValidateSession(bg, 1, s.JTI, ClientInfo{}); err != nil {
+		t.Errorf("ValidateSession: %v", err)
+	}
+	if err := svc.ValidateSession(bg, 2, s.JTI, ClientInfo{}); err == nil {
+		t.Error("session of another user should be rejected")
+	}
+	if err := svc.ValidateSession(bg, 1, "unknown", ClientInfo{}); err == nil {
+		t.Error("unknown jti should be rejected")
+	}
+}
+
+func TestSessionService_ValidateSession_Expired(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewSessionService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	s, err := svc.CreateSession(bg, 1, ClientInfo{}, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if err := svc.ValidateSession(bg, 1, s.JTI, ClientInfo{}); err == nil {
+		t.Error("expired session should be rejected")
+	}
+}
+
+func TestSessionService_RevokeSession_OwnOnly(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewSessionService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	s, _ := svc.CreateSession(bg, 1, ClientInfo{}, time.Now().Add(time.Hour))
+	if err := svc.RevokeSession(bg, s.ID, 2); err == nil {
+		t.Error("user 2 should not revoke user 1's session")
+	}
+	if err := svc.RevokeSession(bg, s.ID, 1); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if err := svc.ValidateSession(bg, 1, s.JTI, ClientInfo{}); err == nil {
+		t.Error("revoked session should be rejected")
+	}
+}
+
+func TestSessionService_RevokeSession_RevokesRefreshTokens(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewSessionService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	s, _ := svc.CreateSession(bg, 1, ClientInfo{}, time.Now().Add(time.Hour))
+	rt := models.RefreshToken{UserID: 1, TokenHash: "h", FamilyID: s.JTI, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := db.Create(&rt).Error; err != nil {
+		t.Fatalf("create refresh token: %v", err)
+	}
+	// userID 为 0 表示管理员操作，可吊销任意会话
+	if err := svc.RevokeSession(bg, s.ID, 0); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	db.First(&rt, rt.ID)
+	if rt.RevokedAt == nil {
+		t.Error("refresh tokens of the session should be revoked")
+	}
+}
+
+func TestSessionService_GetUserSessions(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewSessionService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	a, _ := svc.CreateSession(bg, 1, ClientInfo{}, time.Now().Add(time.Hour))
+	_, _ = svc.CreateSession(bg, 1, ClientInfo{}, time.Now().Add(time.Hour))
+	_, _ = svc.CreateSession(bg, 2, ClientInfo{}, time.Now().Add(time.Hour))
+	_ = svc.RevokeSessionByJTI(bg, a.JTI)
+
+	list, err := svc.GetUserSessions(bg, 1)
+	if err != nil {
+		t.Fatalf("GetUserSessions: %v", err)
+	}
+	if len(list) != 1 {
+		t.Errorf("len = %d, want 1 (revoked excluded)", len(list))
+	}
+
+	all, total, err := svc.GetSessions(bg, 1, 10, map[string]string{})
+	if err != nil {
+		t.Fatalf("GetSessions: %v", err)
+	}
+	if total != 2 || len(all) != 2 {
+		t.Errorf("total = %d len = %d, want 2", total, len(all))
+	}
+}
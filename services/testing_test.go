@@ -13,36 +13,60 @@ import (
 
 // testServiceContext 单测用 ServiceContext，仅提供 DB / Captcha / TokenGenerator，其他 Service 返回 nil
 type testServiceContext struct {
-	db       *gorm.DB
-	captcha  CaptchaProvider
-	tokenGen TokenGenerator
-	logger   logger.ILogger
-	cfg      *config.Config
-	auth     *AuthService
-	user     *UserService
-	role     *RoleService
-	perm     *PermissionService
-	opLog    *OperationLogService
+	db        *gorm.DB
+	captcha   CaptchaProvider
+	tokenGen  TokenGenerator
+	authn     []Authenticator
+	broadcast PermissionCacheBroadcaster
+	logger    logger.ILogger
+	cfg       *config.Config
+	auth      *AuthService
+	session   *SessionService
+	twoFA     *TwoFactorService
+	lockout   *LoginLockService
+	pwd       *PasswordService
+	oidc      *OIDCService
+	apiKey    *APIKeyService
+	user      *UserService
+	role      *RoleService
+	perm      *PermissionService
+	opLog     *OperationLogService
+	secEvent  ISecurityEventService
+	dataScope IDataScopeService
 }
 
-func (c *testServiceContext) DB() *gorm.DB                                 { return c.db }
-func (c *testServiceContext) Logger() logger.ILogger                       { return c.logger }
-func (c *testServiceContext) GetConfig() *config.Config                    { return c.cfg }
-func (c *testServiceContext) GetCaptchaProvider() CaptchaProvider          { return c.captcha }
-func (c *testServiceContext) GetTokenGenerator() TokenGenerator            { return c.tokenGen }
+func (c *testServiceContext) DB() *gorm.DB                        { return c.db }
+func (c *testServiceContext) Logger() logger.ILogger              { return c.logger }
+func (c *testServiceContext) GetConfig() *config.Config           { return c.cfg }
+func (c *testServiceContext) GetCaptchaProvider() CaptchaProvider { return c.captcha }
+func (c *testServiceContext) GetTokenGenerator() TokenGenerator   { return c.tokenGen }
+func (c *testServiceContext) GetAuthenticators() []Authenticator  { return c.authn }
+func (c *testServiceContext) GetPermissionCacheBroadcaster() PermissionCacheBroadcaster {
+	return c.broadcast
+}
 func (c *testServiceContext) GetAuthService() IAuthService                 { return c.auth }
+func (c *testServiceContext) GetSessionService() ISessionService           { return c.session }
+func (c *testServiceContext) GetTwoFactorService() ITwoFactorService       { return c.twoFA }
+func (c *testServiceContext) GetLoginLockService() ILoginLockService       { return c.lockout }
+func (c *testServiceContext) GetPasswordService() IPasswordService         { return c.pwd }
+func (c *testServiceContext) GetOIDCService() IOIDCService                 { return c.oidc }
+func (c *testServiceContext) GetAPIKeyService() IAPIKeyService             { return c.apiKey }
 func (c *testServiceContext) GetUserService() IUserService                 { return c.user }
 func (c *testServiceContext) GetRoleService() IRoleService                 { return c.role }
 func (c *testServiceContext) GetPermissionService() IPermissionService     { return c.perm }
 func (c *testServiceContext) GetOperationLogService() IOperationLogService { return c.opLog }
+func (c *testServiceContext) GetSecurityEventService() ISecurityEventService {
+	return c.secEvent
+}
+func (c *testServiceContext) GetDataScopeService() IDataScopeService { return c.dataScope }
 
 // NewTestDB 委托给 testutil，保持 services 包内单测调用不变
-func NewTestDB(t *testing.T) *gorm.DB {
+func NewTestDB(t testing.TB) *gorm.DB {
 	return testutil.NewTestDB(t)
 }
 
 // NewTestServiceContext 创建单测用 ServiceContext：DB 为内存库，Captcha/Token 为 Fake
-func NewTestServiceContext(t *testing.T, db *gorm.DB, opts ...TestContextOption) ServiceContext {
+func NewTestServiceContext(t testing.TB, db *gorm.DB, opts ...TestContextOption) ServiceContext {
 	t.Helper()
 	ctx := &testServiceContext{
 		db:       db,
@@ -51,6 +75,16 @@ func NewTestServiceContext(t *testing.T, db *gorm.DB, opts ...TestContextOption)
 		captcha:  &FakeCaptchaProvider{VerifyResult: true},
 		tokenGen: &FakeTokenGenerator{Token: "fake-token"},
 	}
+	// 会话、两步验证、登录锁定、密码策略、单点登录、权限、API Key、安全事件、数据范围服务只依赖 DB 与配置，被其他 Service 调用，默认装配真实实现
+	ctx.session = NewSessionService(ctx)
+	ctx.twoFA = NewTwoFactorService(ctx)
+	ctx.lockout = NewLoginLockService(ctx)
+	ctx.pwd = NewPasswordService(ctx)
+	ctx.oidc = NewOIDCService(ctx)
+	ctx.perm = NewPermissionService(ctx)
+	ctx.apiKey = NewAPIKeyService(ctx)
+	ctx.secEvent = NewSecurityEventService(ctx)
+	ctx.dataScope = NewDataScopeService(ctx)
 	for _, opt := range opts {
 		opt(ctx)
 	}
@@ -65,6 +99,11 @@ func WithCaptchaProvider(p CaptchaProvider) TestContextOption {
 	return func(c *testServiceContext) { c.captcha = p }
 }
 
+// WithAuthenticators 指定登录认证方式，未指定时只使用本地密码
+func WithAuthenticators(build func(ctx ServiceContext) []Authenticator) TestContextOption {
+	return func(c *testServiceContext) { c.authn = build(c) }
+}
+
 // WithTokenGenerator 指定 Token 生成器
 func WithTokenGenerator(tg TokenGenerator) TestContextOption {
 	return func(c *testServiceContext) { c.tokenGen = tg }
@@ -74,3 +113,18 @@ func WithTokenGenerator(tg TokenGenerator) TestContextOption {
 func WithConfig(cfg *config.Config) TestContextOption {
 	return func(c *testServiceContext) { c.cfg = cfg }
 }
+
+// WithSecurityEventService 指定安全事件服务，便于断言记录的事件
+func WithSecurityEventService(svc ISecurityEventService) TestContextOption {
+	return func(c *testServiceContext) { c.secEvent = svc }
+}
+
+// WithDataScopeService 指定数据范围服务，便于固定列表查询的数据范围
+func WithDataScopeService(svc IDataScopeService) TestContextOption {
+	return func(c *testServiceContext) { c.dataScope = svc }
+}
+
+// WithPermissionCacheBroadcaster 指定权限缓存失效的广播实现
+func WithPermissionCacheBroadcaster(b PermissionCacheBroadcaster) TestContextOption {
+	return func(c *testServiceContext) { c.broadcast = b }
+}
@@ -1,12 +1,23 @@
 package services
 
 import (
+	"time"
+
 	"github.com/lyuangg/gadmin/utils"
 )
 
+// TokenPair 登录或刷新后签发的访问 Token 与刷新 Token
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"-"`                  // 只写入 HttpOnly cookie，不出现在响应体中
+	ExpiresIn    int64  `json:"expires_in"`         // 访问 Token 剩余有效秒数
+	RefreshIn    int64  `json:"refresh_expires_in"` // 刷新 Token 剩余有效秒数
+}
+
 // TokenGenerator JWT Token 生成器，便于测试时替换为 mock
 type TokenGenerator interface {
-	GenerateToken(userID uint, username, nickname string, userType int, isSuperAdmin bool, roleIDs []uint, tokenVersion uint) (string, error)
+	GenerateToken(userID uint, username, nickname string, userType int, isSuperAdmin bool, roleIDs []uint, tokenVersion uint, sessionID string) (string, error)
+	GenerateImpersonationToken(userID uint, username, nickname string, userType int, roleIDs []uint, tokenVersion uint, sessionID string, impersonatorID uint, impersonatorName string, ttl time.Duration) (string, error)
 }
 
 // realTokenGenerator 生产实现，委托 utils.GenerateToken（需在 main 中先调用 utils.InitJWT）
@@ -17,8 +28,12 @@ func NewRealTokenGenerator() TokenGenerator {
 	return &realTokenGenerator{}
 }
 
-func (t *realTokenGenerator) GenerateToken(userID uint, username, nickname string, userType int, isSuperAdmin bool, roleIDs []uint, tokenVersion uint) (string, error) {
-	return utils.GenerateToken(userID, username, nickname, userType, isSuperAdmin, roleIDs, tokenVersion)
+func (t *realTokenGenerator) GenerateToken(userID uint, username, nickname string, userType int, isSuperAdmin bool, roleIDs []uint, tokenVersion uint, sessionID string) (string, error) {
+	return utils.GenerateToken(userID, username, nickname, userType, isSuperAdmin, roleIDs, tokenVersion, sessionID)
+}
+
+func (t *realTokenGenerator) GenerateImpersonationToken(userID uint, username, nickname string, userType int, roleIDs []uint, tokenVersion uint, sessionID string, impersonatorID uint, impersonatorName string, ttl time.Duration) (string, error) {
+	return utils.GenerateImpersonationToken(userID, username, nickname, userType, roleIDs, tokenVersion, sessionID, impersonatorID, impersonatorName, ttl)
 }
 
 // FakeTokenGenerator 单测用，返回固定 token 或错误
@@ -27,7 +42,14 @@ type FakeTokenGenerator struct {
 	Err   error
 }
 
-func (f *FakeTokenGenerator) GenerateToken(userID uint, username, nickname string, userType int, isSuperAdmin bool, roleIDs []uint, tokenVersion uint) (string, error) {
+func (f *FakeTokenGenerator) GenerateToken(userID uint, username, nickname string, userType int, isSuperAdmin bool, roleIDs []uint, tokenVersion uint, sessionID string) (string, error) {
+	if f.Err != nil {
+		return "", f.Err
+	}
+	return f.Token, nil
+}
+
+func (f *FakeTokenGenerator) GenerateImpersonationToken(userID uint, username, nickname string, userType int, roleIDs []uint, tokenVersion uint, sessionID string, impersonatorID uint, impersonatorName string, ttl time.Duration) (string, error) {
 	if f.Err != nil {
 		return "", f.Err
 	}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	stderrors "errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/lyuangg/gadmin/errors"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/utils"
+
+	"gorm.io/gorm"
+)
+
+// backupCodeCount 每次生成的备用码数量
+const backupCodeCount = 10
+
+// backupCodeAlphabet 备用码字符集，去掉了易混淆的 0/1/i/l/o
+const backupCodeAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+// TwoFactorStatus 用户两步验证状态
+type TwoFactorStatus struct {
+	Enabled              bool  `json:"enabled"`
+	Required             bool  `json:"required"` // 所属角色要求开启
+	BackupCodesRemaining int64 `json:"backup_codes_remaining"`
+}
+
+// TwoFactorSetup 绑定验证器所需信息：密钥供手动输入，uri 用于生成二维码
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// TwoFactorService TOTP 两步验证服务：绑定、校验、备用码与管理员重置
+type TwoFactorService struct {
+	ctx ServiceContext
+}
+
+// NewTwoFactorService 创建两步验证服务实例
+func NewTwoFactorService(ctx ServiceContext) *TwoFactorService {
+	return &TwoFactorService{ctx: ctx}
+}
+
+// GetStatus 获取用户两步验证状态
+func (s *TwoFactorService) GetStatus(ctx context.Context, userID uint) (*TwoFactorStatus, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &TwoFactorStatus{
+		Enabled:  user.TOTPEnabled,
+		Required: requiresTwoFactor(user),
+	}
+	if user.TOTPEnabled {
+		if err := s.ctx.DB().Model(&models.UserBackupCode{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Count(&status.BackupCodesRemaining).Error; err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// IsRequired 用户所属角色中是否有要求两步验证的
+func (s *TwoFactorService) IsRequired(ctx context.Context, userID uint) (bool, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return false, err
+	}
+	return requiresTwoFactor(user), nil
+}
+
+// BeginSetup 生成新的待绑定密钥；在 Enable 校验通过前不生效，重复调用会覆盖未完成的密钥
+func (s *TwoFactorService) BeginSetup(ctx context.Context, userID uint) (*TwoFactorSetup, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errors.BadRequestMsg("已开启两步验证")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ctx.DB().Model(&models.User{}).Where("id = ?", userID).Update("totp_secret", secret).Error; err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{
+		Secret: secret,
+		URI:    utils.TOTPProvisioningURI(s.issuer(), user.Username, secret),
+	}, nil
+}
+
+// Enable 用验证器生成的验证码确认绑定，成功后开启两步验证并返回一组新的备用码（仅此一次明文返回）
+func (s *TwoFactorService) Enable(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errors.BadRequestMsg("已开启两步验证")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.BadRequestMsg("请先获取绑定密钥")
+	}
+	counter, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, errors.BadRequestMsg("验证码错误")
+	}
+
+	var codes []string
+	err = s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_enabled":      true,
+			"totp_last_counter": counter,
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceBackupCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable 用户自行关闭两步验证，需提供有效验证码；角色强制要求时不允许关闭
+func (s *TwoFactorService) Disable(ctx context.Context, userID uint, code string) error {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return errors.BadRequestMsg("未开启两步验证")
+	}
+	if requiresTwoFactor(user) {
+		return errors.ForbiddenMsg("所属角色要求开启两步验证，无法关闭")
+	}
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.clear(userID)
+}
+
+// RegenerateBackupCodes 作废旧备用码并生成新的一组，需提供有效验证码
+func (s *TwoFactorService) RegenerateBackupCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	var codes []string
+	err := s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceBackupCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Reset 管理员重置用户的两步验证（如用户丢失设备），用户下次登录时可重新绑定
+func (s *TwoFactorService) Reset(ctx context.Context, userID uint) error {
+	if _, err := s.getUser(userID); err != nil {
+		return err
+	}
+	return s.clear(userID)
+}
+
+// Verify 校验 TOTP 验证码或备用码；同一 TOTP 时间步、同一备用码都只能使用一次
+func (s *TwoFactorService) Verify(ctx context.Context, userID uint, code string) error {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled || user.TOTPSecret == "" {
+		return errors.BadRequestMsg("未开启两步验证")
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return errors.BadRequestMsg("请输入验证码")
+	}
+
+	if counter, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		// 条件更新保证同一时间步的验证码不会被并发或重复使用
+		result := s.ctx.DB().Model(&models.User{}).
+			Where("id = ? AND totp_last_counter < ?", userID, counter).
+			Update("totp_last_counter", counter)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.BadRequestMsg("验证码已使用，请等待下一个验证码")
+		}
+		return nil
+	}
+
+	result := s.ctx.DB().Model(&models.UserBackupCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashBackupCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.BadRequestMsg("验证码错误")
+	}
+	s.ctx.Logger().InfoContext(ctx, "用户使用备用码完成两步验证", "user_id", userID)
+	return nil
+}
+
+func (s *TwoFactorService) getUser(userID uint) (*models.User, error) {
+	var user models.User
+	if err := s.ctx.DB().Where("id = ?", userID).Preload("Roles").First(&user).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NotFoundMsg("用户不存在")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *TwoFactorService) clear(userID uint) error {
+	return s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":       "",
+			"totp_enabled":      false,
+			"totp_last_counter": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserBackupCode{}).Error
+	})
+}
+
+func (s *TwoFactorService) issuer() string {
+	if issuer := s.ctx.GetConfig().TOTPIssuer; issuer != "" {
+		return issuer
+	}
+	return "gadmin"
+}
+
+// requiresTwoFactor 用户（需已预加载 Roles）是否因角色被强制要求两步验证
+func requiresTwoFactor(user *models.User) bool {
+	for _, role := range user.Roles {
+		if role.Require2FA {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceBackupCodes 删除用户旧备用码并生成新的一组，返回明文
+func replaceBackupCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.UserBackupCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, backupCodeCount)
+	records := make([]models.UserBackupCode, 0, backupCodeCount)
+	for i := 0; i < backupCodeCount; i++ {
+		code, err := generateBackupCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, models.UserBackupCode{UserID: userID, CodeHash: hashBackupCode(code)})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateBackupCode 生成形如 abcd-efgh 的备用码
+func generateBackupCode() (string, error) {
+	max := big.NewInt(int64(len(backupCodeAlphabet)))
+	out := make([]byte, 0, 9)
+	for i := 0; i < 8; i++ {
+		if i == 4 {
+			out = append(out, '-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, backupCodeAlphabet[n.Int64()])
+	}
+	return string(out), nil
+}
+
+// hashBackupCode 忽略大小写与分隔符后取摘要，用户输入时可不带 "-"
+func hashBackupCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return utils.HashToken(code)
+}
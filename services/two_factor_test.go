@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lyuangg/gadmin/config"
+	"github.com/lyuangg/gadmin/models"
+	"github.com/lyuangg/gadmin/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// totpCodeAt 计算当前时间偏移 step 个时间步的验证码
+func totpCodeAt(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	code, err := utils.TOTPCode(secret, utils.TOTPCounter(time.Now())+step)
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+	return code
+}
+
+// enableTwoFactorForTest 为用户完成绑定，返回密钥与备用码
+func enableTwoFactorForTest(t *testing.T, svc *TwoFactorService, userID uint) (string, []string) {
+	t.Helper()
+	bg := context.Background()
+	setup, err := svc.BeginSetup(bg, userID)
+	if err != nil {
+		t.Fatalf("BeginSetup: %v", err)
+	}
+	codes, err := svc.Enable(bg, userID, totpCodeAt(t, setup.Secret, 0))
+	if err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+	return setup.Secret, codes
+}
+
+func TestTwoFactorService_SetupAndEnable(t *testing.T) {
+	db := NewTestDB(t)
+	user := models.User{Username: "tfa", Password: "x", Status: 1}
+	db.Create(&user)
+	svc := NewTwoFactorService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	setup, err := svc.BeginSetup(bg, user.ID)
+	if err != nil {
+		t.Fatalf("BeginSetup: %v", err)
+	}
+	if setup.Secret == "" || setup.URI == "" {
+		t.Fatalf("setup = %+v", setup)
+	}
+	if _, err := svc.Enable(bg, user.ID, "000000"); err == nil {
+		t.Error("wrong code should be rejected")
+	}
+
+	codes, err := svc.Enable(bg, user.ID, totpCodeAt(t, setup.Secret, 0))
+	if err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+	if len(codes) != backupCodeCount {
+		t.Errorf("backup codes = %d, want %d", len(codes), backupCodeCount)
+	}
+	status, err := svc.GetStatus(bg, user.ID)
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	if !status.Enabled || status.BackupCodesRemaining != backupCodeCount {
+		t.Errorf("status = %+v", status)
+	}
+	if _, err := svc.BeginSetup(bg, user.ID); err == nil {
+		t.Error("setup should be rejected when already enabled")
+	}
+}
+
+func TestTwoFactorService_Verify_RejectsReplay(t *testing.T) {
+	db := NewTestDB(t)
+	user := models.User{Username: "tfa", Password: "x", Status: 1}
+	db.Create(&user)
+	svc := NewTwoFactorService(NewTestServiceContext(t, db))
+	bg := context.Background()
+	secret, _ := enableTwoFactorForTest(t, svc, user.ID)
+
+	// 绑定时已使用当前时间步，同一验证码不能再次使用
+	if err := svc.Verify(bg, user.ID, totpCodeAt(t, secret, 0)); err == nil {
+		t.Error("code used for enabling should not be reusable")
+	}
+	next := totpCodeAt(t, secret, 1)
+	if err := svc.Verify(bg, user.ID, next); err != nil {
+		t.Fatalf("Verify next step: %v", err)
+	}
+	if err := svc.Verify(bg, user.ID, next); err == nil {
+		t.Error("replayed code should be rejected")
+	}
+}
+
+func TestTwoFactorService_Verify_BackupCodeOnce(t *testing.T) {
+	db := NewTestDB(t)
+	user := models.User{Username: "tfa", Password: "x", Status: 1}
+	db.Create(&user)
+	svc := NewTwoFactorService(NewTestServiceContext(t, db))
+	bg := context.Background()
+	_, codes := enableTwoFactorForTest(t, svc, user.ID)
+
+	if err := svc.Verify(bg, user.ID, codes[0]); err != nil {
+		t.Fatalf("Verify backup code: %v", err)
+	}
+	if err := svc.Verify(bg, user.ID, codes[0]); err == nil {
+		t.Error("backup code should be single use")
+	}
+	status, _ := svc.GetStatus(bg, user.ID)
+	if status.BackupCodesRemaining != backupCodeCount-1 {
+		t.Errorf("remaining = %d", status.BackupCodesRemaining)
+	}
+}
+
+func TestTwoFactorService_Disable_RequiredByRole(t *testing.T) {
+	db := NewTestDB(t)
+	role := models.Role{Name: "财务", Require2FA: true}
+	db.Create(&role)
+	user := models.User{Username: "tfa", Password: "x", Status: 1, Roles: []models.Role{role}}
+	db.Create(&user)
+	svc := NewTwoFactorService(NewTestServiceContext(t, db))
+	bg := context.Background()
+	secret, _ := enableTwoFactorForTest(t, svc, user.ID)
+
+	if err := svc.Disable(bg, user.ID, totpCodeAt(t, secret, 1)); err == nil {
+		t.Error("disable should be refused when role requires 2FA")
+	}
+}
+
+func TestTwoFactorService_Reset(t *testing.T) {
+	db := NewTestDB(t)
+	user := models.User{Username: "tfa", Password: "x", Status: 1}
+	db.Create(&user)
+	svc := NewTwoFactorService(NewTestServiceContext(t, db))
+	bg := context.Background()
+	enableTwoFactorForTest(t, svc, user.ID)
+
+	if err := svc.Reset(bg, user.ID); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	var got models.User
+	db.First(&got, user.ID)
+	if got.TOTPEnabled || got.TOTPSecret != "" {
+		t.Errorf("user after reset = enabled %v secret %q", got.TOTPEnabled, got.TOTPSecret)
+	}
+	var count int64
+	db.Model(&models.UserBackupCode{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("backup codes left = %d", count)
+	}
+	if err := svc.Reset(bg, 9999); err == nil {
+		t.Error("reset of unknown user should fail")
+	}
+}
+
+func TestAuthService_Login_TwoFactorChallenge(t *testing.T) {
+	db := NewTestDB(t)
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass123"), bcrypt.DefaultCost)
+	user := models.User{Username: "tfa", Password: string(hashed), Status: 1}
+	db.Create(&user)
+	ctx := NewTestServiceContext(t, db)
+	svc := NewAuthService(ctx)
+	bg := context.Background()
+	secret, _ := enableTwoFactorForTest(t, ctx.GetTwoFactorService().(*TwoFactorService), user.ID)
+
+	result, err := svc.Login(bg, "tfa", "pass123", "cid", "val", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if result.Tokens != nil || result.ChallengeToken == "" || result.SetupRequired {
+		t.Fatalf("result = %+v", result)
+	}
+	var sessions int64
+	db.Model(&models.UserSession{}).Count(&sessions)
+	if sessions != 0 {
+		t.Error("session must not be created before 2FA")
+	}
+
+	if _, err := svc.CompleteTwoFactorLogin(bg, result.ChallengeToken, "000000", ClientInfo{}); err == nil {
+		t.Error("wrong code should be rejected")
+	}
+	done, err := svc.CompleteTwoFactorLogin(bg, result.ChallengeToken, totpCodeAt(t, secret, 1), ClientInfo{})
+	if err != nil {
+		t.Fatalf("CompleteTwoFactorLogin: %v", err)
+	}
+	if done.Tokens == nil || done.Tokens.AccessToken == "" {
+		t.Fatalf("expected tokens, got %+v", done)
+	}
+	if _, err := svc.CompleteTwoFactorLogin(bg, result.ChallengeToken, totpCodeAt(t, secret, 1), ClientInfo{}); err == nil {
+		t.Error("challenge should be single use")
+	}
+}
+
+func TestAuthService_CompleteTwoFactorLogin_MaxAttempts(t *testing.T) {
+	db := NewTestDB(t)
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass123"), bcrypt.DefaultCost)
+	user := models.User{Username: "tfa", Password: string(hashed), Status: 1}
+	db.Create(&user)
+	ctx := NewTestServiceContext(t, db)
+	svc := NewAuthService(ctx)
+	bg := context.Background()
+	secret, _ := enableTwoFactorForTest(t, ctx.GetTwoFactorService().(*TwoFactorService), user.ID)
+
+	result, err := svc.Login(bg, "tfa", "pass123", "cid", "val", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	for i := 0; i < loginChallengeMaxAttempts; i++ {
+		svc.CompleteTwoFactorLogin(bg, result.ChallengeToken, "000000", ClientInfo{})
+	}
+	if _, err := svc.CompleteTwoFactorLogin(bg, result.ChallengeToken, totpCodeAt(t, secret, 1), ClientInfo{}); err == nil {
+		t.Error("challenge should be invalid after too many failures")
+	}
+}
+
+// 两步验证的错误次数计入登录锁定，重新输入密码也不能绕过
+func TestAuthService_CompleteTwoFactorLogin_CountsTowardLockout(t *testing.T) {
+	db := NewTestDB(t)
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass123"), bcrypt.DefaultCost)
+	user := models.User{Username: "tfa", Password: string(hashed), Status: 1}
+	db.Create(&user)
+	ctx := NewTestServiceContext(t, db, WithConfig(&config.Config{LoginMaxFailures: 3}))
+	svc := NewAuthService(ctx)
+	bg := context.Background()
+	secret, _ := enableTwoFactorForTest(t, ctx.GetTwoFactorService().(*TwoFactorService), user.ID)
+	client := ClientInfo{IP: "10.0.0.1"}
+
+	// 每次重新登录拿到新的挑战，错误次数仍然累计
+	for i := 0; i < 3; i++ {
+		result, err := svc.Login(bg, "tfa", "pass123", "cid", "val", client)
+		if err != nil {
+			t.Fatalf("Login #%d: %v", i, err)
+		}
+		if _, err := svc.CompleteTwoFactorLogin(bg, result.ChallengeToken, "000000", client); err == nil {
+			t.Fatal("wrong code should be rejected")
+		}
+	}
+	if _, err := svc.Login(bg, "tfa", "pass123", "cid", "val", client); err == nil {
+		t.Error("account should be locked after repeated 2FA failures")
+	}
+	db.Model(&models.LoginLock{}).Where("target = ?", "tfa").Update("locked_until", time.Now().Add(-time.Second))
+
+	// 锁定过期后可正常完成登录，登录完成后清除计数
+	result, err := svc.Login(bg, "tfa", "pass123", "cid", "val", client)
+	if err != nil {
+		t.Fatalf("Login after lock expired: %v", err)
+	}
+	if _, err := svc.CompleteTwoFactorLogin(bg, result.ChallengeToken, totpCodeAt(t, secret, 1), client); err != nil {
+		t.Fatalf("CompleteTwoFactorLogin: %v", err)
+	}
+	var lock models.LoginLock
+	if err := db.Where("scope = ? AND target = ?", models.LoginLockScopeUser, "tfa").First(&lock).Error; err == nil {
+		t.Errorf("failures should be cleared after login, got %+v", lock)
+	}
+}
+
+func TestAuthService_Login_RoleRequiresSetup(t *testing.T) {
+	db := NewTestDB(t)
+	role := models.Role{Name: "财务", Require2FA: true}
+	db.Create(&role)
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass123"), bcrypt.DefaultCost)
+	user := models.User{Username: "tfa", Password: string(hashed), Status: 1, Roles: []models.Role{role}}
+	db.Create(&user)
+	svc := NewAuthService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	result, err := svc.Login(bg, "tfa", "pass123", "cid", "val", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !result.SetupRequired || result.ChallengeToken == "" {
+		t.Fatalf("result = %+v", result)
+	}
+
+	setup, err := svc.BeginTwoFactorSetup(bg, result.ChallengeToken)
+	if err != nil {
+		t.Fatalf("BeginTwoFactorSetup: %v", err)
+	}
+	done, err := svc.CompleteTwoFactorLogin(bg, result.ChallengeToken, totpCodeAt(t, setup.Secret, 0), ClientInfo{})
+	if err != nil {
+		t.Fatalf("CompleteTwoFactorLogin: %v", err)
+	}
+	if done.Tokens == nil || len(done.BackupCodes) != backupCodeCount {
+		t.Errorf("result = %+v", done)
+	}
+	var got models.User
+	db.First(&got, user.ID)
+	if !got.TOTPEnabled {
+		t.Error("2FA should be enabled after setup during login")
+	}
+}
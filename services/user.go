@@ -3,12 +3,11 @@ package services
 import (
 	"context"
 	stderrors "errors"
-	"math/rand"
+	"strconv"
 
 	"github.com/lyuangg/gadmin/errors"
 	"github.com/lyuangg/gadmin/models"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -34,7 +33,11 @@ func (s *UserService) GetUsers(ctx context.Context, page, pageSize int, filters
 	var total int64
 	var users []models.User
 
-	query := s.ctx.DB().Model(&models.User{})
+	scope, err := s.ctx.GetDataScopeService().Resolve(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := s.ctx.DB().Model(&models.User{}).Scopes(scope.Scope("department_id", "id"))
 	if username, ok := filters["username"]; ok && username != "" {
 		query = query.Where("username LIKE ?", "%"+username+"%")
 	}
@@ -51,6 +54,18 @@ func (s *UserService) GetUsers(ctx context.Context, page, pageSize int, filters
 		subQuery := s.ctx.DB().Model(&models.UserRole{}).Select("user_id").Where("role_id = ?", roleID)
 		query = query.Where("id IN (?)", subQuery)
 	}
+	// 按部门筛选时包含其所有下级部门的用户
+	if deptID, ok := filters["department_id"]; ok && deptID != "" {
+		id, err := strconv.ParseUint(deptID, 10, 32)
+		if err != nil {
+			return nil, 0, errors.BadRequestMsg("无效的部门ID")
+		}
+		deptIDs, err := departmentSubtree(s.ctx.DB(), []uint{uint(id)})
+		if err != nil {
+			return nil, 0, err
+		}
+		query = query.Where("department_id IN ?", deptIDs)
+	}
 
 	switch orderBy := filters["order_by"]; orderBy {
 	case "id", "id_asc":
@@ -66,17 +81,18 @@ func (s *UserService) GetUsers(ctx context.Context, page, pageSize int, filters
 	}
 
 	offset := (page - 1) * pageSize
-	if err := query.Preload("Roles").Offset(offset).Limit(pageSize).Find(&users).Error; err != nil {
+	if err := query.Preload("Roles").Preload("Department").Preload("Positions").Offset(offset).Limit(pageSize).Find(&users).Error; err != nil {
 		return nil, 0, err
 	}
 
 	return users, total, nil
 }
 
-// GetUserForAuth 供认证中间件使用，仅查询校验 token_version 与状态所需字段
+// GetUserForAuth 供认证中间件使用，仅查询校验 token_version 与状态所需字段，以及当前角色（用于即时生效的授权判断）
 func (s *UserService) GetUserForAuth(ctx context.Context, userID uint) (*models.User, error) {
 	var user models.User
-	err := s.ctx.DB().Select("id", "username", "nickname", "type", "status", "token_version").
+	err := s.ctx.DB().Select("id", "username", "nickname", "type", "status", "token_version", "must_change_password").
+		Preload("Roles", func(db *gorm.DB) *gorm.DB { return db.Select("id", "code") }).
 		Where("id = ?", userID).First(&user).Error
 	if err != nil {
 		return nil, err
@@ -94,23 +110,26 @@ func (s *UserService) CreateUser(ctx context.Context, username, password, nickna
 		return nil, errors.BadRequestMsg("用户名已存在")
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, errors.InternalErrorMsg("密码加密失败")
-	}
-
+	// 管理员设置的初始密码，用户首次登录后须修改
 	user := models.User{
-		Username: username,
-		Password: string(hashedPassword),
-		Nickname: nickname,
-		Type:     userType,
-		Status:   1,
-		Remark:   remark,
+		Username:           username,
+		Nickname:           nickname,
+		Type:               userType,
+		Status:             1,
+		Remark:             remark,
+		MustChangePassword: true,
+	}
+	passwords := s.ctx.GetPasswordService()
+	if err := passwords.SetPassword(ctx, &user, password); err != nil {
+		return nil, err
 	}
 
 	if err := s.ctx.DB().Create(&user).Error; err != nil {
 		return nil, err
 	}
+	if err := passwords.RecordHistory(ctx, &user); err != nil {
+		return nil, err
+	}
 
 	if len(roleIDs) > 0 {
 		var roles []models.Role
@@ -136,17 +155,22 @@ func (s *UserService) UpdateUser(ctx context.Context, userID uint, nickname, pas
 	}
 	user.Remark = remark
 
+	passwords := s.ctx.GetPasswordService()
 	if password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-		if err != nil {
-			return errors.InternalErrorMsg("密码加密失败")
+		if err := passwords.SetPassword(ctx, &user, password); err != nil {
+			return err
 		}
-		user.Password = string(hashedPassword)
+		user.MustChangePassword = true
 	}
 
 	if err := s.ctx.DB().Save(&user).Error; err != nil {
 		return errors.InternalErrorMsg("更新用户失败")
 	}
+	if password != "" {
+		if err := passwords.RecordHistory(ctx, &user); err != nil {
+			return err
+		}
+	}
 
 	if roleIDs != nil {
 		var roles []models.Role
@@ -155,12 +179,39 @@ func (s *UserService) UpdateUser(ctx context.Context, userID uint, nickname, pas
 				return err
 			}
 		}
+		if !IsSuperAdmin(&models.User{Roles: roles}) {
+			if err := ensureNotLastSuperAdmin(s.ctx.DB(), &user); err != nil {
+				return err
+			}
+		}
 		s.ctx.DB().Model(&user).Association("Roles").Replace(roles)
 	}
 
 	return nil
 }
 
+// ensureNotLastSuperAdmin user 即将被禁用、删除或移除超级管理员角色时，须至少还有一个其他启用的超级管理员，
+// 否则系统将没有人能管理角色与权限
+func ensureNotLastSuperAdmin(db *gorm.DB, user *models.User) error {
+	if user.Status != 1 {
+		return nil
+	}
+	var role models.Role
+	if err := db.Where("code = ?", models.RoleCodeSuperAdmin).First(&role).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if db.Model(user).Where("id = ?", role.ID).Association("Roles").Count() == 0 {
+		return nil
+	}
+	if db.Model(&role).Where("status = ? AND id <> ?", 1, user.ID).Association("Users").Count() == 0 {
+		return errors.BadRequestMsg("不能禁用、删除或移除最后一个启用的超级管理员")
+	}
+	return nil
+}
+
 func (s *UserService) ResetPassword(ctx context.Context, userID uint) (string, error) {
 	var user models.User
 	if err := s.ctx.DB().Where("id = ?", userID).First(&user).Error; err != nil {
@@ -170,16 +221,21 @@ func (s *UserService) ResetPassword(ctx context.Context, userID uint) (string, e
 		return "", err
 	}
 
-	newPassword := s.generateRandomPassword()
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	passwords := s.ctx.GetPasswordService()
+	newPassword, err := passwords.Generate()
 	if err != nil {
-		return "", errors.InternalErrorMsg("密码加密失败")
+		return "", err
 	}
-
-	user.Password = string(hashedPassword)
+	if err := passwords.SetPassword(ctx, &user, newPassword); err != nil {
+		return "", err
+	}
+	user.MustChangePassword = true
 	if err := s.ctx.DB().Save(&user).Error; err != nil {
 		return "", err
 	}
+	if err := passwords.RecordHistory(ctx, &user); err != nil {
+		return "", err
+	}
 
 	return newPassword, nil
 }
@@ -194,6 +250,9 @@ func (s *UserService) ToggleStatus(ctx context.Context, userID uint) error {
 	}
 
 	if user.Status == 1 {
+		if err := ensureNotLastSuperAdmin(s.ctx.DB(), &user); err != nil {
+			return err
+		}
 		user.Status = 0
 	} else {
 		user.Status = 1
@@ -206,24 +265,6 @@ func (s *UserService) ToggleStatus(ctx context.Context, userID uint) error {
 	return nil
 }
 
-func (s *UserService) generateRandomPassword() string {
-	const numbers = "0123456789"
-	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	const allChars = numbers + letters
-
-	password := make([]byte, 6)
-	password[0] = numbers[rand.Intn(len(numbers))]
-	password[1] = letters[rand.Intn(len(letters))]
-	for i := 2; i < 6; i++ {
-		password[i] = allChars[rand.Intn(len(allChars))]
-	}
-	rand.Shuffle(len(password), func(i, j int) {
-		password[i], password[j] = password[j], password[i]
-	})
-
-	return string(password)
-}
-
 func (s *UserService) DeleteUser(ctx context.Context, userID uint) error {
 	var user models.User
 	if err := s.ctx.DB().Where("id = ?", userID).First(&user).Error; err != nil {
@@ -232,6 +273,9 @@ func (s *UserService) DeleteUser(ctx context.Context, userID uint) error {
 		}
 		return err
 	}
+	if err := ensureNotLastSuperAdmin(s.ctx.DB(), &user); err != nil {
+		return err
+	}
 
 	s.ctx.DB().Model(&user).Association("Roles").Clear()
 	if err := s.ctx.DB().Delete(&user).Error; err != nil {
@@ -240,3 +284,45 @@ func (s *UserService) DeleteUser(ctx context.Context, userID uint) error {
 
 	return nil
 }
+
+// AssignOrganization 设置用户所属部门与岗位；departmentID 为 0 表示不属于任何部门，positionIDs 为空时清空岗位
+// 禁用的部门与岗位不能分配
+func (s *UserService) AssignOrganization(ctx context.Context, userID, departmentID uint, positionIDs []uint) error {
+	var user models.User
+	if err := s.ctx.DB().Where("id = ?", userID).First(&user).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NotFoundMsg("用户不存在")
+		}
+		return err
+	}
+
+	if departmentID != 0 && departmentID != user.DepartmentID {
+		var dept models.Department
+		if err := s.ctx.DB().Where("id = ?", departmentID).First(&dept).Error; err != nil {
+			if stderrors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.BadRequestMsg("部门不存在")
+			}
+			return err
+		}
+		if dept.Status != 1 {
+			return errors.BadRequestMsg("部门已禁用")
+		}
+	}
+
+	var positions []models.Position
+	if len(positionIDs) > 0 {
+		if err := s.ctx.DB().Where("id IN ? AND status = ?", positionIDs, 1).Find(&positions).Error; err != nil {
+			return err
+		}
+		if len(positions) != len(uniqueUints(positionIDs)) {
+			return errors.BadRequestMsg("岗位不存在或已禁用")
+		}
+	}
+
+	return s.ctx.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("department_id", departmentID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Association("Positions").Replace(positions)
+	})
+}
@@ -2,16 +2,19 @@ package services
 
 import (
 	"context"
+	"strconv"
 	"testing"
 
 	"github.com/lyuangg/gadmin/models"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestUserService_GetUsers(t *testing.T) {
 	db := NewTestDB(t)
 	ctx := NewTestServiceContext(t, db)
 	svc := NewUserService(ctx)
-	bg := context.Background()
+	bg := Unrestricted(context.Background())
 
 	users, total, err := svc.GetUsers(bg, 1, 10, nil)
 	if err != nil {
@@ -22,7 +25,7 @@ func TestUserService_GetUsers(t *testing.T) {
 	}
 
 	// 创建用户后再查
-	_, err = svc.CreateUser(bg, "u1", "pass123", "昵称", 0, "", nil)
+	_, err = svc.CreateUser(bg, "u1", "Secret#2024", "昵称", 0, "", nil)
 	if err != nil {
 		t.Fatalf("CreateUser: %v", err)
 	}
@@ -41,7 +44,7 @@ func TestUserService_CreateUser(t *testing.T) {
 	svc := NewUserService(ctx)
 	bg := context.Background()
 
-	user, err := svc.CreateUser(bg, "newuser", "mypass6", "新用户", 0, "备注", nil)
+	user, err := svc.CreateUser(bg, "newuser", "Secret#2024", "新用户", 0, "备注", nil)
 	if err != nil {
 		t.Fatalf("CreateUser: %v", err)
 	}
@@ -56,6 +59,9 @@ func TestUserService_CreateUser(t *testing.T) {
 	if u.Password == "" {
 		t.Error("password should be set")
 	}
+	if !u.MustChangePassword {
+		t.Error("user created by admin should change password at first login")
+	}
 }
 
 func TestUserService_CreateUser_DuplicateUsername(t *testing.T) {
@@ -64,7 +70,7 @@ func TestUserService_CreateUser_DuplicateUsername(t *testing.T) {
 	svc := NewUserService(ctx)
 	bg := context.Background()
 
-	_, _ = svc.CreateUser(bg, "dup", "pass123", "", 0, "", nil)
+	_, _ = svc.CreateUser(bg, "dup", "Secret#2024", "", 0, "", nil)
 	_, err := svc.CreateUser(bg, "dup", "other", "", 0, "", nil)
 	if err == nil {
 		t.Error("expected error for duplicate username")
@@ -75,9 +81,9 @@ func TestUserService_GetUsers_ByID(t *testing.T) {
 	db := NewTestDB(t)
 	ctx := NewTestServiceContext(t, db)
 	svc := NewUserService(ctx)
-	bg := context.Background()
+	bg := Unrestricted(context.Background())
 
-	_, _ = svc.CreateUser(bg, "byid", "pass", "ByID", 0, "", nil)
+	_, _ = svc.CreateUser(bg, "byid", "Secret#2024", "ByID", 0, "", nil)
 	users, total, err := svc.GetUsers(bg, 1, 10, map[string]string{"username": "byid"})
 	if err != nil {
 		t.Fatalf("GetUsers: %v", err)
@@ -96,8 +102,8 @@ func TestUserService_UpdateUser(t *testing.T) {
 	svc := NewUserService(ctx)
 	bg := context.Background()
 
-	created, _ := svc.CreateUser(bg, "upuser", "oldpass", "旧昵称", 0, "", nil)
-	err := svc.UpdateUser(bg, created.ID, "新昵称", "newpass6", "备注", nil)
+	created, _ := svc.CreateUser(bg, "upuser", "Secret#2024", "旧昵称", 0, "", nil)
+	err := svc.UpdateUser(bg, created.ID, "新昵称", "Secret#2025", "备注", nil)
 	if err != nil {
 		t.Fatalf("UpdateUser: %v", err)
 	}
@@ -116,7 +122,7 @@ func TestUserService_DeleteUser(t *testing.T) {
 	svc := NewUserService(ctx)
 	bg := context.Background()
 
-	created, _ := svc.CreateUser(bg, "deluser", "pass", "", 0, "", nil)
+	created, _ := svc.CreateUser(bg, "deluser", "Secret#2024", "", 0, "", nil)
 	err := svc.DeleteUser(bg, created.ID)
 	if err != nil {
 		t.Fatalf("DeleteUser: %v", err)
@@ -133,7 +139,7 @@ func TestUserService_ToggleStatus(t *testing.T) {
 	svc := NewUserService(ctx)
 	bg := context.Background()
 
-	created, _ := svc.CreateUser(bg, "toggle", "pass", "", 0, "", nil)
+	created, _ := svc.CreateUser(bg, "toggle", "Secret#2024", "", 0, "", nil)
 	if created.Status != 1 {
 		t.Errorf("default status expected 1, got %d", created.Status)
 	}
@@ -150,8 +156,164 @@ func TestUserService_ToggleStatus(t *testing.T) {
 	}
 }
 
-// ResetPassword 使用随机密码，单测不断言具体密码值，仅跳过或做弱断言。
-// 此处选择跳过：依赖随机生成密码，难以稳定断言。
+// GetUserForAuth 返回当前角色：角色调整与删除后立即反映，供认证中间件计算授权
+func TestUserService_GetUserForAuth_CurrentRoles(t *testing.T) {
+	db := NewTestDB(t)
+	sc := NewTestServiceContext(t, db)
+	svc := NewUserService(sc)
+	bg := context.Background()
+
+	superRole := models.Role{Name: "超级管理员", Code: models.RoleCodeSuperAdmin}
+	opsRole := models.Role{Name: "运维"}
+	db.Create(&superRole)
+	db.Create(&opsRole)
+	svc.CreateUser(bg, "root", "Secret#2024", "", 0, "", []uint{superRole.ID})
+	user, _ := svc.CreateUser(bg, "alice", "Secret#2024", "", 0, "", []uint{superRole.ID, opsRole.ID})
+
+	got, err := svc.GetUserForAuth(bg, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserForAuth: %v", err)
+	}
+	if len(got.Roles) != 2 || !IsSuperAdmin(got) {
+		t.Fatalf("roles = %+v", got.Roles)
+	}
+
+	if err := svc.UpdateUser(bg, user.ID, "", "", "", []uint{opsRole.ID}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	got, _ = svc.GetUserForAuth(bg, user.ID)
+	if len(got.Roles) != 1 || got.Roles[0].ID != opsRole.ID || IsSuperAdmin(got) {
+		t.Errorf("after UpdateUser roles = %+v", got.Roles)
+	}
+
+	if err := NewRoleService(sc).DeleteRole(bg, opsRole.ID); err != nil {
+		t.Fatalf("DeleteRole: %v", err)
+	}
+	got, _ = svc.GetUserForAuth(bg, user.ID)
+	if len(got.Roles) != 0 {
+		t.Errorf("after DeleteRole roles = %+v", got.Roles)
+	}
+}
+
+// 最后一个启用的超级管理员不能被禁用、删除或移除角色
+func TestUserService_LastSuperAdminProtected(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewUserService(NewTestServiceContext(t, db))
+	bg := context.Background()
+
+	superRole := models.Role{Name: "超级管理员", Code: models.RoleCodeSuperAdmin}
+	otherRole := models.Role{Name: "运维"}
+	db.Create(&superRole)
+	db.Create(&otherRole)
+	admin, _ := svc.CreateUser(bg, "root", "Secret#2024", "", 0, "", []uint{superRole.ID})
+
+	if err := svc.ToggleStatus(bg, admin.ID); err == nil {
+		t.Error("expected error when disabling last super admin")
+	}
+	if err := svc.DeleteUser(bg, admin.ID); err == nil {
+		t.Error("expected error when deleting last super admin")
+	}
+	if err := svc.UpdateUser(bg, admin.ID, "", "", "", []uint{otherRole.ID}); err == nil {
+		t.Error("expected error when removing super admin role from last super admin")
+	}
+	if err := svc.UpdateUser(bg, admin.ID, "新昵称", "", "", nil); err != nil {
+		t.Errorf("UpdateUser without role change: %v", err)
+	}
+
+	// 另一个超级管理员被禁用时不算数；启用后即可禁用第一个
+	second, _ := svc.CreateUser(bg, "root2", "Secret#2024", "", 0, "", []uint{superRole.ID})
+	db.Model(second).Update("status", 0)
+	if err := svc.ToggleStatus(bg, admin.ID); err == nil {
+		t.Error("disabled super admin should not count")
+	}
+	db.Model(second).Update("status", 1)
+	if err := svc.ToggleStatus(bg, admin.ID); err != nil {
+		t.Errorf("ToggleStatus with another active super admin: %v", err)
+	}
+}
+
+// ResetPassword 使用随机密码，不断言具体值，只确认返回的密码满足策略且已生效
 func TestUserService_ResetPassword(t *testing.T) {
-	t.Skip("ResetPassword 使用随机密码，单测跳过")
+	db := NewTestDB(t)
+	ctx := NewTestServiceContext(t, db)
+	svc := NewUserService(ctx)
+	bg := context.Background()
+
+	created, _ := svc.CreateUser(bg, "reset", "Secret#2024", "", 0, "", nil)
+	password, err := svc.ResetPassword(bg, created.ID)
+	if err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if err := ctx.GetPasswordService().Validate(bg, &models.User{}, password); err != nil {
+		t.Errorf("reset password %q violates policy: %v", password, err)
+	}
+	var u models.User
+	db.First(&u, created.ID)
+	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) != nil {
+		t.Error("reset password was not saved")
+	}
+	if !u.MustChangePassword {
+		t.Error("reset password should require a change at next login")
+	}
+}
+
+func TestUserService_CreateUser_WeakPassword(t *testing.T) {
+	db := NewTestDB(t)
+	ctx := NewTestServiceContext(t, db)
+	svc := NewUserService(ctx)
+
+	if _, err := svc.CreateUser(context.Background(), "weak", "pass123", "", 0, "", nil); err == nil {
+		t.Error("weak password should be rejected")
+	}
+}
+
+func TestUserService_AssignOrganization(t *testing.T) {
+	db := NewTestDB(t)
+	svc := NewUserService(NewTestServiceContext(t, db))
+	bg := Unrestricted(context.Background())
+
+	root := models.Department{Name: "总部", Status: 1}
+	db.Create(&root)
+	dev := models.Department{Name: "研发", ParentID: root.ID, Status: 1}
+	db.Create(&dev)
+	closed := models.Department{Name: "已撤销"}
+	db.Create(&closed)
+	db.Model(&closed).Update("status", 0)
+	pos := models.Position{Code: "dev", Name: "开发", Status: 1}
+	db.Create(&pos)
+
+	rootUser := models.User{Username: "boss", Password: "x"}
+	db.Create(&rootUser)
+	devUser := models.User{Username: "dev", Password: "x"}
+	db.Create(&devUser)
+	db.Create(&models.User{Username: "other", Password: "x"})
+
+	if err := svc.AssignOrganization(bg, rootUser.ID, root.ID, nil); err != nil {
+		t.Fatalf("AssignOrganization: %v", err)
+	}
+	if err := svc.AssignOrganization(bg, devUser.ID, dev.ID, []uint{pos.ID}); err != nil {
+		t.Fatalf("AssignOrganization: %v", err)
+	}
+	if err := svc.AssignOrganization(bg, devUser.ID, closed.ID, nil); err == nil {
+		t.Error("expected error for disabled department")
+	}
+	if err := svc.AssignOrganization(bg, devUser.ID, dev.ID, []uint{pos.ID + 100}); err == nil {
+		t.Error("expected error for missing position")
+	}
+
+	// 按部门筛选包含下级部门
+	users, total, err := svc.GetUsers(bg, 1, 10, map[string]string{"department_id": strconv.Itoa(int(root.ID)), "order_by": "id_asc"})
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if total != 2 || users[0].Username != "boss" || users[1].Username != "dev" {
+		t.Fatalf("expected boss and dev, got total=%d %+v", total, users)
+	}
+	if users[1].Department == nil || users[1].Department.Name != "研发" || len(users[1].Positions) != 1 {
+		t.Errorf("expected department and positions preloaded, got %+v", users[1])
+	}
+	_, total, _ = svc.GetUsers(bg, 1, 10, map[string]string{"department_id": strconv.Itoa(int(dev.ID))})
+	if total != 1 {
+		t.Errorf("expected 1 user in dev, got %d", total)
+	}
 }
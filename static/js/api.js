@@ -17,6 +17,77 @@ function clearErrorMessageCache() {
     errorMessageCache.forbidden.timestamp = 0;
 }
 
+// 刷新 Token：并发的 401 请求共用同一次刷新
+var refreshPromise = null;
+
+// 保存新签发的访问 Token（刷新 Token 由服务端写入 HttpOnly cookie）
+function saveAccessToken(data) {
+    localStorage.setItem('token', data.token);
+    document.cookie = 'token=' + data.token + '; path=/; max-age=' + (data.expires_in || 900);
+}
+
+function refreshAccessToken() {
+    if (!refreshPromise) {
+        refreshPromise = axios.post('/api/token/refresh', {}).then(function(response) {
+            var data = response.data;
+            if (!data || data.code !== 0 || !data.data) {
+                return Promise.reject(new Error((data && data.msg) || '刷新Token失败'));
+            }
+            saveAccessToken(data.data);
+            return data.data.token;
+        }).finally(function() {
+            refreshPromise = null;
+        });
+    }
+    return refreshPromise;
+}
+
+// 模拟登录状态（发起人与被模拟用户的用户名），未模拟时为 null
+function getImpersonation() {
+    try {
+        return JSON.parse(localStorage.getItem('impersonation') || 'null');
+    } catch (e) {
+        return null;
+    }
+}
+
+// 开始模拟登录：暂存发起人的用户信息，换成被模拟用户的 Token 与用户信息
+function startImpersonation(data) {
+    localStorage.setItem('impersonator_user', localStorage.getItem('user') || '');
+    localStorage.setItem('impersonation', JSON.stringify({
+        impersonator: data.impersonator.username,
+        username: data.user.username
+    }));
+    saveAccessToken(data);
+    localStorage.setItem('user', JSON.stringify(data.user));
+    if (window.PermissionManager) {
+        window.PermissionManager.clearCache();
+    }
+    window.location.href = '/admin';
+}
+
+// 清除模拟登录状态并恢复发起人的用户信息
+function clearImpersonation() {
+    var impersonatorUser = localStorage.getItem('impersonator_user');
+    if (impersonatorUser) {
+        localStorage.setItem('user', impersonatorUser);
+    }
+    localStorage.removeItem('impersonator_user');
+    localStorage.removeItem('impersonation');
+    if (window.PermissionManager) {
+        window.PermissionManager.clearCache();
+    }
+}
+
+// 退出模拟登录：刷新 Token 仍是发起人的，刷新后即换回自己的身份
+function stopImpersonation() {
+    var done = function() {
+        clearImpersonation();
+        window.location.href = '/admin/users';
+    };
+    return refreshAccessToken().then(done, done);
+}
+
 // 创建 axios 实例
 var api = {
     // 基础请求方法
@@ -30,6 +101,15 @@ var api = {
                 }
                 config.headers['Authorization'] = 'Bearer ' + token;
             }
+            // 以 cookie 认证时写请求须带 CSRF Token（双提交，值取自 csrf_token cookie）
+            var method = (config.method || 'get').toLowerCase();
+            var csrfToken = getCookie('csrf_token');
+            if (csrfToken && ['get', 'head', 'options'].indexOf(method) === -1) {
+                if (!config.headers) {
+                    config.headers = {};
+                }
+                config.headers['X-CSRF-Token'] = csrfToken;
+            }
         }
         
         return axios(config).then(function(response) {
@@ -77,6 +157,26 @@ var api = {
                 }
             }
             
+            // 模拟登录到期或发起人的会话失效：结束模拟，不以发起人身份重试
+            if (errorCode === 401 && getImpersonation()) {
+                if (typeof ElMessage !== 'undefined') {
+                    ElMessage.warning('模拟登录已结束');
+                }
+                return stopImpersonation().then(function() {
+                    return Promise.reject(error);
+                });
+            }
+
+            // 访问 Token 过期：先尝试用刷新 Token 换新 Token 并重试一次；
+            // 刷新失败时重试请求仍会 401，走下方的重新登录提示
+            if (errorCode === 401 && !config._retried) {
+                config._retried = true;
+                var retry = function() {
+                    return api.request(config);
+                };
+                return refreshAccessToken().then(retry, retry);
+            }
+
             // 根据业务错误码处理
             if (errorCode === 401) {
                 // 401: 未认证（Token 无效、过期、失效等）
@@ -88,6 +188,7 @@ var api = {
                     // 清除 token
                     localStorage.removeItem('token');
                     localStorage.removeItem('user');
+                    clearImpersonation();
                     document.cookie = 'token=; path=/; max-age=0';
                     // 跳转到登录页
                     window.location.href = '/login';
@@ -227,6 +328,10 @@ var api = {
             return api.post('/admin/api/permissions/batch-delete', {
                 ids: ids
             });
+        },
+        // 预览路由与权限表的差异（不修改数据）
+        syncPreview: function() {
+            return api.get('/admin/api/permissions/sync-preview');
         }
     },
     
@@ -255,6 +360,16 @@ var api = {
             return api.put('/admin/api/roles/' + id + '/permissions', {
                 permission_ids: permissionIds
             });
+        },
+        // 设置是否强制两步验证
+        setRequire2FA: function(id, required) {
+            return api.put('/admin/api/roles/' + id + '/require-2fa', {
+                require_2fa: required
+            });
+        },
+        // 设置数据范围，data: { data_scope, department_ids }
+        setDataScope: function(id, data) {
+            return api.put('/admin/api/roles/' + id + '/data-scope', data);
         }
     },
     
@@ -285,6 +400,111 @@ var api = {
         // 切换用户状态（启用/禁用）
         toggleStatus: function(id) {
             return api.put('/admin/api/users/' + id + '/toggle-status', {});
+        },
+        // 设置所属部门与岗位，data: { department_id, position_ids }
+        assignOrganization: function(id, data) {
+            return api.put('/admin/api/users/' + id + '/organization', data);
+        },
+        // 重置两步验证
+        resetTwoFactor: function(id) {
+            return api.delete('/admin/api/users/' + id + '/2fa');
+        },
+        // 解除登录失败锁定
+        unlock: function(id) {
+            return api.post('/admin/api/users/' + id + '/unlock', {});
+        },
+        // 模拟登录（仅超级管理员）
+        impersonate: function(id) {
+            return api.post('/admin/api/users/' + id + '/impersonate', {});
+        }
+    },
+
+    /**
+     * 登录安全 API
+     */
+    loginLocks: {
+        // 当前被锁定的用户名 / IP（支持按 scope、target 筛选）
+        getList: function(params) {
+            return api.get('/admin/api/login-locks', { params: params || {} });
+        },
+        // 解除锁定
+        unlock: function(id) {
+            return api.delete('/admin/api/login-locks/' + id);
+        },
+        // 锁定与解锁审计记录（支持按 scope、target、action 筛选）
+        getEvents: function(params) {
+            return api.get('/admin/api/login-locks/events', { params: params || {} });
+        }
+    },
+
+    /**
+     * IP 访问规则 API（user_id 为 0 的是全局规则）
+     */
+    ipRules: {
+        // 支持按 scope（global / user）、user_id、action、cidr 筛选
+        getList: function(params) {
+            return api.get('/admin/api/ip-rules', { params: params || {} });
+        },
+        create: function(data) {
+            return api.post('/admin/api/ip-rules', data);
+        },
+        update: function(id, data) {
+            return api.put('/admin/api/ip-rules/' + id, data);
+        },
+        delete: function(id) {
+            return api.delete('/admin/api/ip-rules/' + id);
+        }
+    },
+
+    /**
+     * 组织架构 API
+     */
+    departments: {
+        // 返回部门树，支持按 name、status 筛选
+        getTree: function(params) {
+            return api.get('/admin/api/departments/tree', { params: params || {} });
+        },
+        create: function(data) {
+            return api.post('/admin/api/departments', data);
+        },
+        update: function(id, data) {
+            return api.put('/admin/api/departments/' + id, data);
+        },
+        delete: function(id) {
+            return api.delete('/admin/api/departments/' + id);
+        }
+    },
+    positions: {
+        getList: function(params) {
+            return api.get('/admin/api/positions', { params: params || {} });
+        },
+        create: function(data) {
+            return api.post('/admin/api/positions', data);
+        },
+        update: function(id, data) {
+            return api.put('/admin/api/positions/' + id, data);
+        },
+        delete: function(id) {
+            return api.delete('/admin/api/positions/' + id);
+        }
+    },
+
+    /**
+     * 菜单管理 API
+     */
+    menus: {
+        // 返回完整的菜单树（含按钮与绑定的权限）
+        getTree: function() {
+            return api.get('/admin/api/menus/tree');
+        },
+        create: function(data) {
+            return api.post('/admin/api/menus', data);
+        },
+        update: function(id, data) {
+            return api.put('/admin/api/menus/' + id, data);
+        },
+        delete: function(id) {
+            return api.delete('/admin/api/menus/' + id);
         }
     },
 
@@ -330,6 +550,23 @@ var api = {
         // 获取操作日志列表（支持分页和筛选）
         getList: function(params) {
             return api.get('/admin/api/operation-logs', { params: params });
+        },
+        // 生成导出 CSV 的下载地址（带短期 Token，筛选条件同列表）
+        exportUrl: function(params) {
+            return api.profile.queryTokenUrl('/admin/download/operation-logs').then(function(url) {
+                var query = new URLSearchParams(params || {}).toString();
+                return query ? url + '&' + query : url;
+            });
+        }
+    },
+
+    /**
+     * 安全事件 API
+     */
+    securityEvents: {
+        // 获取安全事件列表（支持分页和筛选）
+        getList: function(params) {
+            return api.get('/admin/api/security-events', { params: params });
         }
     },
     
@@ -349,6 +586,58 @@ var api = {
             return api.put('/admin/api/profile/avatar', {
                 avatar: avatarURL
             });
+        },
+        // 当前用户的登录会话（设备）列表
+        getSessions: function() {
+            return api.get('/admin/api/profile/sessions');
+        },
+        // 下线自己的某个会话
+        revokeSession: function(id) {
+            return api.delete('/admin/api/profile/sessions/' + id);
+        },
+        // 两步验证状态
+        getTwoFactor: function() {
+            return api.get('/admin/api/profile/2fa');
+        },
+        // 获取两步验证绑定密钥
+        setupTwoFactor: function() {
+            return api.post('/admin/api/profile/2fa/setup', {});
+        },
+        // 确认绑定并开启两步验证
+        enableTwoFactor: function(code) {
+            return api.post('/admin/api/profile/2fa/enable', { code: code });
+        },
+        // 关闭两步验证
+        disableTwoFactor: function(code) {
+            return api.post('/admin/api/profile/2fa/disable', { code: code });
+        },
+        // 重新生成备用码
+        regenerateBackupCodes: function(code) {
+            return api.post('/admin/api/profile/2fa/backup-codes', { code: code });
+        },
+        // 为下载类链接生成带短期 Token 的地址（path 须是接受查询参数 Token 的路由）
+        queryTokenUrl: function(path) {
+            return api.post('/admin/api/profile/query-token', { path: path }).then(function(res) {
+                return path + '?token=' + encodeURIComponent(res.data.token);
+            });
+        }
+    },
+
+    /**
+     * 会话管理 API
+     */
+    sessions: {
+        // 查询在线会话（支持按 user_id、username 筛选）
+        getList: function(params) {
+            return api.get('/admin/api/sessions', { params: params || {} });
+        },
+        // 强制下线会话
+        revoke: function(id) {
+            return api.delete('/admin/api/sessions/' + id);
+        },
+        // 强制下线用户的全部会话
+        revokeUser: function(userId) {
+            return api.delete('/admin/api/users/' + userId + '/sessions');
         }
     },
     
@@ -363,6 +652,10 @@ var api = {
         // 获取当前用户权限
         getUserPermissions: function() {
             return api.get('/admin/api/user/permissions');
+        },
+        // 获取当前用户可见的菜单与按钮
+        getUserMenus: function() {
+            return api.get('/admin/api/user/menus');
         }
     }
 };
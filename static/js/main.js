@@ -38,25 +38,31 @@ function handleLogout() {
             window.PermissionManager.clearCache();
         }
         
-        // 获取 token
-        const token = getToken();
+        var clearAndRedirect = function() {
+            // 清除本地存储
+            localStorage.removeItem('token');
+            localStorage.removeItem('user');
+            localStorage.removeItem('impersonation');
+            localStorage.removeItem('impersonator_user');
+            
+            // 清除 cookie
+            document.cookie = 'token=; path=/; max-age=0';
+            
+            // 跳转到登录页
+            window.location.href = '/login';
+        };
         
-        // 调用退出登录接口
-        if (token) {
-            api.auth.logout().catch(function() {
-                // 忽略错误，继续执行退出逻辑
-            });
+        // 等退出登录接口返回后再跳转，否则请求可能被页面跳转取消，服务端会话未吊销
+        if (!getToken()) {
+            clearAndRedirect();
+            return;
         }
-        
-        // 清除本地存储
-        localStorage.removeItem('token');
-        localStorage.removeItem('user');
-        
-        // 清除 cookie
-        document.cookie = 'token=; path=/; max-age=0';
-        
-        // 跳转到登录页
-        window.location.href = '/login';
+        api.auth.logout().then(clearAndRedirect).catch(function(err) {
+            console.error('退出登录失败:', err);
+            var msg = (err.response && err.response.data && err.response.data.msg) || '网络错误';
+            ElMessage.error('退出登录失败：' + msg + '，服务端会话可能仍有效，已清除本地登录状态');
+            setTimeout(clearAndRedirect, 1500);
+        });
     }
 }
 
@@ -177,34 +183,25 @@ window.createBaseAppConfig = function() {
                 // 权限状态（用于触发响应式更新）
                 permissionsReady: false,
                 isSuperAdmin: false,  // 超级管理员状态
-                // 菜单配置（来自 permission.js，唯一数据源）
-                menuItems: window.PermissionManager && window.PermissionManager.menuItems ? window.PermissionManager.menuItems : []
+                impersonation: getImpersonation()  // 模拟登录状态，用于顶部提示条
             };
         },
         computed: {
-            // 过滤后的菜单（根据权限）
+            // 侧栏菜单树（服务端已按权限过滤，目录带 children）
             visibleMenus: function() {
-                var self = this;
-                // 如果权限管理器未初始化，至少显示不需要权限的菜单（如首页）
+                // 读取 permissionsReady 以建立依赖关系，权限加载完成后重新计算
+                var _ = this.permissionsReady;
                 if (!window.PermissionManager || !window.PermissionManager.initialized) {
-                    return this.menuItems.filter(function(menu) {
-                        return !menu.permission; // 只显示不需要权限的菜单
-                    });
+                    return [];
                 }
-                // 使用 permissionsReady 和 isSuperAdmin 触发响应式更新
-                var _ = this.permissionsReady; // 读取 permissionsReady 以建立依赖关系
-                var isSuperAdmin = this.isSuperAdmin; // 读取 isSuperAdmin 以建立依赖关系
-                return this.menuItems.filter(function(menu) {
-                    // 不需要权限的菜单始终显示
-                    if (!menu.permission) {
-                        return true;
-                    }
-                    // 超级管理员显示所有菜单（使用 Vue data 中的状态）
-                    if (isSuperAdmin) {
-                        return true;
-                    }
-                    // 检查菜单权限
-                    return window.PermissionManager.isMenuVisible(menu.path);
+                return window.PermissionManager.menus;
+            },
+            // 默认展开所有目录
+            openedMenus: function() {
+                return this.visibleMenus.filter(function(menu) {
+                    return menu.type === 'directory';
+                }).map(function(menu) {
+                    return 'dir-' + menu.id;
                 });
             }
         },
@@ -225,10 +222,15 @@ window.createBaseAppConfig = function() {
                     this.navigate('/admin/avatar');
                 } else if (command === 'password') {
                     this.navigate('/admin/password');
+                } else if (command === 'twoFactor') {
+                    this.navigate('/admin/two-factor');
                 } else if (command === 'logout') {
                     this.handleLogout();
                 }
             },
+            handleStopImpersonation() {
+                stopImpersonation();
+            },
             handleDropdownVisibleChange(visible) {
                 this.userMenuVisible = visible;
             },
@@ -281,11 +283,15 @@ window.createBaseAppConfig = function() {
                     api.auth.logout().then(() => {
                         localStorage.removeItem('token');
                         localStorage.removeItem('user');
+                        localStorage.removeItem('impersonation');
+                        localStorage.removeItem('impersonator_user');
                         document.cookie = 'token=; path=/; max-age=0';
                         window.location.href = '/login';
                     }).catch(() => {
                         localStorage.removeItem('token');
                         localStorage.removeItem('user');
+                        localStorage.removeItem('impersonation');
+                        localStorage.removeItem('impersonator_user');
                         document.cookie = 'token=; path=/; max-age=0';
                         window.location.href = '/login';
                     });
@@ -17,45 +17,9 @@
         // localStorage 缓存 key
         CACHE_KEY: 'user_permissions_cache',
 
-        // 菜单配置（唯一数据源，供 main.js 侧栏与权限检查共用）
-        menuItems: [
-            { path: '/admin', name: '首页', icon: 'House', permission: null },
-            { path: '/admin/users', name: '用户管理', icon: 'User', permission: { path: '/admin/api/users', method: 'GET' } },
-            { path: '/admin/roles', name: '角色管理', icon: 'Avatar', permission: { path: '/admin/api/roles', method: 'GET' } },
-            { path: '/admin/permissions', name: '权限管理', icon: 'Lock', permission: { path: '/admin/api/permissions', method: 'GET' } },
-            { path: '/admin/dictionaries', name: '字典管理', icon: 'Collection', permission: { path: '/admin/api/dictionaries/types', method: 'GET' } },
-            { path: '/admin/operation-logs', name: '操作日志', icon: 'Document', permission: { path: '/admin/api/operation-logs', method: 'GET' } },
-        ],
-
-        // 按钮权限映射配置（按页面分组）
-        buttonPermissions: {
-            '/admin/users': {
-                'add': { path: '/admin/api/users', method: 'POST' },
-                'edit': { path: '/admin/api/users/:id', method: 'PUT' },
-                'delete': { path: '/admin/api/users/:id', method: 'DELETE' },
-                'toggleStatus': { path: '/admin/api/users/:id/toggle-status', method: 'PUT' },
-                'resetPassword': { path: '/admin/api/users/:id/reset-password', method: 'POST' }
-            },
-            '/admin/roles': {
-                'add': { path: '/admin/api/roles', method: 'POST' },
-                'edit': { path: '/admin/api/roles/:id', method: 'PUT' },
-                'delete': { path: '/admin/api/roles/:id', method: 'DELETE' },
-                'assignPermissions': { path: '/admin/api/roles/:id/permissions', method: 'PUT' }
-            },
-            '/admin/permissions': {
-                'add': { path: '/admin/api/permissions', method: 'POST' },
-                'edit': { path: '/admin/api/permissions/:id', method: 'PUT' },
-                'delete': { path: '/admin/api/permissions/:id', method: 'DELETE' }
-            },
-            '/admin/dictionaries': {
-                'add': { path: '/admin/api/dictionaries/types', method: 'POST' },
-                'edit': { path: '/admin/api/dictionaries/types/:id', method: 'PUT' },
-                'delete': { path: '/admin/api/dictionaries/types/:id', method: 'DELETE' },
-                'addItem': { path: '/admin/api/dictionaries/items', method: 'POST' },
-                'editItem': { path: '/admin/api/dictionaries/items/:id', method: 'PUT' },
-                'deleteItem': { path: '/admin/api/dictionaries/items/:id', method: 'DELETE' }
-            }
-        },
+        // 侧栏菜单树与各页面可用的按钮，来自 /admin/api/user/menus，已按当前用户权限过滤
+        menus: [],
+        buttons: {},
 
         /**
          * 从 JWT token 中解析 claims（不验证签名，仅解析 payload）
@@ -109,7 +73,8 @@
                 // 检查缓存是否有效（需要包含必要字段）
                 if (cacheData && typeof cacheData === 'object' && 
                     typeof cacheData.is_super_admin !== 'undefined' && 
-                    Array.isArray(cacheData.permissions)) {
+                    Array.isArray(cacheData.permissions) &&
+                    Array.isArray(cacheData.menus)) {
                     
                     // 验证缓存是否与当前 token 匹配（通过解析 token 获取 user_id）
                     var token = getToken();
@@ -134,7 +99,7 @@
 
         /**
          * 将权限数据保存到 localStorage
-         * @param {object} data - 权限数据 {is_super_admin: boolean, permissions: array}
+         * @param {object} data - 权限数据 {is_super_admin: boolean, permissions: array, menus: array, buttons: object}
          */
         saveToCache: function(data) {
             try {
@@ -151,6 +116,8 @@
                 var cacheData = {
                     is_super_admin: data.is_super_admin === true,
                     permissions: data.permissions || [],
+                    menus: data.menus || [],
+                    buttons: data.buttons || {},
                     user_id: userID, // 保存 user_id 用于验证
                     cached_at: Date.now() // 缓存时间戳
                 };
@@ -168,6 +135,8 @@
                 localStorage.removeItem(this.CACHE_KEY);
                 this.initialized = false;
                 this.permissions = [];
+                this.menus = [];
+                this.buttons = {};
                 this.initPromise = null;
             } catch (error) {
                 console.error('清除权限缓存失败:', error);
@@ -176,7 +145,7 @@
 
         /**
          * 初始化权限（页面加载时调用）
-         * 只从缓存或 token 中读取，不主动请求接口
+         * 优先从缓存读取；没有缓存但已登录时（如缓存被清除、升级后缓存格式变化）请求接口获取
          * @returns {Promise} 返回 Promise，权限加载完成后 resolve
          */
         initPermissions: function() {
@@ -190,13 +159,13 @@
                 return this.initPromise;
             }
 
-            var self = this;
-            
             // 首先尝试从 localStorage 缓存读取
             var cachedData = this.loadFromCache();
             if (cachedData) {
                 this.isSuperAdmin = cachedData.is_super_admin === true;
                 this.permissions = cachedData.permissions || [];
+                this.menus = cachedData.menus || [];
+                this.buttons = cachedData.buttons || {};
                 this.initialized = true;
                 console.log('从缓存加载权限:', {
                     isSuperAdmin: this.isSuperAdmin,
@@ -205,28 +174,18 @@
                 this.initPromise = Promise.resolve(true);
                 return this.initPromise;
             }
-            
-            // 缓存不存在，尝试从 token 中解析超级管理员状态
-            var token = getToken();
-            if (token) {
-                var claims = this.parseTokenClaims(token);
-                if (claims && claims.is_super_admin === true) {
-                    // 超级管理员，直接设置状态，不需要请求接口
-                    this.isSuperAdmin = true;
-                    this.permissions = [];
-                    this.initialized = true;
-                    // 缓存超级管理员状态
-                    this.saveToCache({ is_super_admin: true, permissions: [] });
-                    console.log('从 token 中检测到超级管理员');
-                    this.initPromise = Promise.resolve(true);
-                    return this.initPromise;
-                }
+
+            // 缓存不存在：在后台页面且已登录时从接口获取（菜单只能由服务端下发）；
+            // 登录页上残留的 Token 可能已失效，由登录成功后的 fetchAndCachePermissions 获取
+            if (getToken() && window.location.pathname.indexOf('/admin') === 0) {
+                return this.fetchAndCachePermissions();
             }
 
-            // 既没有缓存，也不是超级管理员，设置为未初始化状态
-            // 等待登录时主动调用 fetchAndCachePermissions 获取权限
+            // 未登录，设置为未初始化状态
             this.isSuperAdmin = false;
             this.permissions = [];
+            this.menus = [];
+            this.buttons = {};
             this.initialized = false;
             console.log('未找到权限缓存，等待登录时获取');
             this.initPromise = Promise.resolve(false);
@@ -277,42 +236,40 @@
                 return true;
             }
 
-            // 遍历权限列表，查找匹配的权限
+            // 遍历权限列表，与后端一致：命中任一拒绝规则即无权限，否则需命中允许规则
+            var allowed = false;
             for (var i = 0; i < this.permissions.length; i++) {
                 var perm = this.permissions[i];
                 // 方法必须匹配（不区分大小写）
-                // 注意：如果权限的 method 为空，则匹配所有方法
-                if (perm.method && perm.method.toUpperCase() !== method.toUpperCase()) {
+                // 注意：如果权限的 method 为空或为 *，则匹配所有方法
+                if (perm.method && perm.method !== '*' && perm.method.toUpperCase() !== method.toUpperCase()) {
                     continue;
                 }
                 // 路径匹配
-                if (this.matchPermissionPath(perm.path, path)) {
-                    return true;
+                if (!this.matchPermissionPath(perm.path, path)) {
+                    continue;
                 }
+                if (perm.effect === 'deny') {
+                    return false;
+                }
+                allowed = true;
             }
 
-            return false;
+            return allowed;
         },
 
         /**
-         * 检查菜单是否可见
+         * 检查菜单是否可见（是否在服务端下发的菜单树中）
          * @param {string} menuPath - 菜单路径（如 /admin/users）
          * @returns {boolean}
          */
         isMenuVisible: function(menuPath) {
-            var item = this.menuItems.find(function(m) { return m.path === menuPath; });
-            if (!item || !item.permission) {
-                return true;
-            }
-            var hasPerm = this.hasPermission(item.permission.path, item.permission.method);
-            console.log('检查菜单权限:', {
-                menuPath: menuPath,
-                requiredPath: item.permission.path,
-                requiredMethod: item.permission.method,
-                hasPermission: hasPerm,
-                isSuperAdmin: this.isSuperAdmin
-            });
-            return hasPerm;
+            var find = function(menus) {
+                return menus.some(function(m) {
+                    return m.path === menuPath || find(m.children || []);
+                });
+            };
+            return find(this.menus);
         },
 
         /**
@@ -322,23 +279,9 @@
          * @returns {boolean}
          */
         isButtonVisible: function(pagePath, buttonKey) {
-            // 超级管理员拥有所有权限
-            if (this.isSuperAdmin) {
-                return true;
-            }
-
-            var pageButtons = this.buttonPermissions[pagePath];
-            if (!pageButtons) {
-                return false;
-            }
-
-            var buttonPerm = pageButtons[buttonKey];
-            if (!buttonPerm) {
-                return false;
-            }
-
-            // 检查是否有对应的权限
-            return this.hasPermission(buttonPerm.path, buttonPerm.method);
+            // 按钮在菜单管理中配置，服务端只下发当前用户可用的按钮
+            var pageButtons = this.buttons[pagePath] || [];
+            return pageButtons.indexOf(buttonKey) !== -1;
         },
 
         /**
@@ -364,35 +307,27 @@
             this.initialized = false;
             this.initPromise = null;
             
-            // 从 token 中解析超级管理员状态
-            var token = getToken();
-            if (token) {
-                var claims = this.parseTokenClaims(token);
-                if (claims && claims.is_super_admin === true) {
-                    // 超级管理员，直接设置状态并缓存
-                    this.isSuperAdmin = true;
-                    this.permissions = [];
-                    this.initialized = true;
-                    this.saveToCache({ is_super_admin: true, permissions: [] });
-                    console.log('登录成功：从 token 检测到超级管理员，已缓存');
-                    this.initPromise = Promise.resolve(true);
-                    return this.initPromise;
-                }
-            }
-            
-            // 非超级管理员，请求接口获取权限并缓存
-            this.initPromise = api.auth.getUserPermissions()
-                .then(function(response) {
-                    var data = response && response.data !== undefined ? response.data : response;
+            // 请求接口获取权限与菜单并缓存；token 中的 is_super_admin 是签发时的值，角色调整后可能已过期，不作为依据
+            var unwrap = function(response) {
+                return response && response.data !== undefined ? response.data : response;
+            };
+            this.initPromise = Promise.all([api.auth.getUserPermissions(), api.auth.getUserMenus()])
+                .then(function(responses) {
+                    var data = unwrap(responses[0]);
+                    var menuData = unwrap(responses[1]) || {};
                     
                     if (data && typeof data === 'object') {
                         self.isSuperAdmin = data.is_super_admin === true;
                         self.permissions = data.permissions || [];
+                        self.menus = menuData.menus || [];
+                        self.buttons = menuData.buttons || {};
                         self.initialized = true;
                         // 保存到缓存
                         self.saveToCache({
                             is_super_admin: self.isSuperAdmin,
-                            permissions: self.permissions
+                            permissions: self.permissions,
+                            menus: self.menus,
+                            buttons: self.buttons
                         });
                         console.log('登录成功：权限已获取并缓存', {
                             isSuperAdmin: self.isSuperAdmin,
@@ -413,9 +348,8 @@
         }
     };
 
-    // 页面加载时只从缓存读取权限，不主动请求接口
-    // 权限接口请求只在登录成功后由登录页面主动调用 fetchAndCachePermissions
-    // 这里只做初始化，从缓存或 token 中读取
+    // 页面加载时优先从缓存读取权限；登录成功后由登录页面主动调用 fetchAndCachePermissions
+    // 缓存不存在且已登录时由 initPermissions 请求接口
     if (document.readyState === 'loading') {
         document.addEventListener('DOMContentLoaded', function() {
             window.PermissionManager.initPermissions();
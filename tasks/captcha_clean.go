@@ -0,0 +1,37 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/lyuangg/gadmin/app"
+	"github.com/lyuangg/gadmin/services"
+
+	"github.com/robfig/cron/v3"
+)
+
+// StartCaptchaCleanScheduler 每 10 分钟清理数据库中的过期验证码；验证码使用内存存储时不启动
+func StartCaptchaCleanScheduler(a *app.App) {
+	if a.Config.CaptchaStore != services.CaptchaStoreDB {
+		return
+	}
+	cleaner, ok := a.GetCaptchaProvider().(services.CaptchaCleaner)
+	if !ok {
+		return
+	}
+
+	c := cron.New()
+	_, err := c.AddFunc("*/10 * * * *", func() {
+		deleted, err := cleaner.CleanExpired(context.Background())
+		if err != nil {
+			a.Logger().ErrorContext(context.Background(), "过期验证码清理失败", "error", err)
+		} else if deleted > 0 {
+			a.Logger().InfoContext(context.Background(), "过期验证码清理完成", "deleted", deleted)
+		}
+	})
+	if err != nil {
+		a.Logger().ErrorContext(context.Background(), "注册验证码清理任务失败", "error", err)
+		return
+	}
+	c.Start()
+	a.Logger().InfoContext(context.Background(), "验证码定时清理已启动", "spec", "*/10 * * * *")
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/robfig/cron/v3"
 )
 
-// StartOperationLogCleanScheduler 每天 0 点清理操作日志，保留条数见配置 operation_log_retain_count
+// StartOperationLogCleanScheduler 每天 0 点清理操作日志与安全事件，保留条数见配置 operation_log_retain_count
 func StartOperationLogCleanScheduler(a *app.App) {
 	c := cron.New()
 	_, err := c.AddFunc("0 0 * * *", func() { // 每天 0 点 0 分（标准 5 位：分 时 日 月 周），等价于 @daily
@@ -22,6 +22,14 @@ func StartOperationLogCleanScheduler(a *app.App) {
 		} else {
 			a.Logger().InfoContext(context.Background(), "操作日志定时清理完成", "deleted", deleted, "retain", n)
 		}
+
+		// 安全事件与操作日志使用相同的保留条数
+		deleted, err = a.GetSecurityEventService().CleanOldEvents(context.Background(), n)
+		if err != nil {
+			a.Logger().ErrorContext(context.Background(), "安全事件定时清理失败", "error", err)
+		} else {
+			a.Logger().InfoContext(context.Background(), "安全事件定时清理完成", "deleted", deleted, "retain", n)
+		}
 	})
 	if err != nil {
 		a.Logger().ErrorContext(context.Background(), "注册操作日志定时清理任务失败", "error", err)
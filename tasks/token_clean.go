@@ -0,0 +1,28 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/lyuangg/gadmin/app"
+
+	"github.com/robfig/cron/v3"
+)
+
+// StartTokenCleanScheduler 每小时清理已过期的刷新 Token、会话与两步验证登录挑战
+func StartTokenCleanScheduler(a *app.App) {
+	c := cron.New()
+	_, err := c.AddFunc("0 * * * *", func() {
+		deleted, err := a.GetAuthService().CleanExpired(context.Background())
+		if err != nil {
+			a.Logger().ErrorContext(context.Background(), "过期 Token 清理失败", "error", err)
+		} else if deleted > 0 {
+			a.Logger().InfoContext(context.Background(), "过期 Token 清理完成", "deleted", deleted)
+		}
+	})
+	if err != nil {
+		a.Logger().ErrorContext(context.Background(), "注册过期 Token 清理任务失败", "error", err)
+		return
+	}
+	c.Start()
+	a.Logger().InfoContext(context.Background(), "过期 Token 定时清理已启动", "spec", "0 * * * *")
+}
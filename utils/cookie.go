@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/lyuangg/gadmin/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 双提交 CSRF Token：登录时写入可被前端读取的 cookie，前端在非安全方法的请求头中原样带回
+const (
+	CSRFCookieName = "csrf_token"
+	CSRFHeaderName = "X-CSRF-Token"
+)
+
+// SetCookie 按配置的 Secure / SameSite / Domain 写 cookie
+func SetCookie(c *gin.Context, cfg *config.Config, name, value string, maxAge int, path string, httpOnly bool) {
+	c.SetSameSite(CookieSameSite(cfg))
+	c.SetCookie(name, value, maxAge, path, cfg.CookieDomain, cfg.CookieSecure, httpOnly)
+}
+
+// CookieSameSite 解析 cookie_same_site；浏览器会丢弃未设置 Secure 的 SameSite=None cookie，此时按 Lax 处理
+func CookieSameSite(cfg *config.Config) http.SameSite {
+	switch strings.ToLower(cfg.CookieSameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		if cfg.CookieSecure {
+			return http.SameSiteNoneMode
+		}
+	}
+	return http.SameSiteLaxMode
+}
+
+// VerifyCSRF 请求头中的 CSRF Token 须与 cookie 中的一致
+func VerifyCSRF(c *gin.Context) bool {
+	cookie, err := c.Cookie(CSRFCookieName)
+	if err != nil || cookie == "" {
+		return false
+	}
+	header := c.GetHeader(CSRFHeaderName)
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
@@ -3,9 +3,6 @@ package utils
 import (
 	"context"
 	"errors"
-	"fmt"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/lyuangg/gadmin/config"
@@ -13,10 +10,38 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret []byte
+// defaultAccessTokenTTL 未配置 access_token_ttl_minutes 时的访问 Token 有效期
+const defaultAccessTokenTTL = 15 * time.Minute
 
-func InitJWT(cfg *config.Config) {
-	jwtSecret = []byte(cfg.JWTSecret)
+// defaultQueryTokenTTL 未配置 query_token_ttl_seconds 时查询参数 Token 的有效期
+const defaultQueryTokenTTL = 60 * time.Second
+
+var keySet *jwtKeySet
+var accessTokenTTL = defaultAccessTokenTTL
+var queryTokenTTL = defaultQueryTokenTTL
+
+// InitJWT 加载签名密钥：配置了 jwt_keys 时用其中的 RS256 / EdDSA 密钥签名，否则用 jwt_secret（HS256）；
+// 配置有误或 release 模式下仍使用默认 jwt_secret 时返回错误，应拒绝启动
+func InitJWT(cfg *config.Config) error {
+	set, err := newJWTKeySet(cfg)
+	if err != nil {
+		return err
+	}
+	keySet = set
+	accessTokenTTL = defaultAccessTokenTTL
+	if cfg.AccessTokenTTLMinutes > 0 {
+		accessTokenTTL = time.Duration(cfg.AccessTokenTTLMinutes) * time.Minute
+	}
+	queryTokenTTL = defaultQueryTokenTTL
+	if cfg.QueryTokenTTLSeconds > 0 {
+		queryTokenTTL = time.Duration(cfg.QueryTokenTTLSeconds) * time.Second
+	}
+	return nil
+}
+
+// AccessTokenTTL 返回访问 Token 有效期，供设置 cookie 过期时间与响应 expires_in
+func AccessTokenTTL() time.Duration {
+	return accessTokenTTL
 }
 
 func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
@@ -38,14 +63,27 @@ type Claims struct {
 	Type         int    `json:"type"`
 	IsSuperAdmin bool   `json:"is_super_admin"`
 	RoleIDs      []uint `json:"role_ids"`
+	TokenVersion uint   `json:"token_version"`     // 签发时用户的 token_version，小于当前值即失效
+	Purpose      string `json:"purpose,omitempty"` // 仅查询参数 Token 有：允许访问的请求路径，会话 Token 为空
+
+	// 模拟登录：超级管理员以该用户身份访问时为发起人，正常登录为空
+	ImpersonatorID   uint   `json:"impersonator_id,omitempty"`
+	ImpersonatorName string `json:"impersonator_name,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken tokenVersion 用于退出时失效该用户所有 token
-func GenerateToken(userID uint, username, nickname string, userType int, isSuperAdmin bool, roleIDs []uint, tokenVersion uint) (string, error) {
+// SessionUserID 会话（jti）所属的用户：模拟登录沿用发起人的会话，为发起人 ID，否则为 UserID
+func (c *Claims) SessionUserID() uint {
+	if c.ImpersonatorID != 0 {
+		return c.ImpersonatorID
+	}
+	return c.UserID
+}
+
+// GenerateToken tokenVersion 用于失效该用户所有 token；sessionID 作为 jti，标识一次登录会话（刷新后不变）
+func GenerateToken(userID uint, username, nickname string, userType int, isSuperAdmin bool, roleIDs []uint, tokenVersion uint, sessionID string) (string, error) {
 	nowTime := time.Now()
-	expireTime := nowTime.Add(24 * time.Hour)
-	jti := fmt.Sprintf("%d:%d", userID, tokenVersion)
+	expireTime := nowTime.Add(accessTokenTTL)
 
 	claims := Claims{
 		UserID:       userID,
@@ -54,54 +92,116 @@ func GenerateToken(userID uint, username, nickname string, userType int, isSuper
 		Type:         userType,
 		IsSuperAdmin: isSuperAdmin,
 		RoleIDs:      roleIDs,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expireTime),
 			IssuedAt:  jwt.NewNumericDate(nowTime),
 			Issuer:    "gadmin",
-			ID:        jti,
+			ID:        sessionID,
 		},
 	}
 
+	return signClaims(claims)
+}
+
+// GenerateImpersonationToken 签发模拟登录 Token：身份与权限为被模拟用户，ImpersonatorID 为发起的超级管理员；
+// jti 沿用发起人的会话 ID（发起人退出或被下线即失效），有效期为 ttl，不签发刷新 Token
+func GenerateImpersonationToken(userID uint, username, nickname string, userType int, roleIDs []uint, tokenVersion uint, sessionID string, impersonatorID uint, impersonatorName string, ttl time.Duration) (string, error) {
+	nowTime := time.Now()
+	claims := Claims{
+		UserID:           userID,
+		Username:         username,
+		Nickname:         nickname,
+		Type:             userType,
+		RoleIDs:          roleIDs,
+		TokenVersion:     tokenVersion,
+		ImpersonatorID:   impersonatorID,
+		ImpersonatorName: impersonatorName,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(nowTime.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(nowTime),
+			Issuer:    "gadmin",
+			ID:        sessionID,
+		},
+	}
+	return signClaims(claims)
+}
+
+// QueryTokenTTL 返回查询参数 Token 有效期
+func QueryTokenTTL() time.Duration {
+	return queryTokenTTL
+}
+
+// GenerateQueryToken 以当前会话的 claims 签发只能访问 purpose 路径的短期 Token，用于文件下载等只能通过链接访问的接口；
+// jti 沿用会话 ID，会话被吊销后随之失效
+func GenerateQueryToken(session *Claims, purpose string) (string, error) {
+	if purpose == "" {
+		return "", errors.New("query token purpose is required")
+	}
+	nowTime := time.Now()
+	claims := *session
+	claims.Purpose = purpose
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(nowTime.Add(queryTokenTTL)),
+		IssuedAt:  jwt.NewNumericDate(nowTime),
+		Issuer:    "gadmin",
+		ID:        session.ID,
+	}
+	return signClaims(claims)
+}
+
+func signClaims(claims Claims) (string, error) {
+	if keySet == nil {
+		return "", errors.New("jwt not initialized")
+	}
+	if signer := keySet.signer; signer != nil {
+		tokenClaims := jwt.NewWithClaims(signer.method, claims)
+		tokenClaims.Header["kid"] = signer.kid
+		return tokenClaims.SignedString(signer.privateKey)
+	}
 	tokenClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	token, err := tokenClaims.SignedString(jwtSecret)
+	token, err := tokenClaims.SignedString(keySet.secret)
 	return token, err
 }
 
+// ParseToken 校验会话 Token 的签名与有效期：带 kid 的 Token 用对应密钥验签，轮换后旧密钥签发的 Token 在过期前仍有效；
+// 查询参数 Token 不能当作会话 Token 使用
 func ParseToken(token string) (*Claims, error) {
-	tokenClaims, err := jwt.ParseWithClaims(token, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
-
+	claims, err := parseClaims(token)
 	if err != nil {
 		return nil, err
 	}
-
-	if claims, ok := tokenClaims.Claims.(*Claims); ok && tokenClaims.Valid {
-		return claims, nil
+	if claims.Purpose != "" {
+		return nil, errors.New("query token cannot be used as session token")
 	}
-
-	return nil, errors.New("invalid token")
+	return claims, nil
 }
 
-func GetTokenVersion(claims *Claims) (uint, uint, error) {
-	if claims.ID == "" {
-		return 0, 0, errors.New("token中缺少jti字段")
+// ParseQueryToken 校验查询参数 Token，且其用途须为 purpose（即当前请求路径）
+func ParseQueryToken(token, purpose string) (*Claims, error) {
+	claims, err := parseClaims(token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose == "" || claims.Purpose != purpose {
+		return nil, errors.New("query token purpose mismatch")
 	}
+	return claims, nil
+}
 
-	parts := strings.Split(claims.ID, ":")
-	if len(parts) != 2 {
-		return 0, 0, errors.New("token的jti格式错误")
+func parseClaims(token string) (*Claims, error) {
+	if keySet == nil {
+		return nil, errors.New("jwt not initialized")
 	}
+	tokenClaims, err := jwt.ParseWithClaims(token, &Claims{}, keySet.verifyKey)
 
-	userID, err := strconv.ParseUint(parts[0], 10, 32)
 	if err != nil {
-		return 0, 0, errors.New("无法解析token中的user_id")
+		return nil, err
 	}
 
-	tokenVersion, err := strconv.ParseUint(parts[1], 10, 32)
-	if err != nil {
-		return 0, 0, errors.New("无法解析token中的token_version")
+	if claims, ok := tokenClaims.Claims.(*Claims); ok && tokenClaims.Valid {
+		return claims, nil
 	}
 
-	return uint(userID), uint(tokenVersion), nil
+	return nil, errors.New("invalid token")
 }
@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/lyuangg/gadmin/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSAKeyBits RS256 密钥的最小长度
+const minRSAKeyBits = 2048
+
+// jwtKey 一把已加载的签名/验签密钥
+type jwtKey struct {
+	kid        string
+	method     jwt.SigningMethod
+	privateKey interface{} // 只验签的密钥为 nil
+	publicKey  interface{}
+}
+
+// jwtKeySet 当前签名密钥与全部可验签的密钥
+type jwtKeySet struct {
+	signer *jwtKey
+	keys   map[string]*jwtKey
+	order  []*jwtKey // 按配置顺序，用于输出 JWKS
+	secret []byte    // HS256 密钥；配置了 jwt_keys 时仅在迁移期用于验证旧 Token，为 nil 表示不接受 HS256
+}
+
+// newJWTKeySet 按配置加载密钥：未配置 jwt_keys 时使用 HS256 + jwt_secret，release 模式下拒绝默认密钥
+func newJWTKeySet(cfg *config.Config) (*jwtKeySet, error) {
+	set := &jwtKeySet{keys: make(map[string]*jwtKey, len(cfg.JWTKeys))}
+	if len(cfg.JWTKeys) == 0 {
+		if cfg.JWTSecret == "" {
+			return nil, errors.New("未配置 jwt_secret 或 jwt_keys")
+		}
+		if cfg.GinMode == "release" && cfg.JWTSecret == config.DefaultJWTSecret {
+			return nil, errors.New("release 模式下不能使用默认 jwt_secret，请修改 jwt_secret 或配置 jwt_keys")
+		}
+		set.secret = []byte(cfg.JWTSecret)
+		return set, nil
+	}
+
+	for _, kc := range cfg.JWTKeys {
+		if kc.KID == "" {
+			return nil, errors.New("jwt_keys 中的密钥须配置 kid")
+		}
+		if _, ok := set.keys[kc.KID]; ok {
+			return nil, fmt.Errorf("jwt_keys 中 kid %q 重复", kc.KID)
+		}
+		key, err := loadJWTKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("加载 JWT 密钥 %q 失败: %w", kc.KID, err)
+		}
+		set.keys[key.kid] = key
+		set.order = append(set.order, key)
+		if set.signer == nil && cfg.JWTSigningKeyID == "" && key.privateKey != nil {
+			set.signer = key
+		}
+	}
+	if cfg.JWTSigningKeyID != "" {
+		set.signer = set.keys[cfg.JWTSigningKeyID]
+		if set.signer == nil {
+			return nil, fmt.Errorf("jwt_signing_kid %q 不在 jwt_keys 中", cfg.JWTSigningKeyID)
+		}
+	}
+	if set.signer == nil || set.signer.privateKey == nil {
+		return nil, errors.New("签名密钥须配置 private_key_file")
+	}
+	// 从 HS256 迁移过来时继续接受旧 Token 直到过期；默认密钥不接受
+	if cfg.JWTSecret != "" && cfg.JWTSecret != config.DefaultJWTSecret {
+		set.secret = []byte(cfg.JWTSecret)
+	}
+	return set, nil
+}
+
+// loadJWTKey 读取 PEM 私钥或公钥，并按密钥类型确定签名算法
+func loadJWTKey(kc config.JWTKey) (*jwtKey, error) {
+	key := &jwtKey{kid: kc.KID}
+	switch {
+	case kc.PrivateKeyFile != "":
+		block, err := readPEM(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			if rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes); rsaErr == nil {
+				priv = rsaKey
+			} else {
+				return nil, fmt.Errorf("解析私钥失败: %w", err)
+			}
+		}
+		switch k := priv.(type) {
+		case *rsa.PrivateKey:
+			key.privateKey, key.publicKey = k, &k.PublicKey
+		case ed25519.PrivateKey:
+			key.privateKey, key.publicKey = k, k.Public()
+		default:
+			return nil, fmt.Errorf("不支持的私钥类型 %T，仅支持 RSA 与 Ed25519", priv)
+		}
+	case kc.PublicKeyFile != "":
+		block, err := readPEM(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析公钥失败: %w", err)
+		}
+		key.publicKey = pub
+	default:
+		return nil, errors.New("须配置 private_key_file 或 public_key_file")
+	}
+
+	switch k := key.publicKey.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA 密钥长度不能小于 %d 位", minRSAKeyBits)
+		}
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("不支持的公钥类型 %T，仅支持 RSA 与 Ed25519", key.publicKey)
+	}
+	return key, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s 不是 PEM 格式", path)
+	}
+	return block, nil
+}
+
+// verifyKey 按 Token 头部的 kid 选择验签密钥，并要求算法与密钥一致，防止算法混淆
+func (s *jwtKeySet) verifyKey(token *jwt.Token) (interface{}, error) {
+	if kid, _ := token.Header["kid"].(string); kid != "" {
+		key, ok := s.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.publicKey, nil
+	}
+	if s.secret == nil {
+		return nil, errors.New("token missing kid")
+	}
+	if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return s.secret, nil
+}
+
+// JWK JSON Web Key 中的公钥字段（RFC 7517）
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet /.well-known/jwks.json 的响应体
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 返回全部非对称密钥的公钥，供其他服务验证本系统签发的 Token；仅使用 HS256 时为空
+func JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if keySet == nil {
+		return set
+	}
+	for _, key := range keySet.order {
+		jwk := JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+		switch k := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(k)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lyuangg/gadmin/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKeyFiles 生成 PEM 私钥与公钥文件，返回两者路径
+func writeKeyFiles(t *testing.T, name string, priv crypto.Signer) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("marshal private key: %v", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	privPath := filepath.Join(dir, name+".pem")
+	pubPath := filepath.Join(dir, name+".pub.pem")
+	os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0o600)
+	os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644)
+	return privPath, pubPath
+}
+
+func newRSAKeyFiles(t *testing.T, name string) (string, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	return writeKeyFiles(t, name, key)
+}
+
+func newEd25519KeyFiles(t *testing.T, name string) (string, string) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	return writeKeyFiles(t, name, key)
+}
+
+func tokenHeader(t *testing.T, token string) map[string]interface{} {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified: %v", err)
+	}
+	return parsed.Header
+}
+
+func TestInitJWT_AsymmetricKeys(t *testing.T) {
+	rsaPriv, _ := newRSAKeyFiles(t, "rsa")
+	edPriv, _ := newEd25519KeyFiles(t, "ed")
+
+	tests := []struct {
+		name    string
+		key     config.JWTKey
+		wantAlg string
+	}{
+		{"RS256", config.JWTKey{KID: "rsa-1", PrivateKeyFile: rsaPriv}, "RS256"},
+		{"EdDSA", config.JWTKey{KID: "ed-1", PrivateKeyFile: edPriv}, "EdDSA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := InitJWT(&config.Config{GinMode: "release", JWTSecret: config.DefaultJWTSecret, JWTKeys: []config.JWTKey{tt.key}}); err != nil {
+				t.Fatalf("InitJWT: %v", err)
+			}
+			token, err := GenerateToken(1, "u", "n", 0, false, nil, 0, "sess")
+			if err != nil {
+				t.Fatalf("GenerateToken: %v", err)
+			}
+			header := tokenHeader(t, token)
+			if header["alg"] != tt.wantAlg || header["kid"] != tt.key.KID {
+				t.Errorf("header = %v, want alg %s kid %s", header, tt.wantAlg, tt.key.KID)
+			}
+			if claims, err := ParseToken(token); err != nil || claims.UserID != 1 {
+				t.Errorf("ParseToken = %+v, %v", claims, err)
+			}
+		})
+	}
+}
+
+func TestInitJWT_KeyRotation(t *testing.T) {
+	oldPriv, oldPub := newRSAKeyFiles(t, "old")
+	newPriv, _ := newEd25519KeyFiles(t, "new")
+	if err := InitJWT(&config.Config{JWTKeys: []config.JWTKey{{KID: "k1", PrivateKeyFile: oldPriv}}}); err != nil {
+		t.Fatalf("InitJWT: %v", err)
+	}
+	oldToken, _ := GenerateToken(1, "u", "n", 0, false, nil, 0, "sess")
+
+	// 新密钥签名，旧密钥只保留公钥用于验签
+	err := InitJWT(&config.Config{
+		JWTKeys: []config.JWTKey{
+			{KID: "k1", PublicKeyFile: oldPub},
+			{KID: "k2", PrivateKeyFile: newPriv},
+		},
+		JWTSigningKeyID: "k2",
+	})
+	if err != nil {
+		t.Fatalf("InitJWT after rotation: %v", err)
+	}
+	newToken, _ := GenerateToken(2, "u", "n", 0, false, nil, 0, "sess")
+	if kid := tokenHeader(t, newToken)["kid"]; kid != "k2" {
+		t.Errorf("new token kid = %v, want k2", kid)
+	}
+	if _, err := ParseToken(oldToken); err != nil {
+		t.Errorf("token signed by the retired key should stay valid: %v", err)
+	}
+	if _, err := ParseToken(newToken); err != nil {
+		t.Errorf("ParseToken(new): %v", err)
+	}
+
+	// 旧密钥移除后，其签发的 Token 失效
+	InitJWT(&config.Config{JWTKeys: []config.JWTKey{{KID: "k2", PrivateKeyFile: newPriv}}})
+	if _, err := ParseToken(oldToken); err == nil {
+		t.Error("token with unknown kid should be rejected")
+	}
+
+	jwks := JWKS()
+	if len(jwks.Keys) != 1 || jwks.Keys[0].Kid != "k2" || jwks.Keys[0].Kty != "OKP" || jwks.Keys[0].X == "" {
+		t.Errorf("JWKS = %+v", jwks)
+	}
+}
+
+func TestInitJWT_HS256Migration(t *testing.T) {
+	initTestJWT(t)
+	legacy, _ := GenerateToken(1, "u", "n", 0, false, nil, 0, "sess")
+	priv, _ := newRSAKeyFiles(t, "rsa")
+
+	InitJWT(&config.Config{JWTSecret: testJWTSecret, JWTKeys: []config.JWTKey{{KID: "k1", PrivateKeyFile: priv}}})
+	if _, err := ParseToken(legacy); err != nil {
+		t.Errorf("HS256 token should be accepted while jwt_secret is still set: %v", err)
+	}
+	// 默认密钥不参与验签
+	InitJWT(&config.Config{JWTSecret: config.DefaultJWTSecret, JWTKeys: []config.JWTKey{{KID: "k1", PrivateKeyFile: priv}}})
+	if _, err := ParseToken(legacy); err == nil {
+		t.Error("HS256 token should be rejected without a custom jwt_secret")
+	}
+}
+
+func TestInitJWT_RejectsInvalidConfig(t *testing.T) {
+	_, pub := newRSAKeyFiles(t, "rsa")
+	tests := []struct {
+		name string
+		cfg  *config.Config
+	}{
+		{"default secret in release", &config.Config{GinMode: "release", JWTSecret: config.DefaultJWTSecret}},
+		{"empty secret", &config.Config{}},
+		{"missing kid", &config.Config{JWTKeys: []config.JWTKey{{PublicKeyFile: pub}}}},
+		{"signer without private key", &config.Config{JWTKeys: []config.JWTKey{{KID: "k1", PublicKeyFile: pub}}}},
+		{"unknown signing kid", &config.Config{JWTKeys: []config.JWTKey{{KID: "k1", PublicKeyFile: pub}}, JWTSigningKeyID: "k2"}},
+		{"missing file", &config.Config{JWTKeys: []config.JWTKey{{KID: "k1", PrivateKeyFile: "/nonexistent.pem"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := InitJWT(tt.cfg); err == nil {
+				t.Error("InitJWT should fail")
+			}
+		})
+	}
+	if err := InitJWT(&config.Config{GinMode: "debug", JWTSecret: config.DefaultJWTSecret}); err != nil {
+		t.Errorf("default secret should be allowed outside release: %v", err)
+	}
+}
+
+func TestParseToken_RejectsAlgorithmConfusion(t *testing.T) {
+	priv, pub := newRSAKeyFiles(t, "rsa")
+	InitJWT(&config.Config{JWTKeys: []config.JWTKey{{KID: "k1", PrivateKeyFile: priv}}})
+
+	// 用公钥内容当作 HS256 密钥伪造 Token
+	pubPEM, _ := os.ReadFile(pub)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UserID: 1})
+	forged.Header["kid"] = "k1"
+	token, _ := forged.SignedString(pubPEM)
+	if _, err := ParseToken(token); err == nil || !strings.Contains(err.Error(), "signing method") {
+		t.Errorf("forged HS256 token should be rejected, err = %v", err)
+	}
+}
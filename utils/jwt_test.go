@@ -3,6 +3,7 @@ package utils
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/lyuangg/gadmin/config"
 )
@@ -11,12 +12,14 @@ const testJWTSecret = "test-secret-for-jwt-unit-test"
 
 func initTestJWT(t *testing.T) {
 	t.Helper()
-	InitJWT(&config.Config{JWTSecret: testJWTSecret})
+	if err := InitJWT(&config.Config{JWTSecret: testJWTSecret}); err != nil {
+		t.Fatalf("InitJWT: %v", err)
+	}
 }
 
 func TestGenerateToken_ParseToken_Roundtrip(t *testing.T) {
 	initTestJWT(t)
-	token, err := GenerateToken(1, "user1", "用户1", 0, false, []uint{1, 2}, 3)
+	token, err := GenerateToken(1, "user1", "用户1", 0, false, []uint{1, 2}, 3, "sess-1")
 	if err != nil {
 		t.Fatalf("GenerateToken: %v", err)
 	}
@@ -37,8 +40,8 @@ func TestGenerateToken_ParseToken_Roundtrip(t *testing.T) {
 	if len(claims.RoleIDs) != 2 || claims.RoleIDs[0] != 1 || claims.RoleIDs[1] != 2 {
 		t.Errorf("RoleIDs = %v", claims.RoleIDs)
 	}
-	if claims.ID != "1:3" {
-		t.Errorf("jti = %q, want 1:3", claims.ID)
+	if claims.ID != "sess-1" || claims.TokenVersion != 3 {
+		t.Errorf("jti = %q token_version = %d, want sess-1 3", claims.ID, claims.TokenVersion)
 	}
 }
 
@@ -52,7 +55,7 @@ func TestParseToken_InvalidToken(t *testing.T) {
 
 func TestParseToken_WrongSecret(t *testing.T) {
 	initTestJWT(t)
-	token, _ := GenerateToken(1, "u", "n", 0, false, nil, 0)
+	token, _ := GenerateToken(1, "u", "n", 0, false, nil, 0, "sess")
 	InitJWT(&config.Config{JWTSecret: "other-secret"})
 	_, err := ParseToken(token)
 	if err == nil {
@@ -60,54 +63,6 @@ func TestParseToken_WrongSecret(t *testing.T) {
 	}
 }
 
-func TestGetTokenVersion_Valid(t *testing.T) {
-	claims := &Claims{}
-	claims.ID = "42:5"
-	userID, tokenVersion, err := GetTokenVersion(claims)
-	if err != nil {
-		t.Fatalf("GetTokenVersion: %v", err)
-	}
-	if userID != 42 || tokenVersion != 5 {
-		t.Errorf("userID=%d tokenVersion=%d", userID, tokenVersion)
-	}
-}
-
-func TestGetTokenVersion_EmptyJti(t *testing.T) {
-	claims := &Claims{}
-	claims.ID = ""
-	_, _, err := GetTokenVersion(claims)
-	if err == nil {
-		t.Fatal("empty jti should return error")
-	}
-}
-
-func TestGetTokenVersion_BadFormat(t *testing.T) {
-	claims := &Claims{}
-	claims.ID = "only-one-part"
-	_, _, err := GetTokenVersion(claims)
-	if err == nil {
-		t.Fatal("bad jti format should return error")
-	}
-}
-
-func TestGetTokenVersion_InvalidUserID(t *testing.T) {
-	claims := &Claims{}
-	claims.ID = "abc:1"
-	_, _, err := GetTokenVersion(claims)
-	if err == nil {
-		t.Fatal("non-numeric user_id should return error")
-	}
-}
-
-func TestGetTokenVersion_InvalidTokenVersion(t *testing.T) {
-	claims := &Claims{}
-	claims.ID = "1:xyz"
-	_, _, err := GetTokenVersion(claims)
-	if err == nil {
-		t.Fatal("non-numeric token_version should return error")
-	}
-}
-
 func TestClaimsFromContext_NilContext(t *testing.T) {
 	claims, ok := ClaimsFromContext(nil)
 	if ok || claims != nil {
@@ -142,3 +97,76 @@ func TestClaimsFromContext_WrongType(t *testing.T) {
 		t.Errorf("wrong type should return nil, false: %v, %v", claims, ok)
 	}
 }
+
+func TestInitJWT_AccessTokenTTL(t *testing.T) {
+	InitJWT(&config.Config{JWTSecret: testJWTSecret, AccessTokenTTLMinutes: 5})
+	if got := AccessTokenTTL(); got != 5*time.Minute {
+		t.Errorf("AccessTokenTTL = %v, want 5m", got)
+	}
+	token, err := GenerateToken(1, "u", "n", 0, false, nil, 0, "sess")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	claims, err := ParseToken(token)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if ttl := claims.ExpiresAt.Sub(claims.IssuedAt.Time); ttl != 5*time.Minute {
+		t.Errorf("token ttl = %v, want 5m", ttl)
+	}
+
+	InitJWT(&config.Config{JWTSecret: testJWTSecret})
+	if got := AccessTokenTTL(); got != defaultAccessTokenTTL {
+		t.Errorf("default AccessTokenTTL = %v, want %v", got, defaultAccessTokenTTL)
+	}
+}
+
+func TestHashToken_Deterministic(t *testing.T) {
+	token, err := RandomToken(32)
+	if err != nil {
+		t.Fatalf("RandomToken: %v", err)
+	}
+	if HashToken(token) != HashToken(token) || HashToken(token) == token {
+		t.Error("HashToken should be deterministic and differ from input")
+	}
+	other, _ := RandomToken(32)
+	if other == token {
+		t.Error("RandomToken should not repeat")
+	}
+}
+
+func TestGenerateQueryToken_BoundToPurpose(t *testing.T) {
+	initTestJWT(t)
+	session := &Claims{UserID: 1, Username: "user1", RoleIDs: []uint{2}, TokenVersion: 3}
+	session.ID = "sess-1"
+	token, err := GenerateQueryToken(session, "/admin/download/files/1")
+	if err != nil {
+		t.Fatalf("GenerateQueryToken: %v", err)
+	}
+
+	claims, err := ParseQueryToken(token, "/admin/download/files/1")
+	if err != nil {
+		t.Fatalf("ParseQueryToken: %v", err)
+	}
+	if claims.UserID != 1 || claims.ID != "sess-1" || claims.TokenVersion != 3 || len(claims.RoleIDs) != 1 {
+		t.Errorf("claims = %+v", claims)
+	}
+	if ttl := claims.ExpiresAt.Sub(claims.IssuedAt.Time); ttl != defaultQueryTokenTTL {
+		t.Errorf("ttl = %v, want %v", ttl, defaultQueryTokenTTL)
+	}
+
+	if _, err := ParseQueryToken(token, "/admin/download/files/2"); err == nil {
+		t.Error("query token should not be valid for another path")
+	}
+	if _, err := ParseToken(token); err == nil {
+		t.Error("query token should not be accepted as session token")
+	}
+}
+
+func TestParseQueryToken_RejectsSessionToken(t *testing.T) {
+	initTestJWT(t)
+	token, _ := GenerateToken(1, "user1", "用户1", 0, false, nil, 0, "sess-1")
+	if _, err := ParseQueryToken(token, "/admin/download/files/1"); err == nil {
+		t.Error("session token should not be accepted as query token")
+	}
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret RFC 6238 附录 B 的 SHA1 测试密钥 "12345678901234567890" 的 base32 编码
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, TOTPCounter(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, _ := TOTPCode(rfc6238Secret, TOTPCounter(now))
+	prev, _ := TOTPCode(rfc6238Secret, TOTPCounter(now)-1)
+	old, _ := TOTPCode(rfc6238Secret, TOTPCounter(now)-3)
+
+	if counter, ok := ValidateTOTP(rfc6238Secret, code, now); !ok || counter != TOTPCounter(now) {
+		t.Errorf("current code: ok=%v counter=%d", ok, counter)
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, prev, now); !ok {
+		t.Error("previous step should be accepted within skew")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, old, now); ok {
+		t.Error("code three steps old should be rejected")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, "12345", now); ok {
+		t.Error("wrong length should be rejected")
+	}
+	if _, ok := ValidateTOTP("not base32!", code, now); ok {
+		t.Error("invalid secret should be rejected")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	s1, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+	s2, _ := GenerateTOTPSecret()
+	if len(s1) != 32 || s1 == s2 {
+		t.Errorf("secrets = %q, %q", s1, s2)
+	}
+	if _, err := TOTPCode(s1, 1); err != nil {
+		t.Errorf("generated secret not usable: %v", err)
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("gadmin", "admin", rfc6238Secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/gadmin:admin?") {
+		t.Errorf("uri = %s", uri)
+	}
+	for _, part := range []string{"secret=" + rfc6238Secret, "issuer=gadmin", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("uri %s missing %s", uri, part)
+		}
+	}
+}